@@ -0,0 +1,440 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+const (
+	PathSegmentClusterID   = "clusterId"
+	PathSegmentOperationID = "operationId"
+
+	adminErrorCodeUnauthorized = "Unauthorized"
+	adminErrorCodeForbidden    = "AuthorizationFailed"
+	adminErrorCodeConflict     = "Conflict"
+)
+
+// AdminConfig configures the admin API. Clients authenticate with a
+// certificate issued by the client CA, and are authorized if the
+// certificate's subject common name is one of Principals.
+type AdminConfig struct {
+	// Address defaults to ":8445", clear of the frontend's port
+	// and the default port of the local ARM simulator.
+	Address      string   `json:"address,omitempty"`
+	CertFile     string   `json:"certFile,omitempty"`
+	KeyFile      string   `json:"keyFile,omitempty"`
+	ClientCAFile string   `json:"clientCAFile,omitempty"`
+	Principals   []string `json:"principals,omitempty"`
+}
+
+// LoadAdminConfig reads an AdminConfig from a JSON file.
+func LoadAdminConfig(path string) (*AdminConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &AdminConfig{Address: ":8445"}
+	if err = json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if config.CertFile == "" || config.KeyFile == "" || config.ClientCAFile == "" {
+		return nil, fmt.Errorf("%s: certFile, keyFile and clientCAFile are required", path)
+	}
+	if len(config.Principals) == 0 {
+		return nil, fmt.Errorf("%s: at least one principal is required", path)
+	}
+	return config, nil
+}
+
+// Listen returns a TLS listener that requires client certificates
+// issued by the configured client CA.
+func (c *AdminConfig) Listen() (net.Listener, error) {
+	certificate, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(c.ClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("%s: no certificates found", c.ClientCAFile)
+	}
+
+	return tls.Listen("tcp4", c.Address, &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+		MinVersion:   tls.VersionTLS12,
+	})
+}
+
+// Admin serves the internal API SREs use to inspect and repair clusters.
+// It is separate from the ARM routes and listens on its own port. Every
+// request is recorded in the audit log with the client's identity.
+type Admin struct {
+	logger     *slog.Logger
+	listener   net.Listener
+	server     http.Server
+	dbClient   DBClient
+	cache      *Cache
+	principals []string
+	done       chan struct{}
+}
+
+// AdminClusterSummary describes a cluster in the list of all clusters.
+type AdminClusterSummary struct {
+	ID                string                `json:"id"`
+	ClusterID         string                `json:"clusterId"`
+	SubscriptionID    string                `json:"subscriptionId"`
+	ProvisioningState arm.ProvisioningState `json:"provisioningState,omitempty"`
+	Hibernated        bool                  `json:"hibernated,omitempty"`
+}
+
+// AdminProvisioningStateRequest forces a cluster's provisioning state.
+type AdminProvisioningStateRequest struct {
+	ProvisioningState arm.ProvisioningState `json:"provisioningState"`
+	Justification     string                `json:"justification"`
+}
+
+// AdminRetryRequest retries a failed operation.
+type AdminRetryRequest struct {
+	Justification string `json:"justification"`
+}
+
+// NewAdmin returns the admin API for the resources in cache and the
+// database. principals are the client certificate common names allowed
+// to use it.
+func NewAdmin(logger *slog.Logger, listener net.Listener, dbClient DBClient, cache *Cache, principals []string) *Admin {
+	a := &Admin{
+		logger:   logger.With("component", "admin"),
+		listener: listener,
+		server: http.Server{
+			ErrorLog: slog.NewLogLogger(logger.Handler(), slog.LevelError),
+		},
+		dbClient:   dbClient,
+		cache:      cache,
+		principals: principals,
+		done:       make(chan struct{}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/clusters", a.ListClusters)
+	mux.HandleFunc("GET /admin/clusters/{"+PathSegmentClusterID+"}", a.GetCluster)
+	mux.HandleFunc("GET /admin/clusters/{"+PathSegmentClusterID+"}/operations", a.ListOperations)
+	mux.HandleFunc("POST /admin/clusters/{"+PathSegmentClusterID+"}/provisioningstate", a.SetProvisioningState)
+	mux.HandleFunc("POST /admin/clusters/{"+PathSegmentClusterID+"}/operations/{"+PathSegmentOperationID+"}/retry", a.RetryOperation)
+
+	a.server.Handler = a.audit(mux)
+
+	return a
+}
+
+func (a *Admin) Run(ctx context.Context, stop <-chan struct{}) {
+	if stop != nil {
+		go func() {
+			<-stop
+			_ = a.server.Shutdown(ctx)
+		}()
+	}
+
+	a.logger.Info(fmt.Sprintf("listening on %s", a.listener.Addr().String()))
+
+	err := a.server.Serve(a.listener)
+	if err != http.ErrServerClosed {
+		a.logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	close(a.done)
+}
+
+func (a *Admin) Join() {
+	<-a.done
+}
+
+// adminAuditKey is the context key for the audit record of a request,
+// which handlers complete with the justification they were given.
+type adminAuditKey struct{}
+
+type adminAuditRecord struct {
+	justification string
+}
+
+// audit authorizes each request by the common name of the client's
+// verified certificate and records it, once handled, in the audit log.
+func (a *Admin) audit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		record := &adminAuditRecord{}
+		logWriter := &logResponseWriter{writer, http.StatusOK}
+
+		var principal string
+		if request.TLS != nil && len(request.TLS.PeerCertificates) > 0 {
+			principal = request.TLS.PeerCertificates[0].Subject.CommonName
+		}
+
+		switch {
+		case principal == "":
+			arm.WriteError(logWriter, http.StatusUnauthorized, adminErrorCodeUnauthorized, "",
+				"A client certificate is required")
+		case !slices.Contains(a.principals, principal):
+			arm.WriteError(logWriter, http.StatusForbidden, adminErrorCodeForbidden, "",
+				"The client '%s' is not authorized to use the admin API", principal)
+		default:
+			ctx := context.WithValue(request.Context(), adminAuditKey{}, record)
+			next.ServeHTTP(logWriter, request.WithContext(ctx))
+		}
+
+		a.logger.Info("admin request",
+			"audit", true,
+			"principal", principal,
+			"remote_addr", request.RemoteAddr,
+			"method", request.Method,
+			"path", request.URL.Path,
+			"justification", record.justification,
+			"status", logWriter.statusCode)
+	})
+}
+
+// justify records the justification for a change in the audit record of
+// the request. It returns false after writing an error if there is none.
+func justify(writer http.ResponseWriter, request *http.Request, justification string) bool {
+	justification = strings.TrimSpace(justification)
+	if justification == "" {
+		arm.WriteError(writer, http.StatusBadRequest, arm.CloudErrorCodeInvalidRequestContent, "justification",
+			"A justification is required")
+		return false
+	}
+	if record, ok := request.Context().Value(adminAuditKey{}).(*adminAuditRecord); ok {
+		record.justification = justification
+	}
+	return true
+}
+
+// clusterDocs returns the documents of every cluster, or if clusterID is
+// not empty, of the cluster with that Cluster Service UID, ordered by
+// resource ID.
+func (a *Admin) clusterDocs(ctx context.Context, clusterID string) ([]*HCPOpenShiftClusterDocument, error) {
+	docs, err := a.dbClient.QueryClusterDocs(ctx, clusterID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cluster documents: %w", err)
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		return strings.ToLower(docs[i].Key) < strings.ToLower(docs[j].Key)
+	})
+	return docs, nil
+}
+
+// clusterDoc returns the document of the cluster with the Cluster Service
+// UID in the request path. It returns nil after writing an error if there
+// is no such cluster.
+func (a *Admin) clusterDoc(writer http.ResponseWriter, request *http.Request) *HCPOpenShiftClusterDocument {
+	clusterID := request.PathValue(PathSegmentClusterID)
+
+	docs, err := a.clusterDocs(request.Context(), clusterID)
+	if err != nil {
+		arm.WriteInternalServerError(writer, a.logger, err)
+		return nil
+	}
+	if len(docs) > 0 {
+		return docs[0]
+	}
+
+	arm.WriteError(writer, http.StatusNotFound, arm.CloudErrorCodeNotFound, "",
+		"No cluster has the cluster ID '%s'", clusterID)
+	return nil
+}
+
+// ListClusters lists the clusters in all subscriptions, or with the
+// clusterId query parameter, the cluster with that Cluster Service UID.
+func (a *Admin) ListClusters(writer http.ResponseWriter, request *http.Request) {
+	docs, err := a.clusterDocs(request.Context(), request.URL.Query().Get(PathSegmentClusterID))
+	if err != nil {
+		arm.WriteInternalServerError(writer, a.logger, err)
+		return
+	}
+
+	summaries := []AdminClusterSummary{}
+	for _, doc := range docs {
+		summary := AdminClusterSummary{
+			ID:             doc.Key,
+			ClusterID:      doc.ClusterID,
+			SubscriptionID: doc.PartitionKey,
+			Hibernated:     doc.Hibernated,
+		}
+		if doc.Properties != nil {
+			summary.ProvisioningState = doc.Properties.ProvisioningState
+		}
+		summaries = append(summaries, summary)
+	}
+
+	arm.WriteJSONResponse(writer, a.logger, http.StatusOK, map[string]any{"value": summaries})
+}
+
+// GetCluster returns the stored document of a cluster.
+func (a *Admin) GetCluster(writer http.ResponseWriter, request *http.Request) {
+	doc := a.clusterDoc(writer, request)
+	if doc == nil {
+		return
+	}
+	arm.WriteJSONResponse(writer, a.logger, http.StatusOK, doc)
+}
+
+// ListOperations returns the stored operation documents
+// of a cluster, in the order they were queued.
+func (a *Admin) ListOperations(writer http.ResponseWriter, request *http.Request) {
+	doc := a.clusterDoc(writer, request)
+	if doc == nil {
+		return
+	}
+
+	operations, err := a.clusterOperations(request.Context(), doc)
+	if err != nil {
+		arm.WriteInternalServerError(writer, a.logger, err)
+		return
+	}
+	if operations == nil {
+		operations = []*OperationDocument{}
+	}
+
+	arm.WriteJSONResponse(writer, a.logger, http.StatusOK, map[string]any{"value": operations})
+}
+
+func (a *Admin) clusterOperations(ctx context.Context, doc *HCPOpenShiftClusterDocument) ([]*OperationDocument, error) {
+	docs, err := a.dbClient.ListOperationDocs(ctx, strings.ToLower(doc.PartitionKey))
+	if err != nil {
+		return nil, err
+	}
+
+	var operations []*OperationDocument
+	for _, operation := range docs {
+		if strings.EqualFold(operation.ExternalID, doc.Key) {
+			operations = append(operations, operation)
+		}
+	}
+	sort.Slice(operations, func(i, j int) bool {
+		return operations[i].StartTime.Before(operations[j].StartTime)
+	})
+	return operations, nil
+}
+
+// SetProvisioningState forces the provisioning state of a cluster,
+// such as to release a cluster stuck in a non-terminal state.
+func (a *Admin) SetProvisioningState(writer http.ResponseWriter, request *http.Request) {
+	var body AdminProvisioningStateRequest
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		arm.WriteCloudError(writer, arm.NewUnmarshalCloudError(err))
+		return
+	}
+	if !justify(writer, request, body.Justification) {
+		return
+	}
+	switch body.ProvisioningState {
+	case arm.ProvisioningStateSucceeded, arm.ProvisioningStateFailed, arm.ProvisioningStateCanceled,
+		arm.ProvisioningStateAccepted, arm.ProvisioningStateDeleting, arm.ProvisioningStateProvisioning, arm.ProvisioningStateUpdating:
+	default:
+		arm.WriteError(writer, http.StatusBadRequest, arm.CloudErrorCodeInvalidParameter, "provisioningState",
+			"Invalid provisioning state '%s'", body.ProvisioningState)
+		return
+	}
+
+	doc := a.clusterDoc(writer, request)
+	if doc == nil {
+		return
+	}
+	if doc.Properties == nil {
+		arm.WriteError(writer, http.StatusConflict, adminErrorCodeConflict, "",
+			"The document of cluster '%s' has no cluster state", doc.ClusterID)
+		return
+	}
+
+	a.logger.Warn(fmt.Sprintf("Forcing provisioning state of %s from %s to %s", doc.Key, doc.Properties.ProvisioningState, body.ProvisioningState))
+	doc.Properties.ProvisioningState = body.ProvisioningState
+	if err := a.dbClient.SetClusterDoc(request.Context(), doc); err != nil {
+		arm.WriteInternalServerError(writer, a.logger, fmt.Errorf("failed to store document for %s: %w", doc.Key, err))
+		return
+	}
+	a.cache.SetCluster(strings.ToLower(doc.Key), doc.Cluster())
+
+	arm.WriteJSONResponse(writer, a.logger, http.StatusOK, doc)
+}
+
+// RetryOperation queues a failed operation to run again from its first
+// attempt. An operation superseded by a newer one cannot be retried.
+func (a *Admin) RetryOperation(writer http.ResponseWriter, request *http.Request) {
+	var body AdminRetryRequest
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		arm.WriteCloudError(writer, arm.NewUnmarshalCloudError(err))
+		return
+	}
+	if !justify(writer, request, body.Justification) {
+		return
+	}
+
+	doc := a.clusterDoc(writer, request)
+	if doc == nil {
+		return
+	}
+	operations, err := a.clusterOperations(request.Context(), doc)
+	if err != nil {
+		arm.WriteInternalServerError(writer, a.logger, err)
+		return
+	}
+
+	operationID := request.PathValue(PathSegmentOperationID)
+	index := slices.IndexFunc(operations, func(operation *OperationDocument) bool {
+		return operation.ID == operationID
+	})
+	if index < 0 {
+		arm.WriteError(writer, http.StatusNotFound, arm.CloudErrorCodeNotFound, "",
+			"Cluster '%s' has no operation '%s'", doc.ClusterID, operationID)
+		return
+	}
+
+	operation := operations[index]
+	switch {
+	case operation.Status != arm.ProvisioningStateFailed:
+		arm.WriteError(writer, http.StatusConflict, adminErrorCodeConflict, "",
+			"Operation '%s' is %s, only failed operations can be retried", operationID, operation.Status)
+		return
+	case index < len(operations)-1:
+		arm.WriteError(writer, http.StatusConflict, adminErrorCodeConflict, "",
+			"Operation '%s' was superseded by operation '%s'", operationID, operations[len(operations)-1].ID)
+		return
+	}
+
+	operation.Status = arm.ProvisioningStateAccepted
+	operation.LastTransitionTime = time.Now()
+	operation.NotBefore = time.Time{}
+	operation.Attempts = 0
+	operation.Error = nil
+	operation, err = a.dbClient.SetOperationDoc(request.Context(), operation)
+	if errors.Is(err, ErrDocumentConflict) {
+		arm.WriteError(writer, http.StatusConflict, adminErrorCodeConflict, "",
+			"Operation '%s' changed while being retried", operationID)
+		return
+	} else if err != nil {
+		arm.WriteInternalServerError(writer, a.logger, fmt.Errorf("failed to store operation %s: %w", operationID, err))
+		return
+	}
+
+	arm.WriteJSONResponse(writer, a.logger, http.StatusOK, operation)
+}
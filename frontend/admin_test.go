@@ -0,0 +1,296 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+const testAdminPrincipal = "sre.example.com"
+
+// testAdmin is the admin API of a frontend with a registered
+// subscription and a cluster, recording its audit log.
+type testAdmin struct {
+	t         *testing.T
+	replica   *testReplica
+	admin     *Admin
+	auditLog  *bytes.Buffer
+	clusterID string
+}
+
+func newTestAdmin(t *testing.T) *testAdmin {
+	db := NewMemoryDBClient()
+	replica := newTestReplica(t, db)
+
+	version, _ := api.Lookup("2024-06-10-preview")
+	clusterBody, err := json.Marshal(version.NewHCPOpenShiftCluster(newTestValidCluster()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replica.expect(http.MethodPut, "/subscriptions/"+testReplicaSubscriptionID, []byte(`{"state": "Registered"}`), http.StatusOK)
+	replica.expect(http.MethodPut, testReplicaClusterPath+"?"+APIVersionKey+"=2024-06-10-preview", clusterBody, http.StatusCreated)
+
+	doc, _, err := db.GetClusterDoc(context.Background(), testReplicaClusterPath, testReplicaSubscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	auditLog := &bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(auditLog, nil))
+	return &testAdmin{
+		t:         t,
+		replica:   replica,
+		admin:     NewAdmin(logger, nil, db, replica.frontend.cache, []string{testAdminPrincipal}),
+		auditLog:  auditLog,
+		clusterID: doc.ClusterID,
+	}
+}
+
+// do sends a request to the admin API as principal,
+// or without a client certificate if principal is empty.
+func (a *testAdmin) do(principal, method, url string, body any) *httptest.ResponseRecorder {
+	a.t.Helper()
+
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			a.t.Fatal(err)
+		}
+	}
+	request := httptest.NewRequest(method, url, bytes.NewReader(data))
+	if principal != "" {
+		request.TLS = &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: principal}}},
+		}
+	}
+	writer := httptest.NewRecorder()
+	a.admin.server.Handler.ServeHTTP(writer, request)
+	return writer
+}
+
+func (a *testAdmin) expect(method, url string, body any, expectStatus int) *httptest.ResponseRecorder {
+	a.t.Helper()
+
+	writer := a.do(testAdminPrincipal, method, url, body)
+	if writer.Code != expectStatus {
+		a.t.Fatalf("%s %s: expected status %d, got %d: %s", method, url, expectStatus, writer.Code, writer.Body.String())
+	}
+	return writer
+}
+
+// auditRecords returns the audit records logged so far.
+func (a *testAdmin) auditRecords() []map[string]any {
+	a.t.Helper()
+
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(a.auditLog.String()), "\n") {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			a.t.Fatal(err)
+		}
+		if record["audit"] == true {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+func TestAdminAuthorization(t *testing.T) {
+	a := newTestAdmin(t)
+
+	tests := []struct {
+		principal    string
+		expectStatus int
+	}{
+		{"", http.StatusUnauthorized},
+		{"intruder.example.com", http.StatusForbidden},
+		{testAdminPrincipal, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		if writer := a.do(tt.principal, http.MethodGet, "/admin/clusters", nil); writer.Code != tt.expectStatus {
+			t.Errorf("Principal %q: expected status %d, got %d: %s", tt.principal, tt.expectStatus, writer.Code, writer.Body.String())
+		}
+	}
+
+	records := a.auditRecords()
+	if len(records) != len(tests) {
+		t.Fatalf("Expected %d audit records, got %d", len(tests), len(records))
+	}
+	for i, tt := range tests {
+		if records[i]["principal"] != tt.principal || records[i]["status"] != float64(tt.expectStatus) {
+			t.Errorf("Expected an audit record of %q with status %d, got %v", tt.principal, tt.expectStatus, records[i])
+		}
+	}
+}
+
+func TestAdminClusters(t *testing.T) {
+	a := newTestAdmin(t)
+
+	var list struct {
+		Value []AdminClusterSummary `json:"value"`
+	}
+	writer := a.expect(http.MethodGet, "/admin/clusters", nil, http.StatusOK)
+	if err := json.Unmarshal(writer.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Value) != 1 || list.Value[0].ID != testReplicaClusterPath || list.Value[0].ClusterID != a.clusterID {
+		t.Fatalf("Expected the test cluster, got %+v", list.Value)
+	}
+
+	writer = a.expect(http.MethodGet, "/admin/clusters?clusterId=unknown", nil, http.StatusOK)
+	if err := json.Unmarshal(writer.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Value) != 0 {
+		t.Errorf("Expected no clusters with an unknown cluster ID, got %+v", list.Value)
+	}
+
+	var doc HCPOpenShiftClusterDocument
+	writer = a.expect(http.MethodGet, "/admin/clusters/"+a.clusterID, nil, http.StatusOK)
+	if err := json.Unmarshal(writer.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Key != testReplicaClusterPath || doc.ETag == "" {
+		t.Errorf("Expected the stored cluster document, got %+v", doc)
+	}
+
+	a.expect(http.MethodGet, "/admin/clusters/unknown", nil, http.StatusNotFound)
+}
+
+func TestAdminSetProvisioningState(t *testing.T) {
+	a := newTestAdmin(t)
+	url := "/admin/clusters/" + a.clusterID + "/provisioningstate"
+
+	a.expect(http.MethodPost, url, AdminProvisioningStateRequest{ProvisioningState: arm.ProvisioningStateFailed}, http.StatusBadRequest)
+	a.expect(http.MethodPost, url, AdminProvisioningStateRequest{ProvisioningState: "Stuck", Justification: "ICM 1"}, http.StatusBadRequest)
+	a.expect(http.MethodPost, url, AdminProvisioningStateRequest{ProvisioningState: arm.ProvisioningStateFailed, Justification: "ICM 1"}, http.StatusOK)
+
+	cluster, found := a.replica.frontend.cache.GetCluster(strings.ToLower(testReplicaClusterPath))
+	if !found || cluster.Properties.ProvisioningState != arm.ProvisioningStateFailed {
+		t.Errorf("Expected the cached cluster to be %s, got %v", arm.ProvisioningStateFailed, cluster)
+	}
+
+	records := a.auditRecords()
+	if last := records[len(records)-1]; last["justification"] != "ICM 1" {
+		t.Errorf("Expected the justification in the audit record, got %v", last)
+	}
+}
+
+func TestAdminRetryOperation(t *testing.T) {
+	a := newTestAdmin(t)
+	ctx := context.Background()
+	clusterKey := strings.ToLower(testReplicaClusterPath)
+	justification := AdminRetryRequest{Justification: "ICM 2"}
+
+	failed, err := enqueueOperation(ctx, a.admin.dbClient, testReplicaSubscriptionID, clusterKey, OperationRequestHibernate, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed.Status = arm.ProvisioningStateFailed
+	failed.Attempts = operationMaxAttempts
+	failed.Error = &arm.CloudErrorBody{Code: arm.CloudErrorCodeInternalServerError, Message: "database unavailable"}
+	if failed, err = a.admin.dbClient.SetOperationDoc(ctx, failed); err != nil {
+		t.Fatal(err)
+	}
+
+	operationsURL := "/admin/clusters/" + a.clusterID + "/operations"
+	a.expect(http.MethodPost, operationsURL+"/"+failed.ID+"/retry", AdminRetryRequest{}, http.StatusBadRequest)
+	a.expect(http.MethodPost, operationsURL+"/unknown/retry", justification, http.StatusNotFound)
+
+	var retried OperationDocument
+	writer := a.expect(http.MethodPost, operationsURL+"/"+failed.ID+"/retry", justification, http.StatusOK)
+	if err = json.Unmarshal(writer.Body.Bytes(), &retried); err != nil {
+		t.Fatal(err)
+	}
+	if retried.Status != arm.ProvisioningStateAccepted || retried.Attempts != 0 || retried.Error != nil {
+		t.Errorf("Expected the operation to be accepted with no attempts, got %+v", retried)
+	}
+
+	// Only failed operations may be retried.
+	a.expect(http.MethodPost, operationsURL+"/"+failed.ID+"/retry", justification, http.StatusConflict)
+
+	var list struct {
+		Value []*OperationDocument `json:"value"`
+	}
+	writer = a.expect(http.MethodGet, operationsURL, nil, http.StatusOK)
+	if err = json.Unmarshal(writer.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Value) != 1 || list.Value[0].ID != failed.ID {
+		t.Errorf("Expected the retried operation, got %+v", list.Value)
+	}
+
+	// A failed operation superseded by a newer one may not be retried.
+	retried.Status = arm.ProvisioningStateFailed
+	if _, err = a.admin.dbClient.SetOperationDoc(ctx, &retried); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = enqueueOperation(ctx, a.admin.dbClient, testReplicaSubscriptionID, clusterKey, OperationRequestResume, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	writer = a.expect(http.MethodPost, operationsURL+"/"+failed.ID+"/retry", justification, http.StatusConflict)
+	if !strings.Contains(writer.Body.String(), "superseded") {
+		t.Errorf("Expected the operation to be reported as superseded, got %s", writer.Body.String())
+	}
+}
+
+func TestLoadAdminConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name        string
+		config      string
+		expectError bool
+	}{
+		{
+			name:   "Complete",
+			config: `{"certFile": "tls.crt", "keyFile": "tls.key", "clientCAFile": "ca.crt", "principals": ["sre"]}`,
+		},
+		{
+			name:        "Missing client CA",
+			config:      `{"certFile": "tls.crt", "keyFile": "tls.key", "principals": ["sre"]}`,
+			expectError: true,
+		},
+		{
+			name:        "Missing principals",
+			config:      `{"certFile": "tls.crt", "keyFile": "tls.key", "clientCAFile": "ca.crt"}`,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(dir, "admin.json")
+		if err := os.WriteFile(path, []byte(tt.config), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		config, err := LoadAdminConfig(path)
+		if tt.expectError {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.name)
+			}
+		} else if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		} else if config.Address != ":8445" {
+			t.Errorf("%s: expected the default address, got %q", tt.name, config.Address)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/apitest"
+)
+
+func TestAPIVersionsClusterNormalize(t *testing.T) {
+	for _, version := range api.Versions() {
+		t.Run(version.String(), func(t *testing.T) {
+			if err := apitest.FuzzClusterNormalize(version, apitest.DefaultIterations); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestAPIVersionsClusterRoundTrip(t *testing.T) {
+	for _, version := range api.Versions() {
+		t.Run(version.String(), func(t *testing.T) {
+			if err := apitest.RoundTripCluster(version, apitest.DefaultIterations); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestAPIVersionsExternalAuthRoundTrip(t *testing.T) {
+	for _, version := range api.Versions() {
+		t.Run(version.String(), func(t *testing.T) {
+			if err := apitest.RoundTripExternalAuth(version, apitest.DefaultIterations); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestAPIVersionsClusterPreserve(t *testing.T) {
+	for _, version := range api.Versions() {
+		t.Run(version.String(), func(t *testing.T) {
+			if err := apitest.PreserveCluster(version, apitest.DefaultIterations); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// fieldPaths appends the dot-separated paths of the fields of t,
+// following the same rules as api.NewStructTagMap.
+func fieldPaths(paths []string, t reflect.Type, path string) []string {
+	switch t.Kind() {
+	case reflect.Map, reflect.Pointer, reflect.Slice:
+		return fieldPaths(paths, t.Elem(), path)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.Anonymous {
+				paths = fieldPaths(paths, field.Type, path)
+				continue
+			}
+			subpath := field.Name
+			if path != "" {
+				subpath = path + "." + field.Name
+			}
+			paths = fieldPaths(append(paths, subpath), field.Type, subpath)
+		}
+	}
+	return paths
+}
+
+func TestAPIVersionsClusterStructTagMap(t *testing.T) {
+	for _, version := range api.Versions() {
+		t.Run(version.String(), func(t *testing.T) {
+			structTagMap := version.ClusterStructTagMap()
+			versioned := reflect.TypeOf(version.NewHCPOpenShiftCluster(nil))
+			for _, path := range fieldPaths(nil, versioned, "") {
+				if _, ok := structTagMap[path]; ok {
+					continue
+				}
+				// A field named like an internal field but for case
+				// would silently get its parent's visibility.
+				for key := range structTagMap {
+					if strings.EqualFold(key, path) {
+						t.Errorf("Field %s has no struct tag map entry for %s", path, key)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v5"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v4"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+var (
+	// ErrAzureResourceNotFound is returned by an AzureResourceClient
+	// when a referenced resource does not exist.
+	ErrAzureResourceNotFound = errors.New("resource not found")
+
+	// ErrAzureResourceAccessDenied is returned by an AzureResourceClient
+	// when the resource provider is not authorized to read a referenced
+	// resource.
+	ErrAzureResourceAccessDenied = errors.New("access denied")
+)
+
+// AzureResource holds the properties common to all resources
+// referenced by clusters and node pools.
+type AzureResource struct {
+	ID       string
+	Location string
+}
+
+// AzureSubnet holds the subnet properties relevant to validation.
+// A subnet has no location of its own so Location is taken from
+// the subnet's virtual network.
+type AzureSubnet struct {
+	AzureResource
+	// Delegations lists the service names the subnet is delegated to.
+	Delegations []string
+	// NetworkSecurityGroupID is the ID of the network security
+	// group associated with the subnet, or empty if none.
+	NetworkSecurityGroupID string
+}
+
+// AzureResourceClient looks up Azure resources referenced by cluster
+// properties. Methods return an error wrapping either
+// ErrAzureResourceNotFound or ErrAzureResourceAccessDenied when the
+// resource cannot be read for those reasons.
+type AzureResourceClient interface {
+	GetSubnet(ctx context.Context, id *arm.ResourceID) (*AzureSubnet, error)
+	GetNetworkSecurityGroup(ctx context.Context, id *arm.ResourceID) (*AzureResource, error)
+	GetDiskEncryptionSet(ctx context.Context, id *arm.ResourceID) (*AzureResource, error)
+}
+
+// azureResourceClient is an AzureResourceClient using the
+// Azure Resource Manager Network and Compute APIs.
+type azureResourceClient struct {
+	credential azcore.TokenCredential
+}
+
+// NewAzureResourceClient returns an AzureResourceClient that
+// authenticates to Azure Resource Manager with credential.
+func NewAzureResourceClient(credential azcore.TokenCredential) AzureResourceClient {
+	return &azureResourceClient{credential: credential}
+}
+
+// convertAzureError maps SDK response errors onto the
+// sentinel errors documented for AzureResourceClient.
+func convertAzureError(id *arm.ResourceID, err error) error {
+	var responseError *azcore.ResponseError
+	if errors.As(err, &responseError) {
+		switch responseError.StatusCode {
+		case http.StatusNotFound:
+			return fmt.Errorf("%s: %w", id, ErrAzureResourceNotFound)
+		case http.StatusForbidden:
+			return fmt.Errorf("%s: %w", id, ErrAzureResourceAccessDenied)
+		}
+	}
+	return fmt.Errorf("%s: %w", id, err)
+}
+
+func (c *azureResourceClient) GetSubnet(ctx context.Context, id *arm.ResourceID) (*AzureSubnet, error) {
+	vnetID := id.Parent()
+
+	vnetClient, err := armnetwork.NewVirtualNetworksClient(id.SubscriptionID(), c.credential, nil)
+	if err != nil {
+		return nil, err
+	}
+	vnet, err := vnetClient.Get(ctx, vnetID.ResourceGroupName(), vnetID.Name(), nil)
+	if err != nil {
+		return nil, convertAzureError(vnetID, err)
+	}
+
+	subnetClient, err := armnetwork.NewSubnetsClient(id.SubscriptionID(), c.credential, nil)
+	if err != nil {
+		return nil, err
+	}
+	subnet, err := subnetClient.Get(ctx, id.ResourceGroupName(), vnetID.Name(), id.Name(), nil)
+	if err != nil {
+		return nil, convertAzureError(id, err)
+	}
+
+	result := &AzureSubnet{
+		AzureResource: AzureResource{
+			ID:       id.String(),
+			Location: api.Deref(vnet.Location),
+		},
+	}
+	if subnet.Properties != nil {
+		for _, delegation := range subnet.Properties.Delegations {
+			if delegation.Properties != nil {
+				result.Delegations = append(result.Delegations, api.Deref(delegation.Properties.ServiceName))
+			}
+		}
+		if subnet.Properties.NetworkSecurityGroup != nil {
+			result.NetworkSecurityGroupID = api.Deref(subnet.Properties.NetworkSecurityGroup.ID)
+		}
+	}
+	return result, nil
+}
+
+func (c *azureResourceClient) GetNetworkSecurityGroup(ctx context.Context, id *arm.ResourceID) (*AzureResource, error) {
+	client, err := armnetwork.NewSecurityGroupsClient(id.SubscriptionID(), c.credential, nil)
+	if err != nil {
+		return nil, err
+	}
+	nsg, err := client.Get(ctx, id.ResourceGroupName(), id.Name(), nil)
+	if err != nil {
+		return nil, convertAzureError(id, err)
+	}
+	return &AzureResource{ID: id.String(), Location: api.Deref(nsg.Location)}, nil
+}
+
+func (c *azureResourceClient) GetDiskEncryptionSet(ctx context.Context, id *arm.ResourceID) (*AzureResource, error) {
+	client, err := armcompute.NewDiskEncryptionSetsClient(id.SubscriptionID(), c.credential, nil)
+	if err != nil {
+		return nil, err
+	}
+	des, err := client.Get(ctx, id.ResourceGroupName(), id.Name(), nil)
+	if err != nil {
+		return nil, convertAzureError(id, err)
+	}
+	return &AzureResource{ID: id.String(), Location: api.Deref(des.Location)}, nil
+}
@@ -4,12 +4,19 @@ package main
 // Licensed under the Apache License 2.0.
 
 import (
+	"maps"
+	"sync"
+
 	"github.com/Azure/ARO-HCP/internal/api"
 	"github.com/Azure/ARO-HCP/internal/api/arm"
 )
 
+// Cache holds the resources the frontend serves. It is safe for
+// concurrent use by request handlers and the change feed processor.
 type Cache struct {
+	lock         sync.RWMutex
 	cluster      map[string]*api.HCPOpenShiftCluster
+	nodePool     map[string]*api.HCPOpenShiftClusterNodePool
 	subscription map[string]*arm.Subscription
 }
 
@@ -17,32 +24,105 @@ type Cache struct {
 func NewCache() *Cache {
 	return &Cache{
 		cluster:      make(map[string]*api.HCPOpenShiftCluster),
+		nodePool:     make(map[string]*api.HCPOpenShiftClusterNodePool),
 		subscription: make(map[string]*arm.Subscription),
 	}
 }
 
+// Clone returns a copy of the cache that can be changed
+// without affecting the original. Resources are not copied.
+func (c *Cache) Clone() *Cache {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return &Cache{
+		cluster:      maps.Clone(c.cluster),
+		nodePool:     maps.Clone(c.nodePool),
+		subscription: maps.Clone(c.subscription),
+	}
+}
+
 func (c *Cache) GetCluster(id string) (*api.HCPOpenShiftCluster, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
 	cluster, found := c.cluster[id]
 	return cluster, found
 }
 
 func (c *Cache) SetCluster(id string, cluster *api.HCPOpenShiftCluster) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	c.cluster[id] = cluster
 }
 
+// DeleteCluster removes a cluster and its node pools. Node pools are
+// only held in the cache, so every replica removes them when it learns
+// the cluster was deleted.
 func (c *Cache) DeleteCluster(id string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	delete(c.cluster, id)
+	for nodePoolID := range c.nodePool {
+		if nodePoolClusterID(nodePoolID) == id {
+			delete(c.nodePool, nodePoolID)
+		}
+	}
+}
+
+// Clusters returns a snapshot of the cached clusters by cache key.
+func (c *Cache) Clusters() map[string]*api.HCPOpenShiftCluster {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return maps.Clone(c.cluster)
+}
+
+func (c *Cache) GetNodePool(id string) (*api.HCPOpenShiftClusterNodePool, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	nodePool, found := c.nodePool[id]
+	return nodePool, found
+}
+
+func (c *Cache) SetNodePool(id string, nodePool *api.HCPOpenShiftClusterNodePool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.nodePool[id] = nodePool
+}
+
+func (c *Cache) DeleteNodePool(id string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.nodePool, id)
+}
+
+// NodePools returns a snapshot of the cached node pools by cache key.
+func (c *Cache) NodePools() map[string]*api.HCPOpenShiftClusterNodePool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return maps.Clone(c.nodePool)
 }
 
 func (c *Cache) GetSubscription(id string) (*arm.Subscription, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
 	subscription, found := c.subscription[id]
 	return subscription, found
 }
 
 func (c *Cache) SetSubscription(id string, subscription *arm.Subscription) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	c.subscription[id] = subscription
 }
 
 func (c *Cache) DeleteSubscription(id string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	delete(c.subscription, id)
 }
+
+// Subscriptions returns a snapshot of the cached subscriptions by ID.
+func (c *Cache) Subscriptions() map[string]*arm.Subscription {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return maps.Clone(c.subscription)
+}
@@ -0,0 +1,270 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/Azure/ARO-HCP/internal/metrics"
+)
+
+const changeFeedPollInterval = 5 * time.Second
+
+// changeFeedContainers are the containers whose changes the
+// change feed processor applies to the cache.
+var changeFeedContainers = []string{clustersContainer, subscriptionsContainer}
+
+// ChangeFeed is the interface the change feed processor uses to read the
+// documents changed in a container.
+type ChangeFeed interface {
+	// ReadChangeFeed returns the latest version of the documents in a
+	// container changed since continuation, oldest change first. An
+	// empty continuation reads from the beginning of the container.
+	ReadChangeFeed(ctx context.Context, container, continuation string) (*ChangeFeedPage, error)
+
+	// ReadDocuments returns the latest version of every document in a
+	// container.
+	ReadDocuments(ctx context.Context, container string) ([]json.RawMessage, error)
+}
+
+// ChangeFeedLeases is the interface the change feed processor uses to
+// checkpoint its change feed positions.
+type ChangeFeedLeases interface {
+	GetLeaseDoc(ctx context.Context, name string) (*LeaseDocument, bool, error)
+	SetLeaseDoc(ctx context.Context, doc *LeaseDocument) error
+}
+
+// ChangeFeedPage is a page of a container's change feed.
+type ChangeFeedPage struct {
+	Documents    []json.RawMessage
+	Continuation string
+}
+
+// ChangeFeedProcessor keeps a frontend replica's cache coherent with the
+// database by applying the changes every replica writes to it.
+//
+// Every replica has its own cache and so must see every change. Each
+// replica therefore checkpoints its change feed positions in a lease of
+// its own, named after the replica, rather than sharing leases with the
+// other replicas.
+type ChangeFeedProcessor struct {
+	logger  *slog.Logger
+	feed    ChangeFeed
+	leases  ChangeFeedLeases
+	lease   string
+	metrics metrics.Emitter
+
+	pollInterval time.Duration
+	now          func() time.Time
+
+	// Change feed positions, by container. Only
+	// the goroutine running the processor uses them.
+	continuations map[string]string
+}
+
+// NewChangeFeedProcessor returns a change feed processor for the cache
+// of one frontend replica, which checkpoints in the named lease.
+func NewChangeFeedProcessor(logger *slog.Logger, feed ChangeFeed, leases ChangeFeedLeases, lease string, emitter metrics.Emitter) *ChangeFeedProcessor {
+	return &ChangeFeedProcessor{
+		logger:        logger,
+		feed:          feed,
+		leases:        leases,
+		lease:         lease,
+		metrics:       emitter,
+		pollInterval:  changeFeedPollInterval,
+		now:           time.Now,
+		continuations: make(map[string]string),
+	}
+}
+
+// Run warms the cache, retrying until it succeeds, then calls ready and
+// applies changes to the cache until stop is closed.
+func (p *ChangeFeedProcessor) Run(ctx context.Context, cache *Cache, ready func(), stop <-chan struct{}) {
+	ticker := time.NewTicker(p.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		err := p.Warm(ctx, cache)
+		if err == nil {
+			break
+		}
+		p.logger.Error(fmt.Sprintf("Warming the cache failed: %v", err))
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+
+	p.logger.Info("Cache warmed from the change feed")
+	ready()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			p.Poll(ctx, cache)
+		}
+	}
+}
+
+// Warm fills the cache with every document in each container and
+// checkpoints the change feed positions reached.
+//
+// Without a checkpoint, each change feed is read from the beginning, which
+// gives the latest version of every document. With one, the cache is
+// filled with the current documents and the change feed resumes from the
+// checkpoint. Changes since the checkpoint are then applied a second time,
+// which leaves the cache unchanged since the change feed also gives the
+// latest version of each document.
+func (p *ChangeFeedProcessor) Warm(ctx context.Context, cache *Cache) error {
+	lease, found, err := p.leases.GetLeaseDoc(ctx, p.lease)
+	if err != nil {
+		return err
+	}
+
+	for _, container := range changeFeedContainers {
+		var continuation string
+		if found {
+			continuation = lease.Continuations[container]
+		}
+		if continuation != "" {
+			documents, err := p.feed.ReadDocuments(ctx, container)
+			if err != nil {
+				return err
+			}
+			for _, data := range documents {
+				if _, err := p.apply(cache, container, data); err != nil {
+					p.logger.Error(fmt.Sprintf("Applying a %s document failed: %v", container, err))
+				}
+			}
+		}
+
+		continuation, _, err = p.drain(ctx, cache, container, continuation)
+		if err != nil {
+			return err
+		}
+		p.continuations[container] = continuation
+	}
+
+	p.checkpoint(ctx)
+	return nil
+}
+
+// Poll applies the changes made to each container since the last poll
+// and emits how long the oldest change waited to be applied.
+func (p *ChangeFeedProcessor) Poll(ctx context.Context, cache *Cache) {
+	for _, container := range changeFeedContainers {
+		if err := p.poll(ctx, cache, container); err != nil {
+			p.logger.Error(fmt.Sprintf("Reading the %s change feed failed: %v", container, err))
+		}
+	}
+	p.checkpoint(ctx)
+}
+
+// checkpoint stores the change feed positions in the replica's lease.
+// Failing to is not fatal: a replica that restarts from an older
+// checkpoint applies more changes a second time.
+func (p *ChangeFeedProcessor) checkpoint(ctx context.Context) {
+	err := p.leases.SetLeaseDoc(ctx, &LeaseDocument{
+		ID:            p.lease,
+		PartitionKey:  p.lease,
+		Continuations: maps.Clone(p.continuations),
+		TTL:           leaseTTL,
+	})
+	if err != nil {
+		p.logger.Error(fmt.Sprintf("Checkpointing the change feeds in lease %s failed: %v", p.lease, err))
+	}
+}
+
+func (p *ChangeFeedProcessor) poll(ctx context.Context, cache *Cache, container string) error {
+	continuation, oldest, err := p.drain(ctx, cache, container, p.continuations[container])
+	if err != nil {
+		return err
+	}
+	p.continuations[container] = continuation
+
+	var lag time.Duration
+	if !oldest.IsZero() {
+		lag = max(p.now().Sub(oldest), 0)
+	}
+	p.metrics.EmitGauge("frontend_changefeed_lag_seconds", lag.Seconds(), map[string]string{
+		"container": container,
+	})
+
+	return nil
+}
+
+// drain applies changes from continuation until the change feed has no
+// more, returning the continuation to read further changes from and the
+// time of the oldest change applied.
+func (p *ChangeFeedProcessor) drain(ctx context.Context, cache *Cache, container, continuation string) (string, time.Time, error) {
+	var oldest time.Time
+	for {
+		page, err := p.feed.ReadChangeFeed(ctx, container, continuation)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		if page.Continuation != "" {
+			continuation = page.Continuation
+		}
+		if len(page.Documents) == 0 {
+			return continuation, oldest, nil
+		}
+
+		for _, data := range page.Documents {
+			timestamp, err := p.apply(cache, container, data)
+			if err != nil {
+				// Skip the document rather than stall the change feed.
+				p.logger.Error(fmt.Sprintf("Applying a %s change failed: %v", container, err))
+				continue
+			}
+			if oldest.IsZero() || timestamp.Before(oldest) {
+				oldest = timestamp
+			}
+		}
+	}
+}
+
+// apply updates the cache from a changed document and returns the time
+// the document was changed.
+func (p *ChangeFeedProcessor) apply(cache *Cache, container string, data json.RawMessage) (time.Time, error) {
+	switch container {
+	case clustersContainer:
+		var doc HCPOpenShiftClusterDocument
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return time.Time{}, err
+		}
+		key := strings.ToLower(doc.Key)
+		if doc.Deleted {
+			cache.DeleteCluster(key)
+		} else if cluster := doc.Cluster(); cluster != nil {
+			cache.SetCluster(key, cluster)
+		} else {
+			p.logger.Warn(fmt.Sprintf("Document for %s has no cluster state", doc.Key))
+		}
+		return time.Unix(int64(doc.Timestamp), 0), nil
+
+	case subscriptionsContainer:
+		var doc SubscriptionDocument
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return time.Time{}, err
+		}
+		if doc.Subscription != nil {
+			cache.SetSubscription(doc.ID, doc.Subscription)
+		}
+		return time.Unix(int64(doc.Timestamp), 0), nil
+
+	default:
+		return time.Time{}, fmt.Errorf("unexpected container %s", container)
+	}
+}
@@ -0,0 +1,356 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+const (
+	testReplicaSubscriptionID = "00000000-0000-0000-0000-000000000000"
+	testReplicaClusterPath    = "/subscriptions/" + testReplicaSubscriptionID + "/resourceGroups/rg/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/Cluster"
+)
+
+// gaugeRecorder records the last value of each gauge emitted.
+type gaugeRecorder struct {
+	noopEmitter
+	lock   sync.Mutex
+	gauges map[string]float64
+}
+
+func (r *gaugeRecorder) EmitGauge(name string, value float64, labels map[string]string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.gauges == nil {
+		r.gauges = make(map[string]float64)
+	}
+	r.gauges[name+"/"+labels["container"]] = value
+}
+
+// testReplica is a frontend replica with its own cache, sharing a
+// database with other replicas.
+type testReplica struct {
+	t         *testing.T
+	logger    *slog.Logger
+	frontend  *Frontend
+	processor *ChangeFeedProcessor
+}
+
+func newTestReplica(t *testing.T, db *MemoryDBClient) *testReplica {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	processor := NewChangeFeedProcessor(logger, db, db, NewUID(), &gaugeRecorder{})
+	return &testReplica{
+		t:      t,
+		logger: logger,
+		frontend: NewFrontend(logger, nil, noopEmitter{}, db, FrontendOptions{
+			AzureResources: newTestAzureResourceClient(),
+			ChangeFeed:     processor,
+		}),
+		processor: processor,
+	}
+}
+
+func (r *testReplica) do(method, url string, body []byte) *httptest.ResponseRecorder {
+	r.t.Helper()
+
+	request := httptest.NewRequest(method, url, bytes.NewReader(body))
+	request = request.WithContext(ContextWithLogger(request.Context(), r.logger))
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	writer := httptest.NewRecorder()
+	r.frontend.server.Handler.ServeHTTP(writer, request)
+	return writer
+}
+
+func (r *testReplica) expect(method, url string, body []byte, expectStatus int) {
+	r.t.Helper()
+
+	if writer := r.do(method, url, body); writer.Code != expectStatus {
+		r.t.Fatalf("%s %s: expected status %d, got %d: %s", method, url, expectStatus, writer.Code, writer.Body.String())
+	}
+}
+
+func TestChangeFeedCoherence(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryDBClient()
+
+	writer := newTestReplica(t, db)
+	reader := newTestReplica(t, db)
+	for _, replica := range []*testReplica{writer, reader} {
+		if err := replica.processor.Warm(ctx, replica.frontend.cache); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	version, _ := api.Lookup("2024-06-10-preview")
+	clusterBody, err := json.Marshal(version.NewHCPOpenShiftCluster(newTestValidCluster()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clusterURL := testReplicaClusterPath + "?" + APIVersionKey + "=2024-06-10-preview"
+
+	writer.expect(http.MethodPut, "/subscriptions/"+testReplicaSubscriptionID, []byte(`{"state": "Registered"}`), http.StatusOK)
+	writer.expect(http.MethodPut, clusterURL, clusterBody, http.StatusCreated)
+
+	// Without the change feed, the reader knows of neither.
+	reader.expect(http.MethodGet, clusterURL, nil, http.StatusBadRequest)
+
+	reader.processor.Poll(ctx, reader.frontend.cache)
+
+	response := reader.do(http.MethodGet, clusterURL, nil)
+	if response.Code != http.StatusOK {
+		t.Fatalf("Expected status %d after reading the change feed, got %d: %s", http.StatusOK, response.Code, response.Body.String())
+	}
+	var cluster map[string]any
+	if err = json.Unmarshal(response.Body.Bytes(), &cluster); err != nil {
+		t.Fatal(err)
+	}
+	if cluster["id"] != testReplicaClusterPath || cluster["name"] != "Cluster" {
+		t.Errorf("Expected the cluster's ID and name to keep their casing, got '%v' and '%v'", cluster["id"], cluster["name"])
+	}
+
+	// Updates through the reader reach the writer.
+	reader.expect(http.MethodPatch, clusterURL, []byte(`{"tags": {"env": "test"}}`), http.StatusOK)
+	writer.processor.Poll(ctx, writer.frontend.cache)
+	if stored, _ := writer.frontend.cache.GetCluster(strings.ToLower(testReplicaClusterPath)); stored == nil || stored.Tags["env"] != "test" {
+		t.Errorf("Expected the writer to see the reader's update, got %v", stored)
+	}
+
+	writer.expect(http.MethodDelete, clusterURL, nil, http.StatusNoContent)
+	reader.processor.Poll(ctx, reader.frontend.cache)
+	reader.expect(http.MethodGet, clusterURL, nil, http.StatusNotFound)
+
+	// A cluster recreated after deletion replaces the tombstone.
+	writer.expect(http.MethodPut, clusterURL, clusterBody, http.StatusCreated)
+	reader.processor.Poll(ctx, reader.frontend.cache)
+	reader.expect(http.MethodGet, clusterURL, nil, http.StatusOK)
+}
+
+func TestChangeFeedWarm(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryDBClient()
+
+	version, _ := api.Lookup("2024-06-10-preview")
+	clusterBody, err := json.Marshal(version.NewHCPOpenShiftCluster(newTestValidCluster()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writer := newTestReplica(t, db)
+	writer.expect(http.MethodPut, "/subscriptions/"+testReplicaSubscriptionID, []byte(`{"state": "Registered"}`), http.StatusOK)
+	for i := 0; i < memoryChangeFeedPageSize+1; i++ {
+		url := testReplicaClusterPath + strconv.Itoa(i) + "?" + APIVersionKey + "=2024-06-10-preview"
+		writer.expect(http.MethodPut, url, clusterBody, http.StatusCreated)
+	}
+
+	// Fail the first attempt, so warming is retried.
+	feed := &failingChangeFeed{ChangeFeed: db, failures: 1}
+	replica := newTestReplica(t, db)
+	replica.processor.feed = feed
+	replica.processor.pollInterval = time.Millisecond
+
+	stop := make(chan struct{})
+	ready := make(chan struct{})
+	go replica.processor.Run(ctx, replica.frontend.cache, func() { close(ready) }, stop)
+
+	select {
+	case <-ready:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting for the cache to be warmed")
+	}
+	close(stop)
+
+	if clusters := len(replica.frontend.cache.Clusters()); clusters != memoryChangeFeedPageSize+1 {
+		t.Errorf("Expected %d clusters after warming, got %d", memoryChangeFeedPageSize+1, clusters)
+	}
+	if _, found := replica.frontend.cache.GetSubscription(testReplicaSubscriptionID); !found {
+		t.Error("Expected the subscription after warming")
+	}
+}
+
+func TestChangeFeedResume(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryDBClient()
+
+	version, _ := api.Lookup("2024-06-10-preview")
+	clusterBody, err := json.Marshal(version.NewHCPOpenShiftCluster(newTestValidCluster()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clusterURL := func(i int) string {
+		return testReplicaClusterPath + strconv.Itoa(i) + "?" + APIVersionKey + "=2024-06-10-preview"
+	}
+
+	writer := newTestReplica(t, db)
+	writer.expect(http.MethodPut, "/subscriptions/"+testReplicaSubscriptionID, []byte(`{"state": "Registered"}`), http.StatusOK)
+	writer.expect(http.MethodPut, clusterURL(0), clusterBody, http.StatusCreated)
+	writer.expect(http.MethodPut, clusterURL(1), clusterBody, http.StatusCreated)
+
+	replica := newTestReplica(t, db)
+	if err := replica.processor.Warm(ctx, replica.frontend.cache); err != nil {
+		t.Fatal(err)
+	}
+	lease, found, err := db.GetLeaseDoc(ctx, replica.processor.lease)
+	if err != nil || !found {
+		t.Fatalf("Expected a lease after warming, got %v, %v", found, err)
+	}
+	checkpoint := lease.Continuations[clustersContainer]
+	if checkpoint == "" {
+		t.Fatalf("Expected a %s checkpoint, got %v", clustersContainer, lease.Continuations)
+	}
+
+	// Changes made while the replica is down.
+	writer.expect(http.MethodDelete, clusterURL(1), nil, http.StatusNoContent)
+	writer.expect(http.MethodPut, clusterURL(2), clusterBody, http.StatusCreated)
+
+	// The restarted replica has an empty cache and the same lease.
+	restarted := newTestReplica(t, db)
+	feed := &recordingChangeFeed{ChangeFeed: db}
+	restarted.processor.feed = feed
+	restarted.processor.lease = replica.processor.lease
+	if err := restarted.processor.Warm(ctx, restarted.frontend.cache); err != nil {
+		t.Fatal(err)
+	}
+
+	if continuation := feed.continuations[clustersContainer][0]; continuation != checkpoint {
+		t.Errorf("Expected the %s change feed to resume from checkpoint '%s', got '%s'", clustersContainer, checkpoint, continuation)
+	}
+	for i, expectFound := range []bool{true, false, true} {
+		key := strings.ToLower(testReplicaClusterPath + strconv.Itoa(i))
+		if _, found := restarted.frontend.cache.GetCluster(key); found != expectFound {
+			t.Errorf("Expected cluster %d found %v after resuming, got %v", i, expectFound, found)
+		}
+	}
+	if _, found := restarted.frontend.cache.GetSubscription(testReplicaSubscriptionID); !found {
+		t.Error("Expected the subscription after resuming")
+	}
+
+	// Polling moves the checkpoint forward.
+	writer.expect(http.MethodPut, clusterURL(3), clusterBody, http.StatusCreated)
+	restarted.processor.Poll(ctx, restarted.frontend.cache)
+	lease, _, _ = db.GetLeaseDoc(ctx, replica.processor.lease)
+	if lease.Continuations[clustersContainer] != restarted.processor.continuations[clustersContainer] {
+		t.Errorf("Expected checkpoint '%s' after polling, got '%s'", restarted.processor.continuations[clustersContainer], lease.Continuations[clustersContainer])
+	}
+}
+
+func TestChangeFeedReadiness(t *testing.T) {
+	db := NewMemoryDBClient()
+	replica := newTestReplica(t, db)
+	feed := &failingChangeFeed{ChangeFeed: db, failures: -1}
+	replica.processor.feed = feed
+	replica.processor.pollInterval = time.Millisecond
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	replica.frontend.listener = listener
+
+	stop := make(chan struct{})
+	go replica.frontend.Run(context.Background(), stop)
+	defer func() {
+		close(stop)
+		replica.frontend.Join()
+	}()
+
+	// Give the processor a few attempts at warming the cache.
+	time.Sleep(20 * time.Millisecond)
+	replica.expect(http.MethodGet, "/healthz/ready", nil, http.StatusInternalServerError)
+
+	feed.succeed()
+	deadline := time.Now().Add(10 * time.Second)
+	for !replica.frontend.CheckReady() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the frontend to become ready")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	replica.expect(http.MethodGet, "/healthz/ready", nil, http.StatusOK)
+}
+
+func TestChangeFeedLag(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryDBClient()
+	cache := NewCache()
+
+	replica := newTestReplica(t, db)
+	processor := replica.processor
+	recorder := processor.metrics.(*gaugeRecorder)
+	if err := processor.Warm(ctx, cache); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.SetSubscriptionDoc(ctx, &SubscriptionDocument{ID: "sub", PartitionKey: "sub", Subscription: &arm.Subscription{State: arm.Registered}}); err != nil {
+		t.Fatal(err)
+	}
+	processor.now = func() time.Time { return time.Now().Add(30 * time.Second) }
+	processor.Poll(ctx, cache)
+
+	if lag := recorder.gauges["frontend_changefeed_lag_seconds/"+subscriptionsContainer]; lag < 29 || lag > 32 {
+		t.Errorf("Expected a lag of about 30 seconds, got %v", lag)
+	}
+
+	// Without changes, the cache is up to date.
+	processor.Poll(ctx, cache)
+	if lag := recorder.gauges["frontend_changefeed_lag_seconds/"+subscriptionsContainer]; lag != 0 {
+		t.Errorf("Expected no lag without changes, got %v", lag)
+	}
+}
+
+// failingChangeFeed fails to read the change feed a number of times,
+// or until succeed is called if the number is negative.
+type failingChangeFeed struct {
+	ChangeFeed
+	lock     sync.Mutex
+	failures int
+}
+
+func (f *failingChangeFeed) ReadChangeFeed(ctx context.Context, container, continuation string) (*ChangeFeedPage, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.failures != 0 {
+		f.failures--
+		return nil, errors.New("change feed unavailable")
+	}
+	return f.ChangeFeed.ReadChangeFeed(ctx, container, continuation)
+}
+
+func (f *failingChangeFeed) succeed() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.failures = 0
+}
+
+// recordingChangeFeed records the continuations
+// each container's change feed is read from.
+type recordingChangeFeed struct {
+	ChangeFeed
+	continuations map[string][]string
+}
+
+func (f *recordingChangeFeed) ReadChangeFeed(ctx context.Context, container, continuation string) (*ChangeFeedPage, error) {
+	if f.continuations == nil {
+		f.continuations = make(map[string][]string)
+	}
+	f.continuations[container] = append(f.continuations[container], continuation)
+	return f.ChangeFeed.ReadChangeFeed(ctx, container, continuation)
+}
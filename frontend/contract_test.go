@@ -0,0 +1,190 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+type noopEmitter struct{}
+
+func (noopEmitter) EmitCounter(string, float64, map[string]string) {}
+func (noopEmitter) EmitGauge(string, float64, map[string]string)   {}
+
+// TestResponseContract sends requests through the complete middleware and
+// routing stack and checks each response against the ARM resource provider
+// contract for its operation type.
+func TestResponseContract(t *testing.T) {
+	const (
+		subscriptionID = "00000000-0000-0000-0000-000000000000"
+		subscription   = "/subscriptions/" + subscriptionID
+		clusterPath    = subscription + "/resourceGroups/rg/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/cluster"
+		missingPath    = subscription + "/resourceGroups/rg/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/missing"
+		apiVersion     = "?" + APIVersionKey + "=2024-06-10-preview"
+	)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	f := NewFrontend(logger, nil, noopEmitter{}, NewMemoryDBClient(), FrontendOptions{AzureResources: newTestAzureResourceClient()})
+
+	version, _ := api.Lookup("2024-06-10-preview")
+	clusterBody, err := json.Marshal(version.NewHCPOpenShiftCluster(newTestValidCluster()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name            string
+		method          string
+		url             string
+		body            []byte
+		expectStatus    int
+		expectErrorCode string
+		expectBody      bool
+		expectAllow     string
+	}{
+		{
+			name:         "Register subscription",
+			method:       http.MethodPut,
+			url:          subscription,
+			body:         []byte(`{"state": "Registered"}`),
+			expectStatus: http.StatusOK,
+			expectBody:   true,
+		},
+		{
+			name:            "Read missing resource",
+			method:          http.MethodGet,
+			url:             missingPath + apiVersion,
+			expectStatus:    http.StatusNotFound,
+			expectErrorCode: arm.CloudErrorCodeResourceNotFound,
+		},
+		{
+			name:            "Patch missing resource",
+			method:          http.MethodPatch,
+			url:             missingPath + apiVersion,
+			body:            []byte(`{}`),
+			expectStatus:    http.StatusNotFound,
+			expectErrorCode: arm.CloudErrorCodeResourceNotFound,
+		},
+		{
+			name:         "Create resource",
+			method:       http.MethodPut,
+			url:          clusterPath + apiVersion,
+			body:         clusterBody,
+			expectStatus: http.StatusCreated,
+			expectBody:   true,
+		},
+		{
+			name:         "Replace resource",
+			method:       http.MethodPut,
+			url:          clusterPath + apiVersion,
+			body:         clusterBody,
+			expectStatus: http.StatusOK,
+			expectBody:   true,
+		},
+		{
+			name:         "Update resource",
+			method:       http.MethodPatch,
+			url:          clusterPath + apiVersion,
+			body:         []byte(`{"tags": {"env": "test"}}`),
+			expectStatus: http.StatusOK,
+			expectBody:   true,
+		},
+		{
+			name:         "Read resource",
+			method:       http.MethodGet,
+			url:          clusterPath + apiVersion,
+			expectStatus: http.StatusOK,
+			expectBody:   true,
+		},
+		{
+			name:         "List resources",
+			method:       http.MethodGet,
+			url:          subscription + "/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters" + apiVersion,
+			expectStatus: http.StatusOK,
+			expectBody:   true,
+		},
+		{
+			name:            "Unsupported method",
+			method:          http.MethodPost,
+			url:             clusterPath + apiVersion,
+			body:            []byte(`{}`),
+			expectStatus:    http.StatusMethodNotAllowed,
+			expectErrorCode: arm.CloudErrorCodeMethodNotAllowed,
+			expectAllow:     "GET, PUT, PATCH, DELETE",
+		},
+		{
+			name:            "Unknown path",
+			method:          http.MethodGet,
+			url:             "/unknown",
+			expectStatus:    http.StatusNotFound,
+			expectErrorCode: arm.CloudErrorCodeNotFound,
+		},
+		{
+			name:         "Delete resource",
+			method:       http.MethodDelete,
+			url:          clusterPath + apiVersion,
+			expectStatus: http.StatusNoContent,
+		},
+		{
+			name:         "Delete missing resource",
+			method:       http.MethodDelete,
+			url:          clusterPath + apiVersion,
+			expectStatus: http.StatusNoContent,
+		},
+	}
+
+	// Each case runs against the state left by the previous cases.
+	for _, tt := range tests {
+		request := httptest.NewRequest(tt.method, tt.url, bytes.NewReader(tt.body))
+		request = request.WithContext(ContextWithLogger(request.Context(), logger))
+		if tt.body != nil {
+			request.Header.Set("Content-Type", "application/json")
+		}
+		writer := httptest.NewRecorder()
+
+		f.server.Handler.ServeHTTP(writer, request)
+
+		response := writer.Result()
+		if response.StatusCode != tt.expectStatus {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, tt.expectStatus, response.StatusCode, writer.Body.String())
+			continue
+		}
+
+		if tt.expectErrorCode != "" {
+			var cloudError arm.CloudError
+			if err := json.Unmarshal(writer.Body.Bytes(), &cloudError); err != nil {
+				t.Errorf("%s: expected a CloudError body: %v", tt.name, err)
+			} else if cloudError.Code != tt.expectErrorCode {
+				t.Errorf("%s: expected error code %q, got %q", tt.name, tt.expectErrorCode, cloudError.Code)
+			}
+			if code := response.Header.Get(arm.HeaderNameErrorCode); code != tt.expectErrorCode {
+				t.Errorf("%s: expected %s header %q, got %q", tt.name, arm.HeaderNameErrorCode, tt.expectErrorCode, code)
+			}
+		}
+
+		if tt.expectBody || tt.expectErrorCode != "" {
+			if contentType := response.Header.Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("%s: expected JSON content type, got %q", tt.name, contentType)
+			}
+			if !json.Valid(writer.Body.Bytes()) {
+				t.Errorf("%s: expected a JSON body, got %q", tt.name, writer.Body.String())
+			}
+		} else if writer.Body.Len() != 0 {
+			t.Errorf("%s: expected no body, got %q", tt.name, writer.Body.String())
+		}
+
+		if allow := response.Header.Get("Allow"); allow != tt.expectAllow {
+			t.Errorf("%s: expected Allow header %q, got %q", tt.name, tt.expectAllow, allow)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+)
+
+const (
+	cosmosAPIVersion = "2020-11-05"
+	cosmosPageSize   = "100"
+)
+
+// cosmosRESTClient reads Cosmos DB containers through the REST API where
+// the azcosmos module does not expose what is needed yet: the change feed
+// and queries across partitions.
+//
+// A container's change feed is read per partition key range, so its
+// continuation is the ETag reached in each range, encoded as JSON.
+type cosmosRESTClient struct {
+	endpoint string
+	database string
+	pipeline runtime.Pipeline
+}
+
+// cosmosPartitionKeyRange is a range of a container's partitions. A range
+// that results from splitting another lists the range it replaced as a
+// parent.
+type cosmosPartitionKeyRange struct {
+	ID      string   `json:"id"`
+	Parents []string `json:"parents"`
+}
+
+func newCosmosRESTClient(endpoint, database string, credential azcore.TokenCredential) (*cosmosRESTClient, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	authorization := &cosmosAuthorizationPolicy{
+		credential: credential,
+		scopes:     []string{fmt.Sprintf("%s://%s/.default", parsed.Scheme, parsed.Host)},
+	}
+
+	return &cosmosRESTClient{
+		endpoint: endpoint,
+		database: database,
+		pipeline: runtime.NewPipeline("frontend", "v1.0.0", runtime.PipelineOptions{
+			PerRetry: []policy.Policy{authorization},
+		}, nil),
+	}, nil
+}
+
+// ReadChangeFeed returns the documents changed in each partition key
+// range of a container since continuation.
+func (c *cosmosRESTClient) ReadChangeFeed(ctx context.Context, container, continuation string) (*ChangeFeedPage, error) {
+	etags := make(map[string]string)
+	if continuation != "" {
+		if err := json.Unmarshal([]byte(continuation), &etags); err != nil {
+			return nil, fmt.Errorf("invalid continuation: %w", err)
+		}
+	}
+
+	ranges, err := c.partitionKeyRanges(ctx, container)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ChangeFeedPage{}
+	next := make(map[string]string)
+	for _, keyRange := range ranges {
+		etag, ok := etags[keyRange.ID]
+		if !ok {
+			// A range split since the last read continues from its parent.
+			for _, parent := range keyRange.Parents {
+				if parentETag, ok := etags[parent]; ok {
+					etag = parentETag
+				}
+			}
+		}
+
+		documents, etag, err := c.readRange(ctx, container, keyRange.ID, etag)
+		if err != nil {
+			return nil, err
+		}
+		page.Documents = append(page.Documents, documents...)
+		next[keyRange.ID] = etag
+	}
+
+	data, err := json.Marshal(next)
+	if err != nil {
+		return nil, err
+	}
+	page.Continuation = string(data)
+
+	return page, nil
+}
+
+func (c *cosmosRESTClient) partitionKeyRanges(ctx context.Context, container string) ([]cosmosPartitionKeyRange, error) {
+	request, err := runtime.NewRequest(ctx, http.MethodGet, runtime.JoinPaths(c.endpoint, "dbs", c.database, "colls", container, "pkranges"))
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.pipeline.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if !runtime.HasStatusCode(response, http.StatusOK) {
+		return nil, runtime.NewResponseError(response)
+	}
+
+	var body struct {
+		PartitionKeyRanges []cosmosPartitionKeyRange `json:"PartitionKeyRanges"`
+	}
+	if err = runtime.UnmarshalAsJSON(response, &body); err != nil {
+		return nil, err
+	}
+	return body.PartitionKeyRanges, nil
+}
+
+// readRange reads a page of changes in a partition key range after etag,
+// or from the beginning if etag is empty, and returns the ETag to read
+// the next page from.
+func (c *cosmosRESTClient) readRange(ctx context.Context, container, keyRange, etag string) ([]json.RawMessage, string, error) {
+	request, err := runtime.NewRequest(ctx, http.MethodGet, runtime.JoinPaths(c.endpoint, "dbs", c.database, "colls", container, "docs"))
+	if err != nil {
+		return nil, "", err
+	}
+	header := request.Raw().Header
+	header.Set("A-IM", "Incremental feed")
+	header.Set("x-ms-documentdb-partitionkeyrangeid", keyRange)
+	header.Set("x-ms-max-item-count", cosmosPageSize)
+	if etag != "" {
+		header.Set("If-None-Match", etag)
+	}
+
+	response, err := c.pipeline.Do(request)
+	if err != nil {
+		return nil, "", err
+	}
+
+	next := response.Header.Get("ETag")
+	if next == "" {
+		next = etag
+	}
+
+	switch response.StatusCode {
+	case http.StatusNotModified:
+		return nil, next, nil
+	case http.StatusOK:
+		var body struct {
+			Documents []json.RawMessage `json:"Documents"`
+		}
+		if err = runtime.UnmarshalAsJSON(response, &body); err != nil {
+			return nil, "", err
+		}
+		return body.Documents, next, nil
+	default:
+		return nil, "", runtime.NewResponseError(response)
+	}
+}
+
+// cosmosQueryParameter is a named parameter of a Cosmos DB SQL query.
+type cosmosQueryParameter struct {
+	Name  string `json:"name"`
+	Value any    `json:"value"`
+}
+
+// Query runs a SQL query across all partitions of a container
+// and returns every document it selects.
+func (c *cosmosRESTClient) Query(ctx context.Context, container, query string, parameters ...cosmosQueryParameter) ([]json.RawMessage, error) {
+	body, err := json.Marshal(map[string]any{
+		"query":      query,
+		"parameters": parameters,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var documents []json.RawMessage
+	var continuation string
+	for {
+		request, err := runtime.NewRequest(ctx, http.MethodPost, runtime.JoinPaths(c.endpoint, "dbs", c.database, "colls", container, "docs"))
+		if err != nil {
+			return nil, err
+		}
+		if err = request.SetBody(streaming.NopCloser(bytes.NewReader(body)), "application/query+json"); err != nil {
+			return nil, err
+		}
+		header := request.Raw().Header
+		header.Set("x-ms-documentdb-isquery", "True")
+		header.Set("x-ms-documentdb-query-enablecrosspartition", "True")
+		header.Set("x-ms-max-item-count", cosmosPageSize)
+		if continuation != "" {
+			header.Set("x-ms-continuation", continuation)
+		}
+
+		response, err := c.pipeline.Do(request)
+		if err != nil {
+			return nil, err
+		}
+		if !runtime.HasStatusCode(response, http.StatusOK) {
+			return nil, runtime.NewResponseError(response)
+		}
+
+		var page struct {
+			Documents []json.RawMessage `json:"Documents"`
+		}
+		if err = runtime.UnmarshalAsJSON(response, &page); err != nil {
+			return nil, err
+		}
+		documents = append(documents, page.Documents...)
+
+		continuation = response.Header.Get("x-ms-continuation")
+		if continuation == "" {
+			return documents, nil
+		}
+	}
+}
+
+// cosmosAuthorizationPolicy authorizes requests with Microsoft Entra
+// tokens in the format Cosmos DB expects, like the azcosmos client does.
+type cosmosAuthorizationPolicy struct {
+	credential azcore.TokenCredential
+	scopes     []string
+}
+
+func (p *cosmosAuthorizationPolicy) Do(request *policy.Request) (*http.Response, error) {
+	token, err := p.credential.GetToken(request.Raw().Context(), policy.TokenRequestOptions{Scopes: p.scopes})
+	if err != nil {
+		return nil, err
+	}
+
+	header := request.Raw().Header
+	header.Set("Authorization", "type=aad&ver=1.0&sig="+token.Token)
+	header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	header.Set("x-ms-version", cosmosAPIVersion)
+	return request.Next()
+}
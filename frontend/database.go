@@ -3,20 +3,57 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
 )
 
 const (
-	clustersContainer = "Clusters"
+	clustersContainer      = "Clusters"
+	subscriptionsContainer = "Subscriptions"
+	operationsContainer    = "AsyncOperations"
+	leasesContainer        = "Leases"
+
+	// Tombstones of deleted clusters are removed after a day, by which
+	// time every frontend replica has read them from the change feed.
+	tombstoneTTL = 24 * 60 * 60
+
+	// Leases of replicas that stopped reading the change
+	// feed are removed after a day.
+	leaseTTL = 24 * 60 * 60
 )
 
-// DBClient defines the needed values to perform CRUD operations against the async DB
-type DBClient struct {
+// ErrDocumentConflict is returned when conditionally writing a
+// document that was created or changed since it was read.
+var ErrDocumentConflict = errors.New("document was changed since it was read")
+
+// DBClient is the interface the frontend uses to store
+// documents in the async DB.
+type DBClient interface {
+	DBConnectionTest(ctx context.Context) (string, error)
+	GetClusterDoc(ctx context.Context, resourceID string, partitionKey string) (*HCPOpenShiftClusterDocument, bool, error)
+	SetClusterDoc(ctx context.Context, doc *HCPOpenShiftClusterDocument) error
+	DeleteClusterDoc(ctx context.Context, resourceID string, partitionKey string) error
+	// QueryClusterDocs returns the cluster documents of all subscriptions,
+	// or if clusterID is not empty, only those with that cluster ID.
+	QueryClusterDocs(ctx context.Context, clusterID string) ([]*HCPOpenShiftClusterDocument, error)
+	SetSubscriptionDoc(ctx context.Context, doc *SubscriptionDocument) error
+	ListOperationDocs(ctx context.Context, subscriptionID string) ([]*OperationDocument, error)
+	// SetOperationDoc creates an operation document without an ETag, or
+	// replaces one whose ETag is unchanged, and returns the stored document.
+	// Otherwise it returns ErrDocumentConflict.
+	SetOperationDoc(ctx context.Context, doc *OperationDocument) (*OperationDocument, error)
+}
+
+// CosmosDBClient defines the needed values to perform CRUD operations against the async DB
+type CosmosDBClient struct {
 	client *azcosmos.Client
+	rest   *cosmosRESTClient
 	config *DBConfig
 }
 
@@ -39,13 +76,13 @@ func NewDatabaseConfig() *DBConfig {
 }
 
 // NewDatabaseClient instanstiates a Cosmos DatabaseClient targeting Frontends async DB
-func NewDatabaseClient(config *DBConfig) (*DBClient, error) {
+func NewDatabaseClient(config *DBConfig) (*CosmosDBClient, error) {
 	cred, err := azidentity.NewDefaultAzureCredential(config.ClientOptions)
 	if err != nil {
 		return nil, err
 	}
 
-	d := &DBClient{
+	d := &CosmosDBClient{
 		config: config,
 	}
 
@@ -55,11 +92,15 @@ func NewDatabaseClient(config *DBConfig) (*DBClient, error) {
 	}
 
 	d.client = client
+	d.rest, err = newCosmosRESTClient(d.config.DBUrl, d.config.DBName, cred)
+	if err != nil {
+		return nil, err
+	}
 	return d, nil
 }
 
 // DBConnectionTest checks the async database is accessible on startup
-func (d *DBClient) DBConnectionTest(ctx context.Context) (string, error) {
+func (d *CosmosDBClient) DBConnectionTest(ctx context.Context) (string, error) {
 	if d.config.DBName == "none" || d.config.DBName == "" {
 		return "No database configured, skipping", nil
 	}
@@ -76,13 +117,14 @@ func (d *DBClient) DBConnectionTest(ctx context.Context) (string, error) {
 }
 
 // GetCluster retreives a cluster document from async DB using resource ID
-func (d *DBClient) GetClusterDoc(ctx context.Context, resourceID string, partitionKey string) (*HCPOpenShiftClusterDocument, bool, error) {
+func (d *CosmosDBClient) GetClusterDoc(ctx context.Context, resourceID string, partitionKey string) (*HCPOpenShiftClusterDocument, bool, error) {
 	container, err := d.client.NewContainer(d.config.DBName, clustersContainer)
 	if err != nil {
 		return nil, false, err
 	}
 
-	query := "SELECT * FROM c WHERE c.key = @key"
+	// Skip the tombstones of deleted clusters.
+	query := "SELECT * FROM c WHERE STRINGEQUALS(c.key, @key, true) AND NOT (IS_DEFINED(c.deleted) AND c.deleted)"
 	opt := azcosmos.QueryOptions{
 		PageSizeHint:    1,
 		QueryParameters: []azcosmos.QueryParameter{{Name: "@key", Value: resourceID}},
@@ -113,7 +155,7 @@ func (d *DBClient) GetClusterDoc(ctx context.Context, resourceID string, partiti
 }
 
 // SetCluster creates/updates a cluster document in the async DB during cluster creation/patching
-func (d *DBClient) SetClusterDoc(ctx context.Context, doc *HCPOpenShiftClusterDocument) error {
+func (d *CosmosDBClient) SetClusterDoc(ctx context.Context, doc *HCPOpenShiftClusterDocument) error {
 	data, err := json.Marshal(doc)
 	if err != nil {
 		return err
@@ -132,24 +174,183 @@ func (d *DBClient) SetClusterDoc(ctx context.Context, doc *HCPOpenShiftClusterDo
 	return nil
 }
 
-// DeleteCluster removes a cluter document from the async DB using resource ID
-func (d *DBClient) DeleteClusterDoc(ctx context.Context, resourceID string, partitionKey string) error {
+// QueryClusterDocs retrieves cluster documents from the async DB across all subscriptions
+func (d *CosmosDBClient) QueryClusterDocs(ctx context.Context, clusterID string) ([]*HCPOpenShiftClusterDocument, error) {
+	// Skip the tombstones of deleted clusters.
+	query := "SELECT * FROM c WHERE NOT (IS_DEFINED(c.deleted) AND c.deleted)"
+	var parameters []cosmosQueryParameter
+	if clusterID != "" {
+		query += " AND c.clusterid = @clusterId"
+		parameters = append(parameters, cosmosQueryParameter{Name: "@clusterId", Value: clusterID})
+	}
+
+	items, err := d.rest.Query(ctx, clustersContainer, query, parameters...)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]*HCPOpenShiftClusterDocument, 0, len(items))
+	for _, item := range items {
+		var doc *HCPOpenShiftClusterDocument
+		if err = json.Unmarshal(item, &doc); err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// DeleteCluster replaces a cluster document in the async DB with a tombstone
+// using resource ID, so the deletion appears in the change feed
+func (d *CosmosDBClient) DeleteClusterDoc(ctx context.Context, resourceID string, partitionKey string) error {
 	doc, found, err := d.GetClusterDoc(ctx, resourceID, partitionKey)
+	if err != nil {
+		return err
+	}
 	if !found {
 		return fmt.Errorf("document with key %s not found", partitionKey)
 	}
+
+	doc.Deleted = true
+	doc.TTL = tombstoneTTL
+	return d.SetClusterDoc(ctx, doc)
+}
+
+// SetSubscriptionDoc creates/updates a subscription document in the async DB
+func (d *CosmosDBClient) SetSubscriptionDoc(ctx context.Context, doc *SubscriptionDocument) error {
+	data, err := json.Marshal(doc)
 	if err != nil {
 		return err
 	}
 
-	container, err := d.client.NewContainer(d.config.DBName, clustersContainer)
+	container, err := d.client.NewContainer(d.config.DBName, subscriptionsContainer)
 	if err != nil {
 		return err
 	}
 
-	_, err = container.DeleteItem(ctx, azcosmos.NewPartitionKeyString(partitionKey), doc.ID, nil)
+	_, err = container.UpsertItem(ctx, azcosmos.NewPartitionKeyString(doc.PartitionKey), data, nil)
+	return err
+}
+
+// ReadChangeFeed reads the change feed of a container in the async DB
+func (d *CosmosDBClient) ReadChangeFeed(ctx context.Context, container, continuation string) (*ChangeFeedPage, error) {
+	return d.rest.ReadChangeFeed(ctx, container, continuation)
+}
+
+// ReadDocuments reads every document of a container in the async DB
+func (d *CosmosDBClient) ReadDocuments(ctx context.Context, container string) ([]json.RawMessage, error) {
+	return d.rest.Query(ctx, container, "SELECT * FROM c")
+}
+
+// GetLeaseDoc retrieves a change feed lease document from the async DB
+func (d *CosmosDBClient) GetLeaseDoc(ctx context.Context, name string) (*LeaseDocument, bool, error) {
+	container, err := d.client.NewContainer(d.config.DBName, leasesContainer)
+	if err != nil {
+		return nil, false, err
+	}
+
+	response, err := container.ReadItem(ctx, azcosmos.NewPartitionKeyString(name), name, nil)
+	if err != nil {
+		var responseErr *azcore.ResponseError
+		if errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	var doc *LeaseDocument
+	if err = json.Unmarshal(response.Value, &doc); err != nil {
+		return nil, false, err
+	}
+	return doc, true, nil
+}
+
+// SetLeaseDoc creates/updates a change feed lease document in the async DB
+func (d *CosmosDBClient) SetLeaseDoc(ctx context.Context, doc *LeaseDocument) error {
+	data, err := json.Marshal(doc)
 	if err != nil {
 		return err
 	}
-	return nil
+
+	container, err := d.client.NewContainer(d.config.DBName, leasesContainer)
+	if err != nil {
+		return err
+	}
+
+	_, err = container.UpsertItem(ctx, azcosmos.NewPartitionKeyString(doc.PartitionKey), data, nil)
+	return err
+}
+
+// ListOperationDocs retrieves the operation documents of a subscription from the async DB
+func (d *CosmosDBClient) ListOperationDocs(ctx context.Context, subscriptionID string) ([]*OperationDocument, error) {
+	container, err := d.client.NewContainer(d.config.DBName, operationsContainer)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []*OperationDocument
+	queryPager := container.NewQueryItemsPager("SELECT * FROM c", azcosmos.NewPartitionKeyString(subscriptionID), nil)
+	for queryPager.More() {
+		queryResponse, err := queryPager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range queryResponse.Items {
+			var doc *OperationDocument
+			if err = json.Unmarshal(item, &doc); err != nil {
+				return nil, err
+			}
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+// SetOperationDoc creates or conditionally replaces an operation document in the async DB
+func (d *CosmosDBClient) SetOperationDoc(ctx context.Context, doc *OperationDocument) (*OperationDocument, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err = d.setConditional(ctx, operationsContainer, doc.PartitionKey, doc.ID, doc.ETag, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var stored *OperationDocument
+	if err = json.Unmarshal(data, &stored); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// setConditional creates an item if etag is empty, or else replaces it if
+// its ETag is unchanged, and returns the stored item.
+func (d *CosmosDBClient) setConditional(ctx context.Context, containerName, partitionKey, id, etag string, data []byte) ([]byte, error) {
+	container, err := d.client.NewContainer(d.config.DBName, containerName)
+	if err != nil {
+		return nil, err
+	}
+
+	pk := azcosmos.NewPartitionKeyString(partitionKey)
+	options := &azcosmos.ItemOptions{EnableContentResponseOnWrite: true}
+
+	var response azcosmos.ItemResponse
+	if etag == "" {
+		response, err = container.CreateItem(ctx, pk, data, options)
+	} else {
+		ifMatch := azcore.ETag(etag)
+		options.IfMatchEtag = &ifMatch
+		response, err = container.ReplaceItem(ctx, pk, id, data, options)
+	}
+	if err != nil {
+		var responseErr *azcore.ResponseError
+		if errors.As(err, &responseErr) && (responseErr.StatusCode == http.StatusConflict || responseErr.StatusCode == http.StatusPreconditionFailed) {
+			return nil, ErrDocumentConflict
+		}
+		return nil, err
+	}
+	return response.Value, nil
 }
@@ -0,0 +1,542 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	azcorearm "github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+	"github.com/Azure/ARO-HCP/internal/api/fixtures"
+	"github.com/Azure/ARO-HCP/internal/api/v20240610preview/generated"
+)
+
+const (
+	testHarnessAPIVersion = "2024-06-10-preview"
+	testHarnessUser       = "user@example.com"
+)
+
+// testHarness runs a Frontend with an in-memory database on a TLS server
+// and drives it with the generated SDK clients. Every request passes through
+// the complete middleware and routing stack, so tests using the harness are
+// black-box tests of the whole request pipeline.
+//
+// Node pools are not routed by the frontend yet, so the harness only has
+// a client for clusters.
+type testHarness struct {
+	t              *testing.T
+	server         *httptest.Server
+	frontend       *Frontend
+	azureResources *FakeAzureResourceClient
+	subscriptionID string
+	clusters       *generated.HcpOpenShiftClustersClient
+}
+
+// newTestHarness starts a frontend and registers a subscription with it,
+// the way ARM does before forwarding any requests for the subscription.
+// If spec is not nil, the frontend validates request bodies against it.
+func newTestHarness(t *testing.T, spec *OpenAPISpec) *testHarness {
+	t.Helper()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	azureResources := newTestAzureResourceClient()
+	f := NewFrontend(logger, nil, noopEmitter{}, NewMemoryDBClient(), FrontendOptions{
+		AzureResources: azureResources,
+		Spec:           spec,
+	})
+
+	server := httptest.NewUnstartedServer(f.server.Handler)
+	server.Config.BaseContext = f.server.BaseContext
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	h := &testHarness{
+		t:              t,
+		server:         server,
+		frontend:       f,
+		azureResources: azureResources,
+		subscriptionID: "00000000-0000-0000-0000-000000000000",
+	}
+
+	h.putSubscription(h.subscriptionID, arm.Registered)
+
+	var err error
+	h.clusters, err = generated.NewHcpOpenShiftClustersClient(h.subscriptionID, testTokenCredential{}, h.clientOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return h
+}
+
+// clientOptions points SDK clients at the test server and injects the
+// headers ARM adds to requests it forwards to a resource provider.
+func (h *testHarness) clientOptions() *azcorearm.ClientOptions {
+	return &azcorearm.ClientOptions{
+		ClientOptions: policy.ClientOptions{
+			Cloud: cloud.Configuration{
+				Services: map[cloud.ServiceName]cloud.ServiceConfiguration{
+					cloud.ResourceManager: {
+						Endpoint: h.server.URL,
+						Audience: "https://management.core.windows.net",
+					},
+				},
+			},
+			PerCallPolicies: []policy.Policy{armHeadersPolicy{}},
+			Retry:           policy.RetryOptions{MaxRetries: -1},
+			Transport:       h.server.Client(),
+		},
+		DisableRPRegistration: true,
+	}
+}
+
+// putSubscription sends a subscription lifecycle notification for
+// subscriptionID, as ARM does when the subscription changes state.
+func (h *testHarness) putSubscription(subscriptionID string, state arm.RegistrationState) {
+	h.t.Helper()
+
+	body, err := json.Marshal(arm.Subscription{State: state})
+	if err != nil {
+		h.t.Fatal(err)
+	}
+
+	request, err := http.NewRequest(http.MethodPut, h.server.URL+"/subscriptions/"+subscriptionID+"?"+APIVersionKey+"=2.0", bytes.NewReader(body))
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := h.server.Client().Do(request)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(response.Body)
+		h.t.Fatalf("Subscription %s state %s: expected status %d, got %d: %s", subscriptionID, state, http.StatusOK, response.StatusCode, data)
+	}
+}
+
+// newCluster returns a cluster resource that passes validation, in the form
+// the SDK client sends it.
+func (h *testHarness) newCluster() generated.HcpOpenShiftClusterResource {
+	h.t.Helper()
+
+	version, _ := api.Lookup(testHarnessAPIVersion)
+	data, err := json.Marshal(version.NewHCPOpenShiftCluster(newTestValidCluster()))
+	if err != nil {
+		h.t.Fatal(err)
+	}
+
+	var resource generated.HcpOpenShiftClusterResource
+	if err = json.Unmarshal(data, &resource); err != nil {
+		h.t.Fatal(err)
+	}
+
+	// Clients do not send read-only properties.
+	resource.ID = nil
+	resource.Name = nil
+	resource.Type = nil
+	resource.SystemData = nil
+	resource.Properties.ProvisioningState = nil
+
+	return resource
+}
+
+// expectResponseError checks that err is an SDK response error with the
+// given status code and ARM error code.
+func expectResponseError(t *testing.T, err error, statusCode int, errorCode string) {
+	t.Helper()
+
+	var responseError *azcore.ResponseError
+	if !errors.As(err, &responseError) {
+		t.Fatalf("Expected a response error, got %v", err)
+	}
+	if responseError.StatusCode != statusCode {
+		t.Errorf("Expected status %d, got %d", statusCode, responseError.StatusCode)
+	}
+	if responseError.ErrorCode != errorCode {
+		t.Errorf("Expected error code %q, got %q", errorCode, responseError.ErrorCode)
+	}
+}
+
+// testTokenCredential provides a static bearer token. The frontend relies
+// on ARM to authenticate clients, so the token is never checked.
+type testTokenCredential struct{}
+
+func (testTokenCredential) GetToken(context.Context, policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+// armHeadersPolicy adds the system data header ARM sends with
+// requests that create or update a resource.
+type armHeadersPolicy struct{}
+
+func (armHeadersPolicy) Do(req *policy.Request) (*http.Response, error) {
+	switch req.Raw().Method {
+	case http.MethodPut, http.MethodPatch:
+		now := time.Now().UTC()
+		data, err := json.Marshal(arm.SystemData{
+			CreatedBy:          testHarnessUser,
+			CreatedByType:      arm.CreatedByTypeUser,
+			CreatedAt:          &now,
+			LastModifiedBy:     testHarnessUser,
+			LastModifiedByType: arm.CreatedByTypeUser,
+			LastModifiedAt:     &now,
+		})
+		if err != nil {
+			return nil, err
+		}
+		req.Raw().Header.Set(arm.HeaderNameARMResourceSystemData, string(data))
+	}
+	return req.Next()
+}
+
+func TestEndToEndClusterLifecycle(t *testing.T) {
+	const (
+		resourceGroup = "rg"
+		clusterName   = "cluster"
+	)
+
+	h := newTestHarness(t, nil)
+	ctx := context.Background()
+
+	// Create
+	createPoller, err := h.clusters.BeginCreateOrUpdate(ctx, resourceGroup, clusterName, h.newCluster(), nil)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	created, err := createPoller.PollUntilDone(ctx, nil)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	// Resource IDs keep the casing of the request path the SDK sends.
+	expectID := "/subscriptions/" + h.subscriptionID + "/resourceGroups/" + resourceGroup + "/providers/Microsoft.RedHatOpenshift/hcpOpenShiftClusters/" + clusterName
+	if id := api.Deref(created.ID); id != expectID {
+		t.Errorf("Create: expected ID %q, got %q", expectID, id)
+	}
+	if name := api.Deref(created.Name); name != clusterName {
+		t.Errorf("Create: expected name %q, got %q", clusterName, name)
+	}
+	if created.SystemData == nil || api.Deref(created.SystemData.CreatedBy) != testHarnessUser {
+		t.Errorf("Create: expected system data created by %q, got %+v", testHarnessUser, created.SystemData)
+	}
+
+	// Get
+	got, err := h.clusters.Get(ctx, resourceGroup, clusterName, nil)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if id := api.Deref(got.ID); id != expectID {
+		t.Errorf("Get: expected ID %q, got %q", expectID, id)
+	}
+	if prefix := api.Deref(got.Properties.Spec.DNS.BaseDomainPrefix); prefix != "example" {
+		t.Errorf("Get: expected base domain prefix %q, got %q", "example", prefix)
+	}
+
+	// List
+	var listed []*generated.HcpOpenShiftClusterResource
+	subscriptionPager := h.clusters.NewListBySubscriptionPager(nil)
+	for subscriptionPager.More() {
+		page, err := subscriptionPager.NextPage(ctx)
+		if err != nil {
+			t.Fatalf("List by subscription: %v", err)
+		}
+		listed = append(listed, page.Value...)
+	}
+	if len(listed) != 1 || api.Deref(listed[0].ID) != expectID {
+		t.Errorf("List by subscription: expected only %q, got %d clusters", expectID, len(listed))
+	}
+
+	listed = nil
+	resourceGroupPager := h.clusters.NewListByResourceGroupPager(resourceGroup, nil)
+	for resourceGroupPager.More() {
+		page, err := resourceGroupPager.NextPage(ctx)
+		if err != nil {
+			t.Fatalf("List by resource group: %v", err)
+		}
+		listed = append(listed, page.Value...)
+	}
+	if len(listed) != 1 || api.Deref(listed[0].ID) != expectID {
+		t.Errorf("List by resource group: expected only %q, got %d clusters", expectID, len(listed))
+	}
+
+	// Patch
+	updatePoller, err := h.clusters.BeginUpdate(ctx, resourceGroup, clusterName, generated.HcpOpenShiftClusterResourceUpdate{
+		Tags: map[string]*string{"env": api.Ptr("test")},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	updated, err := updatePoller.PollUntilDone(ctx, nil)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if env := api.Deref(updated.Tags["env"]); env != "test" {
+		t.Errorf("Update: expected tag env=test, got %q", env)
+	}
+	if prefix := api.Deref(updated.Properties.Spec.DNS.BaseDomainPrefix); prefix != "example" {
+		t.Errorf("Update: expected base domain prefix to be preserved, got %q", prefix)
+	}
+
+	// Delete
+	deletePoller, err := h.clusters.BeginDelete(ctx, resourceGroup, clusterName, nil)
+	if err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err = deletePoller.PollUntilDone(ctx, nil); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	_, err = h.clusters.Get(ctx, resourceGroup, clusterName, nil)
+	expectResponseError(t, err, http.StatusNotFound, arm.CloudErrorCodeResourceNotFound)
+
+	// Deleting a missing resource succeeds.
+	deletePoller, err = h.clusters.BeginDelete(ctx, resourceGroup, clusterName, nil)
+	if err != nil {
+		t.Fatalf("Delete missing: %v", err)
+	}
+	if _, err = deletePoller.PollUntilDone(ctx, nil); err != nil {
+		t.Fatalf("Delete missing: %v", err)
+	}
+}
+
+// TestEndToEndUpperCaseSubscription creates and deletes a cluster in a
+// subscription whose ID ARM sends in upper case. Documents must be stored
+// and found under the lowercased subscription ID whatever the URL casing.
+func TestEndToEndUpperCaseSubscription(t *testing.T) {
+	const (
+		subscriptionID = "AAAAAAAA-0000-0000-0000-000000000000"
+		resourceGroup  = "rg"
+		clusterName    = "cluster"
+	)
+
+	h := newTestHarness(t, nil)
+	h.putSubscription(subscriptionID, arm.Registered)
+	ctx := context.Background()
+
+	clusters, err := generated.NewHcpOpenShiftClustersClient(subscriptionID, testTokenCredential{}, h.clientOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	createPoller, err := clusters.BeginCreateOrUpdate(ctx, resourceGroup, clusterName, h.newCluster(), nil)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	created, err := createPoller.PollUntilDone(ctx, nil)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	doc, found, err := h.frontend.dbClient.GetClusterDoc(ctx, strings.ToLower(api.Deref(created.ID)), strings.ToLower(subscriptionID))
+	if err != nil || !found {
+		t.Fatalf("Expected the cluster document under the lowercased subscription ID, got %v, %v", found, err)
+	}
+	if doc.PartitionKey != strings.ToLower(subscriptionID) {
+		t.Errorf("Expected partition key %q, got %q", strings.ToLower(subscriptionID), doc.PartitionKey)
+	}
+
+	deletePoller, err := clusters.BeginDelete(ctx, resourceGroup, clusterName, nil)
+	if err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err = deletePoller.PollUntilDone(ctx, nil); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	_, err = clusters.Get(ctx, resourceGroup, clusterName, nil)
+	expectResponseError(t, err, http.StatusNotFound, arm.CloudErrorCodeResourceNotFound)
+}
+
+// TestEndToEndReadWriteRoundTrip writes back a cluster exactly as it was
+// read, which must be accepted even though the response includes values
+// computed by the frontend, such as available upgrades.
+func TestEndToEndReadWriteRoundTrip(t *testing.T) {
+	const (
+		resourceGroup = "rg"
+		clusterName   = "cluster"
+	)
+
+	h := newTestHarness(t, nil)
+	h.frontend.releases = newTestReleaseCatalog(t)
+	ctx := context.Background()
+
+	createPoller, err := h.clusters.BeginCreateOrUpdate(ctx, resourceGroup, clusterName, h.newCluster(), nil)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err = createPoller.PollUntilDone(ctx, nil); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	got, err := h.clusters.Get(ctx, resourceGroup, clusterName, nil)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if len(got.Properties.Spec.Version.AvailableUpgrades) == 0 {
+		t.Fatal("Get: expected available upgrades")
+	}
+
+	putPoller, err := h.clusters.BeginCreateOrUpdate(ctx, resourceGroup, clusterName, got.HcpOpenShiftClusterResource, nil)
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	put, err := putPoller.PollUntilDone(ctx, nil)
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if len(put.Properties.Spec.Version.AvailableUpgrades) != len(got.Properties.Spec.Version.AvailableUpgrades) {
+		t.Errorf("Put: expected available upgrades %v, got %v", got.Properties.Spec.Version.AvailableUpgrades, put.Properties.Spec.Version.AvailableUpgrades)
+	}
+
+	updatePoller, err := h.clusters.BeginUpdate(ctx, resourceGroup, clusterName, generated.HcpOpenShiftClusterResourceUpdate{
+		Tags: map[string]*string{"env": api.Ptr("test")},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if _, err = updatePoller.PollUntilDone(ctx, nil); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	// Available upgrades are computed for responses, not stored.
+	doc, _, err := h.frontend.dbClient.GetClusterDoc(ctx, strings.ToLower(api.Deref(got.ID)), h.subscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if upgrades := doc.Properties.Spec.Version.AvailableUpgrades; len(upgrades) != 0 {
+		t.Errorf("Expected no stored available upgrades, got %v", upgrades)
+	}
+}
+
+func TestEndToEndErrors(t *testing.T) {
+	const resourceGroup = "rg"
+
+	h := newTestHarness(t, nil)
+	ctx := context.Background()
+
+	t.Run("Invalid cluster", func(t *testing.T) {
+		cluster := h.newCluster()
+		cluster.Properties.Spec.Network.PodCidr = api.Ptr("bogus")
+		_, err := h.clusters.BeginCreateOrUpdate(ctx, resourceGroup, "invalid", cluster, nil)
+		expectResponseError(t, err, http.StatusBadRequest, arm.CloudErrorCodeInvalidRequestContent)
+	})
+
+	t.Run("Invalid resource name", func(t *testing.T) {
+		_, err := h.clusters.Get(ctx, resourceGroup, "x", nil)
+		expectResponseError(t, err, http.StatusBadRequest, arm.CloudErrorCodeInvalidResourceName)
+	})
+
+	t.Run("Missing cluster", func(t *testing.T) {
+		_, err := h.clusters.BeginUpdate(ctx, resourceGroup, "missing", generated.HcpOpenShiftClusterResourceUpdate{}, nil)
+		expectResponseError(t, err, http.StatusNotFound, arm.CloudErrorCodeResourceNotFound)
+	})
+
+	t.Run("Unregistered subscription", func(t *testing.T) {
+		h.putSubscription(h.subscriptionID, arm.Unregistered)
+		t.Cleanup(func() { h.putSubscription(h.subscriptionID, arm.Registered) })
+
+		_, err := h.clusters.Get(ctx, resourceGroup, "cluster", nil)
+		expectResponseError(t, err, http.StatusBadRequest, arm.CloudErrorCodeInvalidSubscriptionState)
+	})
+}
+
+// TestEndToEndClusterFixtures sends the generated cluster fixtures of every
+// API version through the frontend with schema validation enabled. Valid
+// fixtures are created and invalid fixtures are rejected for the expected
+// field. Node pools are not routed yet; their fixtures are only checked
+// against the schema.
+func TestEndToEndClusterFixtures(t *testing.T) {
+	spec, err := LoadOpenAPISpec(openapiSpecDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := newTestHarness(t, spec)
+
+	all, err := fixtures.All(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fixture := range all {
+		if fixture.Resource != fixtures.ResourceTypeCluster {
+			continue
+		}
+
+		t.Run(fixture.APIVersion+"/"+fixture.Name, func(t *testing.T) {
+			for _, id := range fixture.References {
+				resource := AzureResource{ID: id, Location: fixtures.Location}
+				switch {
+				case strings.Contains(id, "/subnets/"):
+					h.azureResources.AddSubnet(AzureSubnet{AzureResource: resource})
+				case strings.Contains(id, "/networkSecurityGroups/"):
+					h.azureResources.AddNetworkSecurityGroup(resource)
+				case strings.Contains(id, "/diskEncryptionSets/"):
+					h.azureResources.AddDiskEncryptionSet(resource)
+				}
+			}
+
+			request, err := http.NewRequest(http.MethodPut, h.server.URL+fixture.ResourceID+"?"+APIVersionKey+"="+fixture.APIVersion, bytes.NewReader(fixture.Body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			request.Header.Set("Content-Type", "application/json")
+
+			response, err := h.server.Client().Do(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer response.Body.Close()
+
+			data, err := io.ReadAll(response.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if fixture.Valid() {
+				// Fixtures of every API version share resource IDs,
+				// so later versions update the cluster.
+				if response.StatusCode != http.StatusCreated && response.StatusCode != http.StatusOK {
+					t.Errorf("Expected status %d or %d, got %d: %s", http.StatusCreated, http.StatusOK, response.StatusCode, data)
+				}
+				return
+			}
+
+			if response.StatusCode != http.StatusBadRequest {
+				t.Fatalf("Expected status %d, got %d: %s", http.StatusBadRequest, response.StatusCode, data)
+			}
+
+			var cloudError arm.CloudError
+			if err = json.Unmarshal(data, &cloudError); err != nil {
+				t.Fatal(err)
+			}
+			targets := []string{cloudError.Target}
+			for _, detail := range cloudError.Details {
+				targets = append(targets, detail.Target)
+			}
+			if !slices.Contains(targets, fixture.Target) {
+				t.Errorf("Expected an error for '%s', got %s", fixture.Target, data)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+// FakeAzureResourceClient is an in-memory AzureResourceClient for
+// running without access to Azure. Resources are keyed by resource
+// ID, which is compared case-insensitively.
+type FakeAzureResourceClient struct {
+	lock      sync.RWMutex
+	resources map[string]any
+	denied    map[string]struct{}
+}
+
+// NewFakeAzureResourceClient returns an empty FakeAzureResourceClient.
+func NewFakeAzureResourceClient() *FakeAzureResourceClient {
+	return &FakeAzureResourceClient{
+		resources: make(map[string]any),
+		denied:    make(map[string]struct{}),
+	}
+}
+
+// AddSubnet adds or replaces a subnet.
+func (c *FakeAzureResourceClient) AddSubnet(subnet AzureSubnet) {
+	c.add(subnet.ID, &subnet)
+}
+
+// AddNetworkSecurityGroup adds or replaces a network security group.
+func (c *FakeAzureResourceClient) AddNetworkSecurityGroup(nsg AzureResource) {
+	c.add(nsg.ID, &nsg)
+}
+
+// AddDiskEncryptionSet adds or replaces a disk encryption set.
+func (c *FakeAzureResourceClient) AddDiskEncryptionSet(des AzureResource) {
+	c.add(des.ID, &des)
+}
+
+// Deny causes lookups of the given resource ID to fail with
+// ErrAzureResourceAccessDenied, whether or not the resource exists.
+func (c *FakeAzureResourceClient) Deny(id string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.denied[strings.ToLower(id)] = struct{}{}
+}
+
+func (c *FakeAzureResourceClient) add(id string, resource any) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.resources[strings.ToLower(id)] = resource
+}
+
+// get returns the resource with the given ID if it has type T.
+func get[T any](c *FakeAzureResourceClient, id *arm.ResourceID) (*T, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	key := strings.ToLower(id.String())
+	if _, denied := c.denied[key]; denied {
+		return nil, fmt.Errorf("%s: %w", id, ErrAzureResourceAccessDenied)
+	}
+	resource, ok := c.resources[key].(*T)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", id, ErrAzureResourceNotFound)
+	}
+	copied := *resource
+	return &copied, nil
+}
+
+func (c *FakeAzureResourceClient) GetSubnet(ctx context.Context, id *arm.ResourceID) (*AzureSubnet, error) {
+	return get[AzureSubnet](c, id)
+}
+
+func (c *FakeAzureResourceClient) GetNetworkSecurityGroup(ctx context.Context, id *arm.ResourceID) (*AzureResource, error) {
+	return get[AzureResource](c, id)
+}
+
+func (c *FakeAzureResourceClient) GetDiskEncryptionSet(ctx context.Context, id *arm.ResourceID) (*AzureResource, error) {
+	return get[AzureResource](c, id)
+}
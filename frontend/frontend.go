@@ -14,10 +14,10 @@ import (
 	"path"
 	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
-	"github.com/Azure/go-autorest/autorest/azure"
 	"github.com/google/uuid"
 
 	"github.com/Azure/ARO-HCP/internal/api"
@@ -36,14 +36,20 @@ const (
 )
 
 type Frontend struct {
-	logger   *slog.Logger
-	listener net.Listener
-	server   http.Server
-	cache    Cache
-	dbClient DBClient
-	ready    atomic.Value
-	done     chan struct{}
-	metrics  metrics.Emitter
+	logger         *slog.Logger
+	listener       net.Listener
+	server         http.Server
+	cache          *Cache
+	dbClient       DBClient
+	azureResources AzureResourceClient
+	releases       *ReleaseCatalog
+	policy         *SubscriptionPolicy
+	quota          *QuotaConfig
+	region         *Region
+	changeFeed     *ChangeFeedProcessor
+	ready          atomic.Value
+	done           chan struct{}
+	metrics        metrics.Emitter
 }
 
 // MuxPattern forms a URL pattern suitable for passing to http.ServeMux.
@@ -53,7 +59,19 @@ func MuxPattern(method string, segments ...string) string {
 	return fmt.Sprintf("%s /%s", method, strings.ToLower(path.Join(segments...)))
 }
 
-func NewFrontend(logger *slog.Logger, listener net.Listener, emitter metrics.Emitter, dbClient *DBClient) *Frontend {
+// FrontendOptions holds the optional dependencies of a Frontend. The
+// checks and features a nil dependency provides are skipped.
+type FrontendOptions struct {
+	AzureResources AzureResourceClient
+	Releases       *ReleaseCatalog
+	Policy         *SubscriptionPolicy
+	Quota          *QuotaConfig
+	Region         *Region
+	Spec           *OpenAPISpec
+	ChangeFeed     *ChangeFeedProcessor
+}
+
+func NewFrontend(logger *slog.Logger, listener net.Listener, emitter metrics.Emitter, dbClient DBClient, options FrontendOptions) *Frontend {
 	f := &Frontend{
 		logger:   logger,
 		listener: listener,
@@ -64,12 +82,21 @@ func NewFrontend(logger *slog.Logger, listener net.Listener, emitter metrics.Emi
 				return ContextWithLogger(context.Background(), logger)
 			},
 		},
-		cache:    *NewCache(),
-		dbClient: *dbClient,
-		done:     make(chan struct{}),
+		cache:          NewCache(),
+		dbClient:       dbClient,
+		azureResources: options.AzureResources,
+		releases:       options.Releases,
+		policy:         options.Policy,
+		quota:          options.Quota,
+		region:         options.Region,
+		changeFeed:     options.ChangeFeed,
+		done:           make(chan struct{}),
 	}
 
-	subscriptionStateMuxValidator := NewSubscriptionStateMuxValidator(&f.cache)
+	// Not ready until running.
+	f.ready.Store(false)
+
+	subscriptionStateMuxValidator := NewSubscriptionStateMuxValidator(f.cache)
 
 	// Setup metrics middleware
 	metricsMiddleware := MetricsMiddleware{Emitter: emitter}
@@ -80,7 +107,6 @@ func NewFrontend(logger *slog.Logger, listener net.Listener, emitter metrics.Emi
 		MiddlewareBody,
 		MiddlewareLowercase,
 		MiddlewareSystemData,
-		MiddlewareValidateStatic,
 		metricsMiddleware.Metrics(),
 	)
 
@@ -88,22 +114,39 @@ func NewFrontend(logger *slog.Logger, listener net.Listener, emitter metrics.Emi
 	mux.HandleFunc("/", f.NotFound)
 	mux.HandleFunc(MuxPattern(http.MethodGet, "healthz", "ready"), f.HealthzReady)
 	// TODO: determine where in the auth chain we should allow for this endpoint to be called by ARM
-	mux.HandleFunc(MuxPattern(http.MethodPut, PatternSubscriptions), f.ArmSubscriptionAction)
+	mux.Handle(
+		MuxPattern(http.MethodPut, PatternSubscriptions),
+		NewMiddleware(MiddlewareValidateStatic).HandlerFunc(f.ArmSubscriptionAction))
 
 	// Expose Prometheus metrics endpoint
 	mux.Handle(MuxPattern(http.MethodGet, "metrics"), promhttp.Handler())
 
 	// Authenticated routes
+	// Path segments are validated after multiplexing,
+	// once their values are available to middleware.
 	postMuxMiddleware := NewMiddleware(
 		MiddlewareLoggingPostMux,
+		MiddlewareValidateStatic,
 		MiddlewareValidateAPIVersion,
 		subscriptionStateMuxValidator.MiddlewareValidateSubscriptionState)
+	if options.Policy != nil {
+		postMuxMiddleware.init(options.Policy.MiddlewareValidateSubscriptionPolicy(f.cache))
+	}
+	if options.Region != nil {
+		postMuxMiddleware.init(options.Region.MiddlewareValidateLocation)
+	}
+	if options.Spec != nil {
+		postMuxMiddleware.init(options.Spec.MiddlewareValidateRequestSchema)
+	}
 	mux.Handle(
 		MuxPattern(http.MethodGet, PatternSubscriptions, PatternProviders),
 		postMuxMiddleware.HandlerFunc(f.ArmResourceListBySubscription))
 	mux.Handle(
 		MuxPattern(http.MethodGet, PatternSubscriptions, PatternLocations, PatternProviders),
 		postMuxMiddleware.HandlerFunc(f.ArmResourceListByLocation))
+	mux.Handle(
+		MuxPattern(http.MethodGet, PatternSubscriptions, "providers", api.ProviderNamespace, PatternLocations, "usages"),
+		postMuxMiddleware.HandlerFunc(f.ArmLocationUsages))
 	mux.Handle(
 		MuxPattern(http.MethodGet, PatternSubscriptions, PatternResourceGroups, PatternProviders),
 		postMuxMiddleware.HandlerFunc(f.ArmResourceListByResourceGroup))
@@ -126,6 +169,7 @@ func NewFrontend(logger *slog.Logger, listener net.Listener, emitter metrics.Emi
 	// Exclude ARO-HCP API version validation for endpoints defined by ARM.
 	postMuxMiddleware = NewMiddleware(
 		MiddlewareLoggingPostMux,
+		MiddlewareValidateStatic,
 		subscriptionStateMuxValidator.MiddlewareValidateSubscriptionState)
 	mux.Handle(
 		MuxPattern(http.MethodPost, PatternSubscriptions, PatternResourceGroups, "providers", api.ProviderNamespace, PatternDeployments, "preflight"),
@@ -147,7 +191,12 @@ func (f *Frontend) Run(ctx context.Context, stop <-chan struct{}) {
 
 	f.logger.Info(fmt.Sprintf("listening on %s", f.listener.Addr().String()))
 
-	f.ready.Store(true)
+	if f.changeFeed != nil {
+		// Not ready until the cache holds every resource.
+		go f.changeFeed.Run(ctx, f.cache, func() { f.ready.Store(true) }, stop)
+	} else {
+		f.ready.Store(true)
+	}
 
 	err := f.server.Serve(f.listener)
 	if err != http.ErrServerClosed {
@@ -167,10 +216,14 @@ func (f *Frontend) CheckReady() bool {
 }
 
 func (f *Frontend) NotFound(writer http.ResponseWriter, request *http.Request) {
-	arm.WriteError(
-		writer, http.StatusNotFound,
-		arm.CloudErrorCodeNotFound, "",
-		"The requested path could not be found.")
+	if mux, ok := f.server.Handler.(*MiddlewareMux); ok {
+		if allowed := mux.AllowedMethods(request); len(allowed) > 0 {
+			arm.WriteMethodNotAllowedError(writer, request.Method, allowed)
+			return
+		}
+	}
+
+	arm.ErrorNotFound.Write(writer, "")
 }
 
 func (f *Frontend) HealthzReady(writer http.ResponseWriter, request *http.Request) {
@@ -193,14 +246,16 @@ func (f *Frontend) ArmResourceListBySubscription(writer http.ResponseWriter, req
 
 	versionedInterface, err := VersionFromContext(ctx)
 	if err != nil {
-		f.logger.Error(err.Error())
-		arm.WriteInternalServerError(writer)
+		arm.WriteInternalServerError(writer, f.logger, err)
 		return
 	}
 
 	f.logger.Info(fmt.Sprintf("%s: ArmResourceListBySubscription", versionedInterface))
 
-	writer.WriteHeader(http.StatusOK)
+	subscriptionID := request.PathValue(PathSegmentSubscriptionID)
+	f.listClusters(writer, request, versionedInterface, func(id *arm.ResourceID, _ *api.HCPOpenShiftCluster) bool {
+		return strings.EqualFold(id.SubscriptionID(), subscriptionID)
+	})
 }
 
 func (f *Frontend) ArmResourceListByLocation(writer http.ResponseWriter, request *http.Request) {
@@ -208,14 +263,36 @@ func (f *Frontend) ArmResourceListByLocation(writer http.ResponseWriter, request
 
 	versionedInterface, err := VersionFromContext(ctx)
 	if err != nil {
-		f.logger.Error(err.Error())
-		arm.WriteInternalServerError(writer)
+		arm.WriteInternalServerError(writer, f.logger, err)
 		return
 	}
 
 	f.logger.Info(fmt.Sprintf("%s: ArmResourceListByLocation", versionedInterface))
 
-	writer.WriteHeader(http.StatusOK)
+	subscriptionID := request.PathValue(PathSegmentSubscriptionID)
+	location := normalizeLocation(request.PathValue(PageSegmentLocation))
+	f.listClusters(writer, request, versionedInterface, func(id *arm.ResourceID, cluster *api.HCPOpenShiftCluster) bool {
+		return strings.EqualFold(id.SubscriptionID(), subscriptionID) && normalizeLocation(cluster.Location) == location
+	})
+}
+
+func (f *Frontend) ArmLocationUsages(writer http.ResponseWriter, request *http.Request) {
+	ctx := request.Context()
+
+	versionedInterface, err := VersionFromContext(ctx)
+	if err != nil {
+		arm.WriteInternalServerError(writer, f.logger, err)
+		return
+	}
+
+	f.logger.Info(fmt.Sprintf("%s: ArmLocationUsages", versionedInterface))
+
+	usages := arm.UsageList{Value: []arm.Usage{}}
+	if f.quota != nil {
+		usages = f.quota.Usages(f.cache, request.PathValue(PathSegmentSubscriptionID), request.PathValue(PageSegmentLocation))
+	}
+
+	arm.WriteJSONResponse(writer, f.logger, http.StatusOK, usages)
 }
 
 func (f *Frontend) ArmResourceListByResourceGroup(writer http.ResponseWriter, request *http.Request) {
@@ -223,14 +300,18 @@ func (f *Frontend) ArmResourceListByResourceGroup(writer http.ResponseWriter, re
 
 	versionedInterface, err := VersionFromContext(ctx)
 	if err != nil {
-		f.logger.Error(err.Error())
-		arm.WriteInternalServerError(writer)
+		arm.WriteInternalServerError(writer, f.logger, err)
 		return
 	}
 
 	f.logger.Info(fmt.Sprintf("%s: ArmResourceListByResourceGroup", versionedInterface))
 
-	writer.WriteHeader(http.StatusOK)
+	subscriptionID := request.PathValue(PathSegmentSubscriptionID)
+	resourceGroupName := request.PathValue(PathSegmentResourceGroupName)
+	f.listClusters(writer, request, versionedInterface, func(id *arm.ResourceID, _ *api.HCPOpenShiftCluster) bool {
+		return strings.EqualFold(id.SubscriptionID(), subscriptionID) &&
+			strings.EqualFold(id.ResourceGroupName(), resourceGroupName)
+	})
 }
 
 func (f *Frontend) ArmResourceRead(writer http.ResponseWriter, request *http.Request) {
@@ -238,8 +319,7 @@ func (f *Frontend) ArmResourceRead(writer http.ResponseWriter, request *http.Req
 
 	versionedInterface, err := VersionFromContext(ctx)
 	if err != nil {
-		f.logger.Error(err.Error())
-		arm.WriteInternalServerError(writer)
+		arm.WriteInternalServerError(writer, f.logger, err)
 		return
 	}
 
@@ -249,49 +329,71 @@ func (f *Frontend) ArmResourceRead(writer http.ResponseWriter, request *http.Req
 	resourceID := request.URL.Path
 	cluster, found := f.cache.GetCluster(resourceID)
 	if !found {
-		writer.WriteHeader(http.StatusNotFound)
+		f.writeResourceNotFoundError(writer, request, resourceID)
 		return
 	}
-	versionedResource := versionedInterface.NewHCPOpenShiftCluster(cluster)
-	resp, err := json.Marshal(versionedResource)
+	versionedResource := versionedInterface.NewHCPOpenShiftCluster(f.withAvailableUpgrades(cluster))
+	arm.WriteJSONResponse(writer, f.logger, http.StatusOK, versionedResource)
+}
+
+func (f *Frontend) ArmResourceCreateOrUpdate(writer http.ResponseWriter, request *http.Request) {
+	ctx := request.Context()
+
+	versionedInterface, err := VersionFromContext(ctx)
 	if err != nil {
-		f.logger.Error(err.Error())
-		arm.WriteInternalServerError(writer)
+		arm.WriteInternalServerError(writer, f.logger, err)
 		return
 	}
-	_, err = writer.Write(resp)
-	if err != nil {
-		f.logger.Error(err.Error())
-	}
-	writer.WriteHeader(http.StatusOK)
-}
 
-func (f *Frontend) ArmResourceCreateOrUpdate(writer http.ResponseWriter, request *http.Request) {
-	var err error
+	f.logger.Info(fmt.Sprintf("%s: ArmResourceCreateOrUpdate", versionedInterface))
+
+	f.writeCluster(writer, request, versionedInterface)
+}
 
+func (f *Frontend) ArmResourcePatch(writer http.ResponseWriter, request *http.Request) {
 	ctx := request.Context()
 
 	versionedInterface, err := VersionFromContext(ctx)
 	if err != nil {
-		f.logger.Error(err.Error())
-		arm.WriteInternalServerError(writer)
+		arm.WriteInternalServerError(writer, f.logger, err)
 		return
 	}
 
-	f.logger.Info(fmt.Sprintf("%s: ArmResourceCreateOrUpdate", versionedInterface))
+	f.logger.Info(fmt.Sprintf("%s: ArmResourcePatch", versionedInterface))
+
+	f.writeCluster(writer, request, versionedInterface)
+}
+
+// writeCluster creates or updates a cluster from a PUT or PATCH request.
+// A PUT request body replaces the cluster, whereas a PATCH request body
+// is merged into the existing cluster.
+func (f *Frontend) writeCluster(writer http.ResponseWriter, request *http.Request, versionedInterface api.Version) {
+	var err error
+
+	ctx := request.Context()
 
 	// URL path is already lowercased by middleware.
 	resourceID := request.URL.Path
 
 	cluster, updating := f.cache.GetCluster(resourceID)
 
-	versionedRequestCluster := versionedInterface.NewHCPOpenShiftCluster(nil)
-	versionedCurrentCluster := versionedInterface.NewHCPOpenShiftCluster(cluster)
+	var versionedRequestCluster api.VersionedHCPOpenShiftCluster
+	if request.Method == http.MethodPatch {
+		if !updating {
+			f.writeResourceNotFoundError(writer, request, resourceID)
+			return
+		}
+		versionedRequestCluster = versionedInterface.NewHCPOpenShiftCluster(f.withAvailableUpgrades(cluster))
+	} else {
+		versionedRequestCluster = versionedInterface.NewHCPOpenShiftCluster(nil)
+	}
+	// Compare against the cluster as clients see it in responses,
+	// so a cluster read and written back unmodified is accepted.
+	versionedCurrentCluster := versionedInterface.NewHCPOpenShiftCluster(f.withAvailableUpgrades(cluster))
 
 	body, err := BodyFromContext(ctx)
 	if err != nil {
-		f.logger.Error(err.Error())
-		arm.WriteInternalServerError(writer)
+		arm.WriteInternalServerError(writer, f.logger, err)
 		return
 	}
 	if err = json.Unmarshal(body, versionedRequestCluster); err != nil {
@@ -306,61 +408,109 @@ func (f *Frontend) ArmResourceCreateOrUpdate(writer http.ResponseWriter, request
 		return
 	}
 
+	currentCluster := cluster
 	cluster = api.NewDefaultHCPOpenShiftCluster()
 	versionedRequestCluster.Normalize(cluster)
+	// Available upgrades are computed for each response, never stored.
+	cluster.Properties.Spec.Version.AvailableUpgrades = nil
+	var storedSystemData *arm.SystemData
+	if updating {
+		// Write-only fields are never returned to clients,
+		// so an absent value means "keep the existing value".
+		cluster.PreserveWriteOnly(currentCluster)
+		// Fields added in newer API versions must survive
+		// updates from clients using older API versions.
+		cluster.PreserveUnavailable(currentCluster, versionedInterface)
+		storedSystemData = currentCluster.SystemData
+	} else {
+		currentCluster = nil
+	}
+	stampSystemData(ctx, &cluster.Resource, storedSystemData)
+
+	// ARM requires the resource ID and name to keep the casing they
+	// were created with, whatever the casing of later request URLs.
+	parsed, err := arm.ParseResourceID(originalResourceID(ctx, resourceID))
+	if err != nil {
+		f.logger.Error(err.Error())
+		arm.ErrorInvalidResourceID.Write(writer, "", err)
+		return
+	}
+	if currentCluster != nil && currentCluster.ID != "" {
+		cluster.ID = currentCluster.ID
+		cluster.Name = currentCluster.Name
+	} else {
+		cluster.ID = parsed.String()
+		cluster.Name = parsed.Name()
+	}
+	cluster.Type = api.ResourceType
+
+	errorDetails, err := f.validateClusterDynamic(ctx, f.cache, request.PathValue(PathSegmentSubscriptionID), resourceID, currentCluster, cluster)
+	if err != nil {
+		arm.WriteInternalServerError(writer, f.logger, err)
+		return
+	}
+	if cloudError := arm.NewContentValidationError(errorDetails); cloudError != nil {
+		f.logger.Error(cloudError.Error())
+		arm.WriteCloudError(writer, cloudError)
+		return
+	}
+
+	// Documents are partitioned by the lowercased subscription ID, which
+	// only the resource ID and name above keep in its original casing.
+	subscriptionID := strings.ToLower(parsed.SubscriptionID())
 
-	parsed, _ := azure.ParseResourceID(resourceID)
 	var doc *HCPOpenShiftClusterDocument
-	doc, found, err := f.dbClient.GetClusterDoc(ctx, resourceID, parsed.SubscriptionID)
+	doc, found, err := f.dbClient.GetClusterDoc(ctx, resourceID, subscriptionID)
 	if err != nil {
-		f.logger.Error("failed to fetch document for %s: %v", resourceID, err)
-		arm.WriteInternalServerError(writer)
+		arm.WriteInternalServerError(writer, f.logger, fmt.Errorf("failed to fetch document for %s: %w", resourceID, err))
 		return
 	}
 	if !found {
 		f.logger.Info(fmt.Sprintf("existing document not found for cluster - creating one for %s", resourceID))
 		doc = &HCPOpenShiftClusterDocument{
 			ID:           uuid.New().String(),
-			Key:          resourceID,
+			Key:          cluster.ID,
 			ClusterID:    NewUID(),
-			PartitionKey: parsed.SubscriptionID,
+			PartitionKey: subscriptionID,
 		}
 	}
+	doc.SetCluster(cluster)
 	err = f.dbClient.SetClusterDoc(ctx, doc)
 	if err != nil {
-		f.logger.Error("failed to create document for resource %s: %v", resourceID, err)
+		arm.WriteInternalServerError(writer, f.logger, fmt.Errorf("failed to create document for %s: %w", resourceID, err))
+		return
 	}
 	f.logger.Info(fmt.Sprintf("document created for %s", resourceID))
 
 	f.cache.SetCluster(resourceID, cluster)
 
-	resp, err := json.Marshal(versionedRequestCluster)
-	if err != nil {
-		f.logger.Error(err.Error())
-		arm.WriteInternalServerError(writer)
-		return
-	}
-	f.logger.Info(fmt.Sprintf("%s: ArmResourceCreateOrUpdate", versionedInterface))
-	_, err = writer.Write(resp)
-	if err != nil {
-		f.logger.Error(err.Error())
+	// Writes complete synchronously, so respond with the stored resource:
+	// 201 Created for a new resource and 200 OK for an updated resource.
+	statusCode := http.StatusOK
+	if !updating {
+		statusCode = http.StatusCreated
 	}
-	writer.WriteHeader(http.StatusCreated)
+	arm.WriteJSONResponse(writer, f.logger, statusCode, versionedInterface.NewHCPOpenShiftCluster(f.withAvailableUpgrades(cluster)))
 }
 
-func (f *Frontend) ArmResourcePatch(writer http.ResponseWriter, request *http.Request) {
-	ctx := request.Context()
-
-	versionedInterface, err := VersionFromContext(ctx)
+// writeResourceNotFoundError writes a ResourceNotFound error for resourceID,
+// using the casing of the request URL.
+func (f *Frontend) writeResourceNotFoundError(writer http.ResponseWriter, request *http.Request, resourceID string) {
+	parsed, err := arm.ParseResourceID(originalResourceID(request.Context(), resourceID))
 	if err != nil {
-		f.logger.Error(err.Error())
-		arm.WriteInternalServerError(writer)
+		arm.WriteInternalServerError(writer, f.logger, err)
 		return
 	}
+	arm.WriteResourceNotFoundError(writer, parsed)
+}
 
-	f.logger.Info(fmt.Sprintf("%s: ArmResourcePatch", versionedInterface))
-
-	writer.WriteHeader(http.StatusAccepted)
+// stampSystemData replaces any client-supplied system data on a resource
+// being written with the stored system data updated from the system data
+// ARM sent with the request. For resource creation stored is nil.
+func stampSystemData(ctx context.Context, resource *arm.Resource, stored *arm.SystemData) {
+	// The context has no system data if ARM did not send any.
+	requestSystemData, _ := SystemDataFromContext(ctx)
+	resource.SystemData = arm.MergeSystemData(stored, requestSystemData)
 }
 
 func (f *Frontend) ArmResourceDelete(writer http.ResponseWriter, request *http.Request) {
@@ -368,8 +518,7 @@ func (f *Frontend) ArmResourceDelete(writer http.ResponseWriter, request *http.R
 
 	versionedInterface, err := VersionFromContext(ctx)
 	if err != nil {
-		f.logger.Error(err.Error())
-		arm.WriteInternalServerError(writer)
+		arm.WriteInternalServerError(writer, f.logger, err)
 		return
 	}
 
@@ -379,21 +528,32 @@ func (f *Frontend) ArmResourceDelete(writer http.ResponseWriter, request *http.R
 	resourceID := request.URL.Path
 	_, found := f.cache.GetCluster(resourceID)
 	if !found {
-		writer.WriteHeader(http.StatusNotFound)
+		// ARM expects 204 No Content when deleting a resource that does not exist.
+		writer.WriteHeader(http.StatusNoContent)
 		return
 	}
-	f.cache.DeleteCluster(resourceID)
 
-	parsed, _ := azure.ParseResourceID(resourceID)
-	err = f.dbClient.DeleteClusterDoc(ctx, resourceID, parsed.SubscriptionID)
+	parsed, err := arm.ParseResourceID(resourceID)
 	if err != nil {
 		f.logger.Error(err.Error())
-		arm.WriteInternalServerError(writer)
+		arm.ErrorInvalidResourceID.Write(writer, "", err)
+		return
+	}
+	err = f.dbClient.DeleteClusterDoc(ctx, resourceID, parsed.SubscriptionID())
+	if err != nil {
+		arm.WriteInternalServerError(writer, f.logger, err)
 		return
 	}
 	f.logger.Info(fmt.Sprintf("document deleted for resource %s", resourceID))
 
-	writer.WriteHeader(http.StatusAccepted)
+	// Evict the cluster only once its document is gone, so
+	// a failed delete leaves the cache matching the database.
+	f.cache.DeleteCluster(resourceID)
+
+	// Deletion completes synchronously. A 202 Accepted response would
+	// require an asynchronous operation for ARM to poll, and the API
+	// specification does not declare 200 OK so SDK clients reject it.
+	writer.WriteHeader(http.StatusNoContent)
 }
 
 func (f *Frontend) ArmResourceAction(writer http.ResponseWriter, request *http.Request) {
@@ -401,8 +561,7 @@ func (f *Frontend) ArmResourceAction(writer http.ResponseWriter, request *http.R
 
 	versionedInterface, err := VersionFromContext(ctx)
 	if err != nil {
-		f.logger.Error(err.Error())
-		arm.WriteInternalServerError(writer)
+		arm.WriteInternalServerError(writer, f.logger, err)
 		return
 	}
 
@@ -416,8 +575,7 @@ func (f *Frontend) ArmSubscriptionAction(writer http.ResponseWriter, request *ht
 
 	body, err := BodyFromContext(ctx)
 	if err != nil {
-		f.logger.Error(err.Error())
-		arm.WriteInternalServerError(writer)
+		arm.WriteInternalServerError(writer, f.logger, err)
 		return
 	}
 
@@ -425,23 +583,44 @@ func (f *Frontend) ArmSubscriptionAction(writer http.ResponseWriter, request *ht
 	err = json.Unmarshal(body, &subscription)
 	if err != nil {
 		f.logger.Error(err.Error())
-		writer.WriteHeader(http.StatusBadRequest)
+		arm.WriteCloudError(writer, arm.NewUnmarshalCloudError(err))
 		return
 	}
 
 	subId := request.PathValue(PathSegmentSubscriptionID)
-	f.cache.SetSubscription(subId, &subscription)
 
-	resp, err := json.Marshal(subscription)
-	if err != nil {
-		f.logger.Error(err.Error())
-		writer.WriteHeader(http.StatusInternalServerError)
-		return
+	// Queue the reaction of the subscription's clusters to a change of
+	// state before recording the state, so that if queueing fails ARM
+	// retries the notification and the change is seen again.
+	var previousState arm.RegistrationState
+	if previous, found := f.cache.GetSubscription(subId); found {
+		previousState = previous.State
+	}
+	if operationRequest := lifecycleRequest(previousState, subscription.State); operationRequest != "" {
+		for _, clusterID := range subscriptionClusters(f.cache, subId) {
+			_, err = enqueueOperation(ctx, f.dbClient, subId, clusterID, operationRequest, time.Now())
+			if err != nil {
+				arm.WriteInternalServerError(writer, f.logger, fmt.Errorf("failed to queue %s operation for %s: %w", operationRequest, clusterID, err))
+				return
+			}
+			f.logger.Info(fmt.Sprintf("%s operation queued for %s", operationRequest, clusterID))
+		}
 	}
-	_, err = writer.Write(resp)
+
+	// Other frontend replicas learn of the subscription from the change feed.
+	err = f.dbClient.SetSubscriptionDoc(ctx, &SubscriptionDocument{
+		ID:           subId,
+		PartitionKey: subId,
+		Subscription: &subscription,
+	})
 	if err != nil {
-		f.logger.Error(err.Error())
+		arm.WriteInternalServerError(writer, f.logger, fmt.Errorf("failed to store document for subscription %s: %w", subId, err))
+		return
 	}
+
+	f.cache.SetSubscription(subId, &subscription)
+
+	arm.WriteJSONResponse(writer, f.logger, http.StatusOK, subscription)
 }
 
 func (f *Frontend) ArmDeploymentPreflight(writer http.ResponseWriter, request *http.Request) {
@@ -455,8 +634,7 @@ func (f *Frontend) ArmDeploymentPreflight(writer http.ResponseWriter, request *h
 
 	body, err := BodyFromContext(ctx)
 	if err != nil {
-		f.logger.Error(err.Error())
-		arm.WriteInternalServerError(writer)
+		arm.WriteInternalServerError(writer, f.logger, err)
 		return
 	}
 
@@ -470,6 +648,11 @@ func (f *Frontend) ArmDeploymentPreflight(writer http.ResponseWriter, request *h
 	validate := api.NewValidator()
 	preflightErrors := []arm.CloudErrorBody{}
 
+	// Resources that pass are added to a copy of the cache, so node
+	// pools can find clusters created by the same deployment and quota
+	// checks count every resource the deployment creates.
+	deployment := f.cache.Clone()
+
 	for index, raw := range deploymentPreflight.Resources {
 		resource := &arm.DeploymentPreflightResource{}
 		err = json.Unmarshal(raw, resource)
@@ -492,17 +675,20 @@ func (f *Frontend) ArmDeploymentPreflight(writer http.ResponseWriter, request *h
 
 		// API version is already validated by this point.
 		versionedInterface, _ := api.Lookup(resource.APIVersion)
-		versionedCluster := versionedInterface.NewHCPOpenShiftCluster(nil)
 
-		err = json.Unmarshal(raw, versionedCluster)
-		if err != nil {
-			// Preflight is best effort: failure to parse a resource is not a validation failure.
-			f.logger.Warn(fmt.Sprintf("Failed to unmarshal %s resource named '%s': %s", resource.Type, resource.Name, err))
-			continue
+		target := resource.Name
+		cacheKey := ""
+		if resourceID, err := resource.ResourceID(subscriptionID, resourceGroup); err == nil {
+			target = resourceID.String()
+			cacheKey = strings.ToLower(target)
 		}
 
-		// Perform static validation as if for a cluster creation request.
-		cloudError := versionedCluster.ValidateStatic(versionedCluster, false, http.MethodPut)
+		var cloudError *arm.CloudError
+		if strings.EqualFold(resource.Type, api.NodePoolResourceType) {
+			cloudError = f.preflightNodePool(ctx, deployment, versionedInterface, subscriptionID, cacheKey, resource, raw)
+		} else {
+			cloudError = f.preflightCluster(ctx, deployment, versionedInterface, subscriptionID, cacheKey, resource, raw)
+		}
 		if cloudError != nil {
 			var details []arm.CloudErrorBody
 
@@ -544,7 +730,7 @@ func (f *Frontend) ArmDeploymentPreflight(writer http.ResponseWriter, request *h
 			preflightErrors = append(preflightErrors, arm.CloudErrorBody{
 				Code:    cloudError.Code,
 				Message: fmt.Sprintf("Content validation failed for '%s'", resource.Name),
-				Target:  resource.ResourceID(subscriptionID, resourceGroup),
+				Target:  target,
 				Details: details,
 			})
 			continue
@@ -555,3 +741,158 @@ func (f *Frontend) ArmDeploymentPreflight(writer http.ResponseWriter, request *h
 
 	arm.WriteDeploymentPreflightResponse(writer, preflightErrors)
 }
+
+// preflightCluster validates a cluster resource from a deployment preflight
+// request as if for a cluster creation request, and adds it to the deployment
+// cache if it passes. The resource ID is the cluster's cache key, and may be
+// empty if it is not known.
+func (f *Frontend) preflightCluster(ctx context.Context, deployment *Cache, version api.Version, subscriptionID, resourceID string, resource *arm.DeploymentPreflightResource, raw json.RawMessage) *arm.CloudError {
+	versionedCluster := version.NewHCPOpenShiftCluster(nil)
+	if err := json.Unmarshal(raw, versionedCluster); err != nil {
+		// Preflight is best effort: failure to parse a resource is not a validation failure.
+		f.logger.Warn(fmt.Sprintf("Failed to unmarshal %s resource named '%s': %s", resource.Type, resource.Name, err))
+		return nil
+	}
+
+	// Perform static validation as if for a cluster creation request.
+	if cloudError := versionedCluster.ValidateStatic(versionedCluster, false, http.MethodPut); cloudError != nil {
+		return cloudError
+	}
+
+	// Perform dynamic validation as if for a cluster creation request.
+	cluster := api.NewDefaultHCPOpenShiftCluster()
+	versionedCluster.Normalize(cluster)
+	details, err := f.validateClusterDynamic(ctx, deployment, subscriptionID, resourceID, nil, cluster)
+	if err != nil {
+		// Preflight is best effort: failure to look up a resource is not a validation failure.
+		f.logger.Warn(fmt.Sprintf("Failed to validate %s resource named '%s': %s", resource.Type, resource.Name, err))
+	}
+	if cloudError := arm.NewContentValidationError(details); cloudError != nil {
+		return cloudError
+	}
+
+	if resourceID != "" {
+		deployment.SetCluster(resourceID, cluster)
+	}
+	return nil
+}
+
+// preflightNodePool is like preflightCluster but for a node pool resource.
+// Node pools are validated against their cluster, which may be in the
+// deployment cache.
+func (f *Frontend) preflightNodePool(ctx context.Context, deployment *Cache, version api.Version, subscriptionID, resourceID string, resource *arm.DeploymentPreflightResource, raw json.RawMessage) *arm.CloudError {
+	versionedNodePool := version.NewHCPOpenShiftClusterNodePool(nil)
+	if err := json.Unmarshal(raw, versionedNodePool); err != nil {
+		// Preflight is best effort: failure to parse a resource is not a validation failure.
+		f.logger.Warn(fmt.Sprintf("Failed to unmarshal %s resource named '%s': %s", resource.Type, resource.Name, err))
+		return nil
+	}
+
+	if cloudError := versionedNodePool.ValidateStatic(); cloudError != nil {
+		return cloudError
+	}
+
+	nodePool := &api.HCPOpenShiftClusterNodePool{}
+	versionedNodePool.Normalize(nodePool)
+
+	cluster, found := deployment.GetCluster(nodePoolClusterID(resourceID))
+	if !found {
+		// Preflight is best effort: the cluster may be created elsewhere.
+		f.logger.Warn(fmt.Sprintf("No cluster found for %s resource named '%s'", resource.Type, resource.Name))
+		return nil
+	}
+
+	details, err := f.validateNodePoolDynamic(ctx, deployment, subscriptionID, resourceID, nodePool, cluster)
+	if err != nil {
+		// Preflight is best effort: failure to look up a resource is not a validation failure.
+		f.logger.Warn(fmt.Sprintf("Failed to validate %s resource named '%s': %s", resource.Type, resource.Name, err))
+	}
+	if cloudError := arm.NewContentValidationError(details); cloudError != nil {
+		return cloudError
+	}
+
+	deployment.SetNodePool(resourceID, nodePool)
+	return nil
+}
+
+// validateClusterDynamic performs validation of a normalized cluster that
+// requires external data, returning request content problems as error
+// details. Quota usage is counted from the given cache. The resource ID is
+// the cluster's cache key, and may be empty if it is not known. For cluster
+// creation current is nil.
+func (f *Frontend) validateClusterDynamic(ctx context.Context, cache *Cache, subscriptionID, resourceID string, current, cluster *api.HCPOpenShiftCluster) ([]arm.CloudErrorBody, error) {
+	var errorDetails []arm.CloudErrorBody
+
+	if f.region != nil {
+		errorDetails = append(errorDetails, f.region.CheckClusterLocation(cluster)...)
+	}
+
+	if f.quota != nil && current == nil && resourceID != "" {
+		errorDetails = append(errorDetails, f.quota.CheckClusterCreation(cache, resourceID, cluster)...)
+	}
+
+	// Capabilities gated by subscription policy are immutable after creation.
+	if f.policy != nil && current == nil {
+		if subscription, found := f.cache.GetSubscription(subscriptionID); found {
+			errorDetails = append(errorDetails, f.policy.CheckClusterCreation(subscriptionID, subscription, cluster)...)
+		}
+	}
+
+	if f.releases != nil {
+		var currentVersion *api.VersionProfile
+		if current != nil {
+			currentVersion = &current.Properties.Spec.Version
+		}
+		errorDetails = append(errorDetails, f.releases.ValidateVersion(currentVersion, &cluster.Properties.Spec.Version)...)
+	}
+
+	// Referenced resources are immutable after creation.
+	if current == nil && f.azureResources != nil {
+		referenceErrors, err := ValidateClusterResourceReferences(ctx, f.azureResources, cluster)
+		if err != nil {
+			return nil, err
+		}
+		errorDetails = append(errorDetails, referenceErrors...)
+	}
+
+	return errorDetails, nil
+}
+
+// validateNodePoolDynamic is like validateClusterDynamic but for a
+// normalized node pool being created in the given cluster. The resource
+// ID is the node pool's cache key.
+func (f *Frontend) validateNodePoolDynamic(ctx context.Context, cache *Cache, subscriptionID, resourceID string, nodePool *api.HCPOpenShiftClusterNodePool, cluster *api.HCPOpenShiftCluster) ([]arm.CloudErrorBody, error) {
+	var errorDetails []arm.CloudErrorBody
+
+	if f.region != nil {
+		if subscription, found := cache.GetSubscription(subscriptionID); found {
+			errorDetails = append(errorDetails, f.region.CheckNodePoolAvailabilityZone(subscriptionID, subscription, nodePool)...)
+		}
+	}
+
+	if f.quota != nil {
+		errorDetails = append(errorDetails, f.quota.CheckNodePool(cache, resourceID, nodePool)...)
+	}
+
+	if f.azureResources != nil {
+		referenceErrors, err := ValidateNodePoolResourceReferences(ctx, f.azureResources, nodePool, cluster)
+		if err != nil {
+			return nil, err
+		}
+		errorDetails = append(errorDetails, referenceErrors...)
+	}
+
+	return errorDetails, nil
+}
+
+// withAvailableUpgrades returns a shallow copy of cluster with available
+// upgrades computed from the release catalog, if there is one.
+func (f *Frontend) withAvailableUpgrades(cluster *api.HCPOpenShiftCluster) *api.HCPOpenShiftCluster {
+	if f.releases == nil || cluster == nil {
+		return cluster
+	}
+	copied := *cluster
+	version := &copied.Properties.Spec.Version
+	version.AvailableUpgrades = f.releases.AvailableUpgrades(version.ChannelGroup, version.ID)
+	return &copied
+}
@@ -0,0 +1,378 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+func TestArmResourceReadAPIVersions(t *testing.T) {
+	resourceID := "/subscriptions/00000000-0000-0000-0000-000000000000/resourcegroups/rg/providers/microsoft.redhatopenshift/hcpopenshiftclusters/cluster"
+
+	// Every API version renders from the same stored cluster.
+	cluster := api.NewDefaultHCPOpenShiftCluster()
+	cluster.Resource.ID = resourceID
+	cluster.Properties.Spec.Version.ID = "4.16.0"
+	cluster.Properties.Spec.Version.ChannelGroup = "stable"
+	cluster.Properties.Spec.Proxy.HTTPProxy = "http://proxy.example.com"
+	cluster.Properties.Spec.Proxy.TrustedCA = "secret"
+	cluster.Properties.Spec.DisableUserWorkloadMonitoring = true
+
+	f := &Frontend{
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cache:  NewCache(),
+	}
+	f.cache.SetCluster(resourceID, cluster)
+
+	tests := []struct {
+		apiVersion                      string
+		expectUserWorkloadMonitoringSet bool
+	}{
+		{
+			apiVersion:                      "2024-06-10-preview",
+			expectUserWorkloadMonitoringSet: true,
+		},
+		{
+			apiVersion:                      "2024-03-01-preview",
+			expectUserWorkloadMonitoringSet: false,
+		},
+	}
+
+	for _, test := range tests {
+		version, _ := api.Lookup(test.apiVersion)
+		t.Run(version.String(), func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, resourceID, nil)
+			request = request.WithContext(ContextWithVersion(request.Context(), version))
+			writer := httptest.NewRecorder()
+
+			f.ArmResourceRead(writer, request)
+
+			if writer.Code != http.StatusOK {
+				t.Fatalf("expected status code %d, got %d", http.StatusOK, writer.Code)
+			}
+
+			var body struct {
+				Properties struct {
+					Spec map[string]json.RawMessage `json:"spec"`
+				} `json:"properties"`
+			}
+			if err := json.Unmarshal(writer.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			spec := body.Properties.Spec
+
+			var versionProfile struct {
+				ID           string `json:"id"`
+				ChannelGroup string `json:"channelGroup"`
+			}
+			if err := json.Unmarshal(spec["version"], &versionProfile); err != nil {
+				t.Fatal(err)
+			}
+			if versionProfile.ID != "4.16.0" || versionProfile.ChannelGroup != "stable" {
+				t.Errorf("unexpected version profile: %s", spec["version"])
+			}
+
+			var proxy map[string]any
+			if err := json.Unmarshal(spec["proxy"], &proxy); err != nil {
+				t.Fatal(err)
+			}
+			if proxy["httpProxy"] != "http://proxy.example.com" {
+				t.Errorf("unexpected proxy profile: %s", spec["proxy"])
+			}
+			if _, found := proxy["trustedCa"]; found {
+				t.Errorf("write-only field trustedCa was rendered")
+			}
+
+			disableUserWorkloadMonitoring, found := spec["disableUserWorkloadMonitoring"]
+			if found != test.expectUserWorkloadMonitoringSet {
+				t.Fatalf("expected disableUserWorkloadMonitoring present to be %v, got %v", test.expectUserWorkloadMonitoringSet, found)
+			}
+			if found && string(disableUserWorkloadMonitoring) != "true" {
+				t.Errorf("expected disableUserWorkloadMonitoring to be true, got %s", disableUserWorkloadMonitoring)
+			}
+		})
+	}
+}
+
+func TestResourceIDCasing(t *testing.T) {
+	const (
+		createdID   = "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/MyGroup/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/MyCluster"
+		apiVersion  = "?" + APIVersionKey + "=2024-06-10-preview"
+		contentType = "application/json"
+	)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dbClient := NewMemoryDBClient()
+	f := NewFrontend(logger, nil, noopEmitter{}, dbClient, FrontendOptions{AzureResources: newTestAzureResourceClient()})
+	f.cache.SetSubscription("00000000-0000-0000-0000-000000000000", &arm.Subscription{State: arm.Registered})
+
+	version, _ := api.Lookup("2024-06-10-preview")
+	clusterBody, err := json.Marshal(version.NewHCPOpenShiftCluster(newTestValidCluster()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(method, url string, body []byte) *httptest.ResponseRecorder {
+		t.Helper()
+
+		request := httptest.NewRequest(method, url, bytes.NewReader(body))
+		request = request.WithContext(ContextWithLogger(request.Context(), logger))
+		if body != nil {
+			request.Header.Set("Content-Type", contentType)
+		}
+		writer := httptest.NewRecorder()
+		f.server.Handler.ServeHTTP(writer, request)
+		if writer.Code >= 300 {
+			t.Fatalf("%s %s: unexpected status %d: %s", method, url, writer.Code, writer.Body.String())
+		}
+		return writer
+	}
+
+	checkResource := func(writer *httptest.ResponseRecorder) {
+		t.Helper()
+
+		var resource arm.Resource
+		if err := json.Unmarshal(writer.Body.Bytes(), &resource); err != nil {
+			t.Fatal(err)
+		}
+		if resource.ID != createdID {
+			t.Errorf("Expected ID %s, got %s", createdID, resource.ID)
+		}
+		if resource.Name != "MyCluster" {
+			t.Errorf("Expected name MyCluster, got %s", resource.Name)
+		}
+	}
+
+	checkResource(serve(http.MethodPut, createdID+apiVersion, clusterBody))
+
+	// Later requests with different casing address the same resource
+	// but do not change the casing of its ID.
+	checkResource(serve(http.MethodPut, strings.ToUpper(createdID)+apiVersion, clusterBody))
+	checkResource(serve(http.MethodPatch, strings.ToLower(createdID)+apiVersion, []byte(`{}`)))
+	checkResource(serve(http.MethodGet, strings.ToLower(createdID)+apiVersion, nil))
+
+	doc, found, err := dbClient.GetClusterDoc(context.Background(), strings.ToLower(createdID), "00000000-0000-0000-0000-000000000000")
+	if err != nil || !found {
+		t.Fatalf("Expected cluster document, got found=%v err=%v", found, err)
+	}
+	if doc.Key != createdID {
+		t.Errorf("Expected document key %s, got %s", createdID, doc.Key)
+	}
+}
+
+func TestArmResourceCreateOrUpdatePreservesNewerFields(t *testing.T) {
+	const clusterID = "/subscriptions/00000000-0000-0000-0000-000000000000/resourcegroups/rg/providers/microsoft.redhatopenshift/hcpopenshiftclusters/cluster"
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	f := NewFrontend(logger, nil, noopEmitter{}, NewMemoryDBClient(), FrontendOptions{AzureResources: newTestAzureResourceClient()})
+	f.cache.SetSubscription("00000000-0000-0000-0000-000000000000", &arm.Subscription{State: arm.Registered})
+
+	cluster := newTestValidCluster()
+	cluster.Properties.Spec.DisableUserWorkloadMonitoring = true
+
+	serve := func(method, apiVersion string, body []byte) {
+		t.Helper()
+
+		request := httptest.NewRequest(method, clusterID+"?"+APIVersionKey+"="+apiVersion, bytes.NewReader(body))
+		request = request.WithContext(ContextWithLogger(request.Context(), logger))
+		request.Header.Set("Content-Type", "application/json")
+		writer := httptest.NewRecorder()
+		f.server.Handler.ServeHTTP(writer, request)
+		if writer.Code >= 300 {
+			t.Fatalf("%s with %s: unexpected status %d: %s", method, apiVersion, writer.Code, writer.Body.String())
+		}
+	}
+
+	for _, apiVersion := range []string{"2024-06-10-preview", "2024-03-01-preview"} {
+		version, _ := api.Lookup(apiVersion)
+		body, err := json.Marshal(version.NewHCPOpenShiftCluster(cluster))
+		if err != nil {
+			t.Fatal(err)
+		}
+		serve(http.MethodPut, apiVersion, body)
+	}
+
+	// The older version cannot see disableUserWorkloadMonitoring,
+	// so its PUT must keep the value set through the newer version.
+	stored, found := f.cache.GetCluster(clusterID)
+	if !found {
+		t.Fatal("Expected the cluster to be cached")
+	}
+	if !stored.Properties.Spec.DisableUserWorkloadMonitoring {
+		t.Error("Expected disableUserWorkloadMonitoring to be preserved by a PUT with an older API version")
+	}
+}
+
+func TestArmResourceCreateOrUpdateReadOnlyIdentity(t *testing.T) {
+	const (
+		clusterID  = "/subscriptions/00000000-0000-0000-0000-000000000000/resourcegroups/rg/providers/microsoft.redhatopenshift/hcpopenshiftclusters/cluster"
+		identityID = "/subscriptions/00000000-0000-0000-0000-000000000000/resourcegroups/rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/identity"
+	)
+
+	tests := []struct {
+		name         string
+		identity     *arm.ManagedServiceIdentity
+		expectTarget string
+	}{
+		{
+			name: "Principal ID",
+			identity: &arm.ManagedServiceIdentity{
+				Type:        arm.ManagedServiceIdentityTypeSystemAssigned,
+				PrincipalID: "00000000-0000-0000-0000-000000000001",
+			},
+			expectTarget: "identity.principalId",
+		},
+		{
+			name: "Tenant ID",
+			identity: &arm.ManagedServiceIdentity{
+				Type:     arm.ManagedServiceIdentityTypeSystemAssigned,
+				TenantID: "00000000-0000-0000-0000-000000000001",
+			},
+			expectTarget: "identity.tenantId",
+		},
+		{
+			name: "User assigned client ID",
+			identity: &arm.ManagedServiceIdentity{
+				Type: arm.ManagedServiceIdentityTypeUserAssigned,
+				UserAssignedIdentities: map[string]*arm.UserAssignedIdentity{
+					identityID: {ClientID: "00000000-0000-0000-0000-000000000001"},
+				},
+			},
+			expectTarget: "identity.userAssignedIdentities[\"" + identityID + "\"].clientId",
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	version, _ := api.Lookup("2024-06-10-preview")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFrontend(logger, nil, noopEmitter{}, NewMemoryDBClient(), FrontendOptions{AzureResources: newTestAzureResourceClient()})
+			f.cache.SetSubscription("00000000-0000-0000-0000-000000000000", &arm.Subscription{State: arm.Registered})
+
+			cluster := newTestValidCluster()
+			cluster.Identity = tt.identity
+			body, err := json.Marshal(version.NewHCPOpenShiftCluster(cluster))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			request := httptest.NewRequest(http.MethodPut, clusterID+"?"+APIVersionKey+"=2024-06-10-preview", bytes.NewReader(body))
+			request = request.WithContext(ContextWithLogger(request.Context(), logger))
+			request.Header.Set("Content-Type", "application/json")
+			writer := httptest.NewRecorder()
+			f.server.Handler.ServeHTTP(writer, request)
+
+			if writer.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status %d, got %d: %s", http.StatusBadRequest, writer.Code, writer.Body.String())
+			}
+			var cloudError arm.CloudError
+			if err = json.Unmarshal(writer.Body.Bytes(), &cloudError); err != nil {
+				t.Fatal(err)
+			}
+			if cloudError.Target != tt.expectTarget {
+				t.Errorf("Expected target %s, got %s: %s", tt.expectTarget, cloudError.Target, writer.Body.String())
+			}
+		})
+	}
+}
+
+// failingDeleteDBClient fails to delete cluster documents.
+type failingDeleteDBClient struct {
+	*MemoryDBClient
+}
+
+func (c *failingDeleteDBClient) DeleteClusterDoc(ctx context.Context, resourceID string, partitionKey string) error {
+	return errors.New("database unavailable")
+}
+
+func TestArmResourceDeleteFailure(t *testing.T) {
+	const clusterID = "/subscriptions/00000000-0000-0000-0000-000000000000/resourcegroups/rg/providers/microsoft.redhatopenshift/hcpopenshiftclusters/cluster"
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dbClient := &failingDeleteDBClient{NewMemoryDBClient()}
+	f := NewFrontend(logger, nil, noopEmitter{}, dbClient, FrontendOptions{AzureResources: newTestAzureResourceClient()})
+	f.cache.SetSubscription("00000000-0000-0000-0000-000000000000", &arm.Subscription{State: arm.Registered})
+
+	version, _ := api.Lookup("2024-06-10-preview")
+	clusterBody, err := json.Marshal(version.NewHCPOpenShiftCluster(newTestValidCluster()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, step := range []struct {
+		method       string
+		body         []byte
+		expectStatus int
+	}{
+		{http.MethodPut, clusterBody, http.StatusCreated},
+		{http.MethodDelete, nil, http.StatusInternalServerError},
+	} {
+		request := httptest.NewRequest(step.method, clusterID+"?"+APIVersionKey+"=2024-06-10-preview", bytes.NewReader(step.body))
+		request = request.WithContext(ContextWithLogger(request.Context(), logger))
+		if step.body != nil {
+			request.Header.Set("Content-Type", "application/json")
+		}
+		writer := httptest.NewRecorder()
+		f.server.Handler.ServeHTTP(writer, request)
+		if writer.Code != step.expectStatus {
+			t.Fatalf("%s: expected status %d, got %d: %s", step.method, step.expectStatus, writer.Code, writer.Body.String())
+		}
+	}
+
+	// The document survived, so the cluster must stay cached.
+	if _, found := f.cache.GetCluster(clusterID); !found {
+		t.Error("Expected the cluster to stay cached after a failed delete")
+	}
+}
+
+// failingSetDBClient fails to write cluster documents.
+type failingSetDBClient struct {
+	*MemoryDBClient
+}
+
+func (c *failingSetDBClient) SetClusterDoc(ctx context.Context, doc *HCPOpenShiftClusterDocument) error {
+	return errors.New("database unavailable")
+}
+
+func TestArmResourceCreateOrUpdateFailure(t *testing.T) {
+	const clusterID = "/subscriptions/00000000-0000-0000-0000-000000000000/resourcegroups/rg/providers/microsoft.redhatopenshift/hcpopenshiftclusters/cluster"
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dbClient := &failingSetDBClient{NewMemoryDBClient()}
+	f := NewFrontend(logger, nil, noopEmitter{}, dbClient, FrontendOptions{AzureResources: newTestAzureResourceClient()})
+	f.cache.SetSubscription("00000000-0000-0000-0000-000000000000", &arm.Subscription{State: arm.Registered})
+
+	version, _ := api.Lookup("2024-06-10-preview")
+	clusterBody, err := json.Marshal(version.NewHCPOpenShiftCluster(newTestValidCluster()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := httptest.NewRequest(http.MethodPut, clusterID+"?"+APIVersionKey+"=2024-06-10-preview", bytes.NewReader(clusterBody))
+	request = request.WithContext(ContextWithLogger(request.Context(), logger))
+	request.Header.Set("Content-Type", "application/json")
+	writer := httptest.NewRecorder()
+	f.server.Handler.ServeHTTP(writer, request)
+	if writer.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusInternalServerError, writer.Code, writer.Body.String())
+	}
+
+	// The document was not written, so the cluster must not be cached.
+	if _, found := f.cache.GetCluster(clusterID); found {
+		t.Error("Expected the cluster not to be cached after a failed write")
+	}
+}
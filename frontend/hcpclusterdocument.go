@@ -1,12 +1,42 @@
 package main
 
+import (
+	"path"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
 // HCPOpenShiftClusterDocument represents an HCP OpenShift cluster document.
+// Key holds the resource ID with the casing used to create the resource;
+// documents are looked up by key case-insensitively.
 type HCPOpenShiftClusterDocument struct {
 	ID           string `json:"id,omitempty"`
 	Key          string `json:"key,omitempty"`
 	PartitionKey string `json:"partitionKey,omitempty"`
 	ClusterID    string `json:"clusterid,omitempty"`
 
+	// Cluster state, from which other frontend replicas
+	// rebuild the cluster when they read the change feed
+	Location   string                             `json:"location,omitempty"`
+	Tags       map[string]string                  `json:"tags,omitempty"`
+	Properties *api.HCPOpenShiftClusterProperties `json:"properties,omitempty"`
+
+	// Identities assigned to the cluster, for use by cluster operators
+	Identity *arm.ManagedServiceIdentity `json:"identity,omitempty"`
+
+	// Creation and modification metadata supplied by ARM
+	SystemData *arm.SystemData `json:"systemData,omitempty"`
+
+	// Whether the cluster's workloads are to be hibernated because
+	// its subscription is suspended, for the backend to act on
+	Hibernated bool `json:"hibernated,omitempty"`
+
+	// Deleted clusters leave a tombstone so the deletion appears
+	// in the change feed. Cosmos removes it after TTL seconds.
+	Deleted bool `json:"deleted,omitempty"`
+	TTL     int  `json:"ttl,omitempty"`
+
 	// Values provided by Cosmos after doc creation
 	ResourceID  string `json:"_rid,omitempty"`
 	Self        string `json:"_self,omitempty"`
@@ -14,3 +44,32 @@ type HCPOpenShiftClusterDocument struct {
 	Attachments string `json:"_attachments,omitempty"`
 	Timestamp   int    `json:"_ts,omitempty"`
 }
+
+// SetCluster records the state of a cluster in the document.
+func (doc *HCPOpenShiftClusterDocument) SetCluster(cluster *api.HCPOpenShiftCluster) {
+	doc.Location = cluster.Location
+	doc.Tags = cluster.Tags
+	doc.Properties = &cluster.Properties
+	doc.Identity = cluster.Identity
+	doc.SystemData = cluster.SystemData
+}
+
+// Cluster returns the cluster recorded in the document, or nil if
+// the document predates documents recording cluster state.
+func (doc *HCPOpenShiftClusterDocument) Cluster() *api.HCPOpenShiftCluster {
+	if doc.Properties == nil {
+		return nil
+	}
+
+	cluster := &api.HCPOpenShiftCluster{
+		Identity:   doc.Identity,
+		Properties: *doc.Properties,
+	}
+	cluster.ID = doc.Key
+	cluster.Name = path.Base(doc.Key)
+	cluster.Type = api.ResourceType
+	cluster.SystemData = doc.SystemData
+	cluster.Location = doc.Location
+	cluster.Tags = doc.Tags
+	return cluster
+}
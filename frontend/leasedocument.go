@@ -0,0 +1,25 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+// LeaseDocument records how far a frontend replica has read the change
+// feed of each container. ID and PartitionKey both hold the lease name.
+type LeaseDocument struct {
+	ID           string `json:"id,omitempty"`
+	PartitionKey string `json:"partitionKey,omitempty"`
+
+	// Change feed continuations, by container
+	Continuations map[string]string `json:"continuations,omitempty"`
+
+	// Leases are rewritten on every poll, so Cosmos removes
+	// those of replicas that no longer run after TTL seconds.
+	TTL int `json:"ttl,omitempty"`
+
+	// Values provided by Cosmos after doc creation
+	ResourceID  string `json:"_rid,omitempty"`
+	Self        string `json:"_self,omitempty"`
+	ETag        string `json:"_etag,omitempty"`
+	Attachments string `json:"_attachments,omitempty"`
+	Timestamp   int    `json:"_ts,omitempty"`
+}
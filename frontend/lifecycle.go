@@ -0,0 +1,269 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+	"github.com/Azure/ARO-HCP/internal/metrics"
+)
+
+const (
+	operationPollInterval = 10 * time.Second
+	operationMaxAttempts  = 5
+	operationRetryBackoff = 30 * time.Second
+
+	// An operation that stays in progress this long was abandoned
+	// by the replica that claimed it and may be claimed again.
+	operationClaimTimeout = 5 * time.Minute
+
+	// Operations are kept for a week to show what happened to a cluster.
+	operationTTL = 7 * 24 * 60 * 60
+)
+
+// lifecycleRequest returns the operation to perform on each cluster
+// in a subscription that moves from the previous to the current state,
+// or an empty string if the transition requires none. Per the resource
+// provider subscription lifecycle contract, suspension hibernates
+// workloads, registering again resumes them and deletion purges them.
+// A warning alone requires no action.
+func lifecycleRequest(previous, current arm.RegistrationState) OperationRequest {
+	switch {
+	case current == arm.Deleted && previous != arm.Deleted:
+		return OperationRequestPurge
+	case current == arm.Suspended && previous != arm.Suspended:
+		return OperationRequestHibernate
+	case previous == arm.Suspended && (current == arm.Registered || current == arm.Warned):
+		return OperationRequestResume
+	default:
+		return ""
+	}
+}
+
+// subscriptionClusters returns the cache keys of the
+// cached clusters in a subscription.
+func subscriptionClusters(cache *Cache, subscriptionID string) []string {
+	var ids []string
+	for id := range cache.Clusters() {
+		resourceID, err := arm.ParseResourceID(id)
+		if err == nil && strings.EqualFold(resourceID.SubscriptionID(), subscriptionID) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// enqueueOperation stores a new operation on a cluster and cancels the
+// cluster's operations waiting to run or to be retried, which it supersedes.
+func enqueueOperation(ctx context.Context, dbClient DBClient, subscriptionID, clusterID string, request OperationRequest, now time.Time) (*OperationDocument, error) {
+	docs, err := dbClient.ListOperationDocs(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, doc := range docs {
+		if doc.ExternalID != clusterID || doc.Status != arm.ProvisioningStateAccepted {
+			continue
+		}
+		doc.Status = arm.ProvisioningStateCanceled
+		doc.LastTransitionTime = now
+		// An operation claimed meanwhile runs before the new one.
+		if _, err = dbClient.SetOperationDoc(ctx, doc); err != nil && !errors.Is(err, ErrDocumentConflict) {
+			return nil, err
+		}
+	}
+
+	return dbClient.SetOperationDoc(ctx, &OperationDocument{
+		ID:                 uuid.New().String(),
+		PartitionKey:       subscriptionID,
+		Request:            request,
+		ExternalID:         clusterID,
+		Status:             arm.ProvisioningStateAccepted,
+		StartTime:          now,
+		LastTransitionTime: now,
+		TTL:                operationTTL,
+	})
+}
+
+// OperationRunner performs the operations queued for the clusters of
+// the cached subscriptions.
+//
+// Every frontend replica with a database runs one. A replica claims an
+// operation by updating its status, which fails if another replica
+// changed the operation first, so each attempt runs on one replica.
+// A cluster's operations run one at a time in the order they were
+// queued. A failed attempt is retried with exponential backoff until
+// the operation has been attempted operationMaxAttempts times.
+type OperationRunner struct {
+	logger   *slog.Logger
+	dbClient DBClient
+	cache    *Cache
+	metrics  metrics.Emitter
+
+	pollInterval time.Duration
+	now          func() time.Time
+}
+
+// NewOperationRunner returns an operation runner for
+// the subscriptions and clusters held in cache.
+func NewOperationRunner(logger *slog.Logger, dbClient DBClient, cache *Cache, emitter metrics.Emitter) *OperationRunner {
+	return &OperationRunner{
+		logger:       logger,
+		dbClient:     dbClient,
+		cache:        cache,
+		metrics:      emitter,
+		pollInterval: operationPollInterval,
+		now:          time.Now,
+	}
+}
+
+// Run performs queued operations until stop is closed.
+func (r *OperationRunner) Run(ctx context.Context, stop <-chan struct{}) {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			r.Process(ctx)
+		}
+	}
+}
+
+// Process performs the operations that are due in each cached subscription.
+func (r *OperationRunner) Process(ctx context.Context) {
+	for subscriptionID := range r.cache.Subscriptions() {
+		if err := r.process(ctx, subscriptionID); err != nil {
+			r.logger.Error(fmt.Sprintf("Processing operations for subscription %s failed: %v", subscriptionID, err))
+		}
+	}
+}
+
+func (r *OperationRunner) process(ctx context.Context, subscriptionID string) error {
+	docs, err := r.dbClient.ListOperationDocs(ctx, subscriptionID)
+	if err != nil {
+		return err
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		return docs[i].StartTime.Before(docs[j].StartTime)
+	})
+
+	// Only the oldest unfinished operation on a cluster may run.
+	blocked := make(map[string]bool)
+	for _, doc := range docs {
+		if doc.Terminal() || blocked[doc.ExternalID] {
+			continue
+		}
+		blocked[doc.ExternalID] = true
+
+		if r.due(doc) {
+			r.perform(ctx, doc)
+		}
+	}
+	return nil
+}
+
+// due returns true if an operation is waiting and its retry backoff has
+// passed, or if the replica that claimed it has not finished in time.
+func (r *OperationRunner) due(doc *OperationDocument) bool {
+	now := r.now()
+	if doc.Status == arm.ProvisioningStateAccepted {
+		return !now.Before(doc.NotBefore)
+	}
+	return now.Sub(doc.LastTransitionTime) >= operationClaimTimeout
+}
+
+// perform claims an operation, attempts it and records the outcome.
+func (r *OperationRunner) perform(ctx context.Context, doc *OperationDocument) {
+	doc.Status = doc.runningStatus()
+	doc.LastTransitionTime = r.now()
+	doc.Attempts++
+	claimed, err := r.dbClient.SetOperationDoc(ctx, doc)
+	if errors.Is(err, ErrDocumentConflict) {
+		// Another replica claimed the operation.
+		return
+	} else if err != nil {
+		r.logger.Error(fmt.Sprintf("Claiming operation %s failed: %v", doc.ID, err))
+		return
+	}
+	doc = claimed
+
+	r.logger.Info(fmt.Sprintf("%s %s: attempt %d", doc.Request, doc.ExternalID, doc.Attempts))
+
+	err = r.attempt(ctx, doc)
+	switch {
+	case err == nil:
+		doc.Status = arm.ProvisioningStateSucceeded
+		doc.Error = nil
+	case doc.Attempts < operationMaxAttempts:
+		r.logger.Warn(fmt.Sprintf("%s %s: attempt %d failed: %v", doc.Request, doc.ExternalID, doc.Attempts, err))
+		doc.Status = arm.ProvisioningStateAccepted
+		doc.NotBefore = r.now().Add(operationRetryBackoff << (doc.Attempts - 1))
+		doc.Error = &arm.CloudErrorBody{Code: arm.CloudErrorCodeInternalServerError, Message: err.Error()}
+	default:
+		r.logger.Error(fmt.Sprintf("%s %s: giving up after %d attempts: %v", doc.Request, doc.ExternalID, doc.Attempts, err))
+		doc.Status = arm.ProvisioningStateFailed
+		doc.Error = &arm.CloudErrorBody{Code: arm.CloudErrorCodeInternalServerError, Message: err.Error()}
+	}
+	doc.LastTransitionTime = r.now()
+
+	if _, err = r.dbClient.SetOperationDoc(ctx, doc); err != nil {
+		// The operation is attempted again once its claim times out.
+		r.logger.Error(fmt.Sprintf("Recording the outcome of operation %s failed: %v", doc.ID, err))
+		return
+	}
+
+	r.metrics.EmitCounter("frontend_lifecycle_operation_count", 1.0, map[string]string{
+		"request": string(doc.Request),
+		"status":  string(doc.Status),
+	})
+}
+
+// attempt performs an operation. A cluster that no longer
+// exists needs nothing done, so the operation succeeds.
+func (r *OperationRunner) attempt(ctx context.Context, doc *OperationDocument) error {
+	clusterDoc, found, err := r.dbClient.GetClusterDoc(ctx, doc.ExternalID, doc.PartitionKey)
+	if err != nil {
+		return err
+	}
+	key := strings.ToLower(doc.ExternalID)
+
+	switch doc.Request {
+	case OperationRequestHibernate, OperationRequestResume:
+		if !found {
+			return nil
+		}
+		// Only the flag is recorded. Nothing in this repository stops or
+		// restarts the cluster's workloads: that is left to the backend,
+		// which is to act on the flag.
+		clusterDoc.Hibernated = doc.Request == OperationRequestHibernate
+		return r.dbClient.SetClusterDoc(ctx, clusterDoc)
+
+	case OperationRequestPurge:
+		if found {
+			if err = r.dbClient.DeleteClusterDoc(ctx, doc.ExternalID, doc.PartitionKey); err != nil {
+				return err
+			}
+		}
+		// The cluster's tombstone removes it and its node pools from
+		// the caches of the other replicas through the change feed.
+		r.cache.DeleteCluster(key)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown operation request '%s'", doc.Request)
+	}
+}
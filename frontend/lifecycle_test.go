@@ -0,0 +1,261 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+// counterRecorder records the total of each counter emitted.
+type counterRecorder struct {
+	noopEmitter
+	lock     sync.Mutex
+	counters map[string]float64
+}
+
+func (r *counterRecorder) EmitCounter(name string, value float64, labels map[string]string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.counters == nil {
+		r.counters = make(map[string]float64)
+	}
+	r.counters[name+"/"+labels["request"]+"/"+labels["status"]] += value
+}
+
+func TestLifecycleRequest(t *testing.T) {
+	tests := []struct {
+		previous arm.RegistrationState
+		current  arm.RegistrationState
+		expect   OperationRequest
+	}{
+		{"", arm.Registered, ""},
+		{arm.Registered, arm.Registered, ""},
+		{arm.Registered, arm.Warned, ""},
+		{arm.Registered, arm.Unregistered, ""},
+		{arm.Registered, arm.Suspended, OperationRequestHibernate},
+		{arm.Warned, arm.Suspended, OperationRequestHibernate},
+		{arm.Suspended, arm.Suspended, ""},
+		{arm.Suspended, arm.Registered, OperationRequestResume},
+		{arm.Suspended, arm.Warned, OperationRequestResume},
+		{arm.Suspended, arm.Unregistered, ""},
+		{arm.Registered, arm.Deleted, OperationRequestPurge},
+		{arm.Suspended, arm.Deleted, OperationRequestPurge},
+		{arm.Deleted, arm.Deleted, ""},
+	}
+
+	for _, tt := range tests {
+		if request := lifecycleRequest(tt.previous, tt.current); request != tt.expect {
+			t.Errorf("%q -> %q: expected %q, got %q", tt.previous, tt.current, tt.expect, request)
+		}
+	}
+}
+
+// clusterOperations returns the operations on the test
+// cluster, in the order they were queued.
+func clusterOperations(t *testing.T, db DBClient) []*OperationDocument {
+	t.Helper()
+
+	docs, err := db.ListOperationDocs(context.Background(), testReplicaSubscriptionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var operations []*OperationDocument
+	for _, doc := range docs {
+		if doc.ExternalID == strings.ToLower(testReplicaClusterPath) {
+			operations = append(operations, doc)
+		}
+	}
+	sort.Slice(operations, func(i, j int) bool {
+		return operations[i].StartTime.Before(operations[j].StartTime)
+	})
+	return operations
+}
+
+func TestSubscriptionLifecycle(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryDBClient()
+	replica := newTestReplica(t, db)
+	counters := &counterRecorder{}
+	runner := NewOperationRunner(replica.logger, db, replica.frontend.cache, counters)
+
+	version, _ := api.Lookup("2024-06-10-preview")
+	clusterBody, err := json.Marshal(version.NewHCPOpenShiftCluster(newTestValidCluster()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscriptionURL := "/subscriptions/" + testReplicaSubscriptionID
+	clusterURL := testReplicaClusterPath + "?" + APIVersionKey + "=2024-06-10-preview"
+	clusterKey := strings.ToLower(testReplicaClusterPath)
+	nodePoolKey := clusterKey + "/nodepools/pool"
+
+	replica.expect(http.MethodPut, subscriptionURL, []byte(`{"state": "Registered"}`), http.StatusOK)
+	replica.expect(http.MethodPut, clusterURL, clusterBody, http.StatusCreated)
+	replica.frontend.cache.SetNodePool(nodePoolKey, &api.HCPOpenShiftClusterNodePool{})
+
+	// Another replica holding the node pool.
+	other := newTestReplica(t, db)
+	if err := other.processor.Warm(ctx, other.frontend.cache); err != nil {
+		t.Fatal(err)
+	}
+	other.frontend.cache.SetNodePool(nodePoolKey, &api.HCPOpenShiftClusterNodePool{})
+
+	hibernated := func() bool {
+		t.Helper()
+		doc, found, err := db.GetClusterDoc(ctx, testReplicaClusterPath, testReplicaSubscriptionID)
+		if err != nil || !found {
+			t.Fatalf("Expected the cluster document, got %v, %v", found, err)
+		}
+		return doc.Hibernated
+	}
+
+	// A warning alone queues nothing.
+	replica.expect(http.MethodPut, subscriptionURL, []byte(`{"state": "Warned"}`), http.StatusOK)
+	if operations := clusterOperations(t, db); len(operations) != 0 {
+		t.Fatalf("Expected no operations for a warned subscription, got %d", len(operations))
+	}
+
+	replica.expect(http.MethodPut, subscriptionURL, []byte(`{"state": "Suspended"}`), http.StatusOK)
+	operations := clusterOperations(t, db)
+	if len(operations) != 1 || operations[0].Request != OperationRequestHibernate || operations[0].Status != arm.ProvisioningStateAccepted {
+		t.Fatalf("Expected an accepted Hibernate operation, got %+v", operations)
+	}
+	if hibernated() {
+		t.Error("Expected the cluster to be hibernated only once the operation runs")
+	}
+
+	runner.Process(ctx)
+	if !hibernated() {
+		t.Error("Expected the cluster to be hibernated")
+	}
+	if status := clusterOperations(t, db)[0].Status; status != arm.ProvisioningStateSucceeded {
+		t.Errorf("Expected the Hibernate operation to succeed, got %s", status)
+	}
+
+	replica.expect(http.MethodPut, subscriptionURL, []byte(`{"state": "Registered"}`), http.StatusOK)
+	runner.Process(ctx)
+	if hibernated() {
+		t.Error("Expected the cluster to be resumed")
+	}
+
+	replica.expect(http.MethodPut, subscriptionURL, []byte(`{"state": "Deleted"}`), http.StatusOK)
+	runner.Process(ctx)
+	if _, found, _ := db.GetClusterDoc(ctx, testReplicaClusterPath, testReplicaSubscriptionID); found {
+		t.Error("Expected the cluster document to be purged")
+	}
+	if _, found := replica.frontend.cache.GetCluster(clusterKey); found {
+		t.Error("Expected the cluster to be purged from the cache")
+	}
+	if _, found := replica.frontend.cache.GetNodePool(nodePoolKey); found {
+		t.Error("Expected the node pool to be purged from the cache")
+	}
+	other.processor.Poll(ctx, other.frontend.cache)
+	if _, found := other.frontend.cache.GetCluster(clusterKey); found {
+		t.Error("Expected the cluster to be purged from the other replica's cache")
+	}
+	if _, found := other.frontend.cache.GetNodePool(nodePoolKey); found {
+		t.Error("Expected the node pool to be purged from the other replica's cache")
+	}
+
+	operations = clusterOperations(t, db)
+	if len(operations) != 3 {
+		t.Fatalf("Expected 3 operations, got %d", len(operations))
+	}
+	for i, request := range []OperationRequest{OperationRequestHibernate, OperationRequestResume, OperationRequestPurge} {
+		if operations[i].Request != request || operations[i].Status != arm.ProvisioningStateSucceeded || operations[i].Attempts != 1 {
+			t.Errorf("Expected operation %d to be a %s that succeeded on its first attempt, got %+v", i, request, operations[i])
+		}
+		if counters.counters["frontend_lifecycle_operation_count/"+string(request)+"/Succeeded"] != 1 {
+			t.Errorf("Expected a count of one successful %s operation, got %v", request, counters.counters)
+		}
+	}
+}
+
+func TestOperationSupersede(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryDBClient()
+	clusterKey := strings.ToLower(testReplicaClusterPath)
+	now := time.Now()
+
+	if _, err := enqueueOperation(ctx, db, testReplicaSubscriptionID, clusterKey, OperationRequestHibernate, now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := enqueueOperation(ctx, db, testReplicaSubscriptionID, clusterKey, OperationRequestResume, now.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	operations := clusterOperations(t, db)
+	if len(operations) != 2 {
+		t.Fatalf("Expected 2 operations, got %d", len(operations))
+	}
+	if operations[0].Status != arm.ProvisioningStateCanceled {
+		t.Errorf("Expected the superseded Hibernate operation to be canceled, got %s", operations[0].Status)
+	}
+	if operations[1].Status != arm.ProvisioningStateAccepted {
+		t.Errorf("Expected the Resume operation to be accepted, got %s", operations[1].Status)
+	}
+}
+
+// failingClusterDBClient fails to read cluster documents.
+type failingClusterDBClient struct {
+	*MemoryDBClient
+}
+
+func (c *failingClusterDBClient) GetClusterDoc(ctx context.Context, resourceID string, partitionKey string) (*HCPOpenShiftClusterDocument, bool, error) {
+	return nil, false, errors.New("database unavailable")
+}
+
+func TestOperationRetry(t *testing.T) {
+	ctx := context.Background()
+	db := &failingClusterDBClient{NewMemoryDBClient()}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cache := NewCache()
+	counters := &counterRecorder{}
+	runner := NewOperationRunner(logger, db, cache, counters)
+
+	now := time.Now()
+	runner.now = func() time.Time { return now }
+
+	cache.SetSubscription(testReplicaSubscriptionID, &arm.Subscription{State: arm.Suspended})
+	if _, err := enqueueOperation(ctx, db, testReplicaSubscriptionID, strings.ToLower(testReplicaClusterPath), OperationRequestHibernate, now); err != nil {
+		t.Fatal(err)
+	}
+
+	for attempt := 1; attempt < operationMaxAttempts; attempt++ {
+		runner.Process(ctx)
+		operation := clusterOperations(t, db)[0]
+		if operation.Status != arm.ProvisioningStateAccepted || operation.Attempts != attempt || operation.Error == nil {
+			t.Fatalf("Expected attempt %d to fail and be retried, got %+v", attempt, operation)
+		}
+
+		// Nothing is attempted until the backoff passes.
+		runner.Process(ctx)
+		if attempts := clusterOperations(t, db)[0].Attempts; attempts != attempt {
+			t.Fatalf("Expected no attempt before the backoff passed, got %d attempts", attempts)
+		}
+		now = operation.NotBefore
+	}
+
+	runner.Process(ctx)
+	operation := clusterOperations(t, db)[0]
+	if operation.Status != arm.ProvisioningStateFailed || operation.Attempts != operationMaxAttempts {
+		t.Errorf("Expected the operation to fail after %d attempts, got %+v", operationMaxAttempts, operation)
+	}
+	if counters.counters["frontend_lifecycle_operation_count/Hibernate/Accepted"] != operationMaxAttempts-1 ||
+		counters.counters["frontend_lifecycle_operation_count/Hibernate/Failed"] != 1 {
+		t.Errorf("Expected retries and the failure to be counted, got %v", counters.counters)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+const (
+	// Query parameter names for list requests.
+	FilterKey    = "$filter"
+	TopKey       = "$top"
+	SkipTokenKey = "$skipToken"
+)
+
+// rxTagFilter matches the tag filters Azure Resource Manager supports on
+// list operations. Single quotes in literals are escaped by doubling them.
+var rxTagFilter = regexp.MustCompile(`(?i)^\s*tagName\s+eq\s+'((?:[^']|'')*)'(?:\s+and\s+tagValue\s+eq\s+'((?:[^']|'')*)')?\s*$`)
+
+// listOptions holds the query parameters of a list request.
+type listOptions struct {
+	tagName  string
+	tagValue *string
+	top      int
+	skip     int
+}
+
+// parseListOptions parses the $filter, $top and $skipToken query
+// parameters of a list request.
+func parseListOptions(query url.Values) (*listOptions, *arm.CloudError) {
+	options := &listOptions{}
+
+	if filter := query.Get(FilterKey); filter != "" {
+		m := rxTagFilter.FindStringSubmatchIndex(filter)
+		if m == nil {
+			return nil, arm.ErrorUnsupportedFilter.New(FilterKey,
+				FilterKey, filter, `"tagName eq '{name}'" and "tagName eq '{name}' and tagValue eq '{value}'"`)
+		}
+		unescape := func(s string) string {
+			return strings.ReplaceAll(s, "''", "'")
+		}
+		options.tagName = unescape(filter[m[2]:m[3]])
+		if m[4] >= 0 {
+			options.tagValue = api.Ptr(unescape(filter[m[4]:m[5]]))
+		}
+	}
+
+	parseCount := func(key string, minimum int) (int, *arm.CloudError) {
+		value := query.Get(key)
+		if value == "" {
+			return 0, nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < minimum {
+			return 0, arm.ErrorInvalidQueryParameter.New(key,
+				value, key, fmt.Sprintf("must be an integer of at least %d", minimum))
+		}
+		return n, nil
+	}
+
+	var cloudError *arm.CloudError
+	if options.top, cloudError = parseCount(TopKey, 1); cloudError != nil {
+		return nil, cloudError
+	}
+	if options.skip, cloudError = parseCount(SkipTokenKey, 0); cloudError != nil {
+		return nil, cloudError
+	}
+
+	return options, nil
+}
+
+// matchesTags returns true if tags satisfy the tag filter, if any.
+// Tag names are matched case-insensitively and tag values exactly.
+func (o *listOptions) matchesTags(tags map[string]string) bool {
+	if o.tagName == "" {
+		return true
+	}
+	for name, value := range tags {
+		if strings.EqualFold(name, o.tagName) && (o.tagValue == nil || *o.tagValue == value) {
+			return true
+		}
+	}
+	return false
+}
+
+// listClusters writes a page of the cached clusters for which match
+// returns true and that satisfy the request's list options, in resource
+// ID order. If more clusters remain, the response includes a link to the
+// next page.
+func (f *Frontend) listClusters(writer http.ResponseWriter, request *http.Request, versionedInterface api.Version, match func(*arm.ResourceID, *api.HCPOpenShiftCluster) bool) {
+	options, cloudError := parseListOptions(request.URL.Query())
+	if cloudError != nil {
+		f.logger.Error(cloudError.Error())
+		arm.WriteCloudError(writer, cloudError)
+		return
+	}
+
+	// Work from one snapshot so clusters deleted while the
+	// page is written are not looked up again and missed.
+	clusters := f.cache.Clusters()
+
+	var ids []string
+	for id, cluster := range clusters {
+		resourceID, err := arm.ParseResourceID(id)
+		if err == nil && match(resourceID, cluster) && options.matchesTags(cluster.Tags) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	page := ids[min(options.skip, len(ids)):]
+	if options.top > 0 && options.top < len(page) {
+		page = page[:options.top]
+	}
+
+	response := arm.PagedResponse{Value: make([]any, 0, len(page))}
+	for _, id := range page {
+		response.Value = append(response.Value, versionedInterface.NewHCPOpenShiftCluster(f.withAvailableUpgrades(clusters[id])))
+	}
+
+	if next := options.skip + len(page); next < len(ids) {
+		response.NextLink = nextLink(request, next)
+	}
+
+	arm.WriteJSONResponse(writer, f.logger, http.StatusOK, response)
+}
+
+// nextLink returns the URL of the list page starting at offset skip.
+func nextLink(request *http.Request, skip int) string {
+	path := request.URL.Path
+	if originalPath, err := OriginalPathFromContext(request.Context()); err == nil {
+		path = originalPath
+	}
+
+	query := request.URL.Query()
+	query.Set(SkipTokenKey, strconv.Itoa(skip))
+
+	u := url.URL{
+		Scheme:   "https",
+		Host:     request.Host,
+		Path:     path,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
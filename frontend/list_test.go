@@ -0,0 +1,208 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+)
+
+func TestParseListOptions(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          url.Values
+		expectTagName  string
+		expectTagValue *string
+		expectTop      int
+		expectTarget   string
+	}{
+		{
+			name: "No options",
+		},
+		{
+			name:          "Tag name",
+			query:         url.Values{FilterKey: {"tagName eq 'env'"}},
+			expectTagName: "env",
+		},
+		{
+			name:           "Tag name and value",
+			query:          url.Values{FilterKey: {"tagname EQ 'env' and tagValue eq 'it''s prod'"}},
+			expectTagName:  "env",
+			expectTagValue: api.Ptr("it's prod"),
+		},
+		{
+			name:           "Empty tag value",
+			query:          url.Values{FilterKey: {"tagName eq 'env' and tagValue eq ''"}},
+			expectTagName:  "env",
+			expectTagValue: api.Ptr(""),
+		},
+		{
+			name:      "Top",
+			query:     url.Values{TopKey: {"5"}},
+			expectTop: 5,
+		},
+		{
+			name:         "Unsupported filter",
+			query:        url.Values{FilterKey: {"location eq 'eastus'"}},
+			expectTarget: FilterKey,
+		},
+		{
+			name:         "Invalid top",
+			query:        url.Values{TopKey: {"0"}},
+			expectTarget: TopKey,
+		},
+		{
+			name:         "Invalid skip token",
+			query:        url.Values{SkipTokenKey: {"abc"}},
+			expectTarget: SkipTokenKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options, cloudError := parseListOptions(tt.query)
+
+			if tt.expectTarget != "" {
+				if cloudError == nil {
+					t.Fatalf("Expected an error")
+				}
+				if cloudError.StatusCode != http.StatusBadRequest || cloudError.Target != tt.expectTarget {
+					t.Errorf("Expected 400 error targeting %q, got %d targeting %q", tt.expectTarget, cloudError.StatusCode, cloudError.Target)
+				}
+				return
+			}
+			if cloudError != nil {
+				t.Fatalf("Unexpected error: %v", cloudError)
+			}
+			if options.tagName != tt.expectTagName {
+				t.Errorf("Expected tag name %q, got %q", tt.expectTagName, options.tagName)
+			}
+			if api.Deref(options.tagValue) != api.Deref(tt.expectTagValue) || (options.tagValue == nil) != (tt.expectTagValue == nil) {
+				t.Errorf("Expected tag value %v, got %v", tt.expectTagValue, options.tagValue)
+			}
+			if options.top != tt.expectTop {
+				t.Errorf("Expected top %d, got %d", tt.expectTop, options.top)
+			}
+		})
+	}
+}
+
+func TestArmResourceListByResourceGroup(t *testing.T) {
+	const (
+		subscriptionID = "00000000-0000-0000-0000-000000000000"
+		prefix         = "/subscriptions/" + subscriptionID + "/resourcegroups/"
+		clusters       = "/providers/microsoft.redhatopenshift/hcpopenshiftclusters/"
+	)
+
+	f := &Frontend{
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cache:  NewCache(),
+	}
+	for id, tags := range map[string]map[string]string{
+		prefix + "rg" + clusters + "a":    {"Env": "prod"},
+		prefix + "rg" + clusters + "b":    {"env": "dev"},
+		prefix + "rg" + clusters + "c":    {"env": "prod", "team": "sre"},
+		prefix + "rg" + clusters + "d":    nil,
+		prefix + "other" + clusters + "e": {"env": "prod"},
+	} {
+		cluster := api.NewDefaultHCPOpenShiftCluster()
+		cluster.Name = id[len(id)-1:]
+		cluster.Tags = tags
+		f.cache.SetCluster(id, cluster)
+	}
+
+	list := func(query url.Values) ([]string, string) {
+		t.Helper()
+
+		version, _ := api.Lookup("2024-06-10-preview")
+		request := httptest.NewRequest(http.MethodGet, prefix+"rg"+clusters[:len(clusters)-1]+"?"+query.Encode(), nil)
+		request = request.WithContext(ContextWithVersion(request.Context(), version))
+		request.SetPathValue(PathSegmentSubscriptionID, subscriptionID)
+		request.SetPathValue(PathSegmentResourceGroupName, "RG")
+		writer := httptest.NewRecorder()
+
+		f.ArmResourceListByResourceGroup(writer, request)
+
+		if writer.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, writer.Code, writer.Body.String())
+		}
+		var response struct {
+			Value []struct {
+				Name string `json:"name"`
+			} `json:"value"`
+			NextLink string `json:"nextLink"`
+		}
+		if err := json.Unmarshal(writer.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, item := range response.Value {
+			names = append(names, item.Name)
+		}
+		return names, response.NextLink
+	}
+
+	tests := []struct {
+		name        string
+		query       url.Values
+		expectNames []string
+		expectNext  bool
+	}{
+		{
+			name:        "All clusters in resource group",
+			expectNames: []string{"a", "b", "c", "d"},
+		},
+		{
+			name:        "Filter by tag name",
+			query:       url.Values{FilterKey: {"tagName eq 'ENV'"}},
+			expectNames: []string{"a", "b", "c"},
+		},
+		{
+			name:        "Filter by tag name and value",
+			query:       url.Values{FilterKey: {"tagName eq 'env' and tagValue eq 'prod'"}},
+			expectNames: []string{"a", "c"},
+		},
+		{
+			name:        "First page",
+			query:       url.Values{TopKey: {"3"}},
+			expectNames: []string{"a", "b", "c"},
+			expectNext:  true,
+		},
+		{
+			name:        "Last page",
+			query:       url.Values{TopKey: {"3"}, SkipTokenKey: {"3"}},
+			expectNames: []string{"d"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names, next := list(tt.query)
+
+			if !slices.Equal(names, tt.expectNames) {
+				t.Errorf("Expected clusters %v, got %v", tt.expectNames, names)
+			}
+			if (next != "") != tt.expectNext {
+				t.Errorf("Expected next link %v, got %q", tt.expectNext, next)
+			}
+			if next != "" {
+				u, err := url.Parse(next)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if skip := u.Query().Get(SkipTokenKey); skip != "3" {
+					t.Errorf("Expected next link to skip 3, got %q", skip)
+				}
+			}
+		})
+	}
+}
@@ -11,6 +11,9 @@ import (
 	"os/signal"
 	"runtime/debug"
 	"syscall"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 )
 
 const ProgramName = "ARO HCP Frontend"
@@ -52,7 +55,98 @@ func main() {
 		logger.Error(fmt.Sprintf("Creating the database client failed: %v", err))
 	}
 
-	frontend := NewFrontend(logger, listener, prometheusEmitter, dbClient)
+	// Look up the Azure resources referenced by cluster properties, if enabled.
+	var azureResources AzureResourceClient
+	if os.Getenv("VALIDATE_RESOURCE_REFERENCES") == "true" {
+		credential, err := azidentity.NewDefaultAzureCredential(nil)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Creating the Azure credential failed: %v", err))
+			os.Exit(1)
+		}
+		azureResources = NewAzureResourceClient(credential)
+	} else {
+		logger.Warn("VALIDATE_RESOURCE_REFERENCES is not true, referenced Azure resources will not be validated")
+	}
+
+	// Load the catalog of OpenShift releases, if configured.
+	var releases *ReleaseCatalog
+	if path := os.Getenv("RELEASE_CATALOG"); path != "" {
+		releases, err = LoadReleaseCatalog(path)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Loading the release catalog failed: %v", err))
+			os.Exit(1)
+		}
+	} else {
+		logger.Warn("RELEASE_CATALOG is not set, cluster versions will not be validated")
+	}
+
+	// Load the subscription policy, if configured, and reload
+	// it periodically so changes apply without a restart.
+	var policy *SubscriptionPolicy
+	if path := os.Getenv("SUBSCRIPTION_POLICY"); path != "" {
+		policy, err = LoadSubscriptionPolicy(path)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Loading the subscription policy failed: %v", err))
+			os.Exit(1)
+		}
+		go policy.Watch(logger, time.Minute, stop)
+	}
+
+	// Load the quota limits, if configured, or use the defaults.
+	quota := DefaultQuotaConfig()
+	if path := os.Getenv("QUOTA_CONFIG"); path != "" {
+		quota, err = LoadQuotaConfig(path)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Loading the quota configuration failed: %v", err))
+			os.Exit(1)
+		}
+	}
+
+	// Restrict requests to the region this frontend serves, if configured.
+	var region *Region
+	if location := os.Getenv("LOCATION"); location != "" {
+		region = NewRegion(location)
+	} else {
+		logger.Warn("LOCATION is not set, resource locations will not be validated")
+	}
+
+	// Validate request bodies against the OpenAPI specification, if configured.
+	var spec *OpenAPISpec
+	if dir := os.Getenv("OPENAPI_SPEC_DIR"); dir != "" {
+		spec, err = LoadOpenAPISpec(dir)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Loading the OpenAPI specification failed: %v", err))
+			os.Exit(1)
+		}
+	}
+
+	databaseConfigured := dbClient != nil && dbConfig.DBName != "" && dbConfig.DBName != "none"
+
+	// Keep the cache coherent with changes other replicas make, if a
+	// database is configured.
+	var changeFeed *ChangeFeedProcessor
+	if databaseConfigured {
+		// Each replica checkpoints in a lease named after its host,
+		// which a restarted container of the same pod resumes from.
+		hostname, err := os.Hostname()
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to get the hostname: %v", err))
+			os.Exit(1)
+		}
+		changeFeed = NewChangeFeedProcessor(logger, dbClient, dbClient, hostname, prometheusEmitter)
+	} else {
+		logger.Warn("No database is configured, the cache will not reflect changes made by other replicas")
+	}
+
+	frontend := NewFrontend(logger, listener, prometheusEmitter, dbClient, FrontendOptions{
+		AzureResources: azureResources,
+		Releases:       releases,
+		Policy:         policy,
+		Quota:          quota,
+		Region:         region,
+		Spec:           spec,
+		ChangeFeed:     changeFeed,
+	})
 
 	// Verify the Async DB is available and accessible
 	logger.Info("Testing DB Access")
@@ -65,10 +159,39 @@ func main() {
 
 	go frontend.Run(ctx, stop)
 
+	// Perform the operations queued for subscription
+	// lifecycle changes, if a database is configured.
+	if databaseConfigured {
+		operations := NewOperationRunner(logger, dbClient, frontend.cache, prometheusEmitter)
+		go operations.Run(ctx, stop)
+	} else {
+		logger.Warn("No database is configured, subscription lifecycle operations will not run")
+	}
+
+	// Serve the admin API on its own port, if configured.
+	var admin *Admin
+	if path := os.Getenv("ADMIN_CONFIG"); path != "" {
+		adminConfig, err := LoadAdminConfig(path)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Loading the admin configuration failed: %v", err))
+			os.Exit(1)
+		}
+		adminListener, err := adminConfig.Listen()
+		if err != nil {
+			logger.Error(fmt.Sprintf("Creating the admin listener failed: %v", err))
+			os.Exit(1)
+		}
+		admin = NewAdmin(logger, adminListener, frontend.dbClient, frontend.cache, adminConfig.Principals)
+		go admin.Run(ctx, stop)
+	}
+
 	sig := <-signalChannel
 	logger.Info(fmt.Sprintf("caught %s signal", sig))
 	close(stop)
 	frontend.Join()
+	if admin != nil {
+		admin.Join()
+	}
 
 	logger.Info(fmt.Sprintf("%s (%s) stopped", ProgramName, version))
 }
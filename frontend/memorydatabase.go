@@ -0,0 +1,269 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryChangeFeedPageSize is the most documents a change feed page holds.
+const memoryChangeFeedPageSize = 100
+
+// MemoryDBClient is a DBClient that keeps documents in memory,
+// for use in tests and when running the frontend locally. It also
+// simulates the change feed of each container.
+type MemoryDBClient struct {
+	lock       sync.Mutex
+	containers map[string]map[string]*memoryDocument
+	// lsn numbers document writes, like the logical sequence
+	// numbers that order a Cosmos DB change feed.
+	lsn int64
+}
+
+// memoryDocument is the latest version of a stored document.
+type memoryDocument struct {
+	data []byte
+	lsn  int64
+}
+
+// NewMemoryDBClient returns an empty MemoryDBClient.
+func NewMemoryDBClient() *MemoryDBClient {
+	return &MemoryDBClient{
+		containers: make(map[string]map[string]*memoryDocument),
+	}
+}
+
+// memoryDBKey returns the map key for a document. Like the Cosmos
+// DB query, it matches resource IDs case-insensitively.
+func memoryDBKey(partitionKey, resourceID string) string {
+	return partitionKey + "|" + strings.ToLower(resourceID)
+}
+
+func (m *MemoryDBClient) DBConnectionTest(ctx context.Context) (string, error) {
+	return "In-memory database", nil
+}
+
+// Documents are stored as JSON so callers never share memory with the store.
+
+// get unmarshals a stored document into v. The caller must hold the lock.
+func (m *MemoryDBClient) get(container, key string, v any) (bool, error) {
+	document, ok := m.containers[container][key]
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(document.data, v)
+}
+
+// put stores a document with the system properties Cosmos DB would
+// set, and unmarshals the stored document into stored if it is not
+// nil. The caller must hold the lock.
+func (m *MemoryDBClient) put(container, key string, v any, stored any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var properties map[string]any
+	if err = json.Unmarshal(data, &properties); err != nil {
+		return err
+	}
+	m.lsn++
+	properties["_etag"] = fmt.Sprintf("\"%d\"", m.lsn)
+	properties["_ts"] = time.Now().Unix()
+	if data, err = json.Marshal(properties); err != nil {
+		return err
+	}
+
+	if m.containers[container] == nil {
+		m.containers[container] = make(map[string]*memoryDocument)
+	}
+	m.containers[container][key] = &memoryDocument{data: data, lsn: m.lsn}
+
+	if stored != nil {
+		return json.Unmarshal(data, stored)
+	}
+	return nil
+}
+
+// putConditional creates a document if etag is empty, or replaces it if
+// etag matches the stored document, like a conditional Cosmos DB write.
+// Otherwise it returns ErrDocumentConflict. The caller must hold the lock.
+func (m *MemoryDBClient) putConditional(container, key, etag string, v any, stored any) error {
+	var current struct {
+		ETag string `json:"_etag"`
+	}
+	found, err := m.get(container, key, &current)
+	if err != nil {
+		return err
+	}
+	if (etag == "" && found) || (etag != "" && (!found || current.ETag != etag)) {
+		return ErrDocumentConflict
+	}
+	return m.put(container, key, v, stored)
+}
+
+func (m *MemoryDBClient) GetClusterDoc(ctx context.Context, resourceID string, partitionKey string) (*HCPOpenShiftClusterDocument, bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var doc *HCPOpenShiftClusterDocument
+	found, err := m.get(clustersContainer, memoryDBKey(partitionKey, resourceID), &doc)
+	if !found || err != nil || doc.Deleted {
+		return nil, false, err
+	}
+	return doc, true, nil
+}
+
+func (m *MemoryDBClient) SetClusterDoc(ctx context.Context, doc *HCPOpenShiftClusterDocument) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.put(clustersContainer, memoryDBKey(doc.PartitionKey, doc.Key), doc, nil)
+}
+
+func (m *MemoryDBClient) QueryClusterDocs(ctx context.Context, clusterID string) ([]*HCPOpenShiftClusterDocument, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var docs []*HCPOpenShiftClusterDocument
+	for _, document := range m.containers[clustersContainer] {
+		var doc *HCPOpenShiftClusterDocument
+		if err := json.Unmarshal(document.data, &doc); err != nil {
+			return nil, err
+		}
+		if (clusterID == "" || doc.ClusterID == clusterID) && !doc.Deleted {
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+// DeleteClusterDoc leaves a tombstone in place of the document, so the
+// deletion appears in the change feed. Tombstones are never removed.
+func (m *MemoryDBClient) DeleteClusterDoc(ctx context.Context, resourceID string, partitionKey string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	key := memoryDBKey(partitionKey, resourceID)
+	var doc *HCPOpenShiftClusterDocument
+	found, err := m.get(clustersContainer, key, &doc)
+	if err != nil {
+		return err
+	}
+	if !found || doc.Deleted {
+		return fmt.Errorf("document with key %s not found", resourceID)
+	}
+
+	doc.Deleted = true
+	doc.TTL = tombstoneTTL
+	return m.put(clustersContainer, key, doc, nil)
+}
+
+func (m *MemoryDBClient) SetSubscriptionDoc(ctx context.Context, doc *SubscriptionDocument) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.put(subscriptionsContainer, doc.ID, doc, nil)
+}
+
+// ReadChangeFeed returns the latest version of the documents in a
+// container written after the write numbered by continuation.
+func (m *MemoryDBClient) ReadChangeFeed(ctx context.Context, container, continuation string) (*ChangeFeedPage, error) {
+	var after int64
+	if continuation != "" {
+		var err error
+		if after, err = strconv.ParseInt(continuation, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid continuation '%s': %w", continuation, err)
+		}
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var changed []*memoryDocument
+	for _, document := range m.containers[container] {
+		if document.lsn > after {
+			changed = append(changed, document)
+		}
+	}
+	sort.Slice(changed, func(i, j int) bool {
+		return changed[i].lsn < changed[j].lsn
+	})
+	if len(changed) > memoryChangeFeedPageSize {
+		changed = changed[:memoryChangeFeedPageSize]
+	}
+
+	page := &ChangeFeedPage{Continuation: strconv.FormatInt(after, 10)}
+	for _, document := range changed {
+		page.Documents = append(page.Documents, document.data)
+		page.Continuation = strconv.FormatInt(document.lsn, 10)
+	}
+	return page, nil
+}
+
+// ReadDocuments returns the latest version of every document in a container.
+func (m *MemoryDBClient) ReadDocuments(ctx context.Context, container string) ([]json.RawMessage, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	documents := make([]json.RawMessage, 0, len(m.containers[container]))
+	for _, document := range m.containers[container] {
+		documents = append(documents, document.data)
+	}
+	return documents, nil
+}
+
+func (m *MemoryDBClient) GetLeaseDoc(ctx context.Context, name string) (*LeaseDocument, bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var doc *LeaseDocument
+	found, err := m.get(leasesContainer, name, &doc)
+	if !found || err != nil {
+		return nil, false, err
+	}
+	return doc, true, nil
+}
+
+func (m *MemoryDBClient) SetLeaseDoc(ctx context.Context, doc *LeaseDocument) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.put(leasesContainer, doc.ID, doc, nil)
+}
+
+func (m *MemoryDBClient) ListOperationDocs(ctx context.Context, subscriptionID string) ([]*OperationDocument, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var docs []*OperationDocument
+	for _, document := range m.containers[operationsContainer] {
+		var doc *OperationDocument
+		if err := json.Unmarshal(document.data, &doc); err != nil {
+			return nil, err
+		}
+		if doc.PartitionKey == subscriptionID {
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+func (m *MemoryDBClient) SetOperationDoc(ctx context.Context, doc *OperationDocument) (*OperationDocument, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var stored *OperationDocument
+	if err := m.putConditional(operationsContainer, doc.ID, doc.ETag, doc, &stored); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
@@ -6,6 +6,7 @@ package main
 import (
 	"container/list"
 	"net/http"
+	"strings"
 )
 
 // MiddlewareFunc specifies the call signature for middleware functions.
@@ -82,3 +83,28 @@ func NewMiddlewareMux(functions ...MiddlewareFunc) *MiddlewareMux {
 func (mux *MiddlewareMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	mux.middleware.Handler(&mux.ServeMux).ServeHTTP(w, r)
 }
+
+// routableMethods are the HTTP methods considered by AllowedMethods.
+var routableMethods = []string{
+	http.MethodGet,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodPost,
+}
+
+// AllowedMethods returns the HTTP methods for which the request URL matches
+// a method-specific pattern. Handlers for patterns without a method, such as
+// a catch-all "/" pattern, use this to tell an unsupported method from an
+// unknown path.
+func (mux *MiddlewareMux) AllowedMethods(r *http.Request) []string {
+	var allowed []string
+	for _, method := range routableMethods {
+		probe := *r
+		probe.Method = method
+		if _, pattern := mux.ServeMux.Handler(&probe); strings.HasPrefix(pattern, method+" ") {
+			allowed = append(allowed, method)
+		}
+	}
+	return allowed
+}
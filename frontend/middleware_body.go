@@ -20,21 +20,14 @@ func MiddlewareBody(w http.ResponseWriter, r *http.Request, next http.HandlerFun
 		// See https://github.com/Azure/azure-resource-manager-rpc/blob/master/v1.0/common-api-details.md#max-request-body-size
 		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 4*megabyte))
 		if err != nil {
-			arm.WriteError(
-				w, http.StatusBadRequest,
-				arm.CloudErrorCodeInvalidResource, "",
-				"The resource definition is invalid.")
+			arm.ErrorInvalidResource.Write(w, "")
 			return
 		}
 
 		contentType := strings.SplitN(r.Header.Get("Content-Type"), ";", 2)[0]
 
 		if !strings.EqualFold(contentType, "application/json") && !(len(body) == 0 && contentType == "") {
-			arm.WriteError(
-				w, http.StatusUnsupportedMediaType,
-				arm.CloudErrorCodeUnsupportedMediaType, "",
-				"The content media type '%s' is not supported. Only 'application/json' is supported.",
-				r.Header.Get("Content-Type"))
+			arm.ErrorUnsupportedMediaType.Write(w, "", r.Header.Get("Content-Type"))
 			return
 		}
 
@@ -4,7 +4,6 @@ package main
 // Licensed under the Apache License 2.0.
 
 import (
-	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
@@ -52,8 +51,7 @@ func MiddlewareLogging(w http.ResponseWriter, r *http.Request, next http.Handler
 
 	logger, err := LoggerFromContext(r.Context())
 	if err != nil {
-		DefaultLogger().Error(err.Error())
-		arm.WriteInternalServerError(w)
+		arm.WriteInternalServerError(w, DefaultLogger(), err)
 		return
 	}
 
@@ -87,8 +85,7 @@ func MiddlewareLoggingPostMux(w http.ResponseWriter, r *http.Request, next http.
 
 	logger, err := LoggerFromContext(ctx)
 	if err != nil {
-		DefaultLogger().Error(err.Error())
-		arm.WriteInternalServerError(w)
+		arm.WriteInternalServerError(w, DefaultLogger(), err)
 		return
 	}
 
@@ -141,9 +138,12 @@ func getLogAttrs(correlationData *arm.CorrelationData, r *http.Request) []slog.A
 
 	wholePath := subscriptionID != "" && resourceGroup != "" && resourceName != ""
 	if wholePath {
-		format := "/subscriptions/%s/resourcegroups/%s/providers/%s/%s"
-		resource_id := fmt.Sprintf(format, subscriptionID, resourceGroup, api.ResourceType, resourceName)
-		attrs = append(attrs, slog.String("resource_id", resource_id))
+		// Path segments have not been validated yet, so omit
+		// the attribute if they do not form a valid resource ID.
+		resourceID, err := arm.NewResourceID(subscriptionID, resourceGroup, api.ResourceType, resourceName)
+		if err == nil {
+			attrs = append(attrs, slog.String("resource_id", originalResourceID(r.Context(), resourceID.String())))
+		}
 	}
 
 	return attrs
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -81,7 +82,7 @@ func noModifyReqfunc(req *http.Request) {
 func Test_getLogAttrs(t *testing.T) {
 	var expectedRequestID = uuid.New()
 
-	fakeSubscriptionId := "the_subscription_id"
+	fakeSubscriptionId := "00000000-0000-0000-0000-000000000000"
 	fakeResourceGroupName := "the_resource_group_name"
 	fakeResourceName := "the_resource_name"
 
@@ -153,7 +154,7 @@ func Test_getLogAttrs(t *testing.T) {
 				slog.String(
 					"resource_id",
 					fmt.Sprintf(
-						"/subscriptions/%s/resourcegroups/%s/providers/%s/%s",
+						"/subscriptions/%s/resourceGroups/%s/providers/%s/%s",
 						fakeSubscriptionId,
 						fakeResourceGroupName,
 						api.ResourceType,
@@ -170,6 +171,40 @@ func Test_getLogAttrs(t *testing.T) {
 				req.SetPathValue(PathSegmentResourceGroupName, fakeResourceGroupName)
 			},
 		},
+		{
+			name:            "uses the casing of the original request path in the resourceID attribute",
+			correlationData: sampleCorrelationData,
+			req: (&http.Request{}).WithContext(ContextWithOriginalPath(context.Background(),
+				"/SUBSCRIPTIONS/"+fakeSubscriptionId+"/resourceGroups/The_Resource_Group_Name/providers/"+api.ResourceType+"/The_Resource_Name")),
+			want: append(
+				commonAttrs,
+				slog.String("subscription_id", fakeSubscriptionId),
+				slog.String("resource_group", fakeResourceGroupName),
+				slog.String("resource_name", fakeResourceName),
+				slog.String("resource_id", "/SUBSCRIPTIONS/"+fakeSubscriptionId+"/resourceGroups/The_Resource_Group_Name/providers/"+api.ResourceType+"/The_Resource_Name"),
+			),
+			setReqPathValue: func(req *http.Request) {
+				req.SetPathValue(PathSegmentResourceName, fakeResourceName)
+				req.SetPathValue(PathSegmentSubscriptionID, fakeSubscriptionId)
+				req.SetPathValue(PathSegmentResourceGroupName, fakeResourceGroupName)
+			},
+		},
+		{
+			name:            "omits the resourceID attribute when the path segments do not form a valid resource ID",
+			correlationData: sampleCorrelationData,
+			req:             &http.Request{},
+			want: append(
+				commonAttrs,
+				slog.String("subscription_id", "the_subscription_id"),
+				slog.String("resource_group", fakeResourceGroupName),
+				slog.String("resource_name", fakeResourceName),
+			),
+			setReqPathValue: func(req *http.Request) {
+				req.SetPathValue(PathSegmentResourceName, fakeResourceName)
+				req.SetPathValue(PathSegmentSubscriptionID, "the_subscription_id")
+				req.SetPathValue(PathSegmentResourceGroupName, fakeResourceGroupName)
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -4,6 +4,7 @@ package main
 // Licensed under the Apache License 2.0.
 
 import (
+	"context"
 	"net/http"
 	"strings"
 )
@@ -15,3 +16,14 @@ func MiddlewareLowercase(w http.ResponseWriter, r *http.Request, next http.Handl
 
 	next(w, r)
 }
+
+// originalResourceID returns the lowercased resourceID with the casing the
+// client used in the request URL, or resourceID itself if the request has no
+// original path that begins with it.
+func originalResourceID(ctx context.Context, resourceID string) string {
+	originalPath, err := OriginalPathFromContext(ctx)
+	if err == nil && len(originalPath) >= len(resourceID) && strings.EqualFold(originalPath[:len(resourceID)], resourceID) {
+		return originalPath[:len(resourceID)]
+	}
+	return resourceID
+}
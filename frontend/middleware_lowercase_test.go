@@ -4,6 +4,7 @@ package main
 // Licensed under the Apache License 2.0.
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -35,3 +36,46 @@ func TestMiddlewareLowercase(t *testing.T) {
 		t.Error(originalPath)
 	}
 }
+
+func TestOriginalResourceID(t *testing.T) {
+	const resourceID = "/subscriptions/00000000-0000-0000-0000-000000000000/resourcegroups/rg/providers/microsoft.redhatopenshift/hcpopenshiftclusters/cluster"
+
+	tests := []struct {
+		name         string
+		originalPath string
+		expected     string
+	}{
+		{
+			name:     "No original path",
+			expected: resourceID,
+		},
+		{
+			name:         "Resource path",
+			originalPath: "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/RG/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/Cluster",
+			expected:     "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/RG/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/Cluster",
+		},
+		{
+			name:         "Action path",
+			originalPath: "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/RG/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/Cluster/RequestAdminCredential",
+			expected:     "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/RG/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/Cluster",
+		},
+		{
+			name:         "Unrelated path",
+			originalPath: "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/Other",
+			expected:     resourceID,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.originalPath != "" {
+				ctx = ContextWithOriginalPath(ctx, tt.originalPath)
+			}
+
+			if actual := originalResourceID(ctx, resourceID); actual != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, actual)
+			}
+		})
+	}
+}
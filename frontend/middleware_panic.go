@@ -6,7 +6,6 @@ package main
 import (
 	"fmt"
 	"net/http"
-	"runtime/debug"
 
 	"github.com/Azure/ARO-HCP/internal/api/arm"
 )
@@ -19,9 +18,8 @@ func MiddlewarePanic(w http.ResponseWriter, r *http.Request, next http.HandlerFu
 				logger = DefaultLogger()
 			}
 
-			logger.Error(fmt.Sprintf("panic: %#v\n%s\n", e, string(debug.Stack())))
-
-			arm.WriteInternalServerError(w)
+			// The stack trace is logged with the error ID.
+			arm.WriteInternalServerError(w, logger, fmt.Errorf("panic: %#v", e))
 		}
 	}()
 
@@ -23,8 +23,7 @@ func MiddlewareSystemData(w http.ResponseWriter, r *http.Request, next http.Hand
 		} else {
 			logger, err := LoggerFromContext(r.Context())
 			if err != nil {
-				DefaultLogger().Error(err.Error())
-				arm.WriteInternalServerError(w)
+				arm.WriteInternalServerError(w, DefaultLogger(), err)
 				return
 			}
 
@@ -13,18 +13,9 @@ import (
 func MiddlewareValidateAPIVersion(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
 	apiVersion := r.URL.Query().Get(APIVersionKey)
 	if apiVersion == "" {
-		arm.WriteError(
-			w, http.StatusBadRequest,
-			arm.CloudErrorCodeInvalidParameter, "",
-			"The request is missing required parameter '%s'.",
-			APIVersionKey)
+		arm.ErrorMissingParameter.Write(w, "", APIVersionKey)
 	} else if version, ok := api.Lookup(apiVersion); !ok {
-		arm.WriteError(
-			w, http.StatusBadRequest,
-			arm.CloudErrorCodeInvalidResourceType, "",
-			"The resource type '%s' could not be found API version '%s'.",
-			api.ResourceType,
-			apiVersion)
+		arm.ErrorUnsupportedAPIVersion.Write(w, "", api.ResourceType, apiVersion)
 	} else {
 		ctx := ContextWithVersion(r.Context(), version)
 		r = r.WithContext(ctx)
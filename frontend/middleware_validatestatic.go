@@ -25,21 +25,21 @@ func MiddlewareValidateStatic(w http.ResponseWriter, r *http.Request, next http.
 
 	if subId != "" {
 		if uuid.Validate(subId) != nil {
-			arm.WriteError(w, http.StatusBadRequest, arm.CloudErrorCodeInvalidSubscriptionID, "", "The provided subscription identifier '%s' is malformed or invalid.", subId)
+			arm.ErrorInvalidSubscriptionID.Write(w, "", subId)
 			return
 		}
 	}
 
 	if resourceGroupName != "" {
 		if !rxResourceGroupName.MatchString(resourceGroupName) {
-			arm.WriteError(w, http.StatusBadRequest, arm.CloudErrorInvalidResourceGroupName, "", "Resource group '%s' is invalid.", resourceGroupName)
+			arm.ErrorInvalidResourceGroupName.Write(w, "", resourceGroupName)
 			return
 		}
 	}
 
 	if resourceName != "" {
 		if !rxResourceName.MatchString(resourceName) {
-			arm.WriteError(w, http.StatusBadRequest, arm.CloudErrorInvalidResourceName, "", "The Resource '%s/%s' under resource group '%s' is invalid.", api.ResourceType, resourceName, resourceGroupName)
+			arm.ErrorInvalidResourceName.Write(w, "", api.ResourceType, resourceName, resourceGroupName)
 			return
 		}
 	}
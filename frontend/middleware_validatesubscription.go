@@ -9,12 +9,6 @@ import (
 	"github.com/Azure/ARO-HCP/internal/api/arm"
 )
 
-const (
-	UnregisteredSubscriptionStateMessage = "Request is not allowed in unregistered subscription '%s'."
-	InvalidSubscriptionStateMessage      = "Request is not allowed in subscription in state '%s'."
-	SubscriptionMissingMessage           = "The request is missing required parameter '%s'."
-)
-
 type SubscriptionStateMuxValidator struct {
 	cache *Cache
 }
@@ -30,22 +24,14 @@ func NewSubscriptionStateMuxValidator(c *Cache) *SubscriptionStateMuxValidator {
 func (s *SubscriptionStateMuxValidator) MiddlewareValidateSubscriptionState(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
 	subscriptionId := r.PathValue(PathSegmentSubscriptionID)
 	if subscriptionId == "" {
-		arm.WriteError(
-			w, http.StatusBadRequest,
-			arm.CloudErrorCodeInvalidParameter, "",
-			SubscriptionMissingMessage,
-			PathSegmentSubscriptionID)
+		arm.ErrorMissingParameter.Write(w, "", PathSegmentSubscriptionID)
 		return
 	}
 
 	sub, exists := s.cache.GetSubscription(subscriptionId)
 
 	if !exists {
-		arm.WriteError(
-			w, http.StatusBadRequest,
-			arm.CloudErrorInvalidSubscriptionState, "",
-			UnregisteredSubscriptionStateMessage,
-			subscriptionId)
+		arm.ErrorUnregisteredSubscription.Write(w, "", subscriptionId)
 		return
 	}
 
@@ -56,25 +42,14 @@ func (s *SubscriptionStateMuxValidator) MiddlewareValidateSubscriptionState(w ht
 	case arm.Registered:
 		next(w, r)
 	case arm.Unregistered:
-		arm.WriteError(
-			w, http.StatusBadRequest,
-			arm.CloudErrorInvalidSubscriptionState, "",
-			UnregisteredSubscriptionStateMessage,
-			subscriptionId)
+		arm.ErrorUnregisteredSubscription.Write(w, "", subscriptionId)
 	case arm.Warned, arm.Suspended:
 		if r.Method != http.MethodGet && r.Method != http.MethodDelete {
-			arm.WriteError(w, http.StatusConflict,
-				arm.CloudErrorInvalidSubscriptionState, "",
-				InvalidSubscriptionStateMessage,
-				sub.State)
+			arm.ErrorSubscriptionStateConflict.Write(w, "", sub.State)
 			return
 		}
 		next(w, r)
 	case arm.Deleted:
-		arm.WriteError(
-			w, http.StatusBadRequest,
-			arm.CloudErrorInvalidSubscriptionState, "",
-			InvalidSubscriptionStateMessage,
-			sub.State)
+		arm.ErrorInvalidSubscriptionState.Write(w, "", sub.State)
 	}
 }
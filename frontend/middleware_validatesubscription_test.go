@@ -49,7 +49,7 @@ func TestMiddlewareValidateSubscription(t *testing.T) {
 				StatusCode: http.StatusBadRequest,
 				CloudErrorBody: &arm.CloudErrorBody{
 					Code:    arm.CloudErrorCodeInvalidParameter,
-					Message: fmt.Sprintf(SubscriptionMissingMessage, PathSegmentSubscriptionID),
+					Message: fmt.Sprintf(arm.ErrorMissingParameter.Message, PathSegmentSubscriptionID),
 				},
 			},
 		},
@@ -58,8 +58,8 @@ func TestMiddlewareValidateSubscription(t *testing.T) {
 			expectedError: &arm.CloudError{
 				StatusCode: http.StatusBadRequest,
 				CloudErrorBody: &arm.CloudErrorBody{
-					Code:    arm.CloudErrorInvalidSubscriptionState,
-					Message: fmt.Sprintf(UnregisteredSubscriptionStateMessage, subscriptionId),
+					Code:    arm.CloudErrorCodeInvalidSubscriptionState,
+					Message: fmt.Sprintf(arm.ErrorUnregisteredSubscription.Message, subscriptionId),
 				},
 			},
 			httpMethod:  http.MethodGet,
@@ -71,8 +71,8 @@ func TestMiddlewareValidateSubscription(t *testing.T) {
 			expectedError: &arm.CloudError{
 				StatusCode: http.StatusBadRequest,
 				CloudErrorBody: &arm.CloudErrorBody{
-					Code:    arm.CloudErrorInvalidSubscriptionState,
-					Message: fmt.Sprintf(InvalidSubscriptionStateMessage, arm.Deleted),
+					Code:    arm.CloudErrorCodeInvalidSubscriptionState,
+					Message: fmt.Sprintf(arm.ErrorInvalidSubscriptionState.Message, arm.Deleted),
 				},
 			},
 			httpMethod:  http.MethodGet,
@@ -84,8 +84,8 @@ func TestMiddlewareValidateSubscription(t *testing.T) {
 			expectedError: &arm.CloudError{
 				StatusCode: http.StatusBadRequest,
 				CloudErrorBody: &arm.CloudErrorBody{
-					Code:    arm.CloudErrorInvalidSubscriptionState,
-					Message: fmt.Sprintf(UnregisteredSubscriptionStateMessage, subscriptionId),
+					Code:    arm.CloudErrorCodeInvalidSubscriptionState,
+					Message: fmt.Sprintf(arm.ErrorUnregisteredSubscription.Message, subscriptionId),
 				},
 			},
 			httpMethod:  http.MethodGet,
@@ -119,8 +119,8 @@ func TestMiddlewareValidateSubscription(t *testing.T) {
 			expectedError: &arm.CloudError{
 				StatusCode: http.StatusConflict,
 				CloudErrorBody: &arm.CloudErrorBody{
-					Code:    arm.CloudErrorInvalidSubscriptionState,
-					Message: fmt.Sprintf(InvalidSubscriptionStateMessage, arm.Warned),
+					Code:    arm.CloudErrorCodeInvalidSubscriptionState,
+					Message: fmt.Sprintf(arm.ErrorInvalidSubscriptionState.Message, arm.Warned),
 				},
 			},
 			requestPath: defaultRequestPath,
@@ -132,8 +132,8 @@ func TestMiddlewareValidateSubscription(t *testing.T) {
 			expectedError: &arm.CloudError{
 				StatusCode: http.StatusConflict,
 				CloudErrorBody: &arm.CloudErrorBody{
-					Code:    arm.CloudErrorInvalidSubscriptionState,
-					Message: fmt.Sprintf(InvalidSubscriptionStateMessage, arm.Suspended),
+					Code:    arm.CloudErrorCodeInvalidSubscriptionState,
+					Message: fmt.Sprintf(arm.ErrorInvalidSubscriptionState.Message, arm.Suspended),
 				},
 			},
 			requestPath: defaultRequestPath,
@@ -145,8 +145,8 @@ func TestMiddlewareValidateSubscription(t *testing.T) {
 			expectedError: &arm.CloudError{
 				StatusCode: http.StatusConflict,
 				CloudErrorBody: &arm.CloudErrorBody{
-					Code:    arm.CloudErrorInvalidSubscriptionState,
-					Message: fmt.Sprintf(InvalidSubscriptionStateMessage, arm.Suspended),
+					Code:    arm.CloudErrorCodeInvalidSubscriptionState,
+					Message: fmt.Sprintf(arm.ErrorInvalidSubscriptionState.Message, arm.Suspended),
 				},
 			},
 			requestPath: defaultRequestPath,
@@ -0,0 +1,511 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+// OpenAPISpec holds the OpenAPI documents of the registered API versions.
+// Request bodies are validated against the schemas they declare so clients
+// get precise errors for wrong types, unknown properties and the like.
+type OpenAPISpec struct {
+	loader *openapiLoader
+	// operations is keyed by api-version.
+	operations map[string][]openapiOperation
+}
+
+// LoadOpenAPISpec loads the OpenAPI document of each registered API version
+// from a directory containing an "<api-version>/openapi.json" file for each.
+func LoadOpenAPISpec(dir string) (*OpenAPISpec, error) {
+	spec := &OpenAPISpec{
+		loader:     newOpenAPILoader(),
+		operations: make(map[string][]openapiOperation),
+	}
+
+	for _, version := range api.Versions() {
+		file := filepath.Join(dir, version.String(), "openapi.json")
+		document, err := spec.loader.load(file)
+		if err != nil {
+			return nil, err
+		}
+		spec.operations[version.String()] = openapiOperations(file, document)
+	}
+
+	return spec, nil
+}
+
+// operation returns the operation of an API version that matches a request
+// method and lowercased request path.
+func (s *OpenAPISpec) operation(apiVersion, method, path string) (openapiOperation, bool) {
+	for _, operation := range s.operations[apiVersion] {
+		if operation.matches(method, path) {
+			return operation, true
+		}
+	}
+	return openapiOperation{}, false
+}
+
+// ValidateRequestBody validates a request body against the body schema the
+// matching operation declares and returns an error detail for each violation.
+// Requests with no matching operation or no body schema are not validated.
+func (s *OpenAPISpec) ValidateRequestBody(apiVersion, method, path string, body []byte) []arm.CloudErrorBody {
+	operation, ok := s.operation(apiVersion, method, path)
+	if !ok {
+		return nil
+	}
+
+	file, schema, ok := s.loader.bodySchema(operation)
+	if !ok {
+		return nil
+	}
+
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		// Left for the handler to report.
+		return nil
+	}
+
+	return s.loader.validate(file, schema, value, "", openapiRequest)
+}
+
+// MiddlewareValidateRequestSchema rejects requests whose body does not
+// conform to the OpenAPI schema for the requested API version. It must run
+// after the API version has been validated.
+func (s *OpenAPISpec) MiddlewareValidateRequestSchema(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	body, err := BodyFromContext(r.Context())
+	if err != nil || len(body) == 0 {
+		next(w, r)
+		return
+	}
+
+	errorDetails := s.ValidateRequestBody(r.URL.Query().Get(APIVersionKey), r.Method, r.URL.Path, body)
+
+	switch len(errorDetails) {
+	case 0:
+		next(w, r)
+	case 1:
+		arm.WriteCloudError(w, &arm.CloudError{
+			StatusCode:     http.StatusBadRequest,
+			CloudErrorBody: &errorDetails[0],
+		})
+	default:
+		cloudError := arm.ErrorMultipleErrorsOccurred.New("")
+		cloudError.Details = errorDetails
+		arm.WriteCloudError(w, cloudError)
+	}
+}
+
+// openapiDirection tells the schema validator whether a value is a request
+// or a response body, since some rules only apply to one of them.
+type openapiDirection int
+
+const (
+	openapiRequest openapiDirection = iota
+	openapiResponse
+)
+
+// openapiOperation is an operation in an OpenAPI document.
+type openapiOperation struct {
+	id     string
+	method string
+	path   string
+	file   string
+	node   map[string]any
+}
+
+func openapiOperations(file string, document map[string]any) []openapiOperation {
+	var operations []openapiOperation
+	paths, _ := document["paths"].(map[string]any)
+	for path, item := range paths {
+		for method, node := range item.(map[string]any) {
+			operation, ok := node.(map[string]any)
+			if !ok || method == "parameters" {
+				continue
+			}
+			id, _ := operation["operationId"].(string)
+			operations = append(operations, openapiOperation{
+				id:     id,
+				method: strings.ToUpper(method),
+				path:   path,
+				file:   file,
+				node:   operation,
+			})
+		}
+	}
+	sort.Slice(operations, func(i, j int) bool {
+		return operations[i].id < operations[j].id
+	})
+	return operations
+}
+
+// matches returns true if the operation applies to a request method and
+// path. Path parameters match any single segment and literal segments
+// match regardless of case.
+func (o openapiOperation) matches(method, path string) bool {
+	if o.method != method {
+		return false
+	}
+
+	templateSegments := strings.Split(strings.Trim(o.path, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(templateSegments) != len(pathSegments) {
+		return false
+	}
+
+	for i, segment := range templateSegments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			continue
+		}
+		if !strings.EqualFold(segment, pathSegments[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// openapiLoader loads OpenAPI documents and resolves JSON references
+// between them. Documents and compiled patterns are cached, and the
+// loader is safe for concurrent use.
+type openapiLoader struct {
+	mutex     sync.Mutex
+	documents map[string]map[string]any
+	patterns  map[string]*regexp.Regexp
+}
+
+func newOpenAPILoader() *openapiLoader {
+	return &openapiLoader{
+		documents: make(map[string]map[string]any),
+		patterns:  make(map[string]*regexp.Regexp),
+	}
+}
+
+func (l *openapiLoader) load(file string) (map[string]any, error) {
+	file = filepath.Clean(file)
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if document, ok := l.documents[file]; ok {
+		return document, nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var document map[string]any
+	if err = json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	l.documents[file] = document
+	return document, nil
+}
+
+// pattern returns the compiled form of a pattern keyword, or nil if the
+// pattern uses syntax Go does not support.
+func (l *openapiLoader) pattern(expr string) *regexp.Regexp {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	rx, ok := l.patterns[expr]
+	if !ok {
+		rx, _ = regexp.Compile(expr)
+		l.patterns[expr] = rx
+	}
+	return rx
+}
+
+// resolve returns the node referenced by ref, interpreted relative to the
+// document in file, along with the file that contains the node.
+func (l *openapiLoader) resolve(file, ref string) (string, map[string]any, error) {
+	refFile, pointer, _ := strings.Cut(ref, "#")
+	if refFile != "" {
+		file = filepath.Join(filepath.Dir(file), refFile)
+	}
+
+	document, err := l.load(file)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var node any = document
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if token == "" {
+			continue
+		}
+		object, ok := node.(map[string]any)
+		if !ok {
+			return "", nil, fmt.Errorf("%s: cannot resolve %q", file, ref)
+		}
+		node, ok = object[strings.NewReplacer("~1", "/", "~0", "~").Replace(token)]
+		if !ok {
+			return "", nil, fmt.Errorf("%s: cannot resolve %q", file, ref)
+		}
+	}
+
+	object, ok := node.(map[string]any)
+	if !ok {
+		return "", nil, fmt.Errorf("%s: %q is not an object", file, ref)
+	}
+	return file, object, nil
+}
+
+// deref follows any chain of JSON references starting at node.
+func (l *openapiLoader) deref(file string, node map[string]any) (string, map[string]any, error) {
+	for {
+		ref, ok := node["$ref"].(string)
+		if !ok {
+			return file, node, nil
+		}
+		var err error
+		file, node, err = l.resolve(file, ref)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+}
+
+// bodySchema returns the schema of the body parameter of an operation.
+func (l *openapiLoader) bodySchema(operation openapiOperation) (string, map[string]any, bool) {
+	parameters, _ := operation.node["parameters"].([]any)
+	for _, item := range parameters {
+		parameter, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		file, parameter, err := l.deref(operation.file, parameter)
+		if err != nil || parameter["in"] != "body" {
+			continue
+		}
+		if schema, ok := parameter["schema"].(map[string]any); ok {
+			return file, schema, true
+		}
+	}
+	return "", nil, false
+}
+
+type schemaLocation struct {
+	file   string
+	schema map[string]any
+}
+
+// openapiObject is the merged view of an object schema and the schemas it
+// inherits through allOf.
+type openapiObject struct {
+	properties map[string]schemaLocation
+	required   []string
+	additional *schemaLocation
+	// open is set if the object allows undeclared properties of any type.
+	open bool
+}
+
+func (l *openapiLoader) mergeObject(file string, schema map[string]any, object *openapiObject) error {
+	file, schema, err := l.deref(file, schema)
+	if err != nil {
+		return err
+	}
+	if properties, ok := schema["properties"].(map[string]any); ok {
+		for name, property := range properties {
+			if property, ok := property.(map[string]any); ok {
+				object.properties[name] = schemaLocation{file, property}
+			}
+		}
+	}
+	if required, ok := schema["required"].([]any); ok {
+		for _, name := range required {
+			object.required = append(object.required, name.(string))
+		}
+	}
+	switch additional := schema["additionalProperties"].(type) {
+	case map[string]any:
+		object.additional = &schemaLocation{file, additional}
+	case bool:
+		object.open = object.open || additional
+	}
+	if allOf, ok := schema["allOf"].([]any); ok {
+		for _, item := range allOf {
+			if err = l.mergeObject(file, item.(map[string]any), object); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// requiredIn returns whether a required property must be present in the
+// given direction. Read-only properties are never required in requests,
+// and ARM does not return secrets or properties that lack read mutability.
+func requiredIn(schema map[string]any, direction openapiDirection) bool {
+	switch direction {
+	case openapiRequest:
+		return schema["readOnly"] != true
+	case openapiResponse:
+		if schema["x-ms-secret"] == true {
+			return false
+		}
+		mutability, ok := schema["x-ms-mutability"].([]any)
+		return !ok || slices.Contains(mutability, any("read"))
+	}
+	return true
+}
+
+// schemaViolation returns an error detail for a schema violation.
+func schemaViolation(target, format string, a ...any) arm.CloudErrorBody {
+	return arm.CloudErrorBody{
+		Code:    arm.CloudErrorCodeInvalidRequestContent,
+		Message: fmt.Sprintf(format, a...),
+		Target:  target,
+	}
+}
+
+// fieldName returns the last element of a JSON path target.
+func fieldName(target string) string {
+	if target == "" {
+		return "request content"
+	}
+	return target[strings.LastIndex(target, ".")+1:]
+}
+
+// validate checks value against an OpenAPI (Swagger 2.0) schema and returns
+// an error detail for each violation, targeted at the JSON path of the
+// offending value. Objects may only contain properties that the schema
+// declares, unless the schema allows additional properties.
+func (l *openapiLoader) validate(file string, schema map[string]any, value any, target string, direction openapiDirection) []arm.CloudErrorBody {
+	file, schema, err := l.deref(file, schema)
+	if err != nil {
+		return []arm.CloudErrorBody{schemaViolation(target, "%v", err)}
+	}
+
+	// A null value removes an optional property in a merge patch.
+	if value == nil {
+		return nil
+	}
+
+	schemaType, _ := schema["type"].(string)
+	if schemaType == "" && (schema["properties"] != nil || schema["allOf"] != nil) {
+		schemaType = "object"
+	}
+
+	switch schemaType {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return []arm.CloudErrorBody{schemaViolation(target, "Invalid type for field '%s' (must be an object)", fieldName(target))}
+		}
+		return l.validateObject(file, schema, object, target, direction)
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return []arm.CloudErrorBody{schemaViolation(target, "Invalid type for field '%s' (must be an array)", fieldName(target))}
+		}
+		var errorDetails []arm.CloudErrorBody
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range array {
+				errorDetails = append(errorDetails, l.validate(file, items, item, fmt.Sprintf("%s[%d]", target, i), direction)...)
+			}
+		}
+		return errorDetails
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return []arm.CloudErrorBody{schemaViolation(target, "Invalid type for field '%s' (must be a string)", fieldName(target))}
+		}
+		if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, any(s)) {
+			values := make([]string, len(enum))
+			for i, item := range enum {
+				values[i] = fmt.Sprint(item)
+			}
+			return []arm.CloudErrorBody{schemaViolation(target, "Invalid value '%s' for field '%s' (must be one of: %s)", s, fieldName(target), strings.Join(values, " "))}
+		}
+		if expr, ok := schema["pattern"].(string); ok {
+			if rx := l.pattern(expr); rx != nil && !rx.MatchString(s) {
+				return []arm.CloudErrorBody{schemaViolation(target, "Invalid value '%s' for field '%s' (must match pattern '%s')", s, fieldName(target), expr)}
+			}
+		}
+	case "integer":
+		n, ok := value.(float64)
+		if !ok || n != float64(int64(n)) {
+			return []arm.CloudErrorBody{schemaViolation(target, "Invalid type for field '%s' (must be an integer)", fieldName(target))}
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return []arm.CloudErrorBody{schemaViolation(target, "Invalid type for field '%s' (must be a number)", fieldName(target))}
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []arm.CloudErrorBody{schemaViolation(target, "Invalid type for field '%s' (must be a boolean)", fieldName(target))}
+		}
+	}
+
+	return nil
+}
+
+func (l *openapiLoader) validateObject(file string, schema map[string]any, object map[string]any, target string, direction openapiDirection) []arm.CloudErrorBody {
+	merged := &openapiObject{properties: make(map[string]schemaLocation)}
+	if err := l.mergeObject(file, schema, merged); err != nil {
+		return []arm.CloudErrorBody{schemaViolation(target, "%v", err)}
+	}
+
+	// An object schema that declares no properties is free-form.
+	if len(merged.properties) == 0 && merged.additional == nil {
+		merged.open = true
+	}
+
+	var errorDetails []arm.CloudErrorBody
+
+	join := func(name string) string {
+		if target == "" {
+			return name
+		}
+		return target + "." + name
+	}
+
+	for _, name := range merged.required {
+		if _, ok := object[name]; ok {
+			continue
+		}
+		if property, ok := merged.properties[name]; ok && !requiredIn(property.schema, direction) {
+			continue
+		}
+		errorDetails = append(errorDetails, schemaViolation(join(name), "Missing required field '%s'", name))
+	}
+
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if property, ok := merged.properties[name]; ok {
+			// Read-only properties in requests are ignored.
+			if direction == openapiRequest && property.schema["readOnly"] == true {
+				continue
+			}
+			errorDetails = append(errorDetails, l.validate(property.file, property.schema, object[name], join(name), direction)...)
+		} else if merged.additional != nil {
+			errorDetails = append(errorDetails, l.validate(merged.additional.file, merged.additional.schema, object[name], join(name), direction)...)
+		} else if !merged.open {
+			errorDetails = append(errorDetails, schemaViolation(join(name), "Unrecognized field '%s'", name))
+		}
+	}
+
+	return errorDetails
+}
@@ -0,0 +1,611 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+	"github.com/Azure/ARO-HCP/internal/api/fixtures"
+)
+
+const (
+	openapiSpecDir     = "../api/redhatopenshift/resource-manager/Microsoft.RedHatOpenshift/preview"
+	openapiExamplesDir = "../api/redhatopenshift/HcpCluster/examples"
+)
+
+// unroutedOperations lists operations in the OpenAPI specification that
+// the frontend does not implement yet. The route conformance test fails if
+// an operation listed here becomes routed, so the list stays accurate.
+var unroutedOperations = map[string]string{
+	"Operations_List": "operations are not implemented",
+	"HcpClusterVersionOperations_ListByLocation":  "versions are not implemented",
+	"NodePools_ListByHcpOpenShiftClusterResource": "node pools are not implemented",
+	"NodePools_Get":            "node pools are not implemented",
+	"NodePools_CreateOrUpdate": "node pools are not implemented",
+	"NodePools_Update":         "node pools are not implemented",
+	"NodePools_Delete":         "node pools are not implemented",
+}
+
+// knownRenderingViolations lists schema violations in cluster resources
+// rendered by the frontend that cannot be fixed in the frontend alone.
+var knownRenderingViolations = map[string]string{
+	`.claim.mappings.groups.prefixPolicy: Missing required field 'prefixPolicy'`: "OpenShift group claim mappings have no prefix policy",
+}
+
+// knownViolation returns the reason for a violation listed in known, if any.
+func knownViolation(known map[string]string, violation string) (string, bool) {
+	for suffix, reason := range known {
+		if strings.HasSuffix(violation, suffix) {
+			return reason, true
+		}
+	}
+	return "", false
+}
+
+// openapiPathParameterValues are substituted for path parameters in the
+// OpenAPI specification. Parameters not listed here get a generic name.
+var openapiPathParameterValues = map[string]string{
+	"subscriptionId":    "00000000-0000-0000-0000-000000000000",
+	"resourceGroupName": "rg",
+	"location":          "eastus",
+}
+
+// requestPath substitutes values for the path parameters of the operation.
+func openapiRequestPath(o openapiOperation) string {
+	segments := strings.Split(o.path, "/")
+	for i, segment := range segments {
+		if name, ok := strings.CutPrefix(segment, "{"); ok {
+			name = strings.TrimSuffix(name, "}")
+			if value, ok := openapiPathParameterValues[name]; ok {
+				segments[i] = value
+			} else {
+				segments[i] = "name"
+			}
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func openapiSpecFile(version api.Version) string {
+	return filepath.Join(openapiSpecDir, version.String(), "openapi.json")
+}
+
+// TestOpenAPIRoutes checks that every operation in the OpenAPI document of
+// each registered API version is routed to a handler other than NotFound.
+func TestOpenAPIRoutes(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	f := NewFrontend(logger, nil, noopEmitter{}, NewMemoryDBClient(), FrontendOptions{AzureResources: newTestAzureResourceClient()})
+	mux := f.server.Handler.(*MiddlewareMux)
+
+	for _, version := range api.Versions() {
+		t.Run(version.String(), func(t *testing.T) {
+			document, err := newOpenAPILoader().load(openapiSpecFile(version))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, operation := range openapiOperations(openapiSpecFile(version), document) {
+				// MiddlewareLowercase runs before multiplexing.
+				request := httptest.NewRequest(operation.method, strings.ToLower(openapiRequestPath(operation)), nil)
+				_, pattern := mux.ServeMux.Handler(request)
+				routed := strings.HasPrefix(pattern, operation.method+" ")
+
+				reason, unrouted := unroutedOperations[operation.id]
+				switch {
+				case routed && unrouted:
+					t.Errorf("%s: %s %s is routed to %q; remove it from unroutedOperations", operation.id, operation.method, operation.path, pattern)
+				case !routed && unrouted:
+					t.Logf("%s: %s %s is not routed: %s", operation.id, operation.method, operation.path, reason)
+				case !routed:
+					t.Errorf("%s: %s %s is not routed", operation.id, operation.method, operation.path)
+				}
+			}
+		})
+	}
+}
+
+// renderCluster renders a cluster resource the way the frontend does for
+// a response: by converting it to the internal representation and back.
+func renderCluster(version api.Version, value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	versionedRequest := version.NewHCPOpenShiftCluster(nil)
+	if err = json.Unmarshal(data, versionedRequest); err != nil {
+		return nil, err
+	}
+
+	cluster := api.NewDefaultHCPOpenShiftCluster()
+	versionedRequest.Normalize(cluster)
+
+	data, err = json.Marshal(version.NewHCPOpenShiftCluster(cluster))
+	if err != nil {
+		return nil, err
+	}
+
+	var rendered any
+	err = json.Unmarshal(data, &rendered)
+	return rendered, err
+}
+
+// TestOpenAPIExamples checks that the response bodies in the examples for
+// each registered API version validate against the schemas declared in the
+// OpenAPI document, and that cluster resources in those bodies still do
+// after the frontend renders them.
+func TestOpenAPIExamples(t *testing.T) {
+	for _, version := range api.Versions() {
+		t.Run(version.String(), func(t *testing.T) {
+			loader := newOpenAPILoader()
+			specFile := openapiSpecFile(version)
+
+			document, err := loader.load(specFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			operations := make(map[string]openapiOperation)
+			for _, operation := range openapiOperations(openapiSpecFile(version), document) {
+				operations[operation.id] = operation
+			}
+
+			exampleFiles, err := filepath.Glob(filepath.Join(openapiExamplesDir, version.String(), "*.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(exampleFiles) == 0 {
+				t.Fatalf("no examples found for %s", version)
+			}
+
+			for _, exampleFile := range exampleFiles {
+				data, err := os.ReadFile(exampleFile)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var example struct {
+					OperationID string `json:"operationId"`
+					Responses   map[string]struct {
+						Body any `json:"body"`
+					} `json:"responses"`
+				}
+				if err = json.Unmarshal(data, &example); err != nil {
+					t.Fatalf("%s: %v", exampleFile, err)
+				}
+
+				name := filepath.Base(exampleFile)
+
+				operation, ok := operations[example.OperationID]
+				if !ok {
+					t.Errorf("%s: operation %q is not in the OpenAPI document", name, example.OperationID)
+					continue
+				}
+
+				for statusCode, response := range example.Responses {
+					if response.Body == nil {
+						continue
+					}
+
+					responses := operation.node["responses"].(map[string]any)
+					declared, ok := responses[statusCode].(map[string]any)
+					if !ok {
+						t.Errorf("%s: status code %s is not declared for %s", name, statusCode, operation.id)
+						continue
+					}
+					schema, ok := declared["schema"].(map[string]any)
+					if !ok {
+						t.Errorf("%s: status code %s has a body but no declared schema", name, statusCode)
+						continue
+					}
+
+					for _, violation := range responseViolations(loader, specFile, schema, response.Body) {
+						t.Errorf("%s: %s: %s", name, statusCode, violation)
+					}
+
+					ref, _ := schema["$ref"].(string)
+					switch {
+					case strings.HasSuffix(ref, "/HcpOpenShiftClusterResource"):
+						checkRenderedCluster(t, loader, specFile, schema, version, response.Body, name+": "+statusCode)
+					case strings.HasSuffix(ref, "/HcpOpenShiftClusterResourceListResult"):
+						itemSchema := map[string]any{"$ref": strings.TrimSuffix(ref, "ListResult")}
+						values, _ := response.Body.(map[string]any)["value"].([]any)
+						for i, value := range values {
+							checkRenderedCluster(t, loader, specFile, itemSchema, version, value, fmt.Sprintf("%s: %s: value[%d]", name, statusCode, i))
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+// responseViolations validates a response body against a schema and
+// describes each violation.
+func responseViolations(loader *openapiLoader, file string, schema map[string]any, value any) []string {
+	var violations []string
+	for _, detail := range loader.validate(file, schema, value, "", openapiResponse) {
+		violations = append(violations, detail.Target+": "+detail.Message)
+	}
+	return violations
+}
+
+func checkRenderedCluster(t *testing.T, loader *openapiLoader, specFile string, schema map[string]any, version api.Version, value any, context string) {
+	t.Helper()
+
+	rendered, err := renderCluster(version, value)
+	if err != nil {
+		t.Errorf("%s: failed to render cluster: %v", context, err)
+		return
+	}
+
+	for _, violation := range responseViolations(loader, specFile, schema, rendered) {
+		if reason, ok := knownViolation(knownRenderingViolations, violation); ok {
+			t.Logf("%s: %s: %s", context, violation, reason)
+			continue
+		}
+		t.Errorf("%s: %s", context, violation)
+	}
+}
+
+// TestOpenAPIErrorResponse checks that error responses from the frontend
+// validate against the default response schema of each routed operation.
+func TestOpenAPIErrorResponse(t *testing.T) {
+	internalServerError, _ := arm.NewInternalServerError()
+	resourceNotFound := arm.ErrorResourceNotFound.New("", api.ResourceType, "cluster", "rg")
+	multipleErrors := arm.ErrorMultipleErrorsOccurred.New("")
+	multipleErrors.Details = []arm.CloudErrorBody{
+		arm.ErrorInvalidRequestContent.Body("properties.spec", "invalid"),
+	}
+
+	cloudErrors := map[string]*arm.CloudError{
+		"internal server error": internalServerError,
+		"resource not found":    resourceNotFound,
+		"multiple errors":       multipleErrors,
+	}
+
+	for _, version := range api.Versions() {
+		t.Run(version.String(), func(t *testing.T) {
+			loader := newOpenAPILoader()
+			specFile := openapiSpecFile(version)
+
+			document, err := loader.load(specFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, operation := range openapiOperations(openapiSpecFile(version), document) {
+				if _, unrouted := unroutedOperations[operation.id]; unrouted {
+					continue
+				}
+
+				responses := operation.node["responses"].(map[string]any)
+				declared, ok := responses["default"].(map[string]any)
+				if !ok {
+					t.Errorf("%s: no default response declared", operation.id)
+					continue
+				}
+				schema := declared["schema"].(map[string]any)
+
+				for name, cloudError := range cloudErrors {
+					data, err := json.Marshal(cloudError)
+					if err != nil {
+						t.Fatal(err)
+					}
+					var body any
+					if err = json.Unmarshal(data, &body); err != nil {
+						t.Fatal(err)
+					}
+					for _, violation := range responseViolations(loader, specFile, schema, body) {
+						t.Errorf("%s: %s: %s", operation.id, name, violation)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestValidateRequestBody(t *testing.T) {
+	const (
+		apiVersion  = "2024-06-10-preview"
+		clusterPath = "/subscriptions/00000000-0000-0000-0000-000000000000/resourcegroups/rg/providers/microsoft.redhatopenshift/hcpopenshiftclusters/cluster"
+	)
+
+	spec, err := LoadOpenAPISpec(openapiSpecDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	version, _ := api.Lookup(apiVersion)
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		modify        func(map[string]any)
+		body          string
+		expectTargets []string
+	}{
+		{
+			name:   "Valid cluster",
+			method: http.MethodPut,
+			path:   clusterPath,
+		},
+		{
+			name:   "Wrong type",
+			method: http.MethodPut,
+			path:   clusterPath,
+			modify: func(m map[string]any) {
+				specMap(m, "network")["hostPrefix"] = "23"
+			},
+			expectTargets: []string{"properties.spec.network.hostPrefix"},
+		},
+		{
+			name:   "Invalid enum value",
+			method: http.MethodPut,
+			path:   clusterPath,
+			modify: func(m map[string]any) {
+				specMap(m, "network")["networkType"] = "Calico"
+			},
+			expectTargets: []string{"properties.spec.network.networkType"},
+		},
+		{
+			name:   "Missing required field",
+			method: http.MethodPut,
+			path:   clusterPath,
+			modify: func(m map[string]any) {
+				delete(specMap(m, "platform"), "subnetId")
+			},
+			expectTargets: []string{"properties.spec.platform.subnetId"},
+		},
+		{
+			name:   "Unknown field",
+			method: http.MethodPut,
+			path:   clusterPath,
+			modify: func(m map[string]any) {
+				specMap(m, "network")["bogus"] = true
+			},
+			expectTargets: []string{"properties.spec.network.bogus"},
+		},
+		{
+			name:   "Multiple violations",
+			method: http.MethodPut,
+			path:   clusterPath,
+			modify: func(m map[string]any) {
+				m["tags"] = map[string]any{"env": 1}
+				specMap(m, "dns")["baseDomainPrefix"] = false
+			},
+			expectTargets: []string{"properties.spec.dns.baseDomainPrefix", "tags.env"},
+		},
+		{
+			name:   "Update schema applies to PATCH",
+			method: http.MethodPatch,
+			path:   clusterPath,
+			body:   `{"tags": {"env": "test"}}`,
+		},
+		{
+			name:          "Create-only field rejected by PATCH",
+			method:        http.MethodPatch,
+			path:          clusterPath,
+			body:          `{"properties": {"spec": {"network": {}}}}`,
+			expectTargets: []string{"properties.spec.network"},
+		},
+		{
+			name:   "Unmatched path is not validated",
+			method: http.MethodPut,
+			path:   "/subscriptions/00000000-0000-0000-0000-000000000000",
+			body:   `{"state": 1}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := []byte(tt.body)
+			if tt.body == "" {
+				cluster := newTestValidCluster()
+				cluster.Properties.Spec.Platform.OutboundType = api.OutboundTypeLoadBalancer
+				data, err := json.Marshal(version.NewHCPOpenShiftCluster(cluster))
+				if err != nil {
+					t.Fatal(err)
+				}
+				var m map[string]any
+				if err = json.Unmarshal(data, &m); err != nil {
+					t.Fatal(err)
+				}
+				if tt.modify != nil {
+					tt.modify(m)
+				}
+				if body, err = json.Marshal(m); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			errorDetails := spec.ValidateRequestBody(apiVersion, tt.method, tt.path, body)
+
+			var targets []string
+			for _, detail := range errorDetails {
+				if detail.Code != arm.CloudErrorCodeInvalidRequestContent {
+					t.Errorf("Expected code %q, got %q", arm.CloudErrorCodeInvalidRequestContent, detail.Code)
+				}
+				targets = append(targets, detail.Target)
+			}
+			if !reflect.DeepEqual(targets, tt.expectTargets) {
+				t.Errorf("Expected targets %v, got %v: %v", tt.expectTargets, targets, errorDetails)
+			}
+		})
+	}
+}
+
+// specMap returns a profile from the properties.spec object of a
+// cluster decoded into a map.
+func specMap(m map[string]any, profile string) map[string]any {
+	spec := m["properties"].(map[string]any)["spec"].(map[string]any)
+	return spec[profile].(map[string]any)
+}
+
+// TestValidateRequestBodyFixtures checks the generated fixtures of every
+// API version against the schema: valid fixtures and fixtures only invalid
+// beyond what the schema expresses have no violations, and fixtures that
+// violate the schema are rejected for the expected field.
+func TestValidateRequestBodyFixtures(t *testing.T) {
+	spec, err := LoadOpenAPISpec(openapiSpecDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := fixtures.All(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fixture := range all {
+		t.Run(fmt.Sprintf("%s/%s/%s", fixture.APIVersion, fixture.Resource, fixture.Name), func(t *testing.T) {
+			violations := spec.ValidateRequestBody(fixture.APIVersion, http.MethodPut, fixture.ResourceID, fixture.Body)
+
+			if !fixture.SchemaViolation {
+				for _, violation := range violations {
+					t.Errorf("Unexpected violation: %s: %s", violation.Target, violation.Message)
+				}
+				return
+			}
+
+			var targets []string
+			for _, violation := range violations {
+				targets = append(targets, violation.Target)
+			}
+			if !slices.Contains(targets, fixture.Target) {
+				t.Errorf("Expected a violation for '%s', got %v", fixture.Target, targets)
+			}
+		})
+	}
+}
+
+func TestValidatePattern(t *testing.T) {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"name": map[string]any{"type": "string", "pattern": "^[a-z]{3}$"},
+		},
+	}
+
+	loader := newOpenAPILoader()
+
+	if errorDetails := loader.validate("", schema, map[string]any{"name": "abc"}, "", openapiRequest); errorDetails != nil {
+		t.Errorf("Expected no errors, got %v", errorDetails)
+	}
+
+	errorDetails := loader.validate("", schema, map[string]any{"name": "abcd"}, "", openapiRequest)
+	if len(errorDetails) != 1 || errorDetails[0].Target != "name" {
+		t.Errorf("Expected one error targeting name, got %v", errorDetails)
+	}
+}
+
+func TestMiddlewareValidateRequestSchema(t *testing.T) {
+	const (
+		subscription = "/subscriptions/00000000-0000-0000-0000-000000000000"
+		clusterPath  = subscription + "/resourceGroups/rg/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/cluster"
+		apiVersion   = "?" + APIVersionKey + "=2024-06-10-preview"
+	)
+
+	spec, err := LoadOpenAPISpec(openapiSpecDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	f := NewFrontend(logger, nil, noopEmitter{}, NewMemoryDBClient(), FrontendOptions{
+		AzureResources: newTestAzureResourceClient(),
+		Spec:           spec,
+	})
+
+	tests := []struct {
+		name          string
+		method        string
+		url           string
+		body          string
+		expectStatus  int
+		expectCode    string
+		expectTarget  string
+		expectDetails int
+	}{
+		{
+			name:         "Register subscription",
+			method:       http.MethodPut,
+			url:          subscription,
+			body:         `{"state": "Registered"}`,
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:         "Single violation",
+			method:       http.MethodPatch,
+			url:          clusterPath + apiVersion,
+			body:         `{"tags": {"env": 1}}`,
+			expectStatus: http.StatusBadRequest,
+			expectCode:   arm.CloudErrorCodeInvalidRequestContent,
+			expectTarget: "tags.env",
+		},
+		{
+			name:          "Multiple violations",
+			method:        http.MethodPatch,
+			url:           clusterPath + apiVersion,
+			body:          `{"tags": {"env": 1}, "bogus": true}`,
+			expectStatus:  http.StatusBadRequest,
+			expectCode:    arm.CloudErrorCodeMultipleErrorsOccurred,
+			expectDetails: 2,
+		},
+		{
+			name:         "Valid body reaches the handler",
+			method:       http.MethodPatch,
+			url:          clusterPath + apiVersion,
+			body:         `{"tags": {"env": "test"}}`,
+			expectStatus: http.StatusNotFound,
+			expectCode:   arm.CloudErrorCodeResourceNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		request := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+		request = request.WithContext(ContextWithLogger(request.Context(), logger))
+		request.Header.Set("Content-Type", "application/json")
+		writer := httptest.NewRecorder()
+
+		f.server.Handler.ServeHTTP(writer, request)
+
+		if writer.Code != tt.expectStatus {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, tt.expectStatus, writer.Code, writer.Body.String())
+			continue
+		}
+		if tt.expectCode == "" {
+			continue
+		}
+
+		var cloudError arm.CloudError
+		if err := json.Unmarshal(writer.Body.Bytes(), &cloudError); err != nil {
+			t.Errorf("%s: expected a CloudError body: %v", tt.name, err)
+			continue
+		}
+		if cloudError.Code != tt.expectCode {
+			t.Errorf("%s: expected code %q, got %q", tt.name, tt.expectCode, cloudError.Code)
+		}
+		if cloudError.Target != tt.expectTarget {
+			t.Errorf("%s: expected target %q, got %q", tt.name, tt.expectTarget, cloudError.Target)
+		}
+		if len(cloudError.Details) != tt.expectDetails {
+			t.Errorf("%s: expected %d details, got %d", tt.name, tt.expectDetails, len(cloudError.Details))
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"time"
+
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+// OperationRequest is the action an operation performs on a resource.
+type OperationRequest string
+
+const (
+	// OperationRequestHibernate marks a cluster hibernated when its
+	// subscription is suspended. Its workloads are not stopped yet.
+	OperationRequestHibernate OperationRequest = "Hibernate"
+	// OperationRequestResume clears a cluster's hibernated mark
+	// when its subscription is registered again.
+	OperationRequestResume OperationRequest = "Resume"
+	// OperationRequestPurge removes a cluster and its node pools
+	// when its subscription is deleted.
+	OperationRequestPurge OperationRequest = "Purge"
+)
+
+// OperationDocument tracks an asynchronous operation on a resource.
+// PartitionKey holds the subscription ID and ExternalID the resource ID.
+//
+// Status is Accepted while the operation waits to run or to be retried,
+// Updating or Deleting while it runs, and Succeeded, Failed or Canceled
+// once it is done.
+type OperationDocument struct {
+	ID                 string                `json:"id,omitempty"`
+	PartitionKey       string                `json:"partitionKey,omitempty"`
+	Request            OperationRequest      `json:"request,omitempty"`
+	ExternalID         string                `json:"externalId,omitempty"`
+	Status             arm.ProvisioningState `json:"status,omitempty"`
+	StartTime          time.Time             `json:"startTime,omitempty"`
+	LastTransitionTime time.Time             `json:"lastTransitionTime,omitempty"`
+	NotBefore          time.Time             `json:"notBefore,omitempty"`
+	Attempts           int                   `json:"attempts,omitempty"`
+	Error              *arm.CloudErrorBody   `json:"error,omitempty"`
+	TTL                int                   `json:"ttl,omitempty"`
+
+	// Values provided by Cosmos after doc creation
+	ResourceID  string `json:"_rid,omitempty"`
+	Self        string `json:"_self,omitempty"`
+	ETag        string `json:"_etag,omitempty"`
+	Attachments string `json:"_attachments,omitempty"`
+	Timestamp   int    `json:"_ts,omitempty"`
+}
+
+// Terminal returns true if the operation is done.
+func (doc *OperationDocument) Terminal() bool {
+	switch doc.Status {
+	case arm.ProvisioningStateSucceeded, arm.ProvisioningStateFailed, arm.ProvisioningStateCanceled:
+		return true
+	default:
+		return false
+	}
+}
+
+// runningStatus returns the status of the operation while it runs.
+func (doc *OperationDocument) runningStatus() arm.ProvisioningState {
+	if doc.Request == OperationRequestPurge {
+		return arm.ProvisioningStateDeleting
+	}
+	return arm.ProvisioningStateUpdating
+}
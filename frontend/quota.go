@@ -0,0 +1,307 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+const (
+	// Usage names reported by the usages endpoint.
+	UsageNameClusters = "clusters"
+)
+
+// QuotaLimits are the resource limits for a subscription.
+type QuotaLimits struct {
+	// Clusters is the number of clusters allowed per location.
+	Clusters int64 `json:"clusters,omitempty"`
+
+	// NodePoolsPerCluster is the number of node pools allowed per cluster.
+	NodePoolsPerCluster int64 `json:"nodePoolsPerCluster,omitempty"`
+
+	// WorkerReplicas is the number of worker nodes allowed per location
+	// in each VM family. VMFamilies overrides it for individual families.
+	WorkerReplicas int64            `json:"workerReplicas,omitempty"`
+	VMFamilies     map[string]int64 `json:"vmFamilies,omitempty"`
+}
+
+// QuotaConfig holds the default quota limits and overrides for
+// individual subscriptions. Zero values in an override fall back
+// to the default limits.
+type QuotaConfig struct {
+	QuotaLimits
+	Subscriptions map[string]QuotaLimits `json:"subscriptions,omitempty"`
+}
+
+// DefaultQuotaConfig returns the limits that apply when
+// no quota configuration file is provided.
+func DefaultQuotaConfig() *QuotaConfig {
+	return &QuotaConfig{
+		QuotaLimits: QuotaLimits{
+			Clusters:            10,
+			NodePoolsPerCluster: 10,
+			WorkerReplicas:      200,
+		},
+	}
+}
+
+// LoadQuotaConfig reads a QuotaConfig from a JSON file. Default
+// limits absent from the file are taken from DefaultQuotaConfig.
+func LoadQuotaConfig(path string) (*QuotaConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := DefaultQuotaConfig()
+	if err = json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+// Limits returns the effective quota limits for a subscription.
+func (q *QuotaConfig) Limits(subscriptionID string) QuotaLimits {
+	limits := q.QuotaLimits
+
+	for id, override := range q.Subscriptions {
+		if !strings.EqualFold(id, subscriptionID) {
+			continue
+		}
+		if override.Clusters != 0 {
+			limits.Clusters = override.Clusters
+		}
+		if override.NodePoolsPerCluster != 0 {
+			limits.NodePoolsPerCluster = override.NodePoolsPerCluster
+		}
+		if override.WorkerReplicas != 0 {
+			limits.WorkerReplicas = override.WorkerReplicas
+		}
+		if len(override.VMFamilies) > 0 {
+			families := make(map[string]int64, len(limits.VMFamilies)+len(override.VMFamilies))
+			for family, limit := range limits.VMFamilies {
+				families[family] = limit
+			}
+			for family, limit := range override.VMFamilies {
+				families[family] = limit
+			}
+			limits.VMFamilies = families
+		}
+		break
+	}
+
+	return limits
+}
+
+// WorkerReplicaLimit returns the worker node limit for a VM family.
+func (l QuotaLimits) WorkerReplicaLimit(family string) int64 {
+	for f, limit := range l.VMFamilies {
+		if strings.EqualFold(f, family) {
+			return limit
+		}
+	}
+	return l.WorkerReplicas
+}
+
+var rxVMSize = regexp.MustCompile(`^([A-Za-z]+)_([A-Za-z]+)[0-9-]+([A-Za-z]*)((?:_[A-Za-z0-9]+)*)$`)
+
+// vmFamily returns the Azure compute usage name of the VM family for a
+// VM size, for example "Standard_D8s_v3" belongs to "standardDSv3Family".
+// Sizes that do not follow the usual naming scheme are their own family.
+func vmFamily(vmSize string) string {
+	m := rxVMSize.FindStringSubmatch(vmSize)
+	if m == nil {
+		return vmSize
+	}
+
+	family := strings.ToLower(m[1]) + strings.ToUpper(m[2]+m[3])
+	for _, part := range strings.Split(strings.TrimPrefix(m[4], "_"), "_") {
+		if len(part) > 1 && (part[0] == 'v' || part[0] == 'V') {
+			family += strings.ToLower(part[:1]) + part[1:]
+		} else {
+			family += strings.ToUpper(part)
+		}
+	}
+	return family + "Family"
+}
+
+// nodePoolReplicas returns the number of worker nodes a node pool
+// can scale to, which is what counts against quota.
+func nodePoolReplicas(profile *api.NodePoolProfile) int64 {
+	return int64(max(profile.Replicas, profile.Autoscaling.MaxReplicas))
+}
+
+// quotaUsage is the consumption of quota by one subscription in one location.
+type quotaUsage struct {
+	clusters int64
+	replicas map[string]int64
+}
+
+// computeQuotaUsage totals the cached clusters and node pools of a
+// subscription in a location. The resource with ID exclude, if any,
+// is left out so that it can be replaced with its updated form.
+func computeQuotaUsage(cache *Cache, subscriptionID, location, exclude string) quotaUsage {
+	usage := quotaUsage{replicas: make(map[string]int64)}
+	location = normalizeLocation(location)
+
+	inScope := func(id string, cluster *api.HCPOpenShiftCluster) bool {
+		resourceID, err := arm.ParseResourceID(id)
+		return err == nil &&
+			strings.EqualFold(resourceID.SubscriptionID(), subscriptionID) &&
+			normalizeLocation(cluster.Location) == location
+	}
+
+	for id, cluster := range cache.Clusters() {
+		if id != exclude && inScope(id, cluster) {
+			usage.clusters++
+		}
+	}
+
+	for id, nodePool := range cache.NodePools() {
+		if id == exclude {
+			continue
+		}
+		clusterID := nodePoolClusterID(id)
+		cluster, found := cache.GetCluster(clusterID)
+		if !found || !inScope(clusterID, cluster) {
+			continue
+		}
+		profile := &nodePool.Properties.Profile
+		if profile.VMSize != "" {
+			usage.replicas[vmFamily(profile.VMSize)] += nodePoolReplicas(profile)
+		}
+	}
+
+	return usage
+}
+
+// nodePoolClusterID returns the cache key of the cluster
+// a node pool belongs to, or an empty string if it has none.
+func nodePoolClusterID(nodePoolID string) string {
+	resourceID, err := arm.ParseResourceID(nodePoolID)
+	if err != nil || resourceID.Parent() == nil {
+		return ""
+	}
+	return strings.ToLower(resourceID.Parent().String())
+}
+
+func quotaExceeded(target, quota, location, subscriptionID string, limit, current, required int64) arm.CloudErrorBody {
+	return arm.ErrorQuotaExceeded.Body(target, quota, location, subscriptionID, limit, current, required)
+}
+
+// CheckClusterCreation returns an error detail if creating the normalized
+// cluster would exceed the subscription's cluster quota in its location.
+// The resource ID is the cluster's cache key.
+func (q *QuotaConfig) CheckClusterCreation(cache *Cache, resourceID string, cluster *api.HCPOpenShiftCluster) []arm.CloudErrorBody {
+	parsed, err := arm.ParseResourceID(resourceID)
+	if err != nil {
+		return nil
+	}
+	subscriptionID := parsed.SubscriptionID()
+	limits := q.Limits(subscriptionID)
+	usage := computeQuotaUsage(cache, subscriptionID, cluster.Location, resourceID)
+
+	if usage.clusters+1 > limits.Clusters {
+		return []arm.CloudErrorBody{
+			quotaExceeded("", "cluster", cluster.Location, subscriptionID, limits.Clusters, usage.clusters, 1),
+		}
+	}
+	return nil
+}
+
+// CheckNodePool returns error details if creating or updating the normalized
+// node pool would exceed the node pool quota of its cluster or the worker
+// node quota for its VM family. The resource ID is the node pool's cache key.
+func (q *QuotaConfig) CheckNodePool(cache *Cache, resourceID string, nodePool *api.HCPOpenShiftClusterNodePool) []arm.CloudErrorBody {
+	var errorDetails []arm.CloudErrorBody
+
+	clusterID := nodePoolClusterID(resourceID)
+	cluster, found := cache.GetCluster(clusterID)
+	if !found {
+		return nil
+	}
+	parsed, err := arm.ParseResourceID(clusterID)
+	if err != nil {
+		return nil
+	}
+	subscriptionID := parsed.SubscriptionID()
+	limits := q.Limits(subscriptionID)
+
+	if _, updating := cache.GetNodePool(resourceID); !updating {
+		var nodePools int64
+		for id := range cache.NodePools() {
+			if nodePoolClusterID(id) == clusterID {
+				nodePools++
+			}
+		}
+		if nodePools+1 > limits.NodePoolsPerCluster {
+			errorDetails = append(errorDetails,
+				quotaExceeded("", fmt.Sprintf("node pools per cluster for '%s'", parsed.Name()), cluster.Location, subscriptionID, limits.NodePoolsPerCluster, nodePools, 1))
+		}
+	}
+
+	profile := &nodePool.Properties.Profile
+	if profile.VMSize != "" {
+		family := vmFamily(profile.VMSize)
+		limit := limits.WorkerReplicaLimit(family)
+		usage := computeQuotaUsage(cache, subscriptionID, cluster.Location, resourceID)
+		required := nodePoolReplicas(profile)
+		if usage.replicas[family]+required > limit {
+			target := "properties.spec.replicas"
+			if profile.Autoscaling.MaxReplicas > profile.Replicas {
+				target = "properties.spec.autoScaling.max"
+			}
+			errorDetails = append(errorDetails,
+				quotaExceeded(target, fmt.Sprintf("'%s' worker node", family), cluster.Location, subscriptionID, limit, usage.replicas[family], required))
+		}
+	}
+
+	return errorDetails
+}
+
+// Usages returns the consumption of each quota by a subscription in a
+// location. Worker node usage is reported for VM families that are in
+// use or have a limit configured.
+func (q *QuotaConfig) Usages(cache *Cache, subscriptionID, location string) arm.UsageList {
+	limits := q.Limits(subscriptionID)
+	usage := computeQuotaUsage(cache, subscriptionID, location, "")
+
+	list := arm.UsageList{
+		Value: []arm.Usage{{
+			Name:         arm.UsageName{Value: UsageNameClusters, LocalizedValue: "Clusters"},
+			Unit:         arm.UsageUnitCount,
+			CurrentValue: usage.clusters,
+			Limit:        limits.Clusters,
+		}},
+	}
+
+	families := make([]string, 0, len(usage.replicas)+len(limits.VMFamilies))
+	for family := range usage.replicas {
+		families = append(families, family)
+	}
+	for family := range limits.VMFamilies {
+		if _, ok := usage.replicas[family]; !ok {
+			families = append(families, family)
+		}
+	}
+	sort.Strings(families)
+
+	for _, family := range families {
+		list.Value = append(list.Value, arm.Usage{
+			Name:         arm.UsageName{Value: family, LocalizedValue: fmt.Sprintf("Worker Nodes (%s)", family)},
+			Unit:         arm.UsageUnitCount,
+			CurrentValue: usage.replicas[family],
+			Limit:        limits.WorkerReplicaLimit(family),
+		})
+	}
+
+	return list
+}
@@ -0,0 +1,339 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+const (
+	testQuotaSubscriptionID = "00000000-0000-0000-0000-000000000000"
+	testQuotaClusterPrefix  = "/subscriptions/" + testQuotaSubscriptionID + "/resourcegroups/rg/providers/microsoft.redhatopenshift/hcpopenshiftclusters/"
+)
+
+func newTestQuotaCache() *Cache {
+	cache := NewCache()
+
+	for name, location := range map[string]string{"one": "eastus", "two": "East US", "three": "westus"} {
+		cluster := api.NewDefaultHCPOpenShiftCluster()
+		cluster.Location = location
+		cache.SetCluster(testQuotaClusterPrefix+name, cluster)
+	}
+
+	for name, profile := range map[string]api.NodePoolProfile{
+		"one/nodepools/a":   {VMSize: "Standard_D8s_v3", Replicas: 3},
+		"one/nodepools/b":   {VMSize: "Standard_D4s_v3", Replicas: 1, Autoscaling: api.NodePoolAutoscaling{MinReplicas: 1, MaxReplicas: 5}},
+		"two/nodepools/a":   {VMSize: "Standard_E16s_v5", Replicas: 2},
+		"three/nodepools/a": {VMSize: "Standard_D8s_v3", Replicas: 10},
+	} {
+		nodePool := &api.HCPOpenShiftClusterNodePool{}
+		nodePool.Properties.Profile = profile
+		cache.SetNodePool(testQuotaClusterPrefix+name, nodePool)
+	}
+
+	return cache
+}
+
+func TestVMFamily(t *testing.T) {
+	tests := map[string]string{
+		"Standard_D8s_v3":          "standardDSv3Family",
+		"Standard_D8_v3":           "standardDv3Family",
+		"Standard_D8ds_v5":         "standardDDSv5Family",
+		"Standard_E16as_v4":        "standardEASv4Family",
+		"Standard_NC24ads_A100_v4": "standardNCADSA100v4Family",
+		"Standard_F8":              "standardFFamily",
+		"custom":                   "custom",
+	}
+
+	for vmSize, expected := range tests {
+		t.Run(vmSize, func(t *testing.T) {
+			if actual := vmFamily(vmSize); actual != expected {
+				t.Errorf("Expected %q, got %q", expected, actual)
+			}
+		})
+	}
+}
+
+func TestQuotaConfigLimits(t *testing.T) {
+	config := DefaultQuotaConfig()
+	config.VMFamilies = map[string]int64{"standardDSv3Family": 50}
+	config.Subscriptions = map[string]QuotaLimits{
+		"AAAAAAAA-0000-0000-0000-000000000000": {
+			Clusters:   2,
+			VMFamilies: map[string]int64{"standardESv5Family": 5},
+		},
+	}
+
+	limits := config.Limits("aaaaaaaa-0000-0000-0000-000000000000")
+	if limits.Clusters != 2 {
+		t.Errorf("Expected overridden cluster limit 2, got %d", limits.Clusters)
+	}
+	if limits.NodePoolsPerCluster != config.NodePoolsPerCluster {
+		t.Errorf("Expected default node pool limit %d, got %d", config.NodePoolsPerCluster, limits.NodePoolsPerCluster)
+	}
+	for family, expected := range map[string]int64{
+		"standardDSv3Family": 50,
+		"standardESv5Family": 5,
+		"standardFFamily":    config.WorkerReplicas,
+	} {
+		if actual := limits.WorkerReplicaLimit(family); actual != expected {
+			t.Errorf("Expected %s limit %d, got %d", family, expected, actual)
+		}
+	}
+	if len(config.VMFamilies) != 1 {
+		t.Errorf("Default limits were modified")
+	}
+}
+
+func TestQuotaConfigCheckClusterCreation(t *testing.T) {
+	tests := []struct {
+		name        string
+		resourceID  string
+		location    string
+		limit       int64
+		expectError bool
+	}{
+		{
+			name:       "Under limit",
+			resourceID: testQuotaClusterPrefix + "four",
+			location:   "westus",
+			limit:      2,
+		},
+		{
+			name:        "At limit",
+			resourceID:  testQuotaClusterPrefix + "four",
+			location:    "eastus",
+			limit:       2,
+			expectError: true,
+		},
+		{
+			name:       "Existing cluster is not counted twice",
+			resourceID: testQuotaClusterPrefix + "two",
+			location:   "eastus",
+			limit:      2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &QuotaConfig{QuotaLimits: QuotaLimits{Clusters: tt.limit}}
+			cluster := api.NewDefaultHCPOpenShiftCluster()
+			cluster.Location = tt.location
+
+			errorDetails := config.CheckClusterCreation(newTestQuotaCache(), tt.resourceID, cluster)
+
+			if (len(errorDetails) > 0) != tt.expectError {
+				t.Fatalf("Expected error %v, got %v", tt.expectError, errorDetails)
+			}
+			if tt.expectError && errorDetails[0].Code != arm.CloudErrorCodeQuotaExceeded {
+				t.Errorf("Expected code %q, got %q", arm.CloudErrorCodeQuotaExceeded, errorDetails[0].Code)
+			}
+		})
+	}
+}
+
+func TestQuotaConfigCheckNodePool(t *testing.T) {
+	tests := []struct {
+		name          string
+		nodePoolName  string
+		profile       api.NodePoolProfile
+		expectTargets []string
+	}{
+		{
+			name:         "New node pool under limits",
+			nodePoolName: "two/nodepools/b",
+			profile:      api.NodePoolProfile{VMSize: "Standard_D8s_v3", Replicas: 2},
+		},
+		{
+			name:          "New node pool over node pool limit",
+			nodePoolName:  "one/nodepools/c",
+			profile:       api.NodePoolProfile{VMSize: "Standard_E16s_v5", Replicas: 1},
+			expectTargets: []string{""},
+		},
+		{
+			name:         "Scaling an existing node pool within limit",
+			nodePoolName: "one/nodepools/a",
+			profile:      api.NodePoolProfile{VMSize: "Standard_D8s_v3", Replicas: 5},
+		},
+		{
+			name:          "Scaling an existing node pool over replica limit",
+			nodePoolName:  "one/nodepools/a",
+			profile:       api.NodePoolProfile{VMSize: "Standard_D8s_v3", Replicas: 6},
+			expectTargets: []string{"properties.spec.replicas"},
+		},
+		{
+			name:          "Autoscaling over replica limit",
+			nodePoolName:  "two/nodepools/b",
+			profile:       api.NodePoolProfile{VMSize: "Standard_D8s_v3", Replicas: 1, Autoscaling: api.NodePoolAutoscaling{MinReplicas: 1, MaxReplicas: 6}},
+			expectTargets: []string{"properties.spec.autoScaling.max"},
+		},
+		{
+			name:         "Replicas in other locations are not counted",
+			nodePoolName: "three/nodepools/a",
+			profile:      api.NodePoolProfile{VMSize: "Standard_D8s_v3", Replicas: 10},
+		},
+	}
+
+	config := &QuotaConfig{
+		QuotaLimits: QuotaLimits{
+			NodePoolsPerCluster: 2,
+			WorkerReplicas:      10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodePool := &api.HCPOpenShiftClusterNodePool{}
+			nodePool.Properties.Profile = tt.profile
+
+			errorDetails := config.CheckNodePool(newTestQuotaCache(), testQuotaClusterPrefix+tt.nodePoolName, nodePool)
+
+			if len(errorDetails) != len(tt.expectTargets) {
+				t.Fatalf("Expected %d errors, got %v", len(tt.expectTargets), errorDetails)
+			}
+			for i, detail := range errorDetails {
+				if detail.Target != tt.expectTargets[i] {
+					t.Errorf("Expected target %q, got %q", tt.expectTargets[i], detail.Target)
+				}
+			}
+		})
+	}
+}
+
+func TestArmDeploymentPreflightQuota(t *testing.T) {
+	version, _ := api.Lookup("2024-06-10-preview")
+
+	newFrontend := func(limits QuotaLimits) *Frontend {
+		return &Frontend{
+			logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+			cache:  NewCache(),
+			quota:  &QuotaConfig{QuotaLimits: limits},
+		}
+	}
+	newNodePool := func(replicas int32) any {
+		nodePool := &api.HCPOpenShiftClusterNodePool{}
+		nodePool.Location = "eastus"
+		nodePool.Properties.Profile.VMSize = "Standard_D8s_v3"
+		nodePool.Properties.Profile.Replicas = replicas
+		return version.NewHCPOpenShiftClusterNodePool(nodePool)
+	}
+	cluster := version.NewHCPOpenShiftCluster(newTestValidCluster())
+
+	tests := []struct {
+		name         string
+		limits       QuotaLimits
+		resources    func(t *testing.T) []any
+		expectTarget string
+	}{
+		{
+			name:   "Clusters that each fit but not together",
+			limits: QuotaLimits{Clusters: 1, NodePoolsPerCluster: 10, WorkerReplicas: 10},
+			resources: func(t *testing.T) []any {
+				return []any{
+					newTestPreflightResource(t, "one", api.ResourceType, cluster),
+					newTestPreflightResource(t, "two", api.ResourceType, cluster),
+				}
+			},
+			expectTarget: "/hcpOpenShiftClusters/two",
+		},
+		{
+			name:   "Node pools that each fit but not together",
+			limits: QuotaLimits{Clusters: 1, NodePoolsPerCluster: 1, WorkerReplicas: 10},
+			resources: func(t *testing.T) []any {
+				return []any{
+					newTestPreflightResource(t, "one", api.ResourceType, cluster),
+					newTestPreflightResource(t, "one/a", api.NodePoolResourceType, newNodePool(1)),
+					newTestPreflightResource(t, "one/b", api.NodePoolResourceType, newNodePool(1)),
+				}
+			},
+			expectTarget: "/nodePools/b",
+		},
+		{
+			name:   "Worker nodes that each fit but not together",
+			limits: QuotaLimits{Clusters: 1, NodePoolsPerCluster: 10, WorkerReplicas: 10},
+			resources: func(t *testing.T) []any {
+				return []any{
+					newTestPreflightResource(t, "one", api.ResourceType, cluster),
+					newTestPreflightResource(t, "one/a", api.NodePoolResourceType, newNodePool(6)),
+					newTestPreflightResource(t, "one/b", api.NodePoolResourceType, newNodePool(6)),
+				}
+			},
+			expectTarget: "/nodePools/b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := runTestPreflight(t, newFrontend(tt.limits), tt.resources(t)...)
+
+			if response.Status != arm.DeploymentPreflightStatusFailed || response.Error == nil {
+				t.Fatalf("Expected preflight to fail, got %+v", response)
+			}
+			if !strings.HasSuffix(response.Error.Target, tt.expectTarget) ||
+				len(response.Error.Details) != 1 || response.Error.Details[0].Code != arm.CloudErrorCodeQuotaExceeded {
+				t.Errorf("Expected a quota error for %s, got %+v", tt.expectTarget, response.Error)
+			}
+		})
+	}
+}
+
+func TestArmLocationUsages(t *testing.T) {
+	config := DefaultQuotaConfig()
+	config.VMFamilies = map[string]int64{"standardFFamily": 20}
+
+	f := &Frontend{
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cache:  newTestQuotaCache(),
+		quota:  config,
+	}
+
+	version, _ := api.Lookup("2024-06-10-preview")
+	request := httptest.NewRequest(http.MethodGet, "/subscriptions/"+testQuotaSubscriptionID+"/providers/microsoft.redhatopenshift/locations/eastus/usages", nil)
+	request = request.WithContext(ContextWithVersion(request.Context(), version))
+	request.SetPathValue(PathSegmentSubscriptionID, testQuotaSubscriptionID)
+	request.SetPathValue(PageSegmentLocation, "eastus")
+	writer := httptest.NewRecorder()
+
+	f.ArmLocationUsages(writer, request)
+
+	if writer.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, writer.Code)
+	}
+
+	var actual arm.UsageList
+	if err := json.Unmarshal(writer.Body.Bytes(), &actual); err != nil {
+		t.Fatal(err)
+	}
+
+	usage := func(name, localized string, current, limit int64) arm.Usage {
+		return arm.Usage{
+			Name:         arm.UsageName{Value: name, LocalizedValue: localized},
+			Unit:         arm.UsageUnitCount,
+			CurrentValue: current,
+			Limit:        limit,
+		}
+	}
+	expected := arm.UsageList{
+		Value: []arm.Usage{
+			usage(UsageNameClusters, "Clusters", 2, 10),
+			usage("standardDSv3Family", "Worker Nodes (standardDSv3Family)", 8, 200),
+			usage("standardESv5Family", "Worker Nodes (standardESv5Family)", 2, 200),
+			usage("standardFFamily", "Worker Nodes (standardFFamily)", 0, 20),
+		},
+	}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("Unexpected usages (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+// Region is the Azure region served by the frontend. Each region has its
+// own frontend deployment, so requests for other locations are rejected.
+type Region struct {
+	location string
+}
+
+// NewRegion returns a Region for a location name such as "eastus".
+func NewRegion(location string) *Region {
+	return &Region{location: normalizeLocation(location)}
+}
+
+// Location returns the canonical name of the region.
+func (r *Region) Location() string {
+	return r.location
+}
+
+// Contains returns true if location, given as either a canonical
+// name or a display name, refers to the region.
+func (r *Region) Contains(location string) bool {
+	return normalizeLocation(location) == r.location
+}
+
+func (r *Region) locationError(target, location string) *arm.CloudError {
+	return arm.ErrorLocationNotAvailableForResourceType.New(target, location, api.ResourceType, r.location)
+}
+
+// CheckClusterLocation returns an error detail if a
+// normalized cluster is not located in the region.
+func (r *Region) CheckClusterLocation(cluster *api.HCPOpenShiftCluster) []arm.CloudErrorBody {
+	if r.Contains(cluster.Location) {
+		return nil
+	}
+	return []arm.CloudErrorBody{*r.locationError("location", cluster.Location).CloudErrorBody}
+}
+
+// zoneMappings returns the subscription's availability zone
+// mappings for the region, or nil if ARM did not provide any.
+func (r *Region) zoneMappings(subscription *arm.Subscription) []arm.ZoneMapping {
+	if subscription.Properties == nil || subscription.Properties.AvailabilityZones == nil {
+		return nil
+	}
+	zones := subscription.Properties.AvailabilityZones
+	if !r.Contains(api.Deref(zones.Location)) || zones.ZoneMappings == nil {
+		return nil
+	}
+	return *zones.ZoneMappings
+}
+
+// PhysicalZone translates one of the subscription's logical availability
+// zones in the region, such as "1", to the physical zone it maps to.
+func (r *Region) PhysicalZone(subscription *arm.Subscription, logicalZone string) (string, bool) {
+	for _, mapping := range r.zoneMappings(subscription) {
+		if api.Deref(mapping.LogicalZone) == logicalZone {
+			return api.Deref(mapping.PhysicalZone), true
+		}
+	}
+	return "", false
+}
+
+// LogicalZone translates a physical availability zone in the region
+// to the subscription's logical zone that maps to it.
+func (r *Region) LogicalZone(subscription *arm.Subscription, physicalZone string) (string, bool) {
+	for _, mapping := range r.zoneMappings(subscription) {
+		if api.Deref(mapping.PhysicalZone) == physicalZone {
+			return api.Deref(mapping.LogicalZone), true
+		}
+	}
+	return "", false
+}
+
+// CheckNodePoolAvailabilityZone returns an error detail if a normalized
+// node pool requests a logical availability zone that the subscription
+// does not have in the region. Zones are not checked if the subscription
+// has no zone mappings for the region.
+func (r *Region) CheckNodePoolAvailabilityZone(subscriptionID string, subscription *arm.Subscription, nodePool *api.HCPOpenShiftClusterNodePool) []arm.CloudErrorBody {
+	zone := nodePool.Properties.Profile.AvailabilityZone
+	mappings := r.zoneMappings(subscription)
+	if zone == "" || len(mappings) == 0 {
+		return nil
+	}
+	if _, ok := r.PhysicalZone(subscription, zone); ok {
+		return nil
+	}
+
+	supported := make([]string, 0, len(mappings))
+	for _, mapping := range mappings {
+		supported = append(supported, api.Deref(mapping.LogicalZone))
+	}
+	return []arm.CloudErrorBody{arm.ErrorAvailabilityZoneNotSupported.Body("properties.spec.platform.availabilityZone",
+		zone, r.location, subscriptionID, strings.Join(supported, ", "))}
+}
+
+// MiddlewareValidateLocation rejects requests whose
+// location path segment names a different region.
+func (r *Region) MiddlewareValidateLocation(w http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+	if location := req.PathValue(PageSegmentLocation); location != "" && !r.Contains(location) {
+		arm.WriteCloudError(w, r.locationError("", location))
+		return
+	}
+	next(w, req)
+}
@@ -0,0 +1,186 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+func newTestZonedSubscription(location string) *arm.Subscription {
+	return &arm.Subscription{
+		State: arm.Registered,
+		Properties: &arm.Properties{
+			AvailabilityZones: &arm.AvailabilityZone{
+				Location: api.Ptr(location),
+				ZoneMappings: &[]arm.ZoneMapping{
+					{LogicalZone: api.Ptr("1"), PhysicalZone: api.Ptr("eastus-az3")},
+					{LogicalZone: api.Ptr("2"), PhysicalZone: api.Ptr("eastus-az1")},
+					{LogicalZone: api.Ptr("3"), PhysicalZone: api.Ptr("eastus-az2")},
+				},
+			},
+		},
+	}
+}
+
+func TestRegionCheckClusterLocation(t *testing.T) {
+	region := NewRegion("eastus")
+
+	for location, expectError := range map[string]bool{
+		"eastus":  false,
+		"East US": false,
+		"westus":  true,
+		"":        true,
+	} {
+		t.Run(location, func(t *testing.T) {
+			cluster := api.NewDefaultHCPOpenShiftCluster()
+			cluster.Location = location
+
+			errorDetails := region.CheckClusterLocation(cluster)
+
+			if (len(errorDetails) > 0) != expectError {
+				t.Fatalf("Expected error %v, got %v", expectError, errorDetails)
+			}
+			if expectError && errorDetails[0].Code != arm.CloudErrorCodeLocationNotAvailableForResourceType {
+				t.Errorf("Expected code %q, got %q", arm.CloudErrorCodeLocationNotAvailableForResourceType, errorDetails[0].Code)
+			}
+		})
+	}
+}
+
+func TestRegionZoneTranslation(t *testing.T) {
+	region := NewRegion("eastus")
+	subscription := newTestZonedSubscription("East US")
+
+	if physical, ok := region.PhysicalZone(subscription, "1"); !ok || physical != "eastus-az3" {
+		t.Errorf("Expected physical zone eastus-az3, got %q", physical)
+	}
+	if logical, ok := region.LogicalZone(subscription, "eastus-az1"); !ok || logical != "2" {
+		t.Errorf("Expected logical zone 2, got %q", logical)
+	}
+	if _, ok := region.PhysicalZone(subscription, "4"); ok {
+		t.Errorf("Expected no physical zone for logical zone 4")
+	}
+	if _, ok := region.PhysicalZone(newTestZonedSubscription("westus"), "1"); ok {
+		t.Errorf("Expected no zone mappings for another location")
+	}
+}
+
+func TestRegionCheckNodePoolAvailabilityZone(t *testing.T) {
+	const subscriptionID = "00000000-0000-0000-0000-000000000000"
+
+	tests := []struct {
+		name         string
+		subscription *arm.Subscription
+		zone         string
+		expectError  bool
+	}{
+		{
+			name:         "No zone requested",
+			subscription: newTestZonedSubscription("eastus"),
+		},
+		{
+			name:         "Mapped zone",
+			subscription: newTestZonedSubscription("eastus"),
+			zone:         "3",
+		},
+		{
+			name:         "Unmapped zone",
+			subscription: newTestZonedSubscription("eastus"),
+			zone:         "4",
+			expectError:  true,
+		},
+		{
+			name:         "Physical zone instead of logical zone",
+			subscription: newTestZonedSubscription("eastus"),
+			zone:         "eastus-az1",
+			expectError:  true,
+		},
+		{
+			name:         "Subscription without zone mappings",
+			subscription: &arm.Subscription{State: arm.Registered},
+			zone:         "4",
+		},
+	}
+
+	region := NewRegion("eastus")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodePool := &api.HCPOpenShiftClusterNodePool{}
+			nodePool.Properties.Profile.AvailabilityZone = tt.zone
+
+			errorDetails := region.CheckNodePoolAvailabilityZone(subscriptionID, tt.subscription, nodePool)
+
+			if (len(errorDetails) > 0) != tt.expectError {
+				t.Fatalf("Expected error %v, got %v", tt.expectError, errorDetails)
+			}
+			if tt.expectError && errorDetails[0].Target != "properties.spec.platform.availabilityZone" {
+				t.Errorf("Unexpected target %q", errorDetails[0].Target)
+			}
+		})
+	}
+}
+
+func TestArmDeploymentPreflightAvailabilityZone(t *testing.T) {
+	f := &Frontend{
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cache:  NewCache(),
+		region: NewRegion("eastus"),
+	}
+	f.cache.SetSubscription("00000000-0000-0000-0000-000000000000", newTestZonedSubscription("eastus"))
+
+	version, _ := api.Lookup("2024-06-10-preview")
+
+	nodePool := &api.HCPOpenShiftClusterNodePool{}
+	nodePool.Location = "eastus"
+	nodePool.Properties.Profile.VMSize = "Standard_D8s_v3"
+	nodePool.Properties.Profile.AvailabilityZone = "4"
+
+	response := runTestPreflight(t, f,
+		newTestPreflightResource(t, "cluster", api.ResourceType, version.NewHCPOpenShiftCluster(newTestValidCluster())),
+		newTestPreflightResource(t, "cluster/nodepool", api.NodePoolResourceType, version.NewHCPOpenShiftClusterNodePool(nodePool)))
+
+	if response.Status != arm.DeploymentPreflightStatusFailed || response.Error == nil {
+		t.Fatalf("Expected preflight to fail, got %+v", response)
+	}
+	if len(response.Error.Details) != 1 || response.Error.Details[0].Code != arm.CloudErrorCodeAvailabilityZoneNotSupported {
+		t.Errorf("Expected an availability zone error, got %+v", response.Error)
+	}
+}
+
+func TestMiddlewareValidateLocation(t *testing.T) {
+	region := NewRegion("eastus")
+
+	for location, expectStatus := range map[string]int{
+		"":       http.StatusOK,
+		"eastus": http.StatusOK,
+		"westus": http.StatusBadRequest,
+	} {
+		t.Run(location, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request.SetPathValue(PageSegmentLocation, location)
+			writer := httptest.NewRecorder()
+
+			region.MiddlewareValidateLocation(writer, request, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			if writer.Code != expectStatus {
+				t.Errorf("Expected status %d, got %d", expectStatus, writer.Code)
+			}
+			if expectStatus != http.StatusOK {
+				if code := writer.Header().Get(arm.HeaderNameErrorCode); code != arm.CloudErrorCodeLocationNotAvailableForResourceType {
+					t.Errorf("Expected error code %q, got %q", arm.CloudErrorCodeLocationNotAvailableForResourceType, code)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/blang/semver/v4"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+// CincinnatiGraph is an OpenShift update graph in the format served by
+// the Cincinnati update service. Edges index into Nodes. Conditional
+// edges carry known risks and are not offered as upgrade targets.
+//
+// See https://github.com/openshift/cincinnati/blob/master/docs/design/cincinnati.md
+type CincinnatiGraph struct {
+	Nodes []CincinnatiNode `json:"nodes"`
+	Edges [][2]int         `json:"edges"`
+}
+
+// CincinnatiNode is a release in a CincinnatiGraph.
+type CincinnatiNode struct {
+	Version  string            `json:"version"`
+	Payload  string            `json:"payload,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// releaseGraph is a parsed CincinnatiGraph for one channel group.
+type releaseGraph struct {
+	// upgrades maps each version to its sorted upgrade targets.
+	upgrades map[string][]string
+}
+
+// ReleaseCatalog holds the OpenShift versions available in each channel
+// group and the upgrade edges allowed between them. Channel groups are
+// matched case-sensitively, like Cincinnati channels.
+type ReleaseCatalog struct {
+	channelGroups map[string]*releaseGraph
+}
+
+// NewReleaseCatalog builds a ReleaseCatalog from an update graph for each
+// channel group. It returns an error if a graph contains an invalid version
+// or an edge that does not refer to a node.
+func NewReleaseCatalog(graphs map[string]CincinnatiGraph) (*ReleaseCatalog, error) {
+	catalog := &ReleaseCatalog{
+		channelGroups: make(map[string]*releaseGraph, len(graphs)),
+	}
+
+	for channelGroup, graph := range graphs {
+		g := &releaseGraph{
+			upgrades: make(map[string][]string, len(graph.Nodes)),
+		}
+		for _, node := range graph.Nodes {
+			if _, err := semver.Parse(node.Version); err != nil {
+				return nil, fmt.Errorf("channel group '%s': invalid version '%s': %w", channelGroup, node.Version, err)
+			}
+			g.upgrades[node.Version] = []string{}
+		}
+		for _, edge := range graph.Edges {
+			from, to := edge[0], edge[1]
+			if from < 0 || from >= len(graph.Nodes) || to < 0 || to >= len(graph.Nodes) {
+				return nil, fmt.Errorf("channel group '%s': edge %v refers to a missing node", channelGroup, edge)
+			}
+			fromVersion := graph.Nodes[from].Version
+			g.upgrades[fromVersion] = append(g.upgrades[fromVersion], graph.Nodes[to].Version)
+		}
+		for _, targets := range g.upgrades {
+			sortVersions(targets)
+		}
+		catalog.channelGroups[channelGroup] = g
+	}
+
+	return catalog, nil
+}
+
+// LoadReleaseCatalog reads a ReleaseCatalog from a JSON file holding
+// an object that maps each channel group name to a CincinnatiGraph.
+func LoadReleaseCatalog(path string) (*ReleaseCatalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var graphs map[string]CincinnatiGraph
+	if err = json.Unmarshal(data, &graphs); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return NewReleaseCatalog(graphs)
+}
+
+// sortVersions sorts versions in ascending semantic version order.
+// Versions are assumed to have already been validated.
+func sortVersions(versions []string) {
+	sort.Slice(versions, func(i, j int) bool {
+		return semver.MustParse(versions[i]).LT(semver.MustParse(versions[j]))
+	})
+}
+
+// ChannelGroups returns the sorted names of all channel groups.
+func (c *ReleaseCatalog) ChannelGroups() []string {
+	channelGroups := make([]string, 0, len(c.channelGroups))
+	for channelGroup := range c.channelGroups {
+		channelGroups = append(channelGroups, channelGroup)
+	}
+	sort.Strings(channelGroups)
+	return channelGroups
+}
+
+// HasVersion returns true if version is available in channelGroup.
+func (c *ReleaseCatalog) HasVersion(channelGroup, version string) bool {
+	g, ok := c.channelGroups[channelGroup]
+	if !ok {
+		return false
+	}
+	_, ok = g.upgrades[version]
+	return ok
+}
+
+// AvailableUpgrades returns the versions that version can be upgraded
+// to in channelGroup, in ascending order. It returns nil if the version
+// is not in the channel group.
+func (c *ReleaseCatalog) AvailableUpgrades(channelGroup, version string) []string {
+	g, ok := c.channelGroups[channelGroup]
+	if !ok {
+		return nil
+	}
+	targets, ok := g.upgrades[version]
+	if !ok {
+		return nil
+	}
+	return append([]string{}, targets...)
+}
+
+// ValidateVersion checks a requested version profile against the catalog.
+// For cluster creation current is nil and the requested version must be in
+// the requested channel group. For updates, a changed version must be one
+// of the available upgrades from the current version.
+func (c *ReleaseCatalog) ValidateVersion(current, requested *api.VersionProfile) []arm.CloudErrorBody {
+	const target = "properties.spec.version"
+
+	if current == nil {
+		if _, ok := c.channelGroups[requested.ChannelGroup]; !ok {
+			return []arm.CloudErrorBody{{
+				Code:    arm.CloudErrorCodeInvalidRequestContent,
+				Message: fmt.Sprintf("Channel group '%s' is not supported. Supported channel groups are: %s", requested.ChannelGroup, strings.Join(c.ChannelGroups(), ", ")),
+				Target:  target + ".channelGroup",
+			}}
+		}
+		if !c.HasVersion(requested.ChannelGroup, requested.ID) {
+			return []arm.CloudErrorBody{{
+				Code:    arm.CloudErrorCodeInvalidRequestContent,
+				Message: fmt.Sprintf("Version '%s' is not available in channel group '%s'", requested.ID, requested.ChannelGroup),
+				Target:  target + ".id",
+			}}
+		}
+		return nil
+	}
+
+	if requested.ID == current.ID {
+		return nil
+	}
+
+	upgrades := c.AvailableUpgrades(current.ChannelGroup, current.ID)
+	for _, upgrade := range upgrades {
+		if upgrade == requested.ID {
+			return nil
+		}
+	}
+
+	message := fmt.Sprintf("Cannot upgrade from version '%s' to '%s' in channel group '%s'", current.ID, requested.ID, current.ChannelGroup)
+	if len(upgrades) > 0 {
+		message += fmt.Sprintf(". Available upgrades are: %s", strings.Join(upgrades, ", "))
+	} else {
+		message += ". No upgrades are available"
+	}
+	return []arm.CloudErrorBody{{
+		Code:    arm.CloudErrorCodeInvalidRequestContent,
+		Message: message,
+		Target:  target + ".id",
+	}}
+}
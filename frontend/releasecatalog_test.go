@@ -0,0 +1,247 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+)
+
+// testReleaseGraphs is a small update graph:
+//
+//	stable:    4.16.0 -> 4.16.2, 4.16.0 -> 4.16.10, 4.16.2 -> 4.16.10
+//	candidate: 4.17.0-rc.1 -> 4.17.0-rc.2
+var testReleaseGraphs = map[string]CincinnatiGraph{
+	"stable": {
+		Nodes: []CincinnatiNode{
+			{Version: "4.16.10"},
+			{Version: "4.16.0"},
+			{Version: "4.16.2"},
+		},
+		Edges: [][2]int{{1, 0}, {1, 2}, {2, 0}},
+	},
+	"candidate": {
+		Nodes: []CincinnatiNode{
+			{Version: "4.17.0-rc.1"},
+			{Version: "4.17.0-rc.2"},
+		},
+		Edges: [][2]int{{0, 1}},
+	},
+}
+
+func newTestReleaseCatalog(t *testing.T) *ReleaseCatalog {
+	catalog, err := NewReleaseCatalog(testReleaseGraphs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return catalog
+}
+
+func TestNewReleaseCatalog(t *testing.T) {
+	tests := []struct {
+		name        string
+		graphs      map[string]CincinnatiGraph
+		expectError bool
+	}{
+		{
+			name:   "Valid graphs",
+			graphs: testReleaseGraphs,
+		},
+		{
+			name: "Invalid version",
+			graphs: map[string]CincinnatiGraph{
+				"stable": {Nodes: []CincinnatiNode{{Version: "4.16"}}},
+			},
+			expectError: true,
+		},
+		{
+			name: "Edge to missing node",
+			graphs: map[string]CincinnatiGraph{
+				"stable": {
+					Nodes: []CincinnatiNode{{Version: "4.16.0"}},
+					Edges: [][2]int{{0, 1}},
+				},
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewReleaseCatalog(tt.graphs)
+			if (err != nil) != tt.expectError {
+				t.Errorf("Expected error %v, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
+
+func TestLoadReleaseCatalog(t *testing.T) {
+	data, err := json.Marshal(testReleaseGraphs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "releases.json")
+	if err = os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	catalog, err := LoadReleaseCatalog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(catalog.ChannelGroups(), []string{"candidate", "stable"}) {
+		t.Errorf("Unexpected channel groups: %v", catalog.ChannelGroups())
+	}
+}
+
+func TestReleaseCatalogAvailableUpgrades(t *testing.T) {
+	catalog := newTestReleaseCatalog(t)
+
+	tests := []struct {
+		channelGroup string
+		version      string
+		expected     []string
+	}{
+		{"stable", "4.16.0", []string{"4.16.2", "4.16.10"}},
+		{"stable", "4.16.10", []string{}},
+		{"stable", "4.17.0-rc.1", nil},
+		{"candidate", "4.17.0-rc.1", []string{"4.17.0-rc.2"}},
+		{"fast", "4.16.0", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.channelGroup+"/"+tt.version, func(t *testing.T) {
+			actual := catalog.AvailableUpgrades(tt.channelGroup, tt.version)
+			if !slices.Equal(actual, tt.expected) || (actual == nil) != (tt.expected == nil) {
+				t.Errorf("Expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestReleaseCatalogValidateVersion(t *testing.T) {
+	catalog := newTestReleaseCatalog(t)
+
+	tests := []struct {
+		name          string
+		current       *api.VersionProfile
+		requested     api.VersionProfile
+		expectTarget  string
+		expectMessage string
+	}{
+		{
+			name:      "Create with available version",
+			requested: api.VersionProfile{ID: "4.16.2", ChannelGroup: "stable"},
+		},
+		{
+			name:          "Create with unknown channel group",
+			requested:     api.VersionProfile{ID: "4.16.2", ChannelGroup: "fast"},
+			expectTarget:  "properties.spec.version.channelGroup",
+			expectMessage: "Channel group 'fast' is not supported. Supported channel groups are: candidate, stable",
+		},
+		{
+			name:          "Create with version from another channel group",
+			requested:     api.VersionProfile{ID: "4.17.0-rc.1", ChannelGroup: "stable"},
+			expectTarget:  "properties.spec.version.id",
+			expectMessage: "Version '4.17.0-rc.1' is not available in channel group 'stable'",
+		},
+		{
+			name:      "Update without version change",
+			current:   &api.VersionProfile{ID: "4.15.0", ChannelGroup: "stable"},
+			requested: api.VersionProfile{ID: "4.15.0", ChannelGroup: "stable"},
+		},
+		{
+			name:      "Update along an upgrade edge",
+			current:   &api.VersionProfile{ID: "4.16.0", ChannelGroup: "stable"},
+			requested: api.VersionProfile{ID: "4.16.10", ChannelGroup: "stable"},
+		},
+		{
+			name:          "Update against an upgrade edge",
+			current:       &api.VersionProfile{ID: "4.16.10", ChannelGroup: "stable"},
+			requested:     api.VersionProfile{ID: "4.16.2", ChannelGroup: "stable"},
+			expectTarget:  "properties.spec.version.id",
+			expectMessage: "Cannot upgrade from version '4.16.10' to '4.16.2' in channel group 'stable'. No upgrades are available",
+		},
+		{
+			name:          "Update to a version not in the graph",
+			current:       &api.VersionProfile{ID: "4.16.0", ChannelGroup: "stable"},
+			requested:     api.VersionProfile{ID: "4.16.5", ChannelGroup: "stable"},
+			expectTarget:  "properties.spec.version.id",
+			expectMessage: "Cannot upgrade from version '4.16.0' to '4.16.5' in channel group 'stable'. Available upgrades are: 4.16.2, 4.16.10",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errorDetails := catalog.ValidateVersion(tt.current, &tt.requested)
+
+			if tt.expectTarget == "" {
+				if len(errorDetails) > 0 {
+					t.Errorf("Expected no errors, got %v", errorDetails)
+				}
+				return
+			}
+			if len(errorDetails) != 1 {
+				t.Fatalf("Expected 1 error, got %v", errorDetails)
+			}
+			if errorDetails[0].Target != tt.expectTarget {
+				t.Errorf("Expected target %q, got %q", tt.expectTarget, errorDetails[0].Target)
+			}
+			if errorDetails[0].Message != tt.expectMessage {
+				t.Errorf("Expected message %q, got %q", tt.expectMessage, errorDetails[0].Message)
+			}
+		})
+	}
+}
+
+func TestArmResourceReadAvailableUpgrades(t *testing.T) {
+	resourceID := "/subscriptions/00000000-0000-0000-0000-000000000000/resourcegroups/rg/providers/microsoft.redhatopenshift/hcpopenshiftclusters/cluster"
+
+	cluster := api.NewDefaultHCPOpenShiftCluster()
+	cluster.Resource.ID = resourceID
+	cluster.Properties.Spec.Version.ID = "4.16.0"
+	cluster.Properties.Spec.Version.ChannelGroup = "stable"
+
+	f := &Frontend{
+		logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cache:    NewCache(),
+		releases: newTestReleaseCatalog(t),
+	}
+	f.cache.SetCluster(resourceID, cluster)
+
+	version, _ := api.Lookup("2024-06-10-preview")
+	request := httptest.NewRequest(http.MethodGet, resourceID, nil)
+	request = request.WithContext(ContextWithVersion(request.Context(), version))
+	writer := httptest.NewRecorder()
+
+	f.ArmResourceRead(writer, request)
+
+	var body struct {
+		Properties struct {
+			Spec struct {
+				Version api.VersionProfile `json:"version"`
+			} `json:"spec"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(writer.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"4.16.2", "4.16.10"}
+	if !slices.Equal(body.Properties.Spec.Version.AvailableUpgrades, expected) {
+		t.Errorf("Expected available upgrades %v, got %v", expected, body.Properties.Spec.Version.AvailableUpgrades)
+	}
+	if cluster.Properties.Spec.Version.AvailableUpgrades != nil {
+		t.Errorf("Cached cluster was modified")
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+// referenceCheck validates one Azure resource referenced by a request.
+// It returns validation problems as error details, and reserves the
+// error return for failures to perform the check at all.
+type referenceCheck func(ctx context.Context) ([]arm.CloudErrorBody, error)
+
+// ValidateClusterResourceReferences resolves the Azure resources referenced
+// by a normalized cluster and checks that they exist, are in the cluster's
+// location and are configured suitably for use by the cluster. Referenced
+// IDs that are empty or malformed are skipped, since static validation will
+// already have reported them.
+func ValidateClusterResourceReferences(ctx context.Context, client AzureResourceClient, cluster *api.HCPOpenShiftCluster) ([]arm.CloudErrorBody, error) {
+	const target = "properties.spec.platform"
+	platform := &cluster.Properties.Spec.Platform
+
+	return runReferenceChecks(ctx,
+		checkSubnet(client, target+".subnetId", platform.SubnetID, cluster.Location, platform.NetworkSecurityGroupID),
+		checkLocatedResource(client.GetNetworkSecurityGroup, "network security group", target+".networkSecurityGroupId", platform.NetworkSecurityGroupID, cluster.Location),
+		checkLocatedResource(client.GetDiskEncryptionSet, "disk encryption set", target+".etcdEncryptionSetId", platform.EtcdEncryptionSetID, cluster.Location),
+	)
+}
+
+// ValidateNodePoolResourceReferences is like ValidateClusterResourceReferences
+// but for a normalized node pool belonging to the given cluster.
+func ValidateNodePoolResourceReferences(ctx context.Context, client AzureResourceClient, nodePool *api.HCPOpenShiftClusterNodePool, cluster *api.HCPOpenShiftCluster) ([]arm.CloudErrorBody, error) {
+	const target = "properties.spec.platform"
+	profile := &nodePool.Properties.Profile
+
+	return runReferenceChecks(ctx,
+		checkSubnet(client, target+".subnetId", profile.SubnetID, cluster.Location, cluster.Properties.Spec.Platform.NetworkSecurityGroupID),
+		checkLocatedResource(client.GetDiskEncryptionSet, "disk encryption set", target+".discEncryptionSetId", profile.DiscEncryptionSetID, cluster.Location),
+	)
+}
+
+// runReferenceChecks runs checks concurrently and collects their
+// results in the order the checks were given.
+func runReferenceChecks(ctx context.Context, checks ...referenceCheck) ([]arm.CloudErrorBody, error) {
+	var wg sync.WaitGroup
+
+	results := make([][]arm.CloudErrorBody, len(checks))
+	errs := make([]error, len(checks))
+
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = check(ctx)
+		}()
+	}
+	wg.Wait()
+
+	var errorDetails []arm.CloudErrorBody
+	for _, result := range results {
+		errorDetails = append(errorDetails, result...)
+	}
+	return errorDetails, errors.Join(errs...)
+}
+
+// parseReference parses a referenced resource ID, returning
+// nil if the ID is empty or malformed.
+func parseReference(id string) *arm.ResourceID {
+	if id == "" {
+		return nil
+	}
+	resourceID, err := arm.ParseResourceID(id)
+	if err != nil {
+		return nil
+	}
+	return resourceID
+}
+
+// lookupError converts an AzureResourceClient error to error details
+// for conditions the client can fix, or returns the error otherwise.
+func lookupError(description, target, id string, err error) ([]arm.CloudErrorBody, error) {
+	switch {
+	case errors.Is(err, ErrAzureResourceNotFound):
+		return []arm.CloudErrorBody{arm.ErrorLinkedResourceNotFound.Body(target, description, id)}, nil
+	case errors.Is(err, ErrAzureResourceAccessDenied):
+		return []arm.CloudErrorBody{arm.ErrorLinkedAuthorizationFailed.Body(target, description, id)}, nil
+	default:
+		return nil, err
+	}
+}
+
+// normalizeLocation converts a location display name like "East US"
+// to its canonical name like "eastus" so locations can be compared.
+func normalizeLocation(location string) string {
+	return strings.ToLower(strings.ReplaceAll(location, " ", ""))
+}
+
+// checkLocation returns an error detail if a referenced
+// resource is not in the same location as the cluster.
+func checkLocation(description, target, id, location, clusterLocation string) []arm.CloudErrorBody {
+	if normalizeLocation(location) == normalizeLocation(clusterLocation) {
+		return nil
+	}
+	return []arm.CloudErrorBody{arm.ErrorLinkedResourceLocationMismatch.Body(target, description, id, location, clusterLocation)}
+}
+
+func checkLocatedResource(get func(context.Context, *arm.ResourceID) (*AzureResource, error), description, target, id, clusterLocation string) referenceCheck {
+	return func(ctx context.Context) ([]arm.CloudErrorBody, error) {
+		resourceID := parseReference(id)
+		if resourceID == nil {
+			return nil, nil
+		}
+		resource, err := get(ctx, resourceID)
+		if err != nil {
+			return lookupError(description, target, id, err)
+		}
+		return checkLocation(description, target, id, resource.Location, clusterLocation), nil
+	}
+}
+
+func checkSubnet(client AzureResourceClient, target, id, clusterLocation, nsgID string) referenceCheck {
+	const description = "subnet"
+
+	return func(ctx context.Context) ([]arm.CloudErrorBody, error) {
+		resourceID := parseReference(id)
+		if resourceID == nil {
+			return nil, nil
+		}
+		subnet, err := client.GetSubnet(ctx, resourceID)
+		if err != nil {
+			return lookupError(description, target, id, err)
+		}
+
+		errorDetails := checkLocation(description, target, id, subnet.Location, clusterLocation)
+
+		// Cluster nodes are attached to the subnet directly,
+		// which Azure disallows for delegated subnets.
+		if len(subnet.Delegations) > 0 {
+			errorDetails = append(errorDetails, arm.ErrorSubnetDelegated.Body(target, id, strings.Join(subnet.Delegations, "', '")))
+		}
+
+		// A subnet with no network security group is fine; one
+		// will be associated with it during cluster provisioning.
+		if nsg := parseReference(nsgID); nsg != nil && subnet.NetworkSecurityGroupID != "" {
+			associated, err := arm.ParseResourceID(subnet.NetworkSecurityGroupID)
+			if err != nil || !associated.Equal(nsg) {
+				errorDetails = append(errorDetails, arm.ErrorSubnetNetworkSecurityGroupMismatch.Body(target, id, subnet.NetworkSecurityGroupID, nsgID))
+			}
+		}
+
+		return errorDetails, nil
+	}
+}
@@ -0,0 +1,366 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+const (
+	testResourceGroupID     = "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg"
+	testSubnetID            = testResourceGroupID + "/providers/Microsoft.Network/virtualNetworks/vnet/subnets/subnet"
+	testNSGID               = testResourceGroupID + "/providers/Microsoft.Network/networkSecurityGroups/nsg"
+	testOtherNSGID          = testResourceGroupID + "/providers/Microsoft.Network/networkSecurityGroups/other"
+	testDiskEncryptionSetID = testResourceGroupID + "/providers/Microsoft.Compute/diskEncryptionSets/des"
+)
+
+// errFakeLookup stands in for a transient Azure error.
+var errFakeLookup = errors.New("fake lookup failure")
+
+type failingAzureResourceClient struct {
+	*FakeAzureResourceClient
+}
+
+func (c failingAzureResourceClient) GetNetworkSecurityGroup(ctx context.Context, id *arm.ResourceID) (*AzureResource, error) {
+	return nil, errFakeLookup
+}
+
+func newTestAzureResourceClient() *FakeAzureResourceClient {
+	client := NewFakeAzureResourceClient()
+	client.AddSubnet(AzureSubnet{
+		AzureResource: AzureResource{ID: testSubnetID, Location: "eastus"},
+	})
+	client.AddNetworkSecurityGroup(AzureResource{ID: testNSGID, Location: "eastus"})
+	client.AddDiskEncryptionSet(AzureResource{ID: testDiskEncryptionSetID, Location: "eastus"})
+	return client
+}
+
+func newTestReferencingCluster() *api.HCPOpenShiftCluster {
+	cluster := api.NewDefaultHCPOpenShiftCluster()
+	cluster.Location = "East US"
+	cluster.Properties.Spec.Platform.SubnetID = testSubnetID
+	cluster.Properties.Spec.Platform.NetworkSecurityGroupID = testNSGID
+	cluster.Properties.Spec.Platform.EtcdEncryptionSetID = testDiskEncryptionSetID
+	return cluster
+}
+
+// newTestValidCluster returns a cluster that passes static validation
+// and, with newTestAzureResourceClient, dynamic validation.
+func newTestValidCluster() *api.HCPOpenShiftCluster {
+	cluster := newTestReferencingCluster()
+	cluster.Properties.Spec.Version.ID = "4.16.0"
+	cluster.Properties.Spec.Version.ChannelGroup = "stable"
+	cluster.Properties.Spec.Network.PodCIDR = "10.128.0.0/14"
+	cluster.Properties.Spec.Network.ServiceCIDR = "172.30.0.0/16"
+	cluster.Properties.Spec.Network.MachineCIDR = "10.0.0.0/16"
+	cluster.Properties.Spec.Network.HostPrefix = 23
+	cluster.Properties.Spec.DNS.BaseDomainPrefix = "example"
+	cluster.Properties.Spec.API.Visibility = api.VisibilityPublic
+	cluster.Properties.Spec.Platform.ManagedResourceGroup = "managed"
+	return cluster
+}
+
+func TestValidateClusterResourceReferences(t *testing.T) {
+	const target = "properties.spec.platform"
+
+	tests := []struct {
+		name           string
+		modifyClient   func(*FakeAzureResourceClient)
+		modifyCluster  func(*api.HCPOpenShiftCluster)
+		expectCodes    []string
+		expectTargets  []string
+		expectMessages []string
+	}{
+		{
+			name: "Valid references",
+		},
+		{
+			name: "Empty and malformed references are skipped",
+			modifyCluster: func(c *api.HCPOpenShiftCluster) {
+				c.Properties.Spec.Platform.SubnetID = "bogus"
+				c.Properties.Spec.Platform.EtcdEncryptionSetID = ""
+			},
+		},
+		{
+			name: "Subnet not found",
+			modifyCluster: func(c *api.HCPOpenShiftCluster) {
+				c.Properties.Spec.Platform.SubnetID = strings.Replace(testSubnetID, "subnets/subnet", "subnets/missing", 1)
+			},
+			expectCodes:   []string{arm.CloudErrorCodeInvalidLinkedResource},
+			expectTargets: []string{target + ".subnetId"},
+		},
+		{
+			name: "Network security group access denied",
+			modifyClient: func(c *FakeAzureResourceClient) {
+				c.Deny(testNSGID)
+			},
+			expectCodes:   []string{arm.CloudErrorCodeLinkedAuthorizationFailed},
+			expectTargets: []string{target + ".networkSecurityGroupId"},
+		},
+		{
+			name: "Disk encryption set in another location",
+			modifyClient: func(c *FakeAzureResourceClient) {
+				c.AddDiskEncryptionSet(AzureResource{ID: testDiskEncryptionSetID, Location: "westus"})
+			},
+			expectCodes:   []string{arm.CloudErrorCodeInvalidLinkedResource},
+			expectTargets: []string{target + ".etcdEncryptionSetId"},
+			expectMessages: []string{
+				"The disk encryption set '" + testDiskEncryptionSetID + "' is in location 'westus' but the cluster is in location 'East US'.",
+			},
+		},
+		{
+			name: "Delegated subnet",
+			modifyClient: func(c *FakeAzureResourceClient) {
+				c.AddSubnet(AzureSubnet{
+					AzureResource: AzureResource{ID: testSubnetID, Location: "eastus"},
+					Delegations:   []string{"Microsoft.Web/serverFarms"},
+				})
+			},
+			expectCodes:   []string{arm.CloudErrorCodeInvalidLinkedResource},
+			expectTargets: []string{target + ".subnetId"},
+			expectMessages: []string{
+				"The subnet '" + testSubnetID + "' must not be delegated, but is delegated to 'Microsoft.Web/serverFarms'.",
+			},
+		},
+		{
+			name: "Subnet associated with the same network security group",
+			modifyClient: func(c *FakeAzureResourceClient) {
+				c.AddSubnet(AzureSubnet{
+					AzureResource:          AzureResource{ID: testSubnetID, Location: "eastus"},
+					NetworkSecurityGroupID: strings.ToLower(testNSGID),
+				})
+			},
+		},
+		{
+			name: "Subnet associated with another network security group",
+			modifyClient: func(c *FakeAzureResourceClient) {
+				c.AddSubnet(AzureSubnet{
+					AzureResource:          AzureResource{ID: testSubnetID, Location: "westus"},
+					NetworkSecurityGroupID: testOtherNSGID,
+				})
+			},
+			expectCodes: []string{
+				arm.CloudErrorCodeInvalidLinkedResource,
+				arm.CloudErrorCodeInvalidLinkedResource,
+			},
+			expectTargets: []string{
+				target + ".subnetId",
+				target + ".subnetId",
+			},
+			expectMessages: []string{
+				"The subnet '" + testSubnetID + "' is in location 'westus' but the cluster is in location 'East US'.",
+				"The subnet '" + testSubnetID + "' is associated with network security group '" + testOtherNSGID + "' instead of '" + testNSGID + "'.",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestAzureResourceClient()
+			if tt.modifyClient != nil {
+				tt.modifyClient(client)
+			}
+			cluster := newTestReferencingCluster()
+			if tt.modifyCluster != nil {
+				tt.modifyCluster(cluster)
+			}
+
+			errorDetails, err := ValidateClusterResourceReferences(context.Background(), client, cluster)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(errorDetails) != len(tt.expectTargets) {
+				t.Fatalf("Expected %d errors, got %d: %v", len(tt.expectTargets), len(errorDetails), errorDetails)
+			}
+			for i, detail := range errorDetails {
+				if detail.Code != tt.expectCodes[i] {
+					t.Errorf("Expected code %q, got %q", tt.expectCodes[i], detail.Code)
+				}
+				if detail.Target != tt.expectTargets[i] {
+					t.Errorf("Expected target %q, got %q", tt.expectTargets[i], detail.Target)
+				}
+				if i < len(tt.expectMessages) && detail.Message != tt.expectMessages[i] {
+					t.Errorf("Expected message %q, got %q", tt.expectMessages[i], detail.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateClusterResourceReferencesLookupFailure(t *testing.T) {
+	client := failingAzureResourceClient{newTestAzureResourceClient()}
+
+	_, err := ValidateClusterResourceReferences(context.Background(), client, newTestReferencingCluster())
+	if !errors.Is(err, errFakeLookup) {
+		t.Errorf("Expected lookup failure, got %v", err)
+	}
+}
+
+func TestValidateNodePoolResourceReferences(t *testing.T) {
+	client := newTestAzureResourceClient()
+	cluster := newTestReferencingCluster()
+
+	nodePool := &api.HCPOpenShiftClusterNodePool{}
+	nodePool.Properties.Profile.SubnetID = testSubnetID
+	nodePool.Properties.Profile.DiscEncryptionSetID = strings.Replace(testDiskEncryptionSetID, "/des", "/missing", 1)
+
+	errorDetails, err := ValidateNodePoolResourceReferences(context.Background(), client, nodePool, cluster)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errorDetails) != 1 || errorDetails[0].Code != arm.CloudErrorCodeInvalidLinkedResource || errorDetails[0].Target != "properties.spec.platform.discEncryptionSetId" {
+		t.Errorf("Expected one disk encryption set error, got %v", errorDetails)
+	}
+}
+
+func TestValidateClusterDynamicWithoutAzureResources(t *testing.T) {
+	f := &Frontend{
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cache:  NewCache(),
+	}
+
+	// Without an Azure resource client, references are not looked up.
+	errorDetails, err := f.validateClusterDynamic(context.Background(), f.cache, "00000000-0000-0000-0000-000000000000", "", nil, newTestReferencingCluster())
+	if err != nil || len(errorDetails) != 0 {
+		t.Errorf("Expected no validation, got %v, %v", errorDetails, err)
+	}
+}
+
+func TestArmDeploymentPreflightResourceReferences(t *testing.T) {
+	const resourceGroupPath = "/subscriptions/00000000-0000-0000-0000-000000000000/resourcegroups/rg"
+
+	client := newTestAzureResourceClient()
+	client.Deny(testNSGID)
+
+	f := &Frontend{
+		logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cache:          NewCache(),
+		azureResources: client,
+	}
+
+	cluster := newTestValidCluster()
+
+	version, _ := api.Lookup("2024-06-10-preview")
+	resource, err := json.Marshal(version.NewHCPOpenShiftCluster(cluster))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var preflightResource map[string]any
+	if err := json.Unmarshal(resource, &preflightResource); err != nil {
+		t.Fatal(err)
+	}
+	preflightResource["name"] = "cluster"
+	preflightResource["type"] = api.ResourceType
+	preflightResource["apiVersion"] = "2024-06-10-preview"
+	body, err := json.Marshal(map[string]any{"resources": []any{preflightResource}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := httptest.NewRequest(http.MethodPost, resourceGroupPath+"/providers/microsoft.resources/deployments/deployment/preflight", nil)
+	request.SetPathValue(PathSegmentSubscriptionID, "00000000-0000-0000-0000-000000000000")
+	request.SetPathValue(PathSegmentResourceGroupName, "rg")
+	request = request.WithContext(ContextWithBody(request.Context(), body))
+	writer := httptest.NewRecorder()
+
+	f.ArmDeploymentPreflight(writer, request)
+
+	var response arm.DeploymentPreflightResponse
+	if err := json.Unmarshal(writer.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Status != arm.DeploymentPreflightStatusFailed || response.Error == nil {
+		t.Fatalf("Expected preflight to fail, got %s", writer.Body.String())
+	}
+	if len(response.Error.Details) != 1 || response.Error.Details[0].Code != arm.CloudErrorCodeLinkedAuthorizationFailed {
+		t.Errorf("Expected a linked authorization error, got %s", writer.Body.String())
+	}
+}
+
+// newTestPreflightResource returns a versioned resource as it
+// appears in the body of a deployment preflight request.
+func newTestPreflightResource(t *testing.T, name, resourceType string, versioned any) map[string]any {
+	data, err := json.Marshal(versioned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resource map[string]any
+	if err = json.Unmarshal(data, &resource); err != nil {
+		t.Fatal(err)
+	}
+	resource["name"] = name
+	resource["type"] = resourceType
+	resource["apiVersion"] = "2024-06-10-preview"
+	return resource
+}
+
+// runTestPreflight sends a deployment preflight request for the
+// resources to resource group "rg" and returns the response.
+func runTestPreflight(t *testing.T, f *Frontend, resources ...any) *arm.DeploymentPreflightResponse {
+	body, err := json.Marshal(map[string]any{"resources": resources})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := httptest.NewRequest(http.MethodPost, "/subscriptions/00000000-0000-0000-0000-000000000000/resourcegroups/rg/providers/microsoft.resources/deployments/deployment/preflight", nil)
+	request.SetPathValue(PathSegmentSubscriptionID, "00000000-0000-0000-0000-000000000000")
+	request.SetPathValue(PathSegmentResourceGroupName, "rg")
+	request = request.WithContext(ContextWithBody(request.Context(), body))
+	writer := httptest.NewRecorder()
+
+	f.ArmDeploymentPreflight(writer, request)
+
+	response := &arm.DeploymentPreflightResponse{}
+	if err := json.Unmarshal(writer.Body.Bytes(), response); err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+func TestArmDeploymentPreflightNodePoolResourceReferences(t *testing.T) {
+	f := &Frontend{
+		logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cache:          NewCache(),
+		azureResources: newTestAzureResourceClient(),
+	}
+
+	version, _ := api.Lookup("2024-06-10-preview")
+
+	// The node pool's cluster is created by the same deployment.
+	nodePool := &api.HCPOpenShiftClusterNodePool{}
+	nodePool.Location = "eastus"
+	nodePool.Properties.Profile.SubnetID = testSubnetID
+	nodePool.Properties.Profile.VMSize = "Standard_D8s_v3"
+	nodePool.Properties.Profile.DiscEncryptionSetID = strings.Replace(testDiskEncryptionSetID, "/des", "/missing", 1)
+
+	response := runTestPreflight(t, f,
+		newTestPreflightResource(t, "cluster", api.ResourceType, version.NewHCPOpenShiftCluster(newTestValidCluster())),
+		newTestPreflightResource(t, "cluster/nodepool", api.NodePoolResourceType, version.NewHCPOpenShiftClusterNodePool(nodePool)))
+
+	if response.Status != arm.DeploymentPreflightStatusFailed || response.Error == nil {
+		t.Fatalf("Expected preflight to fail, got %+v", response)
+	}
+	if !strings.HasSuffix(response.Error.Target, "/nodePools/nodepool") ||
+		len(response.Error.Details) != 1 || response.Error.Details[0].Code != arm.CloudErrorCodeInvalidLinkedResource {
+		t.Errorf("Expected a linked resource error for the node pool, got %+v", response.Error)
+	}
+
+	// Preflight does not change the cache.
+	if len(f.cache.Clusters()) != 0 || len(f.cache.NodePools()) != 0 {
+		t.Error("Expected preflight to leave the cache unchanged")
+	}
+}
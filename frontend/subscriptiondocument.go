@@ -0,0 +1,23 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+// SubscriptionDocument represents the state ARM last reported for a
+// subscription. ID and PartitionKey both hold the subscription ID.
+type SubscriptionDocument struct {
+	ID           string            `json:"id,omitempty"`
+	PartitionKey string            `json:"partitionKey,omitempty"`
+	Subscription *arm.Subscription `json:"subscription,omitempty"`
+
+	// Values provided by Cosmos after doc creation
+	ResourceID  string `json:"_rid,omitempty"`
+	Self        string `json:"_self,omitempty"`
+	ETag        string `json:"_etag,omitempty"`
+	Attachments string `json:"_attachments,omitempty"`
+	Timestamp   int    `json:"_ts,omitempty"`
+}
@@ -0,0 +1,227 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+const (
+	// featureStateRegistered is the AFEC state of a registered feature.
+	featureStateRegistered = "Registered"
+)
+
+// SubscriptionPolicyConfig configures which capabilities require a
+// subscription to have registered a preview feature. Feature names are
+// AFEC feature names of the form "{ResourceProviderNamespace}/{Feature}".
+// An empty feature name leaves the capability available to everyone.
+type SubscriptionPolicyConfig struct {
+	FIPS         string `json:"fips,omitempty"`
+	PrivateAPI   string `json:"privateApi,omitempty"`
+	ExternalAuth string `json:"externalAuth,omitempty"`
+
+	// ChannelGroups and APIVersions map a channel group or API version
+	// to the feature required to use it. Unlisted ones are ungated.
+	ChannelGroups map[string]string `json:"channelGroups,omitempty"`
+	APIVersions   map[string]string `json:"apiVersions,omitempty"`
+
+	// IneligibleQuotaIDs lists subscription offer quota IDs, such as
+	// "FreeTrial_2014-09-01", that are not allowed to create clusters.
+	IneligibleQuotaIDs []string `json:"ineligibleQuotaIds,omitempty"`
+}
+
+// SubscriptionPolicy evaluates a SubscriptionPolicyConfig against the
+// properties ARM sends for each subscription. The configuration can be
+// reloaded from a file while the frontend is running.
+type SubscriptionPolicy struct {
+	config atomic.Pointer[SubscriptionPolicyConfig]
+
+	path    string
+	lock    sync.Mutex
+	modTime time.Time
+}
+
+// NewSubscriptionPolicy returns a SubscriptionPolicy with a fixed configuration.
+func NewSubscriptionPolicy(config *SubscriptionPolicyConfig) *SubscriptionPolicy {
+	p := &SubscriptionPolicy{}
+	p.config.Store(config)
+	return p
+}
+
+// LoadSubscriptionPolicy returns a SubscriptionPolicy whose configuration
+// is read from a JSON file. Call Reload or Watch to pick up changes.
+func LoadSubscriptionPolicy(path string) (*SubscriptionPolicy, error) {
+	p := &SubscriptionPolicy{path: path}
+	if _, err := p.Reload(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Reload re-reads the configuration file if it has been modified since it
+// was last read, and returns true if the configuration changed. On error
+// the previous configuration remains in effect.
+func (p *SubscriptionPolicy) Reload() (bool, error) {
+	if p.path == "" {
+		return false, nil
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return false, err
+	}
+	if info.ModTime().Equal(p.modTime) {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return false, err
+	}
+	config := &SubscriptionPolicyConfig{}
+	if err = json.Unmarshal(data, config); err != nil {
+		return false, fmt.Errorf("%s: %w", p.path, err)
+	}
+
+	p.config.Store(config)
+	p.modTime = info.ModTime()
+	return true, nil
+}
+
+// Watch calls Reload at the given interval until stop is closed,
+// logging the outcome of each reload attempt that did something.
+func (p *SubscriptionPolicy) Watch(logger *slog.Logger, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			changed, err := p.Reload()
+			if err != nil {
+				logger.Error(fmt.Sprintf("Failed to reload subscription policy: %v", err))
+			} else if changed {
+				logger.Info(fmt.Sprintf("Reloaded subscription policy from %s", p.path))
+			}
+		}
+	}
+}
+
+// isFeatureRegistered returns true if the subscription has registered
+// the named feature. Feature names are compared case-insensitively.
+func isFeatureRegistered(subscription *arm.Subscription, feature string) bool {
+	if subscription.Properties == nil || subscription.Properties.RegisteredFeatures == nil {
+		return false
+	}
+	for _, f := range *subscription.Properties.RegisteredFeatures {
+		if strings.EqualFold(api.Deref(f.Name), feature) && strings.EqualFold(api.Deref(f.State), featureStateRegistered) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupFold returns the value for key in m, matching keys case-insensitively.
+func lookupFold(m map[string]string, key string) string {
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
+
+// CheckAPIVersion returns an error if the subscription may not
+// use the given API version, or nil if it may.
+func (p *SubscriptionPolicy) CheckAPIVersion(subscriptionID string, subscription *arm.Subscription, apiVersion string) *arm.CloudError {
+	feature := lookupFold(p.config.Load().APIVersions, apiVersion)
+	if feature == "" || isFeatureRegistered(subscription, feature) {
+		return nil
+	}
+	return arm.ErrorSubscriptionNotRegisteredForFeature.New("",
+		subscriptionID, feature, fmt.Sprintf("API version '%s'", apiVersion))
+}
+
+// CheckClusterCreation returns error details for each capability requested
+// by a normalized cluster that the subscription is not allowed to use, and
+// for the subscription's offer type if it is not eligible for clusters.
+func (p *SubscriptionPolicy) CheckClusterCreation(subscriptionID string, subscription *arm.Subscription, cluster *api.HCPOpenShiftCluster) []arm.CloudErrorBody {
+	var errorDetails []arm.CloudErrorBody
+
+	config := p.config.Load()
+	spec := &cluster.Properties.Spec
+
+	if subscription.Properties != nil && subscription.Properties.QuotaId != nil {
+		quotaID := *subscription.Properties.QuotaId
+		for _, ineligible := range config.IneligibleQuotaIDs {
+			if strings.EqualFold(quotaID, ineligible) {
+				errorDetails = append(errorDetails, arm.ErrorSubscriptionOfferNotSupported.Body("",
+					subscriptionID, quotaID, api.ResourceTypeDisplay))
+				break
+			}
+		}
+	}
+
+	gates := []struct {
+		requested   bool
+		feature     string
+		description string
+		target      string
+	}{
+		{spec.FIPS, config.FIPS, "FIPS", "properties.spec.fips"},
+		{spec.API.Visibility == api.VisibilityPrivate, config.PrivateAPI, "private API visibility", "properties.spec.api.visibility"},
+		{spec.ExternalAuth.Enabled, config.ExternalAuth, "external authentication", "properties.spec.externalAuth.enabled"},
+		{true, lookupFold(config.ChannelGroups, spec.Version.ChannelGroup), fmt.Sprintf("channel group '%s'", spec.Version.ChannelGroup), "properties.spec.version.channelGroup"},
+	}
+
+	for _, gate := range gates {
+		if gate.requested && gate.feature != "" && !isFeatureRegistered(subscription, gate.feature) {
+			errorDetails = append(errorDetails, arm.ErrorSubscriptionNotRegisteredForFeature.Body(gate.target,
+				subscriptionID, gate.feature, gate.description))
+		}
+	}
+
+	return errorDetails
+}
+
+// MiddlewareValidateSubscriptionPolicy rejects requests that create or update
+// resources using an API version the subscription is not registered for.
+// Reading and deleting resources is always allowed, so a subscription that
+// loses a feature can still manage what it created. It must run after the
+// subscription state has been validated.
+func (p *SubscriptionPolicy) MiddlewareValidateSubscriptionPolicy(cache *Cache) MiddlewareFunc {
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		if r.Method != http.MethodPut && r.Method != http.MethodPatch {
+			next(w, r)
+			return
+		}
+
+		subscriptionID := r.PathValue(PathSegmentSubscriptionID)
+		subscription, found := cache.GetSubscription(subscriptionID)
+		if found {
+			cloudError := p.CheckAPIVersion(subscriptionID, subscription, r.URL.Query().Get(APIVersionKey))
+			if cloudError != nil {
+				arm.WriteCloudError(w, cloudError)
+				return
+			}
+		}
+		next(w, r)
+	}
+}
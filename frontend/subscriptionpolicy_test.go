@@ -0,0 +1,291 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+const (
+	testFIPSFeature      = "Microsoft.RedHatOpenShift/FIPSPreview"
+	testCandidateFeature = "Microsoft.RedHatOpenShift/CandidateChannel"
+	testAPIFeature       = "Microsoft.RedHatOpenShift/NextAPIVersion"
+)
+
+func newTestSubscriptionPolicy() *SubscriptionPolicy {
+	return NewSubscriptionPolicy(&SubscriptionPolicyConfig{
+		FIPS:               testFIPSFeature,
+		PrivateAPI:         "Microsoft.RedHatOpenShift/PrivateAPI",
+		ChannelGroups:      map[string]string{"candidate": testCandidateFeature},
+		APIVersions:        map[string]string{"2024-06-10-preview": testAPIFeature},
+		IneligibleQuotaIDs: []string{"FreeTrial_2014-09-01"},
+	})
+}
+
+func newTestSubscription(quotaID string, features ...string) *arm.Subscription {
+	registered := []arm.Feature{}
+	for _, feature := range features {
+		registered = append(registered, arm.Feature{Name: api.Ptr(feature), State: api.Ptr("Registered")})
+	}
+	return &arm.Subscription{
+		State: arm.Registered,
+		Properties: &arm.Properties{
+			QuotaId:            api.Ptr(quotaID),
+			RegisteredFeatures: &registered,
+		},
+	}
+}
+
+func TestSubscriptionPolicyCheckClusterCreation(t *testing.T) {
+	const subscriptionID = "00000000-0000-0000-0000-000000000000"
+
+	tests := []struct {
+		name          string
+		subscription  *arm.Subscription
+		modifyCluster func(*api.HCPOpenShiftCluster)
+		expectCodes   []string
+		expectTargets []string
+	}{
+		{
+			name:         "No gated capabilities",
+			subscription: newTestSubscription("PayAsYouGo_2014-09-01"),
+		},
+		{
+			name:         "Subscription without properties",
+			subscription: &arm.Subscription{State: arm.Registered},
+		},
+		{
+			name:          "Ineligible offer",
+			subscription:  newTestSubscription("freetrial_2014-09-01"),
+			expectCodes:   []string{arm.CloudErrorCodeSubscriptionOfferNotSupported},
+			expectTargets: []string{""},
+		},
+		{
+			name:         "FIPS without feature",
+			subscription: newTestSubscription("PayAsYouGo_2014-09-01"),
+			modifyCluster: func(c *api.HCPOpenShiftCluster) {
+				c.Properties.Spec.FIPS = true
+			},
+			expectCodes:   []string{arm.CloudErrorCodeSubscriptionNotRegisteredForFeature},
+			expectTargets: []string{"properties.spec.fips"},
+		},
+		{
+			name:         "FIPS with feature",
+			subscription: newTestSubscription("PayAsYouGo_2014-09-01", "microsoft.redhatopenshift/fipspreview"),
+			modifyCluster: func(c *api.HCPOpenShiftCluster) {
+				c.Properties.Spec.FIPS = true
+			},
+		},
+		{
+			name: "FIPS with feature still pending",
+			subscription: &arm.Subscription{
+				State: arm.Registered,
+				Properties: &arm.Properties{
+					RegisteredFeatures: &[]arm.Feature{{Name: api.Ptr(testFIPSFeature), State: api.Ptr("Pending")}},
+				},
+			},
+			modifyCluster: func(c *api.HCPOpenShiftCluster) {
+				c.Properties.Spec.FIPS = true
+			},
+			expectCodes:   []string{arm.CloudErrorCodeSubscriptionNotRegisteredForFeature},
+			expectTargets: []string{"properties.spec.fips"},
+		},
+		{
+			name:         "Ungated external auth",
+			subscription: newTestSubscription("PayAsYouGo_2014-09-01"),
+			modifyCluster: func(c *api.HCPOpenShiftCluster) {
+				c.Properties.Spec.ExternalAuth.Enabled = true
+			},
+		},
+		{
+			name:         "Gated channel group and private API on ineligible offer",
+			subscription: newTestSubscription("FreeTrial_2014-09-01"),
+			modifyCluster: func(c *api.HCPOpenShiftCluster) {
+				c.Properties.Spec.Version.ChannelGroup = "candidate"
+				c.Properties.Spec.API.Visibility = api.VisibilityPrivate
+			},
+			expectCodes: []string{
+				arm.CloudErrorCodeSubscriptionOfferNotSupported,
+				arm.CloudErrorCodeSubscriptionNotRegisteredForFeature,
+				arm.CloudErrorCodeSubscriptionNotRegisteredForFeature,
+			},
+			expectTargets: []string{
+				"",
+				"properties.spec.api.visibility",
+				"properties.spec.version.channelGroup",
+			},
+		},
+	}
+
+	policy := newTestSubscriptionPolicy()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := api.NewDefaultHCPOpenShiftCluster()
+			cluster.Properties.Spec.Version.ChannelGroup = "stable"
+			if tt.modifyCluster != nil {
+				tt.modifyCluster(cluster)
+			}
+
+			errorDetails := policy.CheckClusterCreation(subscriptionID, tt.subscription, cluster)
+
+			if len(errorDetails) != len(tt.expectCodes) {
+				t.Fatalf("Expected %d errors, got %d: %v", len(tt.expectCodes), len(errorDetails), errorDetails)
+			}
+			for i, detail := range errorDetails {
+				if detail.Code != tt.expectCodes[i] {
+					t.Errorf("Expected code %q, got %q", tt.expectCodes[i], detail.Code)
+				}
+				if detail.Target != tt.expectTargets[i] {
+					t.Errorf("Expected target %q, got %q", tt.expectTargets[i], detail.Target)
+				}
+			}
+		})
+	}
+}
+
+func TestMiddlewareValidateSubscriptionPolicy(t *testing.T) {
+	const subscriptionID = "00000000-0000-0000-0000-000000000000"
+
+	tests := []struct {
+		name         string
+		method       string
+		apiVersion   string
+		subscription *arm.Subscription
+		expectStatus int
+	}{
+		{
+			name:         "Ungated API version",
+			method:       http.MethodPut,
+			apiVersion:   "2024-03-01-preview",
+			subscription: newTestSubscription("PayAsYouGo_2014-09-01"),
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:         "Gated API version without feature",
+			method:       http.MethodPut,
+			apiVersion:   "2024-06-10-preview",
+			subscription: newTestSubscription("PayAsYouGo_2014-09-01"),
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "Gated API version update without feature",
+			method:       http.MethodPatch,
+			apiVersion:   "2024-06-10-preview",
+			subscription: newTestSubscription("PayAsYouGo_2014-09-01"),
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "Gated API version with feature",
+			method:       http.MethodPut,
+			apiVersion:   "2024-06-10-preview",
+			subscription: newTestSubscription("PayAsYouGo_2014-09-01", testAPIFeature),
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:         "Gated API version read without feature",
+			method:       http.MethodGet,
+			apiVersion:   "2024-06-10-preview",
+			subscription: newTestSubscription("PayAsYouGo_2014-09-01"),
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:         "Gated API version delete without feature",
+			method:       http.MethodDelete,
+			apiVersion:   "2024-06-10-preview",
+			subscription: newTestSubscription("PayAsYouGo_2014-09-01"),
+			expectStatus: http.StatusOK,
+		},
+	}
+
+	policy := newTestSubscriptionPolicy()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewCache()
+			cache.SetSubscription(subscriptionID, tt.subscription)
+
+			request := httptest.NewRequest(tt.method, fmt.Sprintf("/subscriptions/%s?%s=%s", subscriptionID, APIVersionKey, tt.apiVersion), nil)
+			request.SetPathValue(PathSegmentSubscriptionID, subscriptionID)
+			writer := httptest.NewRecorder()
+
+			next := func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}
+			policy.MiddlewareValidateSubscriptionPolicy(cache)(writer, request, next)
+
+			if writer.Code != tt.expectStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectStatus, writer.Code, writer.Body.String())
+			}
+			if tt.expectStatus != http.StatusOK {
+				if code := writer.Header().Get(arm.HeaderNameErrorCode); code != arm.CloudErrorCodeSubscriptionNotRegisteredForFeature {
+					t.Errorf("Expected error code %q, got %q", arm.CloudErrorCodeSubscriptionNotRegisteredForFeature, code)
+				}
+			}
+		})
+	}
+}
+
+func TestSubscriptionPolicyReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(path, []byte(`{"fips": "Microsoft.RedHatOpenShift/FIPSPreview"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	policy, err := LoadSubscriptionPolicy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cluster := api.NewDefaultHCPOpenShiftCluster()
+	cluster.Properties.Spec.FIPS = true
+	subscription := newTestSubscription("PayAsYouGo_2014-09-01")
+
+	if errorDetails := policy.CheckClusterCreation("", subscription, cluster); len(errorDetails) != 1 {
+		t.Fatalf("Expected FIPS to be gated, got %v", errorDetails)
+	}
+
+	// An unmodified file is not reloaded.
+	if changed, err := policy.Reload(); changed || err != nil {
+		t.Errorf("Expected no change, got %v, %v", changed, err)
+	}
+
+	// A malformed file leaves the previous policy in effect.
+	modTime := time.Now().Add(time.Minute)
+	if err = os.WriteFile(path, []byte(`{`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := policy.Reload(); err == nil {
+		t.Errorf("Expected an error reloading a malformed file")
+	}
+	if errorDetails := policy.CheckClusterCreation("", subscription, cluster); len(errorDetails) != 1 {
+		t.Errorf("Expected FIPS to remain gated, got %v", errorDetails)
+	}
+
+	modTime = modTime.Add(time.Minute)
+	if err = os.WriteFile(path, []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := policy.Reload(); !changed || err != nil {
+		t.Errorf("Expected a change, got %v, %v", changed, err)
+	}
+	if errorDetails := policy.CheckClusterCreation("", subscription, cluster); len(errorDetails) != 0 {
+		t.Errorf("Expected FIPS to be ungated, got %v", errorDetails)
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+func newTestSystemData(user string, createdAt, modifiedAt time.Time) *arm.SystemData {
+	return &arm.SystemData{
+		CreatedBy:          user,
+		CreatedByType:      arm.CreatedByTypeUser,
+		CreatedAt:          &createdAt,
+		LastModifiedBy:     user,
+		LastModifiedByType: arm.CreatedByTypeUser,
+		LastModifiedAt:     &modifiedAt,
+	}
+}
+
+func TestClusterWriteSystemData(t *testing.T) {
+	const (
+		subscriptionID = "00000000-0000-0000-0000-000000000000"
+		resourceID     = "/subscriptions/" + subscriptionID + "/resourcegroups/rg/providers/microsoft.redhatopenshift/hcpopenshiftclusters/cluster"
+	)
+
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	updated := created.Add(time.Hour)
+	patched := updated.Add(time.Hour)
+
+	dbClient := NewMemoryDBClient()
+	f := &Frontend{
+		logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cache:          NewCache(),
+		dbClient:       dbClient,
+		azureResources: newTestAzureResourceClient(),
+	}
+
+	cluster := newTestValidCluster()
+	// Clients cannot set system data; it must be ignored.
+	cluster.SystemData = newTestSystemData("mallory@example.com", created.Add(-time.Hour), created.Add(-time.Hour))
+
+	version, _ := api.Lookup("2024-06-10-preview")
+	clusterBody, err := json.Marshal(version.NewHCPOpenShiftCluster(cluster))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(method string, body []byte, systemData *arm.SystemData) {
+		t.Helper()
+
+		request := httptest.NewRequest(method, resourceID, nil)
+		ctx := ContextWithVersion(request.Context(), version)
+		ctx = ContextWithBody(ctx, body)
+		ctx = ContextWithSystemData(ctx, systemData)
+		request = request.WithContext(ctx)
+		request.SetPathValue(PathSegmentSubscriptionID, subscriptionID)
+		writer := httptest.NewRecorder()
+
+		if method == http.MethodPatch {
+			f.ArmResourcePatch(writer, request)
+		} else {
+			f.ArmResourceCreateOrUpdate(writer, request)
+		}
+
+		if writer.Code >= 300 {
+			t.Fatalf("Expected success, got %d: %s", writer.Code, writer.Body.String())
+		}
+
+		var response struct {
+			SystemData *arm.SystemData `json:"systemData"`
+		}
+		if err := json.Unmarshal(writer.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		stored, _ := f.cache.GetCluster(resourceID)
+		if diff := cmp.Diff(stored.SystemData, response.SystemData); diff != "" {
+			t.Errorf("Response system data differs from stored system data (-stored +response):\n%s", diff)
+		}
+		doc, found, err := dbClient.GetClusterDoc(context.Background(), resourceID, subscriptionID)
+		if err != nil || !found {
+			t.Fatalf("Expected cluster document, got found=%v err=%v", found, err)
+		}
+		if diff := cmp.Diff(stored.SystemData, doc.SystemData); diff != "" {
+			t.Errorf("Persisted system data differs from stored system data (-stored +persisted):\n%s", diff)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		body       []byte
+		systemData *arm.SystemData
+		expected   *arm.SystemData
+	}{
+		{
+			name:       "Create",
+			method:     http.MethodPut,
+			body:       clusterBody,
+			systemData: newTestSystemData("alice@example.com", created, created),
+			expected:   newTestSystemData("alice@example.com", created, created),
+		},
+		{
+			name:       "Update",
+			method:     http.MethodPut,
+			body:       clusterBody,
+			systemData: newTestSystemData("bob@example.com", updated, updated),
+			expected: &arm.SystemData{
+				CreatedBy:          "alice@example.com",
+				CreatedByType:      arm.CreatedByTypeUser,
+				CreatedAt:          &created,
+				LastModifiedBy:     "bob@example.com",
+				LastModifiedByType: arm.CreatedByTypeUser,
+				LastModifiedAt:     &updated,
+			},
+		},
+		{
+			name:       "Patch",
+			method:     http.MethodPatch,
+			body:       []byte(`{"tags": {"env": "test"}, "systemData": {"createdBy": "mallory@example.com"}}`),
+			systemData: newTestSystemData("carol@example.com", patched, patched),
+			expected: &arm.SystemData{
+				CreatedBy:          "alice@example.com",
+				CreatedByType:      arm.CreatedByTypeUser,
+				CreatedAt:          &created,
+				LastModifiedBy:     "carol@example.com",
+				LastModifiedByType: arm.CreatedByTypeUser,
+				LastModifiedAt:     &patched,
+			},
+		},
+	}
+
+	// Each case builds on the cluster written by the previous one.
+	for _, tt := range tests {
+		write(tt.method, tt.body, tt.systemData)
+
+		stored, _ := f.cache.GetCluster(resourceID)
+		if diff := cmp.Diff(tt.expected, stored.SystemData); diff != "" {
+			t.Errorf("%s: unexpected system data (-want +got):\n%s", tt.name, diff)
+		}
+	}
+
+	stored, _ := f.cache.GetCluster(resourceID)
+	if stored.Tags["env"] != "test" {
+		t.Errorf("Expected patch to merge tags, got %v", stored.Tags)
+	}
+}
+
+func TestArmResourcePatchNotFound(t *testing.T) {
+	f := &Frontend{
+		logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cache:    NewCache(),
+		dbClient: NewMemoryDBClient(),
+	}
+
+	version, _ := api.Lookup("2024-06-10-preview")
+	request := httptest.NewRequest(http.MethodPatch, "/subscriptions/00000000-0000-0000-0000-000000000000/resourcegroups/rg/providers/microsoft.redhatopenshift/hcpopenshiftclusters/missing", nil)
+	ctx := ContextWithVersion(request.Context(), version)
+	request = request.WithContext(ContextWithBody(ctx, []byte(`{}`)))
+	writer := httptest.NewRecorder()
+
+	f.ArmResourcePatch(writer, request)
+
+	if writer.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, writer.Code)
+	}
+}
+
+// TestStampSystemData covers the helper directly. Only cluster writes
+// call it; the frontend has no node pool write path yet.
+func TestStampSystemData(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	updated := created.Add(time.Hour)
+
+	resource := &arm.Resource{}
+
+	// A resource without system data from ARM gets none.
+	stampSystemData(context.Background(), resource, nil)
+	if resource.SystemData != nil {
+		t.Errorf("Expected no system data, got %v", resource.SystemData)
+	}
+
+	ctx := ContextWithSystemData(context.Background(), newTestSystemData("alice@example.com", created, created))
+	stampSystemData(ctx, resource, nil)
+	if diff := cmp.Diff(newTestSystemData("alice@example.com", created, created), resource.SystemData); diff != "" {
+		t.Errorf("Unexpected system data after create (-want +got):\n%s", diff)
+	}
+
+	ctx = ContextWithSystemData(context.Background(), newTestSystemData("bob@example.com", updated, updated))
+	stampSystemData(ctx, resource, resource.SystemData)
+	expected := &arm.SystemData{
+		CreatedBy:          "alice@example.com",
+		CreatedByType:      arm.CreatedByTypeUser,
+		CreatedAt:          &created,
+		LastModifiedBy:     "bob@example.com",
+		LastModifiedByType: arm.CreatedByTypeUser,
+		LastModifiedAt:     &updated,
+	}
+	if diff := cmp.Diff(expected, resource.SystemData); diff != "" {
+		t.Errorf("Unexpected system data after update (-want +got):\n%s", diff)
+	}
+}
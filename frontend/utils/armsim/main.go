@@ -0,0 +1,122 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+// armsim is a local stand-in for Azure Resource Manager that forwards
+// requests to a frontend running on the same machine. See the frontend
+// README for usage.
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+func main() {
+	listen := flag.String("listen", "localhost:8444", "address to listen on")
+	frontend := flag.String("frontend", "http://localhost:8443", "URL of the frontend")
+	insecure := flag.Bool("insecure", false, "serve plain HTTP instead of HTTPS with a self-signed certificate")
+	principalName := flag.String("principal-name", "developer@example.com", "client principal name sent to the frontend")
+	objectID := flag.String("object-id", "00000000-0000-0000-0000-000000000001", "client object ID sent to the frontend")
+	tenantID := flag.String("tenant-id", "00000000-0000-0000-0000-000000000000", "client and home tenant ID sent to the frontend")
+	resourceGroups := flag.String("resource-groups", "", "comma-separated subscription/resourceGroup/location triples to create at startup")
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	frontendURL, err := url.Parse(*frontend)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Invalid frontend URL: %v", err))
+		os.Exit(1)
+	}
+
+	simulator := NewSimulator(logger, frontendURL, http.DefaultClient, Identity{
+		PrincipalName: *principalName,
+		ObjectID:      *objectID,
+		TenantID:      *tenantID,
+	})
+
+	if *resourceGroups != "" {
+		for _, triple := range strings.Split(*resourceGroups, ",") {
+			parts := strings.Split(triple, "/")
+			if len(parts) != 3 {
+				logger.Error(fmt.Sprintf("Invalid resource group '%s', expected subscription/resourceGroup/location", triple))
+				os.Exit(1)
+			}
+			simulator.AddResourceGroup(parts[0], parts[1], parts[2])
+		}
+	}
+
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	server := &http.Server{
+		Handler:           simulator,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	scheme := "http"
+	if !*insecure {
+		certificate, err := selfSignedCertificate(listener.Addr())
+		if err != nil {
+			logger.Error(fmt.Sprintf("Creating a certificate failed: %v", err))
+			os.Exit(1)
+		}
+		listener = tls.NewListener(listener, &tls.Config{Certificates: []tls.Certificate{certificate}})
+		scheme = "https"
+	}
+
+	logger.Info(fmt.Sprintf("Forwarding %s://%s to %s", scheme, listener.Addr(), frontendURL))
+
+	if err = server.Serve(listener); err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+}
+
+// selfSignedCertificate returns a short-lived certificate for
+// localhost and the listener address.
+func selfSignedCertificate(addr net.Addr) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	if tcpAddr, ok := addr.(*net.TCPAddr); ok && !tcpAddr.IP.IsUnspecified() {
+		template.IPAddresses = append(template.IPAddresses, tcpAddr.IP)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
@@ -0,0 +1,391 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+// subscriptionAPIVersion is the API version ARM uses for
+// subscription lifecycle notifications.
+const subscriptionAPIVersion = "2.0"
+
+// Identity is the client identity the simulator presents to the frontend,
+// as ARM does for the caller of each request it forwards.
+type Identity struct {
+	PrincipalName string
+	ObjectID      string
+	TenantID      string
+}
+
+// ResourceGroup is a resource group known to the simulator.
+type ResourceGroup struct {
+	ID         string                  `json:"id"`
+	Name       string                  `json:"name"`
+	Type       string                  `json:"type"`
+	Location   string                  `json:"location"`
+	Tags       map[string]string       `json:"tags,omitempty"`
+	Properties ResourceGroupProperties `json:"properties"`
+}
+
+type ResourceGroupProperties struct {
+	ProvisioningState arm.ProvisioningState `json:"provisioningState"`
+}
+
+// Simulator stands in for Azure Resource Manager in front of a frontend so
+// developers can use tools like "az rest" or the SDK against localhost. It
+// registers subscriptions on first use, adds the headers ARM adds to each
+// forwarded request, keeps track of resource groups, and rewrites
+// asynchronous operation headers so clients poll through the simulator.
+type Simulator struct {
+	logger   *slog.Logger
+	frontend *url.URL
+	client   *http.Client
+	identity Identity
+
+	mutex sync.Mutex
+	// subscriptions holds the last state each subscription was put in,
+	// keyed by the lowercased subscription ID.
+	subscriptions map[string]arm.RegistrationState
+	// resourceGroups is keyed by the lowercased resource group ID.
+	resourceGroups map[string]*ResourceGroup
+}
+
+// NewSimulator returns a Simulator that forwards requests to the frontend
+// at the given URL using client.
+func NewSimulator(logger *slog.Logger, frontend *url.URL, client *http.Client, identity Identity) *Simulator {
+	return &Simulator{
+		logger:         logger,
+		frontend:       frontend,
+		client:         client,
+		identity:       identity,
+		subscriptions:  make(map[string]arm.RegistrationState),
+		resourceGroups: make(map[string]*ResourceGroup),
+	}
+}
+
+// AddResourceGroup creates a resource group without a request.
+func (s *Simulator) AddResourceGroup(subscriptionID, name, location string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	id := resourceGroupID(subscriptionID, name)
+	s.resourceGroups[strings.ToLower(id)] = newResourceGroup(id, name, location, nil)
+}
+
+func resourceGroupID(subscriptionID, name string) string {
+	return "/subscriptions/" + subscriptionID + "/resourceGroups/" + name
+}
+
+func newResourceGroup(id, name, location string, tags map[string]string) *ResourceGroup {
+	return &ResourceGroup{
+		ID:       id,
+		Name:     name,
+		Type:     "Microsoft.Resources/resourceGroups",
+		Location: location,
+		Tags:     tags,
+		Properties: ResourceGroupProperties{
+			ProvisioningState: arm.ProvisioningStateSucceeded,
+		},
+	}
+}
+
+func (s *Simulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Segments alternate between names and values:
+	// subscriptions/{id}/resourceGroups/{name}/...
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(segments) < 2 || !strings.EqualFold(segments[0], "subscriptions") {
+		s.forward(w, r, "")
+		return
+	}
+
+	subscriptionID := segments[1]
+
+	// Subscription lifecycle notifications pass through unchanged,
+	// but the simulator remembers the state so it does not register
+	// a subscription that was deliberately put in another state.
+	if len(segments) == 2 {
+		if r.Method == http.MethodPut {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				arm.WriteInternalServerError(w, s.logger, err)
+				return
+			}
+			var subscription arm.Subscription
+			if err = json.Unmarshal(body, &subscription); err == nil {
+				s.mutex.Lock()
+				s.subscriptions[strings.ToLower(subscriptionID)] = subscription.State
+				s.mutex.Unlock()
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		s.forward(w, r, "")
+		return
+	}
+
+	if len(segments) >= 4 && strings.EqualFold(segments[2], "resourceGroups") {
+		if len(segments) == 4 {
+			s.serveResourceGroup(w, r, subscriptionID, segments[3])
+			return
+		}
+
+		s.mutex.Lock()
+		_, found := s.resourceGroups[strings.ToLower(resourceGroupID(subscriptionID, segments[3]))]
+		s.mutex.Unlock()
+		if !found {
+			arm.ErrorResourceGroupNotFound.Write(w, "", segments[3])
+			return
+		}
+	}
+
+	s.forward(w, r, subscriptionID)
+}
+
+// serveResourceGroup handles requests for a resource group, which ARM
+// serves itself rather than forwarding to a resource provider.
+func (s *Simulator) serveResourceGroup(w http.ResponseWriter, r *http.Request, subscriptionID, name string) {
+	id := resourceGroupID(subscriptionID, name)
+	key := strings.ToLower(id)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	resourceGroup, found := s.resourceGroups[key]
+
+	switch r.Method {
+	case http.MethodGet:
+		if !found {
+			arm.ErrorResourceGroupNotFound.Write(w, "", name)
+			return
+		}
+		arm.WriteJSONResponse(w, s.logger, http.StatusOK, resourceGroup)
+	case http.MethodHead:
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodPut:
+		var body struct {
+			Location string            `json:"location"`
+			Tags     map[string]string `json:"tags"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			arm.WriteCloudError(w, arm.NewUnmarshalCloudError(err))
+			return
+		}
+		if body.Location == "" {
+			arm.ErrorMissingParameter.Write(w, "location", "location")
+			return
+		}
+		statusCode := http.StatusCreated
+		if found {
+			// The location of a resource group cannot change.
+			body.Location = resourceGroup.Location
+			statusCode = http.StatusOK
+		}
+		resourceGroup = newResourceGroup(id, name, body.Location, body.Tags)
+		s.resourceGroups[key] = resourceGroup
+		arm.WriteJSONResponse(w, s.logger, statusCode, resourceGroup)
+	case http.MethodDelete:
+		// Resources in the group are not deleted.
+		if !found {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		delete(s.resourceGroups, key)
+		w.WriteHeader(http.StatusOK)
+	default:
+		arm.WriteMethodNotAllowedError(w, r.Method, []string{http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete})
+	}
+}
+
+// register sends a subscription lifecycle notification registering
+// subscriptionID with the frontend.
+func (s *Simulator) register(r *http.Request, subscriptionID string) error {
+	registrationDate := time.Now().UTC().Format(time.RFC1123)
+	body, err := json.Marshal(arm.Subscription{
+		State:            arm.Registered,
+		RegsitrationDate: &registrationDate,
+		Properties: &arm.Properties{
+			TenantId: &s.identity.TenantID,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	target := s.frontend.JoinPath("subscriptions", subscriptionID)
+	target.RawQuery = url.Values{"api-version": []string{subscriptionAPIVersion}}.Encode()
+
+	request, err := http.NewRequestWithContext(r.Context(), http.MethodPut, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(response.Body)
+		return fmt.Errorf("registering subscription %s failed with status %d: %s", subscriptionID, response.StatusCode, data)
+	}
+
+	s.mutex.Lock()
+	s.subscriptions[strings.ToLower(subscriptionID)] = arm.Registered
+	s.mutex.Unlock()
+
+	s.logger.Info(fmt.Sprintf("registered subscription %s", subscriptionID))
+	return nil
+}
+
+// forward sends a request to the frontend and copies the response back to
+// the client. If subscriptionID is not empty, the subscription is registered
+// first if necessary, and registered again if the frontend has forgotten it,
+// for example after a restart.
+func (s *Simulator) forward(w http.ResponseWriter, r *http.Request, subscriptionID string) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		arm.WriteInternalServerError(w, s.logger, err)
+		return
+	}
+
+	var state arm.RegistrationState
+	if subscriptionID != "" {
+		s.mutex.Lock()
+		state = s.subscriptions[strings.ToLower(subscriptionID)]
+		s.mutex.Unlock()
+
+		if state == "" {
+			state = arm.Registered
+			if err = s.register(r, subscriptionID); err != nil {
+				arm.WriteInternalServerError(w, s.logger, err)
+				return
+			}
+		}
+	}
+
+	// ARM sends the same correlation ID for every attempt.
+	correlationRequestID := r.Header.Get(arm.HeaderNameCorrelationRequestID)
+	if correlationRequestID == "" {
+		correlationRequestID = uuid.New().String()
+	}
+
+	response, err := s.send(r, body, correlationRequestID)
+	if err != nil {
+		arm.WriteInternalServerError(w, s.logger, err)
+		return
+	}
+
+	if state == arm.Registered && response.Header.Get(arm.HeaderNameErrorCode) == arm.CloudErrorCodeInvalidSubscriptionState {
+		response.Body.Close()
+		if err = s.register(r, subscriptionID); err != nil {
+			arm.WriteInternalServerError(w, s.logger, err)
+			return
+		}
+		if response, err = s.send(r, body, correlationRequestID); err != nil {
+			arm.WriteInternalServerError(w, s.logger, err)
+			return
+		}
+	}
+	defer response.Body.Close()
+
+	for name, values := range response.Header {
+		w.Header()[name] = values
+	}
+	for _, name := range []string{arm.HeaderNameAsyncOperation, arm.HeaderNameLocation} {
+		if value := response.Header.Get(name); value != "" {
+			w.Header().Set(name, s.rewriteURL(r, value))
+		}
+	}
+	w.WriteHeader(response.StatusCode)
+
+	if _, err = io.Copy(w, response.Body); err != nil {
+		s.logger.Error(err.Error())
+	}
+}
+
+// send sends a copy of the client request to the frontend with the headers
+// ARM adds to requests it forwards to a resource provider.
+func (s *Simulator) send(r *http.Request, body []byte, correlationRequestID string) (*http.Response, error) {
+	target := s.frontend.JoinPath(r.URL.Path)
+	target.RawQuery = r.URL.RawQuery
+
+	request, err := http.NewRequestWithContext(r.Context(), r.Method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header = r.Header.Clone()
+	// The frontend relies on ARM to authenticate clients.
+	request.Header.Del("Authorization")
+	request.Header.Set("Referer", requestURL(r).String())
+	request.Header.Set(arm.HeaderNameCorrelationRequestID, correlationRequestID)
+	if request.Header.Get(arm.HeaderNameClientRequestID) == "" {
+		request.Header.Set(arm.HeaderNameClientRequestID, uuid.New().String())
+	}
+	request.Header.Set(arm.HeaderNameHomeTenantID, s.identity.TenantID)
+	request.Header.Set(arm.HeaderNameClientTenantID, s.identity.TenantID)
+	request.Header.Set(arm.HeaderNameClientObjectID, s.identity.ObjectID)
+	request.Header.Set(arm.HeaderNameClientPrincipalName, s.identity.PrincipalName)
+
+	switch r.Method {
+	case http.MethodPut, http.MethodPatch:
+		now := time.Now().UTC()
+		systemData, err := json.Marshal(arm.SystemData{
+			CreatedBy:          s.identity.PrincipalName,
+			CreatedByType:      arm.CreatedByTypeUser,
+			CreatedAt:          &now,
+			LastModifiedBy:     s.identity.PrincipalName,
+			LastModifiedByType: arm.CreatedByTypeUser,
+			LastModifiedAt:     &now,
+		})
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set(arm.HeaderNameARMResourceSystemData, string(systemData))
+	}
+
+	return s.client.Do(request)
+}
+
+// rewriteURL points a URL the frontend returned at the simulator instead.
+func (s *Simulator) rewriteURL(r *http.Request, value string) string {
+	u, err := url.Parse(value)
+	if err != nil || !strings.EqualFold(u.Host, s.frontend.Host) {
+		return value
+	}
+	client := requestURL(r)
+	u.Scheme = client.Scheme
+	u.Host = client.Host
+	return u.String()
+}
+
+// requestURL returns the URL the client used for a request.
+func requestURL(r *http.Request) *url.URL {
+	u := *r.URL
+	u.Host = r.Host
+	u.Scheme = "http"
+	if r.TLS != nil {
+		u.Scheme = "https"
+	}
+	return &u
+}
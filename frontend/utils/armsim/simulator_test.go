@@ -0,0 +1,239 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+const (
+	testSubscriptionID = "00000000-0000-0000-0000-000000000000"
+	testClusterPath    = "/subscriptions/" + testSubscriptionID + "/resourceGroups/myRG/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/myCluster"
+)
+
+// fakeFrontend records the requests it receives and
+// rejects requests for unregistered subscriptions.
+type fakeFrontend struct {
+	mutex         sync.Mutex
+	requests      []*http.Request
+	subscriptions map[string]arm.RegistrationState
+}
+
+func (f *fakeFrontend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.requests = append(f.requests, r)
+
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(segments) == 2 {
+		var subscription arm.Subscription
+		if err := json.NewDecoder(r.Body).Decode(&subscription); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.subscriptions[segments[1]] = subscription.State
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	switch f.subscriptions[segments[1]] {
+	case "":
+		arm.ErrorUnregisteredSubscription.Write(w, "", segments[1])
+		return
+	case arm.Registered:
+	default:
+		arm.ErrorInvalidSubscriptionState.Write(w, "", f.subscriptions[segments[1]])
+		return
+	}
+
+	w.Header().Set(arm.HeaderNameAsyncOperation, "http://"+r.Host+"/operations/1?api-version=2024-06-10-preview")
+	w.WriteHeader(http.StatusCreated)
+	_, _ = io.WriteString(w, `{}`)
+}
+
+func newTestSimulator(t *testing.T) (*Simulator, *fakeFrontend) {
+	frontend := &fakeFrontend{subscriptions: make(map[string]arm.RegistrationState)}
+	server := httptest.NewServer(frontend)
+	t.Cleanup(server.Close)
+
+	frontendURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	simulator := NewSimulator(slog.New(slog.NewTextHandler(io.Discard, nil)), frontendURL, server.Client(), Identity{
+		PrincipalName: "developer@example.com",
+		ObjectID:      "00000000-0000-0000-0000-000000000001",
+		TenantID:      "00000000-0000-0000-0000-000000000002",
+	})
+
+	return simulator, frontend
+}
+
+func TestSimulatorForward(t *testing.T) {
+	simulator, frontend := newTestSimulator(t)
+	simulator.AddResourceGroup(testSubscriptionID, "myRG", "eastus")
+
+	request := httptest.NewRequest(http.MethodPut, "http://localhost:8444"+testClusterPath+"?api-version=2024-06-10-preview", strings.NewReader(`{}`))
+	request.Header.Set("Authorization", "Bearer token")
+	writer := httptest.NewRecorder()
+	simulator.ServeHTTP(writer, request)
+
+	if writer.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, writer.Code, writer.Body)
+	}
+
+	if len(frontend.requests) != 2 {
+		t.Fatalf("Expected 2 requests to the frontend, got %d", len(frontend.requests))
+	}
+	if frontend.requests[0].Method != http.MethodPut || frontend.requests[0].URL.Query().Get("api-version") != subscriptionAPIVersion {
+		t.Errorf("Expected a subscription registration, got %s %s", frontend.requests[0].Method, frontend.requests[0].URL)
+	}
+
+	forwarded := frontend.requests[1]
+	for _, name := range []string{
+		arm.HeaderNameARMResourceSystemData,
+		arm.HeaderNameCorrelationRequestID,
+		arm.HeaderNameClientRequestID,
+		arm.HeaderNameHomeTenantID,
+		arm.HeaderNameClientObjectID,
+		arm.HeaderNameClientPrincipalName,
+		"Referer",
+	} {
+		if forwarded.Header.Get(name) == "" {
+			t.Errorf("Expected header %s to be set", name)
+		}
+	}
+	if forwarded.Header.Get("Authorization") != "" {
+		t.Error("Expected the Authorization header to be removed")
+	}
+
+	expected := "http://localhost:8444/operations/1?api-version=2024-06-10-preview"
+	if actual := writer.Header().Get(arm.HeaderNameAsyncOperation); actual != expected {
+		t.Errorf("Expected %s header '%s', got '%s'", arm.HeaderNameAsyncOperation, expected, actual)
+	}
+}
+
+func TestSimulatorReregister(t *testing.T) {
+	simulator, frontend := newTestSimulator(t)
+	simulator.AddResourceGroup(testSubscriptionID, "myRG", "eastus")
+
+	// The simulator believes the subscription is registered
+	// but the frontend has forgotten it, as after a restart.
+	simulator.subscriptions[testSubscriptionID] = arm.Registered
+
+	request := httptest.NewRequest(http.MethodPut, testClusterPath+"?api-version=2024-06-10-preview", strings.NewReader(`{}`))
+	writer := httptest.NewRecorder()
+	simulator.ServeHTTP(writer, request)
+
+	if writer.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, writer.Code, writer.Body)
+	}
+	if len(frontend.requests) != 3 {
+		t.Fatalf("Expected 3 requests to the frontend, got %d", len(frontend.requests))
+	}
+	first := frontend.requests[0].Header.Get(arm.HeaderNameCorrelationRequestID)
+	retry := frontend.requests[2].Header.Get(arm.HeaderNameCorrelationRequestID)
+	if first != retry {
+		t.Errorf("Expected the retry to reuse correlation request ID '%s', got '%s'", first, retry)
+	}
+}
+
+func TestSimulatorResourceGroups(t *testing.T) {
+	simulator, frontend := newTestSimulator(t)
+
+	resourceGroupPath := "/subscriptions/" + testSubscriptionID + "/resourcegroups/myRG"
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		statusCode int
+	}{
+		{"Resource under missing resource group", http.MethodPut, testClusterPath, `{}`, http.StatusNotFound},
+		{"Get missing resource group", http.MethodGet, resourceGroupPath, "", http.StatusNotFound},
+		{"Head missing resource group", http.MethodHead, resourceGroupPath, "", http.StatusNotFound},
+		{"Create resource group without location", http.MethodPut, resourceGroupPath, `{}`, http.StatusBadRequest},
+		{"Create resource group", http.MethodPut, resourceGroupPath, `{"location":"eastus"}`, http.StatusCreated},
+		{"Update resource group", http.MethodPut, resourceGroupPath, `{"location":"westus","tags":{"env":"dev"}}`, http.StatusOK},
+		{"Get resource group", http.MethodGet, resourceGroupPath, "", http.StatusOK},
+		{"Head resource group", http.MethodHead, resourceGroupPath, "", http.StatusNoContent},
+		{"Resource under resource group", http.MethodPut, testClusterPath, `{}`, http.StatusCreated},
+		{"Delete resource group", http.MethodDelete, resourceGroupPath, "", http.StatusOK},
+		{"Delete missing resource group", http.MethodDelete, resourceGroupPath, "", http.StatusNoContent},
+		{"Patch resource group", http.MethodPatch, resourceGroupPath, `{}`, http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			writer := httptest.NewRecorder()
+			simulator.ServeHTTP(writer, request)
+
+			if writer.Code != tt.statusCode {
+				t.Errorf("Expected status code %d, got %d: %s", tt.statusCode, writer.Code, writer.Body)
+			}
+		})
+	}
+
+	// Only the resource under the existing resource group
+	// is forwarded, after registering the subscription.
+	if len(frontend.requests) != 2 {
+		t.Errorf("Expected 2 requests to the frontend, got %d", len(frontend.requests))
+	}
+
+	resourceGroup := simulator.resourceGroups[strings.ToLower(resourceGroupID(testSubscriptionID, "myRG"))]
+	if resourceGroup != nil {
+		t.Errorf("Expected resource group to be deleted")
+	}
+}
+
+func TestSimulatorResourceGroupLocation(t *testing.T) {
+	simulator, _ := newTestSimulator(t)
+	simulator.AddResourceGroup(testSubscriptionID, "myRG", "eastus")
+
+	request := httptest.NewRequest(http.MethodPut, "/subscriptions/"+testSubscriptionID+"/resourceGroups/myRG", strings.NewReader(`{"location":"westus"}`))
+	writer := httptest.NewRecorder()
+	simulator.ServeHTTP(writer, request)
+
+	var resourceGroup ResourceGroup
+	if err := json.Unmarshal(writer.Body.Bytes(), &resourceGroup); err != nil {
+		t.Fatal(err)
+	}
+	if resourceGroup.Location != "eastus" {
+		t.Errorf("Expected location 'eastus', got '%s'", resourceGroup.Location)
+	}
+}
+
+func TestSimulatorSuspendedSubscription(t *testing.T) {
+	simulator, frontend := newTestSimulator(t)
+	simulator.AddResourceGroup(testSubscriptionID, "myRG", "eastus")
+
+	request := httptest.NewRequest(http.MethodPut, "/subscriptions/"+testSubscriptionID+"?api-version=2.0", strings.NewReader(`{"state":"Suspended"}`))
+	writer := httptest.NewRecorder()
+	simulator.ServeHTTP(writer, request)
+
+	request = httptest.NewRequest(http.MethodPut, testClusterPath+"?api-version=2024-06-10-preview", strings.NewReader(`{}`))
+	writer = httptest.NewRecorder()
+	simulator.ServeHTTP(writer, request)
+
+	if writer.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d: %s", http.StatusBadRequest, writer.Code, writer.Body)
+	}
+	if len(frontend.requests) != 2 {
+		t.Errorf("Expected 2 requests to the frontend, got %d", len(frontend.requests))
+	}
+}
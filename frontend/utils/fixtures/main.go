@@ -0,0 +1,67 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+// fixtures writes the cluster and node pool request bodies generated by the
+// internal fixtures package to files, one directory per API version. See the
+// frontend README for usage.
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Azure/ARO-HCP/internal/api/fixtures"
+)
+
+func main() {
+	seed := flag.Int64("seed", 1, "seed the fixtures are derived from")
+	apiVersion := flag.String("api-version", "", "only write fixtures for this API version")
+	validOnly := flag.Bool("valid-only", false, "only write valid fixtures")
+	output := flag.String("o", ".", "directory to write fixtures to")
+	flag.Parse()
+
+	if err := write(*output, *seed, *apiVersion, *validOnly); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// write writes each fixture to <dir>/<api-version>/<resource>-<name>.json
+// and prints the file name and the resource ID to send it to.
+func write(dir string, seed int64, apiVersion string, validOnly bool) error {
+	all, err := fixtures.All(seed)
+	if err != nil {
+		return err
+	}
+
+	written := 0
+	for _, fixture := range all {
+		if apiVersion != "" && fixture.APIVersion != apiVersion {
+			continue
+		}
+		if validOnly && !fixture.Valid() {
+			continue
+		}
+
+		versionDir := filepath.Join(dir, fixture.APIVersion)
+		if err = os.MkdirAll(versionDir, 0o755); err != nil {
+			return err
+		}
+
+		name := filepath.Join(versionDir, fmt.Sprintf("%s-%s.json", fixture.Resource, fixture.Name))
+		if err = os.WriteFile(name, append(fixture.Body, '\n'), 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("%s\t%s\n", name, fixture.ResourceID)
+		written++
+	}
+
+	if written == 0 {
+		return fmt.Errorf("no fixtures for API version '%s'", apiVersion)
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	azcorearm "github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+
+	"github.com/Azure/ARO-HCP/internal/api/v20240610preview/generated"
+)
+
+// globalOptions are the options accepted before the resource name.
+type globalOptions struct {
+	subscriptionID string
+	endpoint       string
+	insecure       bool
+	output         string
+	pollInterval   time.Duration
+}
+
+// newClientFactory returns a client factory for the configured subscription.
+// With an endpoint override the clients send a placeholder token, since a
+// local frontend relies on ARM for authentication. If transport is not nil,
+// the clients send requests through it instead of over the network.
+func newClientFactory(options globalOptions, transport policy.Transporter) (*generated.ClientFactory, error) {
+	var credential azcore.TokenCredential
+	clientOptions := &azcorearm.ClientOptions{}
+
+	if options.endpoint != "" {
+		credential = localTokenCredential{}
+		clientOptions.Cloud = cloud.Configuration{
+			Services: map[cloud.ServiceName]cloud.ServiceConfiguration{
+				cloud.ResourceManager: {
+					Endpoint: options.endpoint,
+					Audience: cloud.AzurePublic.Services[cloud.ResourceManager].Audience,
+				},
+			},
+		}
+		clientOptions.InsecureAllowCredentialWithHTTP = true
+		clientOptions.DisableRPRegistration = true
+	} else {
+		var err error
+		credential, err = azidentity.NewDefaultAzureCredential(nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case transport != nil:
+		clientOptions.Transport = transport
+	case options.insecure:
+		clientOptions.Transport = &http.Client{
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				// #nosec G402 -- requested explicitly for local
+				// endpoints with self-signed certificates
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		}
+	}
+
+	return generated.NewClientFactory(options.subscriptionID, credential, clientOptions)
+}
+
+// localTokenCredential provides a placeholder token for local endpoints.
+type localTokenCredential struct{}
+
+func (localTokenCredential) GetToken(ctx context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "local", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
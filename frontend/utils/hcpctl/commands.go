@@ -0,0 +1,349 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+
+	"github.com/Azure/ARO-HCP/internal/api/v20240610preview/generated"
+)
+
+const usage = `Usage: hcpctl [options] <resource> <command> [command options]
+
+Resources and commands:
+  cluster   create|get|list|update|delete|kubeconfig|admin-credentials
+  nodepool  create|get|list|update|delete
+
+Command options:
+  -g, -resource-group  resource group name
+  -c, -cluster         cluster name (node pools only)
+  -n, -name            resource name
+  -f, -file            YAML or JSON resource file for create and update, or "-" for standard input
+
+Options:
+`
+
+// cli holds the clients and settings shared by all commands.
+type cli struct {
+	stdin        io.Reader
+	stdout       io.Writer
+	stderr       io.Writer
+	output       string
+	pollInterval time.Duration
+	clusters     *generated.HcpOpenShiftClustersClient
+	nodePools    *generated.NodePoolsClient
+}
+
+// run executes the command in args. Results are written to stdout and
+// progress to stderr. If transport is not nil, the SDK clients send
+// requests through it.
+func run(ctx context.Context, args []string, stdout, stderr io.Writer, transport policy.Transporter) error {
+	var options globalOptions
+
+	flags := flag.NewFlagSet("hcpctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&options.subscriptionID, "subscription", os.Getenv("AZURE_SUBSCRIPTION_ID"), "subscription ID (default $AZURE_SUBSCRIPTION_ID)")
+	flags.StringVar(&options.endpoint, "endpoint", "", "resource manager endpoint override, such as a local frontend or ARM simulator")
+	flags.BoolVar(&options.insecure, "insecure", false, "skip TLS certificate verification")
+	flags.StringVar(&options.output, "output", outputTable, "output format: table or json")
+	flags.DurationVar(&options.pollInterval, "poll-interval", 10*time.Second, "interval between polls of long-running operations")
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	if flags.NArg() < 2 {
+		flags.Usage()
+		return errors.New("a resource and command are required")
+	}
+	if options.output != outputTable && options.output != outputJSON {
+		return fmt.Errorf("unsupported output format '%s'", options.output)
+	}
+	if options.subscriptionID == "" {
+		return errors.New("a subscription ID is required")
+	}
+
+	factory, err := newClientFactory(options, transport)
+	if err != nil {
+		return err
+	}
+
+	c := &cli{
+		stdin:        os.Stdin,
+		stdout:       stdout,
+		stderr:       stderr,
+		output:       options.output,
+		pollInterval: options.pollInterval,
+		clusters:     factory.NewHcpOpenShiftClustersClient(),
+		nodePools:    factory.NewNodePoolsClient(),
+	}
+
+	resource, command, args := flags.Arg(0), flags.Arg(1), flags.Args()[2:]
+
+	switch resource {
+	case "cluster":
+		return c.cluster(ctx, command, args)
+	case "nodepool":
+		return c.nodePool(ctx, command, args)
+	default:
+		return fmt.Errorf("unknown resource '%s'", resource)
+	}
+}
+
+// commandFlags are the options accepted after a command.
+type commandFlags struct {
+	resourceGroup string
+	cluster       string
+	name          string
+	file          string
+}
+
+// parseCommandFlags parses the options of a command and checks that
+// the named options are set.
+func (c *cli) parseCommandFlags(command string, args []string, required ...string) (commandFlags, error) {
+	var values commandFlags
+
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	stringVar := func(p *string, short, long string) {
+		flags.StringVar(p, short, "", "")
+		flags.StringVar(p, long, "", "")
+	}
+	stringVar(&values.resourceGroup, "g", "resource-group")
+	stringVar(&values.cluster, "c", "cluster")
+	stringVar(&values.name, "n", "name")
+	stringVar(&values.file, "f", "file")
+
+	if err := flags.Parse(args); err != nil {
+		return values, err
+	}
+	if flags.NArg() > 0 {
+		return values, fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+
+	for _, name := range required {
+		if flags.Lookup(name).Value.String() == "" {
+			return values, fmt.Errorf("%s requires -%s", command, name)
+		}
+	}
+
+	return values, nil
+}
+
+func (c *cli) cluster(ctx context.Context, command string, args []string) error {
+	switch command {
+	case "create":
+		values, err := c.parseCommandFlags(command, args, "resource-group", "name", "file")
+		if err != nil {
+			return err
+		}
+		var resource generated.HcpOpenShiftClusterResource
+		if err = readInput(values.file, c.stdin, &resource); err != nil {
+			return err
+		}
+		poller, err := c.clusters.BeginCreateOrUpdate(ctx, values.resourceGroup, values.name, resource, nil)
+		if err != nil {
+			return err
+		}
+		response, err := wait(ctx, c.stderr, poller, fmt.Sprintf("creation of cluster %s", values.name), c.pollInterval)
+		if err != nil {
+			return err
+		}
+		return printClusters(c.stdout, c.output, []*generated.HcpOpenShiftClusterResource{&response.HcpOpenShiftClusterResource})
+
+	case "get":
+		values, err := c.parseCommandFlags(command, args, "resource-group", "name")
+		if err != nil {
+			return err
+		}
+		response, err := c.clusters.Get(ctx, values.resourceGroup, values.name, nil)
+		if err != nil {
+			return err
+		}
+		return printClusters(c.stdout, c.output, []*generated.HcpOpenShiftClusterResource{&response.HcpOpenShiftClusterResource})
+
+	case "list":
+		values, err := c.parseCommandFlags(command, args)
+		if err != nil {
+			return err
+		}
+		var clusters []*generated.HcpOpenShiftClusterResource
+		if values.resourceGroup != "" {
+			pager := c.clusters.NewListByResourceGroupPager(values.resourceGroup, nil)
+			for pager.More() {
+				page, err := pager.NextPage(ctx)
+				if err != nil {
+					return err
+				}
+				clusters = append(clusters, page.Value...)
+			}
+		} else {
+			pager := c.clusters.NewListBySubscriptionPager(nil)
+			for pager.More() {
+				page, err := pager.NextPage(ctx)
+				if err != nil {
+					return err
+				}
+				clusters = append(clusters, page.Value...)
+			}
+		}
+		return printClusters(c.stdout, c.output, clusters)
+
+	case "update":
+		values, err := c.parseCommandFlags(command, args, "resource-group", "name", "file")
+		if err != nil {
+			return err
+		}
+		var properties generated.HcpOpenShiftClusterResourceUpdate
+		if err = readInput(values.file, c.stdin, &properties); err != nil {
+			return err
+		}
+		poller, err := c.clusters.BeginUpdate(ctx, values.resourceGroup, values.name, properties, nil)
+		if err != nil {
+			return err
+		}
+		response, err := wait(ctx, c.stderr, poller, fmt.Sprintf("update of cluster %s", values.name), c.pollInterval)
+		if err != nil {
+			return err
+		}
+		return printClusters(c.stdout, c.output, []*generated.HcpOpenShiftClusterResource{&response.HcpOpenShiftClusterResource})
+
+	case "delete":
+		values, err := c.parseCommandFlags(command, args, "resource-group", "name")
+		if err != nil {
+			return err
+		}
+		poller, err := c.clusters.BeginDelete(ctx, values.resourceGroup, values.name, nil)
+		if err != nil {
+			return err
+		}
+		_, err = wait(ctx, c.stderr, poller, fmt.Sprintf("deletion of cluster %s", values.name), c.pollInterval)
+		return err
+
+	case "kubeconfig":
+		values, err := c.parseCommandFlags(command, args, "resource-group", "name")
+		if err != nil {
+			return err
+		}
+		response, err := c.clusters.KubeConfig(ctx, values.resourceGroup, values.name, nil)
+		if err != nil {
+			return err
+		}
+		return printKubeconfig(c.stdout, c.output, response.HcpOpenShiftClusterKubeconfig)
+
+	case "admin-credentials":
+		values, err := c.parseCommandFlags(command, args, "resource-group", "name")
+		if err != nil {
+			return err
+		}
+		response, err := c.clusters.AdminCredentials(ctx, values.resourceGroup, values.name, nil)
+		if err != nil {
+			return err
+		}
+		return printCredentials(c.stdout, c.output, response.HcpOpenShiftClusterCredentials)
+
+	default:
+		return fmt.Errorf("unknown cluster command '%s'", command)
+	}
+}
+
+func (c *cli) nodePool(ctx context.Context, command string, args []string) error {
+	switch command {
+	case "create":
+		values, err := c.parseCommandFlags(command, args, "resource-group", "cluster", "name", "file")
+		if err != nil {
+			return err
+		}
+		var resource generated.HcpOpenShiftClusterNodePoolResource
+		if err = readInput(values.file, c.stdin, &resource); err != nil {
+			return err
+		}
+		poller, err := c.nodePools.BeginCreateOrUpdate(ctx, values.resourceGroup, values.cluster, values.name, resource, nil)
+		if err != nil {
+			return err
+		}
+		response, err := wait(ctx, c.stderr, poller, fmt.Sprintf("creation of node pool %s", values.name), c.pollInterval)
+		if err != nil {
+			return err
+		}
+		return printNodePools(c.stdout, c.output, []*generated.HcpOpenShiftClusterNodePoolResource{&response.HcpOpenShiftClusterNodePoolResource})
+
+	case "get":
+		values, err := c.parseCommandFlags(command, args, "resource-group", "cluster", "name")
+		if err != nil {
+			return err
+		}
+		response, err := c.nodePools.Get(ctx, values.resourceGroup, values.cluster, values.name, nil)
+		if err != nil {
+			return err
+		}
+		return printNodePools(c.stdout, c.output, []*generated.HcpOpenShiftClusterNodePoolResource{&response.HcpOpenShiftClusterNodePoolResource})
+
+	case "list":
+		values, err := c.parseCommandFlags(command, args, "resource-group", "cluster")
+		if err != nil {
+			return err
+		}
+		var nodePools []*generated.HcpOpenShiftClusterNodePoolResource
+		pager := c.nodePools.NewListByHcpOpenShiftClusterResourcePager(values.resourceGroup, values.cluster, nil)
+		for pager.More() {
+			page, err := pager.NextPage(ctx)
+			if err != nil {
+				return err
+			}
+			nodePools = append(nodePools, page.Value...)
+		}
+		return printNodePools(c.stdout, c.output, nodePools)
+
+	case "update":
+		values, err := c.parseCommandFlags(command, args, "resource-group", "cluster", "name", "file")
+		if err != nil {
+			return err
+		}
+		var properties generated.HcpOpenShiftClusterNodePoolResourceUpdate
+		if err = readInput(values.file, c.stdin, &properties); err != nil {
+			return err
+		}
+		poller, err := c.nodePools.BeginUpdate(ctx, values.resourceGroup, values.cluster, values.name, properties, nil)
+		if err != nil {
+			return err
+		}
+		response, err := wait(ctx, c.stderr, poller, fmt.Sprintf("update of node pool %s", values.name), c.pollInterval)
+		if err != nil {
+			return err
+		}
+		return printNodePools(c.stdout, c.output, []*generated.HcpOpenShiftClusterNodePoolResource{&response.HcpOpenShiftClusterNodePoolResource})
+
+	case "delete":
+		values, err := c.parseCommandFlags(command, args, "resource-group", "cluster", "name")
+		if err != nil {
+			return err
+		}
+		poller, err := c.nodePools.BeginDelete(ctx, values.resourceGroup, values.cluster, values.name, nil)
+		if err != nil {
+			return err
+		}
+		_, err = wait(ctx, c.stderr, poller, fmt.Sprintf("deletion of node pool %s", values.name), c.pollInterval)
+		return err
+
+	default:
+		return fmt.Errorf("unknown node pool command '%s'", command)
+	}
+}
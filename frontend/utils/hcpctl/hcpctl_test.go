@@ -0,0 +1,291 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	azfake "github.com/Azure/azure-sdk-for-go/sdk/azcore/fake"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/v20240610preview/generated"
+	"github.com/Azure/ARO-HCP/internal/api/v20240610preview/generated/fake"
+)
+
+const (
+	testSubscriptionID    = "00000000-0000-0000-0000-000000000000"
+	testResourceGroupName = "myRG"
+	testClusterName       = "myCluster"
+	testNodePoolName      = "myNodePool"
+	testClusterID         = "/subscriptions/" + testSubscriptionID + "/resourceGroups/" + testResourceGroupName + "/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/" + testClusterName
+)
+
+func newTestCluster() generated.HcpOpenShiftClusterResource {
+	return generated.HcpOpenShiftClusterResource{
+		ID:       api.Ptr(testClusterID),
+		Name:     api.Ptr(testClusterName),
+		Location: api.Ptr("eastus"),
+		Properties: &generated.HcpOpenShiftClusterProperties{
+			ProvisioningState: api.Ptr(generated.ProvisioningStateSucceeded),
+			Spec: &generated.ClusterSpec{
+				Version: &generated.VersionProfile{ID: api.Ptr("4.15.1")},
+			},
+		},
+	}
+}
+
+func newTestNodePool() generated.HcpOpenShiftClusterNodePoolResource {
+	return generated.HcpOpenShiftClusterNodePoolResource{
+		ID:       api.Ptr(testClusterID + "/nodePools/" + testNodePoolName),
+		Name:     api.Ptr(testNodePoolName),
+		Location: api.Ptr("eastus"),
+		Properties: &generated.NodePoolProperties{
+			ProvisioningState: api.Ptr(generated.ResourceProvisioningStateSucceeded),
+			Spec: &generated.NodePoolSpec{
+				Version:  &generated.VersionProfile{ID: api.Ptr("4.15.1")},
+				Platform: &generated.NodePoolPlatformProfile{VMSize: api.Ptr("Standard_D8s_v3")},
+				Replicas: api.Ptr(int32(3)),
+			},
+		},
+	}
+}
+
+// runTest runs a command against server and returns its standard output.
+func runTest(t *testing.T, server *fake.ServerFactory, args ...string) (string, error) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	args = append([]string{"-subscription", testSubscriptionID, "-endpoint", "https://localhost:8444", "-poll-interval", "0"}, args...)
+	err := run(context.Background(), args, &stdout, &stderr, fake.NewServerFactoryTransport(server))
+	return stdout.String(), err
+}
+
+func TestClusterCreate(t *testing.T) {
+	var received generated.HcpOpenShiftClusterResource
+
+	server := &fake.ServerFactory{
+		HcpOpenShiftClustersServer: fake.HcpOpenShiftClustersServer{
+			BeginCreateOrUpdate: func(ctx context.Context, resourceGroupName string, hcpOpenShiftClusterName string, resource generated.HcpOpenShiftClusterResource, options *generated.HcpOpenShiftClustersClientBeginCreateOrUpdateOptions) (resp azfake.PollerResponder[generated.HcpOpenShiftClustersClientCreateOrUpdateResponse], errResp azfake.ErrorResponder) {
+				received = resource
+				resp.AddNonTerminalResponse(http.StatusCreated, nil)
+				resp.SetTerminalResponse(http.StatusOK, generated.HcpOpenShiftClustersClientCreateOrUpdateResponse{HcpOpenShiftClusterResource: newTestCluster()}, nil)
+				return
+			},
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "cluster.yaml")
+	err := os.WriteFile(path, []byte("location: eastus\nproperties:\n  spec:\n    version:\n      id: 4.15.1\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, err := runTest(t, server, "cluster", "create", "-g", testResourceGroupName, "-n", testClusterName, "-f", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if value(received.Location) != "eastus" || value(received.Properties.Spec.Version.ID) != "4.15.1" {
+		t.Errorf("Unexpected cluster sent: %+v", received)
+	}
+
+	for _, expected := range []string{"NAME", testClusterName, testResourceGroupName, "eastus", "4.15.1", "Succeeded"} {
+		if !strings.Contains(stdout, expected) {
+			t.Errorf("Expected output to contain '%s', got:\n%s", expected, stdout)
+		}
+	}
+}
+
+func TestClusterGetJSON(t *testing.T) {
+	server := &fake.ServerFactory{
+		HcpOpenShiftClustersServer: fake.HcpOpenShiftClustersServer{
+			Get: func(ctx context.Context, resourceGroupName string, hcpOpenShiftClusterName string, options *generated.HcpOpenShiftClustersClientGetOptions) (resp azfake.Responder[generated.HcpOpenShiftClustersClientGetResponse], errResp azfake.ErrorResponder) {
+				resp.SetResponse(http.StatusOK, generated.HcpOpenShiftClustersClientGetResponse{HcpOpenShiftClusterResource: newTestCluster()}, nil)
+				return
+			},
+		},
+	}
+
+	stdout, err := runTest(t, server, "-output", "json", "cluster", "get", "-resource-group", testResourceGroupName, "-name", testClusterName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var clusters []generated.HcpOpenShiftClusterResource
+	if err = json.Unmarshal([]byte(stdout), &clusters); err != nil {
+		t.Fatal(err)
+	}
+	if len(clusters) != 1 || value(clusters[0].ID) != testClusterID {
+		t.Errorf("Unexpected output:\n%s", stdout)
+	}
+}
+
+func TestClusterGetError(t *testing.T) {
+	server := &fake.ServerFactory{
+		HcpOpenShiftClustersServer: fake.HcpOpenShiftClustersServer{
+			Get: func(ctx context.Context, resourceGroupName string, hcpOpenShiftClusterName string, options *generated.HcpOpenShiftClustersClientGetOptions) (resp azfake.Responder[generated.HcpOpenShiftClustersClientGetResponse], errResp azfake.ErrorResponder) {
+				errResp.SetResponseError(http.StatusNotFound, "ResourceNotFound")
+				return
+			},
+		},
+	}
+
+	_, err := runTest(t, server, "cluster", "get", "-g", testResourceGroupName, "-n", testClusterName)
+	if err == nil || !strings.Contains(err.Error(), "ResourceNotFound") {
+		t.Errorf("Expected a ResourceNotFound error, got %v", err)
+	}
+}
+
+func TestClusterList(t *testing.T) {
+	server := &fake.ServerFactory{
+		HcpOpenShiftClustersServer: fake.HcpOpenShiftClustersServer{
+			NewListBySubscriptionPager: func(options *generated.HcpOpenShiftClustersClientListBySubscriptionOptions) (resp azfake.PagerResponder[generated.HcpOpenShiftClustersClientListBySubscriptionResponse]) {
+				for _, name := range []string{"cluster1", "cluster2"} {
+					cluster := newTestCluster()
+					cluster.Name = api.Ptr(name)
+					resp.AddPage(http.StatusOK, generated.HcpOpenShiftClustersClientListBySubscriptionResponse{
+						HcpOpenShiftClusterResourceListResult: generated.HcpOpenShiftClusterResourceListResult{
+							Value: []*generated.HcpOpenShiftClusterResource{&cluster},
+						},
+					}, nil)
+				}
+				return
+			},
+		},
+	}
+
+	stdout, err := runTest(t, server, "cluster", "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], "cluster1") || !strings.HasPrefix(lines[2], "cluster2") {
+		t.Errorf("Expected a header and both pages, got:\n%s", stdout)
+	}
+}
+
+func TestClusterDelete(t *testing.T) {
+	server := &fake.ServerFactory{
+		HcpOpenShiftClustersServer: fake.HcpOpenShiftClustersServer{
+			BeginDelete: func(ctx context.Context, resourceGroupName string, hcpOpenShiftClusterName string, options *generated.HcpOpenShiftClustersClientBeginDeleteOptions) (resp azfake.PollerResponder[generated.HcpOpenShiftClustersClientDeleteResponse], errResp azfake.ErrorResponder) {
+				resp.AddNonTerminalResponse(http.StatusAccepted, nil)
+				resp.AddNonTerminalResponse(http.StatusAccepted, nil)
+				resp.SetTerminalResponse(http.StatusNoContent, generated.HcpOpenShiftClustersClientDeleteResponse{}, nil)
+				return
+			},
+		},
+	}
+
+	stdout, err := runTest(t, server, "cluster", "delete", "-g", testResourceGroupName, "-n", testClusterName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stdout != "" {
+		t.Errorf("Expected no output, got:\n%s", stdout)
+	}
+}
+
+func TestClusterActions(t *testing.T) {
+	server := &fake.ServerFactory{
+		HcpOpenShiftClustersServer: fake.HcpOpenShiftClustersServer{
+			KubeConfig: func(ctx context.Context, resourceGroupName string, hcpOpenShiftClusterName string, options *generated.HcpOpenShiftClustersClientKubeConfigOptions) (resp azfake.Responder[generated.HcpOpenShiftClustersClientKubeConfigResponse], errResp azfake.ErrorResponder) {
+				resp.SetResponse(http.StatusOK, generated.HcpOpenShiftClustersClientKubeConfigResponse{
+					HcpOpenShiftClusterKubeconfig: generated.HcpOpenShiftClusterKubeconfig{Kubeconfig: api.Ptr("apiVersion: v1")},
+				}, nil)
+				return
+			},
+			AdminCredentials: func(ctx context.Context, resourceGroupName string, hcpOpenShiftClusterName string, options *generated.HcpOpenShiftClustersClientAdminCredentialsOptions) (resp azfake.Responder[generated.HcpOpenShiftClustersClientAdminCredentialsResponse], errResp azfake.ErrorResponder) {
+				resp.SetResponse(http.StatusOK, generated.HcpOpenShiftClustersClientAdminCredentialsResponse{
+					HcpOpenShiftClusterCredentials: generated.HcpOpenShiftClusterCredentials{
+						KubeadminUsername: api.Ptr("kubeadmin"),
+						KubeadminPassword: api.Ptr("secret"),
+					},
+				}, nil)
+				return
+			},
+		},
+	}
+
+	stdout, err := runTest(t, server, "cluster", "kubeconfig", "-g", testResourceGroupName, "-n", testClusterName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stdout != "apiVersion: v1\n" {
+		t.Errorf("Expected the kubeconfig file, got:\n%s", stdout)
+	}
+
+	stdout, err = runTest(t, server, "cluster", "admin-credentials", "-g", testResourceGroupName, "-n", testClusterName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout, "kubeadmin") || !strings.Contains(stdout, "secret") {
+		t.Errorf("Expected the credentials, got:\n%s", stdout)
+	}
+}
+
+func TestNodePoolUpdate(t *testing.T) {
+	var received generated.HcpOpenShiftClusterNodePoolResourceUpdate
+
+	server := &fake.ServerFactory{
+		NodePoolsServer: fake.NodePoolsServer{
+			BeginUpdate: func(ctx context.Context, resourceGroupName string, hcpOpenShiftClusterName string, nodePoolName string, properties generated.HcpOpenShiftClusterNodePoolResourceUpdate, options *generated.NodePoolsClientBeginUpdateOptions) (resp azfake.PollerResponder[generated.NodePoolsClientUpdateResponse], errResp azfake.ErrorResponder) {
+				received = properties
+				resp.SetTerminalResponse(http.StatusOK, generated.NodePoolsClientUpdateResponse{HcpOpenShiftClusterNodePoolResource: newTestNodePool()}, nil)
+				return
+			},
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "nodepool.json")
+	if err := os.WriteFile(path, []byte(`{"properties": {"replicas": 3}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, err := runTest(t, server, "nodepool", "update", "-g", testResourceGroupName, "-c", testClusterName, "-n", testNodePoolName, "-f", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if value(received.Properties.Replicas) != 3 {
+		t.Errorf("Unexpected node pool update sent: %+v", received.Properties)
+	}
+
+	for _, expected := range []string{testNodePoolName, testClusterName, "Standard_D8s_v3", "3", "Succeeded"} {
+		if !strings.Contains(stdout, expected) {
+			t.Errorf("Expected output to contain '%s', got:\n%s", expected, stdout)
+		}
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"Missing command", []string{"cluster"}},
+		{"Unknown resource", []string{"machinepool", "list"}},
+		{"Unknown command", []string{"nodepool", "kubeconfig"}},
+		{"Missing name", []string{"cluster", "get", "-g", testResourceGroupName}},
+		{"Missing file", []string{"cluster", "create", "-g", testResourceGroupName, "-n", testClusterName}},
+		{"Missing cluster", []string{"nodepool", "list", "-g", testResourceGroupName}},
+		{"Unsupported output", []string{"-output", "yaml", "cluster", "list"}},
+		{"Unexpected argument", []string{"cluster", "list", "extra"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := runTest(t, &fake.ServerFactory{}, tt.args...); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"sigs.k8s.io/yaml"
+)
+
+// readInput decodes a YAML or JSON file into v, which is one of the
+// generated SDK models. A path of "-" reads standard input.
+func readInput(path string, stdin io.Reader, v any) error {
+	var data []byte
+	var err error
+
+	if path == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return err
+	}
+
+	// YAML is a superset of JSON, so this accepts either. The SDK
+	// models only implement JSON unmarshalling.
+	data, err = yaml.YAMLToJSON(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	if err = json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+// hcpctl manages ARO HCP clusters and node pools through the generated
+// SDK clients, against Azure or a local frontend. See the frontend README
+// for usage.
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stdout, os.Stderr, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		stop()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	azcorearm "github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+
+	"github.com/Azure/ARO-HCP/internal/api/v20240610preview/generated"
+)
+
+// Output formats
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// printJSON writes v, which is a generated SDK model or a slice of them,
+// as indented JSON.
+func printJSON(w io.Writer, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// printTable writes rows as aligned columns under a header.
+func printTable(w io.Writer, header []string, rows [][]string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+func printClusters(w io.Writer, output string, clusters []*generated.HcpOpenShiftClusterResource) error {
+	if output == outputJSON {
+		return printJSON(w, clusters)
+	}
+
+	rows := make([][]string, 0, len(clusters))
+	for _, cluster := range clusters {
+		var version, state string
+		if properties := cluster.Properties; properties != nil {
+			if properties.Spec != nil && properties.Spec.Version != nil {
+				version = value(properties.Spec.Version.ID)
+			}
+			state = string(value(properties.ProvisioningState))
+		}
+		rows = append(rows, []string{
+			value(cluster.Name),
+			resourceGroupName(cluster.ID),
+			value(cluster.Location),
+			version,
+			state,
+		})
+	}
+
+	return printTable(w, []string{"NAME", "RESOURCE GROUP", "LOCATION", "VERSION", "STATE"}, rows)
+}
+
+func printNodePools(w io.Writer, output string, nodePools []*generated.HcpOpenShiftClusterNodePoolResource) error {
+	if output == outputJSON {
+		return printJSON(w, nodePools)
+	}
+
+	rows := make([][]string, 0, len(nodePools))
+	for _, nodePool := range nodePools {
+		var version, vmSize, replicas, state string
+		if properties := nodePool.Properties; properties != nil {
+			if spec := properties.Spec; spec != nil {
+				if spec.Version != nil {
+					version = value(spec.Version.ID)
+				}
+				if spec.Platform != nil {
+					vmSize = value(spec.Platform.VMSize)
+				}
+				if spec.Replicas != nil {
+					replicas = fmt.Sprint(*spec.Replicas)
+				}
+			}
+			state = string(value(properties.ProvisioningState))
+		}
+		rows = append(rows, []string{
+			value(nodePool.Name),
+			clusterName(nodePool.ID),
+			version,
+			vmSize,
+			replicas,
+			state,
+		})
+	}
+
+	return printTable(w, []string{"NAME", "CLUSTER", "VERSION", "VM SIZE", "REPLICAS", "STATE"}, rows)
+}
+
+func printCredentials(w io.Writer, output string, credentials generated.HcpOpenShiftClusterCredentials) error {
+	if output == outputJSON {
+		return printJSON(w, credentials)
+	}
+
+	return printTable(w, []string{"USERNAME", "PASSWORD"}, [][]string{
+		{value(credentials.KubeadminUsername), value(credentials.KubeadminPassword)},
+	})
+}
+
+// printKubeconfig writes the kubeconfig file itself in table format, so
+// the output can be redirected to a file.
+func printKubeconfig(w io.Writer, output string, kubeconfig generated.HcpOpenShiftClusterKubeconfig) error {
+	if output == outputJSON {
+		return printJSON(w, kubeconfig)
+	}
+
+	data := value(kubeconfig.Kubeconfig)
+	if !strings.HasSuffix(data, "\n") {
+		data += "\n"
+	}
+	_, err := io.WriteString(w, data)
+	return err
+}
+
+// value returns the value p points to, or the zero value if p is nil.
+func value[T any](p *T) T {
+	var zero T
+	if p == nil {
+		return zero
+	}
+	return *p
+}
+
+func resourceGroupName(id *string) string {
+	resourceID, err := azcorearm.ParseResourceID(value(id))
+	if err != nil {
+		return ""
+	}
+	return resourceID.ResourceGroupName
+}
+
+func clusterName(id *string) string {
+	resourceID, err := azcorearm.ParseResourceID(value(id))
+	if err != nil || resourceID.Parent == nil {
+		return ""
+	}
+	return resourceID.Parent.Name
+}
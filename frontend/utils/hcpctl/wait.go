@@ -0,0 +1,69 @@
+package main
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+// wait polls a long-running operation until it completes, reporting its
+// status to w after each poll, and returns the final result.
+func wait[T any](ctx context.Context, w io.Writer, poller *runtime.Poller[T], description string, interval time.Duration) (T, error) {
+	start := time.Now()
+
+	for !poller.Done() {
+		response, err := poller.Poll(ctx)
+		if err != nil {
+			var zero T
+			return zero, err
+		}
+		if poller.Done() {
+			break
+		}
+
+		fmt.Fprintf(w, "Waiting for %s: %s (%s elapsed)\n", description, operationStatus(response), time.Since(start).Round(time.Second))
+
+		select {
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+
+	result, err := poller.Result(ctx)
+	if err == nil {
+		fmt.Fprintf(w, "Completed %s after %s\n", description, time.Since(start).Round(time.Second))
+	}
+	return result, err
+}
+
+// operationStatus returns the status reported by an asynchronous operation
+// status response, or the provisioning state of a resource.
+func operationStatus(response *http.Response) string {
+	var body struct {
+		Status     string `json:"status"`
+		Properties struct {
+			ProvisioningState string `json:"provisioningState"`
+		} `json:"properties"`
+	}
+
+	if data, err := runtime.Payload(response); err == nil && json.Unmarshal(data, &body) == nil {
+		switch {
+		case body.Status != "":
+			return body.Status
+		case body.Properties.ProvisioningState != "":
+			return body.Properties.ProvisioningState
+		}
+	}
+
+	return response.Status
+}
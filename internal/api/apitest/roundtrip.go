@@ -0,0 +1,243 @@
+// Package apitest provides reusable test harnesses for API version
+// conversions. Every function here accepts an api.Version so that each
+// registered API version receives the same coverage, and returns an
+// error describing the first failure for the caller to report.
+package apitest
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"fmt"
+	"reflect"
+	"runtime/debug"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	fuzz "github.com/google/gofuzz"
+	configv1 "github.com/openshift/api/config/v1"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+)
+
+// DefaultIterations is a reasonable number of fuzz iterations for unit tests.
+const DefaultIterations = 500
+
+// newVersionedFuzzer returns a fuzzer for versioned API models. Generated
+// API structs are all pointer fields, so a high nil chance exercises the
+// "field absent from request body" code paths of Normalize.
+func newVersionedFuzzer(seed int64) *fuzz.Fuzzer {
+	return fuzz.NewWithSeed(seed).NilChance(0.3).NumElements(0, 3)
+}
+
+// newInternalFuzzer returns a fuzzer for internal API models. Internal
+// models are only ever produced by Normalize, so constrain the fuzzer to
+// values Normalize can produce.
+func newInternalFuzzer(seed int64) *fuzz.Fuzzer {
+	return fuzz.NewWithSeed(seed).NilChance(0).NumElements(0, 3).Funcs(
+		func(rule *configv1.TokenClaimValidationRule, c fuzz.Continue) {
+			rule.Type = configv1.TokenValidationRuleTypeRequiredClaim
+			rule.RequiredClaim = &configv1.TokenRequiredClaim{}
+			c.Fuzz(rule.RequiredClaim)
+		},
+	)
+}
+
+// newVersionedCluster returns a new zero-valued instance of the
+// versioned cluster type for the given API version.
+func newVersionedCluster(version api.Version) api.VersionedHCPOpenShiftCluster {
+	t := reflect.TypeOf(version.NewHCPOpenShiftCluster(nil))
+	if t.Kind() != reflect.Pointer {
+		panic(fmt.Sprintf("%s: versioned cluster type %s is not a pointer", version, t))
+	}
+	return reflect.New(t.Elem()).Interface().(api.VersionedHCPOpenShiftCluster)
+}
+
+// FuzzClusterNormalize fills the versioned cluster model of the given API
+// version with random values and verifies that Normalize never panics.
+func FuzzClusterNormalize(version api.Version, iterations int) error {
+	for i := 0; i < iterations; i++ {
+		seed := int64(i)
+
+		versioned := newVersionedCluster(version)
+		newVersionedFuzzer(seed).Fuzz(versioned)
+
+		if err := recoverPanic(func() {
+			versioned.Normalize(&api.HCPOpenShiftCluster{})
+		}); err != nil {
+			return fmt.Errorf("%s: Normalize with seed %d: %w", version, seed, err)
+		}
+	}
+	return nil
+}
+
+// recoverPanic calls f and returns an error describing
+// any panic, including the stack trace where it occurred.
+func recoverPanic(f func()) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("panic: %v\n%s", e, debug.Stack())
+		}
+	}()
+	f()
+	return nil
+}
+
+// RoundTripCluster fills the internal cluster model with random values,
+// converts it to the versioned model of the given API version and back,
+// and verifies that every field which can be set by clients survives.
+//
+// Read-only fields are excluded since clients cannot set them, write-only
+// fields are excluded since they are never returned, and fields unavailable
+// in the API version are excluded since they cannot be represented.
+func RoundTripCluster(version api.Version, iterations int) error {
+	for i := 0; i < iterations; i++ {
+		seed := int64(i)
+
+		original := &api.HCPOpenShiftCluster{}
+		newInternalFuzzer(seed).Fuzz(original)
+
+		roundTripped := &api.HCPOpenShiftCluster{}
+		if err := recoverPanic(func() {
+			version.NewHCPOpenShiftCluster(original).Normalize(roundTripped)
+		}); err != nil {
+			return fmt.Errorf("%s: round-trip with seed %d: %w", version, seed, err)
+		}
+
+		ClearInvisibleFields(original, version.ClusterStructTagMap())
+		ClearInvisibleFields(roundTripped, version.ClusterStructTagMap())
+
+		if diff := cmp.Diff(original, roundTripped, cmpopts.EquateEmpty()); diff != "" {
+			return fmt.Errorf("%s: round-trip with seed %d lost data (-original +round-tripped):\n%s", version, seed, diff)
+		}
+	}
+	return nil
+}
+
+// RoundTripExternalAuth fills the internal cluster model with random values,
+// converts it to the versioned model of the given API version and back, and
+// verifies that the external auth configuration survives.
+//
+// External auth configuration is read-only, so RoundTripCluster excludes it,
+// but it is converted with every response and must be represented faithfully.
+// Provider names are excluded since no API version represents them, and
+// client secrets are excluded since they are write-only.
+func RoundTripExternalAuth(version api.Version, iterations int) error {
+	for i := 0; i < iterations; i++ {
+		seed := int64(i)
+
+		original := &api.HCPOpenShiftCluster{}
+		newInternalFuzzer(seed).Fuzz(original)
+
+		roundTripped := &api.HCPOpenShiftCluster{}
+		if err := recoverPanic(func() {
+			version.NewHCPOpenShiftCluster(original).Normalize(roundTripped)
+		}); err != nil {
+			return fmt.Errorf("%s: round-trip with seed %d: %w", version, seed, err)
+		}
+
+		if diff := cmp.Diff(
+			original.Properties.Spec.ExternalAuth.ExternalAuths,
+			roundTripped.Properties.Spec.ExternalAuth.ExternalAuths,
+			cmpopts.EquateEmpty(),
+			cmpopts.IgnoreFields(configv1.OIDCProvider{}, "Name"),
+			cmpopts.IgnoreFields(configv1.OIDCClientConfig{}, "ClientSecret"),
+		); diff != "" {
+			return fmt.Errorf("%s: external auth round-trip with seed %d lost data (-original +round-tripped):\n%s", version, seed, diff)
+		}
+	}
+	return nil
+}
+
+// PreserveCluster fills the internal cluster model with random values to
+// simulate a stored cluster, then simulates a client using the given API
+// version reading the cluster and writing it back unmodified. It verifies
+// that no field the client could not see or set is lost in the process.
+//
+// Read-only fields are excluded since the backend owns them.
+func PreserveCluster(version api.Version, iterations int) error {
+	for i := 0; i < iterations; i++ {
+		seed := int64(i)
+
+		stored := &api.HCPOpenShiftCluster{}
+		newInternalFuzzer(seed).Fuzz(stored)
+
+		updated := &api.HCPOpenShiftCluster{}
+		if err := recoverPanic(func() {
+			version.NewHCPOpenShiftCluster(stored).Normalize(updated)
+		}); err != nil {
+			return fmt.Errorf("%s: update with seed %d: %w", version, seed, err)
+		}
+		updated.PreserveWriteOnly(stored)
+		updated.PreserveUnavailable(stored, version)
+
+		clearFields(reflect.ValueOf(stored), version.ClusterStructTagMap(), "", api.VisibilityDefault, api.VisibilityFlags.ReadOnly)
+		clearFields(reflect.ValueOf(updated), version.ClusterStructTagMap(), "", api.VisibilityDefault, api.VisibilityFlags.ReadOnly)
+
+		if diff := cmp.Diff(stored, updated, cmpopts.EquateEmpty()); diff != "" {
+			return fmt.Errorf("%s: update with seed %d lost data (-stored +updated):\n%s", version, seed, diff)
+		}
+	}
+	return nil
+}
+
+// ClearInvisibleFields sets read-only, write-only and unavailable fields
+// in v to their zero values, leaving only fields whose values clients can
+// both set and read back. v must be a pointer so it can be modified.
+func ClearInvisibleFields(v any, structTagMap api.StructTagMap) {
+	clearFields(reflect.ValueOf(v), structTagMap, "", api.VisibilityDefault, func(flags api.VisibilityFlags) bool {
+		return flags.ReadOnly() || flags.WriteOnly() || flags.Unavailable()
+	})
+}
+
+// clearFields sets fields in v to their zero values wherever shouldClear
+// returns true for the field's visibility flags.
+func clearFields(v reflect.Value, structTagMap api.StructTagMap, mapKey string, implicitVisibility api.VisibilityFlags, shouldClear func(api.VisibilityFlags) bool) {
+	flags, ok := api.GetVisibilityFlags(structTagMap[mapKey])
+	if !ok {
+		flags = implicitVisibility
+	}
+
+	if shouldClear(flags) {
+		if v.CanSet() {
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			clearFields(v.Elem(), structTagMap, mapKey, flags, shouldClear)
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			clearFields(v.Index(i), structTagMap, mapKey, flags, shouldClear)
+		}
+
+	case reflect.Map:
+		// Map values are not addressable, so only
+		// pointer values can be modified in place.
+		iter := v.MapRange()
+		for iter.Next() {
+			clearFields(iter.Value(), structTagMap, mapKey, flags, shouldClear)
+		}
+
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			structField := v.Type().Field(i)
+			if !structField.IsExported() {
+				continue
+			}
+			mapKeyNext := mapKey
+			if !structField.Anonymous {
+				if mapKeyNext != "" {
+					mapKeyNext += "."
+				}
+				mapKeyNext += structField.Name
+			}
+			clearFields(v.Field(i), structTagMap, mapKeyNext, flags, shouldClear)
+		}
+	}
+}
@@ -6,25 +6,37 @@ package arm
 import (
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"runtime/debug"
+
+	"github.com/google/uuid"
 )
 
 // CloudError codes
 const (
-	CloudErrorCodeInternalServerError    = "InternalServerError"
-	CloudErrorCodeInvalidParameter       = "InvalidParameter"
-	CloudErrorCodeInvalidRequestContent  = "InvalidRequestContent"
-	CloudErrorCodeInvalidResource        = "InvalidResource"
-	CloudErrorCodeInvalidResourceType    = "InvalidResourceType"
-	CloudErrorCodeMultipleErrorsOccurred = "MultipleErrorsOccurred"
-	CloudErrorCodeUnsupportedMediaType   = "UnsupportedMediaType"
-	CloudErrorCodeNotFound               = "NotFound"
-	CloudErrorInvalidSubscriptionState   = "InvalidSubscriptionState"
-	CloudErrorCodeResourceNotFound       = "ResourceNotFound"
-	CloudErrorCodeResourceGroupNotFound  = "ResourceGroupNotFound"
-	CloudErrorCodeInvalidSubscriptionID  = "InvalidSubscriptionID"
-	CloudErrorInvalidResourceName        = "InvalidResourceName"
-	CloudErrorInvalidResourceGroupName   = "InvalidResourceGroupName"
+	CloudErrorCodeInternalServerError                 = "InternalServerError"
+	CloudErrorCodeInvalidParameter                    = "InvalidParameter"
+	CloudErrorCodeInvalidRequestContent               = "InvalidRequestContent"
+	CloudErrorCodeInvalidResource                     = "InvalidResource"
+	CloudErrorCodeInvalidResourceType                 = "InvalidResourceType"
+	CloudErrorCodeMultipleErrorsOccurred              = "MultipleErrorsOccurred"
+	CloudErrorCodeUnsupportedMediaType                = "UnsupportedMediaType"
+	CloudErrorCodeNotFound                            = "NotFound"
+	CloudErrorCodeMethodNotAllowed                    = "MethodNotAllowed"
+	CloudErrorCodeInvalidSubscriptionState            = "InvalidSubscriptionState"
+	CloudErrorCodeResourceNotFound                    = "ResourceNotFound"
+	CloudErrorCodeResourceGroupNotFound               = "ResourceGroupNotFound"
+	CloudErrorCodeInvalidSubscriptionID               = "InvalidSubscriptionID"
+	CloudErrorCodeInvalidResourceName                 = "InvalidResourceName"
+	CloudErrorCodeInvalidResourceGroupName            = "InvalidResourceGroupName"
+	CloudErrorCodeInvalidLinkedResource               = "InvalidLinkedResource"
+	CloudErrorCodeLinkedAuthorizationFailed           = "LinkedAuthorizationFailed"
+	CloudErrorCodeSubscriptionNotRegisteredForFeature = "SubscriptionNotRegisteredForFeature"
+	CloudErrorCodeSubscriptionOfferNotSupported       = "SubscriptionOfferNotSupported"
+	CloudErrorCodeQuotaExceeded                       = "QuotaExceeded"
+	CloudErrorCodeLocationNotAvailableForResourceType = "LocationNotAvailableForResourceType"
+	CloudErrorCodeAvailabilityZoneNotSupported        = "AvailabilityZoneNotSupported"
 )
 
 // CloudError represents a complete resource provider error.
@@ -60,6 +72,37 @@ type CloudErrorBody struct {
 
 	// A list of additional details about the error.
 	Details []CloudErrorBody `json:"details,omitempty"`
+
+	// A list of additional information about the error.
+	AdditionalInfo []CloudErrorAdditionalInfo `json:"additionalInfo,omitempty"`
+}
+
+// CloudErrorAdditionalInfo represents additional information about a
+// resource provider error, matching the ErrorAdditionalInfo API model.
+type CloudErrorAdditionalInfo struct {
+	// The additional info type.
+	Type string `json:"type,omitempty"`
+
+	// The additional info.
+	Info any `json:"info,omitempty"`
+}
+
+// FailureCause indicates whether the client or
+// the service is responsible for an error.
+type FailureCause string
+
+const (
+	FailureCauseClient  FailureCause = "client"
+	FailureCauseService FailureCause = "service"
+)
+
+// FailureCause returns the party responsible for the error,
+// which is the service for 5xx status codes and otherwise the client.
+func (err *CloudError) FailureCause() FailureCause {
+	if err.StatusCode >= http.StatusInternalServerError {
+		return FailureCauseService
+	}
+	return FailureCauseClient
 }
 
 func (body *CloudErrorBody) String() string {
@@ -94,6 +137,25 @@ func NewCloudError(statusCode int, code, target, format string, a ...interface{}
 	}
 }
 
+// NewContentValidationError returns a CloudError with a 400 Bad Request
+// status code for request content validation failures, or nil if there
+// are none. A single failure is promoted out of the details.
+func NewContentValidationError(details []CloudErrorBody) *CloudError {
+	switch len(details) {
+	case 0:
+		return nil
+	case 1:
+		return &CloudError{
+			StatusCode:     http.StatusBadRequest,
+			CloudErrorBody: &details[0],
+		}
+	default:
+		cloudError := ErrorMultipleErrorsOccurred.New("")
+		cloudError.Details = details
+		return cloudError
+	}
+}
+
 // WriteError constructs and writes a CloudError to the given ResponseWriter
 func WriteError(w http.ResponseWriter, statusCode int, code, target, format string, a ...interface{}) {
 	WriteCloudError(w, NewCloudError(statusCode, code, target, format, a...))
@@ -103,36 +165,46 @@ func WriteError(w http.ResponseWriter, statusCode int, code, target, format stri
 func WriteCloudError(w http.ResponseWriter, err *CloudError) {
 	w.Header()["Content-Type"] = []string{"application/json"}
 	w.Header()[HeaderNameErrorCode] = []string{err.Code}
+	w.Header()[HeaderNameFailureCause] = []string{string(err.FailureCause())}
 	w.WriteHeader(err.StatusCode)
 	encoder := json.NewEncoder(w)
 	encoder.SetIndent("", "    ")
 	_ = encoder.Encode(err)
 }
 
-// WriteInternalServerError writes an internal server error to the given ResponseWriter
-func WriteInternalServerError(w http.ResponseWriter) {
-	WriteError(
-		w, http.StatusInternalServerError,
-		CloudErrorCodeInternalServerError, "",
-		"Internal server error.")
+// NewInternalServerError returns an internal server error with a new unique
+// error ID. The error ID appears in the message and additional info so that
+// failures reported by customers can be traced to service logs.
+func NewInternalServerError() (*CloudError, string) {
+	errorID := uuid.New().String()
+	cloudError := ErrorInternalServerError.New("", errorID)
+	cloudError.AdditionalInfo = []CloudErrorAdditionalInfo{{
+		Type: AdditionalInfoTypeErrorID,
+		Info: map[string]string{"errorId": errorID},
+	}}
+	return cloudError, errorID
+}
+
+// WriteInternalServerError logs err with a stack trace under a new error ID
+// and writes an internal server error carrying that error ID to the given
+// ResponseWriter. The cause is never exposed to the client.
+func WriteInternalServerError(w http.ResponseWriter, logger *slog.Logger, err error) {
+	cloudError, errorID := NewInternalServerError()
+	logger.Error("internal server error",
+		"error_id", errorID,
+		"error", err,
+		"stack", string(debug.Stack()))
+	WriteCloudError(w, cloudError)
 }
 
 // NewUnmarshalCloudError creates an appropriate CloudError for JSON unmarshaling errors
 func NewUnmarshalCloudError(err error) *CloudError {
-	const message = "The request content was invalid and could not be deserialized: %q"
-
 	switch err := err.(type) {
 	case *CloudError:
 		return err
 	case *json.UnmarshalTypeError:
-		return NewCloudError(
-			http.StatusBadRequest,
-			CloudErrorCodeInvalidRequestContent,
-			err.Field, message, err)
+		return ErrorInvalidRequestContent.New(err.Field, err)
 	default:
-		return NewCloudError(
-			http.StatusBadRequest,
-			CloudErrorCodeInvalidRequestContent,
-			"", message, err)
+		return ErrorInvalidRequestContent.New("", err)
 	}
 }
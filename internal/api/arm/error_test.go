@@ -1,6 +1,15 @@
 package arm
 
-import "testing"
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
 
 func TestCloudErrorBody_String(t *testing.T) {
 	tests := []struct {
@@ -58,3 +67,68 @@ func TestCloudErrorBody_String(t *testing.T) {
 		})
 	}
 }
+
+func TestWriteCloudErrorFailureCause(t *testing.T) {
+	tests := []struct {
+		name        string
+		statusCode  int
+		expectCause FailureCause
+	}{
+		{
+			name:        "Client error",
+			statusCode:  http.StatusBadRequest,
+			expectCause: FailureCauseClient,
+		},
+		{
+			name:        "Service error",
+			statusCode:  http.StatusServiceUnavailable,
+			expectCause: FailureCauseService,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			writer := httptest.NewRecorder()
+
+			WriteCloudError(writer, NewCloudError(test.statusCode, "code", "", "message"))
+
+			if cause := writer.Header().Get(HeaderNameFailureCause); cause != string(test.expectCause) {
+				t.Errorf("expected failure cause %q, got %q", test.expectCause, cause)
+			}
+		})
+	}
+}
+
+func TestWriteInternalServerError(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+	writer := httptest.NewRecorder()
+
+	WriteInternalServerError(writer, logger, errors.New("secret cause"))
+
+	if writer.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, writer.Code)
+	}
+	if strings.Contains(writer.Body.String(), "secret cause") {
+		t.Errorf("expected the cause to be hidden from the client, got %s", writer.Body.String())
+	}
+
+	var response CloudError
+	if err := json.Unmarshal(writer.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.AdditionalInfo) != 1 || response.AdditionalInfo[0].Type != AdditionalInfoTypeErrorID {
+		t.Fatalf("expected error ID additional info, got %v", response.AdditionalInfo)
+	}
+	errorID := response.AdditionalInfo[0].Info.(map[string]any)["errorId"].(string)
+	if !strings.Contains(response.Message, errorID) {
+		t.Errorf("expected message to contain error ID %s, got %q", errorID, response.Message)
+	}
+
+	var entry map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["error_id"] != errorID || entry["error"] != "secret cause" || entry["stack"] == "" {
+		t.Errorf("expected log entry with error ID, cause and stack, got %v", entry)
+	}
+}
@@ -0,0 +1,185 @@
+package arm
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// AdditionalInfoTypeErrorID is the additional info type of
+// the unique error ID attached to internal server errors.
+const AdditionalInfoTypeErrorID = "ErrorID"
+
+// ErrorTemplate is an entry in the error catalog. It defines the code,
+// HTTP status code and message format for one kind of error.
+type ErrorTemplate struct {
+	Code       string
+	StatusCode int
+	Message    string
+}
+
+// New returns a CloudError from the template with its message formatted
+// using a.
+func (t ErrorTemplate) New(target string, a ...any) *CloudError {
+	return NewCloudError(t.StatusCode, t.Code, target, t.Message, a...)
+}
+
+// Body returns a CloudErrorBody from the template with its message formatted
+// using a, for use as an error detail.
+func (t ErrorTemplate) Body(target string, a ...any) CloudErrorBody {
+	return CloudErrorBody{
+		Code:    t.Code,
+		Message: fmt.Sprintf(t.Message, a...),
+		Target:  target,
+	}
+}
+
+// Write writes a CloudError from the template to the given ResponseWriter.
+func (t ErrorTemplate) Write(w http.ResponseWriter, target string, a ...any) {
+	WriteCloudError(w, t.New(target, a...))
+}
+
+// The error catalog. Each message format documents its arguments through
+// the surrounding text; the failure cause follows from the status code.
+var (
+	// Request errors
+
+	ErrorInternalServerError = ErrorTemplate{
+		CloudErrorCodeInternalServerError, http.StatusInternalServerError,
+		"An internal server error occurred. Error ID: '%s'.",
+	}
+	ErrorNotFound = ErrorTemplate{
+		CloudErrorCodeNotFound, http.StatusNotFound,
+		"The requested path could not be found.",
+	}
+	ErrorMethodNotAllowed = ErrorTemplate{
+		CloudErrorCodeMethodNotAllowed, http.StatusMethodNotAllowed,
+		"The HTTP method '%s' is not supported for this path. Supported methods are: %s.",
+	}
+	ErrorUnsupportedMediaType = ErrorTemplate{
+		CloudErrorCodeUnsupportedMediaType, http.StatusUnsupportedMediaType,
+		"The content media type '%s' is not supported. Only 'application/json' is supported.",
+	}
+	ErrorMissingParameter = ErrorTemplate{
+		CloudErrorCodeInvalidParameter, http.StatusBadRequest,
+		"The request is missing required parameter '%s'.",
+	}
+	ErrorInvalidQueryParameter = ErrorTemplate{
+		CloudErrorCodeInvalidParameter, http.StatusBadRequest,
+		"Invalid value '%s' for query parameter '%s' (%s)",
+	}
+	ErrorUnsupportedFilter = ErrorTemplate{
+		CloudErrorCodeInvalidParameter, http.StatusBadRequest,
+		"Unsupported %s expression '%s'. Supported expressions are %s",
+	}
+	ErrorUnsupportedAPIVersion = ErrorTemplate{
+		CloudErrorCodeInvalidResourceType, http.StatusBadRequest,
+		"The resource type '%s' could not be found API version '%s'.",
+	}
+	ErrorInvalidResourceID = ErrorTemplate{
+		CloudErrorCodeInvalidParameter, http.StatusBadRequest,
+		"%v",
+	}
+	ErrorInvalidSubscriptionID = ErrorTemplate{
+		CloudErrorCodeInvalidSubscriptionID, http.StatusBadRequest,
+		"The provided subscription identifier '%s' is malformed or invalid.",
+	}
+	ErrorInvalidResourceGroupName = ErrorTemplate{
+		CloudErrorCodeInvalidResourceGroupName, http.StatusBadRequest,
+		"Resource group '%s' is invalid.",
+	}
+	ErrorInvalidResourceName = ErrorTemplate{
+		CloudErrorCodeInvalidResourceName, http.StatusBadRequest,
+		"The Resource '%s/%s' under resource group '%s' is invalid.",
+	}
+	ErrorResourceGroupNotFound = ErrorTemplate{
+		CloudErrorCodeResourceGroupNotFound, http.StatusNotFound,
+		"Resource group '%s' could not be found.",
+	}
+	ErrorResourceNotFound = ErrorTemplate{
+		CloudErrorCodeResourceNotFound, http.StatusNotFound,
+		"The Resource '%s/%s' under resource group '%s' was not found.",
+	}
+
+	// Request content errors
+
+	ErrorInvalidResource = ErrorTemplate{
+		CloudErrorCodeInvalidResource, http.StatusBadRequest,
+		"The resource definition is invalid.",
+	}
+	ErrorInvalidRequestContent = ErrorTemplate{
+		CloudErrorCodeInvalidRequestContent, http.StatusBadRequest,
+		"The request content was invalid and could not be deserialized: %q",
+	}
+	ErrorMultipleErrorsOccurred = ErrorTemplate{
+		CloudErrorCodeMultipleErrorsOccurred, http.StatusBadRequest,
+		"Content validation failed on multiple fields",
+	}
+	ErrorMultiplePreflightErrors = ErrorTemplate{
+		CloudErrorCodeMultipleErrorsOccurred, http.StatusBadRequest,
+		"Preflight validation failed on multiple resources",
+	}
+
+	// Subscription errors
+
+	ErrorUnregisteredSubscription = ErrorTemplate{
+		CloudErrorCodeInvalidSubscriptionState, http.StatusBadRequest,
+		"Request is not allowed in unregistered subscription '%s'.",
+	}
+	ErrorInvalidSubscriptionState = ErrorTemplate{
+		CloudErrorCodeInvalidSubscriptionState, http.StatusBadRequest,
+		"Request is not allowed in subscription in state '%s'.",
+	}
+	ErrorSubscriptionStateConflict = ErrorTemplate{
+		CloudErrorCodeInvalidSubscriptionState, http.StatusConflict,
+		"Request is not allowed in subscription in state '%s'.",
+	}
+	ErrorSubscriptionNotRegisteredForFeature = ErrorTemplate{
+		CloudErrorCodeSubscriptionNotRegisteredForFeature, http.StatusBadRequest,
+		"Subscription '%s' is not registered for feature '%s' required to use %s.",
+	}
+	ErrorSubscriptionOfferNotSupported = ErrorTemplate{
+		CloudErrorCodeSubscriptionOfferNotSupported, http.StatusBadRequest,
+		"Subscription '%s' has offer type '%s', which is not eligible to create %s.",
+	}
+	ErrorQuotaExceeded = ErrorTemplate{
+		CloudErrorCodeQuotaExceeded, http.StatusBadRequest,
+		"Operation would exceed the %s quota in location '%s' for subscription '%s'. Limit: %d, current usage: %d, additional required: %d.",
+	}
+
+	// Location errors
+
+	ErrorLocationNotAvailableForResourceType = ErrorTemplate{
+		CloudErrorCodeLocationNotAvailableForResourceType, http.StatusBadRequest,
+		"The provided location '%s' is not available for resource type '%s'. List of available regions for the resource type is '%s'.",
+	}
+	ErrorAvailabilityZoneNotSupported = ErrorTemplate{
+		CloudErrorCodeAvailabilityZoneNotSupported, http.StatusBadRequest,
+		"The availability zone '%s' is not supported in location '%s' for subscription '%s'. Supported availability zones are: %s",
+	}
+
+	// Linked resource errors
+
+	ErrorLinkedResourceNotFound = ErrorTemplate{
+		CloudErrorCodeInvalidLinkedResource, http.StatusBadRequest,
+		"The %s '%s' could not be found.",
+	}
+	ErrorLinkedAuthorizationFailed = ErrorTemplate{
+		CloudErrorCodeLinkedAuthorizationFailed, http.StatusBadRequest,
+		"The resource provider does not have permission to read the %s '%s'.",
+	}
+	ErrorLinkedResourceLocationMismatch = ErrorTemplate{
+		CloudErrorCodeInvalidLinkedResource, http.StatusBadRequest,
+		"The %s '%s' is in location '%s' but the cluster is in location '%s'.",
+	}
+	ErrorSubnetDelegated = ErrorTemplate{
+		CloudErrorCodeInvalidLinkedResource, http.StatusBadRequest,
+		"The subnet '%s' must not be delegated, but is delegated to '%s'.",
+	}
+	ErrorSubnetNetworkSecurityGroupMismatch = ErrorTemplate{
+		CloudErrorCodeInvalidLinkedResource, http.StatusBadRequest,
+		"The subnet '%s' is associated with network security group '%s' instead of '%s'.",
+	}
+)
@@ -0,0 +1,40 @@
+package arm
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestErrorTemplate(t *testing.T) {
+	template := ErrorTemplate{
+		Code:       "TestCode",
+		StatusCode: http.StatusConflict,
+		Message:    "Resource '%s' is %s.",
+	}
+
+	cloudError := template.New("target", "name", "busy")
+	if cloudError.StatusCode != http.StatusConflict || cloudError.Code != "TestCode" || cloudError.Target != "target" {
+		t.Errorf("unexpected error %v", cloudError)
+	}
+	if cloudError.Message != "Resource 'name' is busy." {
+		t.Errorf("unexpected message %q", cloudError.Message)
+	}
+
+	body := template.Body("target", "name", "busy")
+	if body.Code != cloudError.Code || body.Message != cloudError.Message || body.Target != cloudError.Target {
+		t.Errorf("expected body %v, got %v", cloudError.CloudErrorBody, body)
+	}
+
+	writer := httptest.NewRecorder()
+	template.Write(writer, "", "name", "busy")
+	if writer.Code != http.StatusConflict {
+		t.Errorf("expected status %d, got %d", http.StatusConflict, writer.Code)
+	}
+	if code := writer.Header().Get(HeaderNameErrorCode); code != "TestCode" {
+		t.Errorf("expected error code header %q, got %q", "TestCode", code)
+	}
+}
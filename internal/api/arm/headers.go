@@ -6,9 +6,18 @@ package arm
 const (
 	// Microsoft-specific HTTP header names
 	HeaderNameErrorCode             = "X-Ms-Error-Code"
+	HeaderNameFailureCause          = "X-Ms-Failure-Cause"
 	HeaderNameRequestID             = "X-Ms-Request-Id"
 	HeaderNameClientRequestID       = "X-Ms-Client-Request-Id"
 	HeaderNameCorrelationRequestID  = "X-Ms-Correlation-Request-Id"
 	HeaderNameReturnClientRequestID = "X-Ms-Return-Client-Request-Id"
 	HeaderNameARMResourceSystemData = "X-Ms-Arm-Resource-System-Data"
+	HeaderNameHomeTenantID          = "X-Ms-Home-Tenant-Id"
+	HeaderNameClientObjectID        = "X-Ms-Client-Object-Id"
+	HeaderNameClientPrincipalName   = "X-Ms-Client-Principal-Name"
+	HeaderNameClientTenantID        = "X-Ms-Client-Tenant-Id"
+
+	// Asynchronous operation HTTP header names
+	HeaderNameAsyncOperation = "Azure-AsyncOperation"
+	HeaderNameLocation       = "Location"
 )
@@ -6,7 +6,7 @@ package arm
 import (
 	"encoding/json"
 	"net/http"
-	"path"
+	"strings"
 )
 
 // See https://learn.microsoft.com/en-us/rest/api/datareplication/deployment-preflight/deployment-preflight?view=rest-datareplication-2021-02-16-preview&tabs=Go
@@ -42,9 +42,10 @@ type DeploymentPreflightResource struct {
 	APIVersion string `json:"apiVersion" validate:"required,api_version"`
 }
 
-// ResourceID returns a resource ID string for the resource.
-func (r *DeploymentPreflightResource) ResourceID(subscriptionID, resourceGroup string) string {
-	return path.Join("/subscriptions", subscriptionID, "resourcegroups", resourceGroup, "providers", r.Type, r.Name)
+// ResourceID returns a resource ID for the resource. Nested resource
+// types use a slash-separated name, such as "cluster/nodepool".
+func (r *DeploymentPreflightResource) ResourceID(subscriptionID, resourceGroup string) (*ResourceID, error) {
+	return NewResourceID(subscriptionID, resourceGroup, r.Type, strings.Split(r.Name, "/")...)
 }
 
 // DeploymentPreflightStatus is used in a DeploymentPreflightResponse.
@@ -79,13 +80,11 @@ func WriteDeploymentPreflightResponse(w http.ResponseWriter, preflightErrors []C
 			Error:  &preflightErrors[0],
 		}
 	default:
+		body := ErrorMultiplePreflightErrors.Body("")
+		body.Details = preflightErrors
 		response = &DeploymentPreflightResponse{
 			Status: DeploymentPreflightStatusFailed,
-			Error: &CloudErrorBody{
-				Code:    CloudErrorCodeMultipleErrorsOccurred,
-				Message: "Preflight validation failed on multiple resources",
-				Details: preflightErrors,
-			},
+			Error:  &body,
 		}
 	}
 
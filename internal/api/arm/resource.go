@@ -41,6 +41,36 @@ func (src *TrackedResource) Copy(dst *TrackedResource) {
 	dst.Tags = maps.Clone(src.Tags)
 }
 
+// ManagedServiceIdentityType is the type of managed service identity
+// assigned to a resource
+type ManagedServiceIdentityType string
+
+const (
+	ManagedServiceIdentityTypeNone                       ManagedServiceIdentityType = "None"
+	ManagedServiceIdentityTypeSystemAssigned             ManagedServiceIdentityType = "SystemAssigned"
+	ManagedServiceIdentityTypeSystemAssignedUserAssigned ManagedServiceIdentityType = "SystemAssigned,UserAssigned"
+	ManagedServiceIdentityTypeUserAssigned               ManagedServiceIdentityType = "UserAssigned"
+)
+
+// HasUserAssigned returns true if the identity type permits user assigned identities.
+func (t ManagedServiceIdentityType) HasUserAssigned() bool {
+	return t == ManagedServiceIdentityTypeUserAssigned || t == ManagedServiceIdentityTypeSystemAssignedUserAssigned
+}
+
+// ManagedServiceIdentity represents the managed service identities assigned to a resource
+type ManagedServiceIdentity struct {
+	PrincipalID            string                           `json:"principalId,omitempty"            visibility:"read"`
+	TenantID               string                           `json:"tenantId,omitempty"               visibility:"read"`
+	Type                   ManagedServiceIdentityType       `json:"type"                             validate:"required,enum_managedserviceidentitytype"`
+	UserAssignedIdentities map[string]*UserAssignedIdentity `json:"userAssignedIdentities,omitempty" validate:"dive,keys,resource_id=Microsoft.ManagedIdentity/userAssignedIdentities,endkeys"`
+}
+
+// UserAssignedIdentity represents a user assigned identity
+type UserAssignedIdentity struct {
+	ClientID    string `json:"clientId,omitempty"    visibility:"read"`
+	PrincipalID string `json:"principalId,omitempty" visibility:"read"`
+}
+
 // CreatedByType is the type of identity that created (or modified) the resource
 type CreatedByType string
 
@@ -65,20 +95,58 @@ type SystemData struct {
 func (src *SystemData) Copy(dst *SystemData) {
 	dst.CreatedBy = src.CreatedBy
 	dst.CreatedByType = src.CreatedByType
-	if src.CreatedAt == nil {
-		dst.CreatedAt = nil
-	} else {
-		t := time.Unix(src.CreatedAt.Unix(), 0)
-		dst.CreatedAt = &t
-	}
+	dst.CreatedAt = copyTime(src.CreatedAt)
 	dst.LastModifiedBy = src.LastModifiedBy
 	dst.LastModifiedByType = src.LastModifiedByType
-	if dst.LastModifiedAt == nil {
-		dst.LastModifiedAt = nil
-	} else {
-		t := time.Unix(src.LastModifiedAt.Unix(), 0)
-		dst.LastModifiedAt = &t
+	dst.LastModifiedAt = copyTime(src.LastModifiedAt)
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
 	}
+	c := *t
+	return &c
+}
+
+// MergeSystemData returns the system data to store for a resource write,
+// given the system data already stored for the resource (nil on creation)
+// and the system data ARM supplied for the request (nil if absent).
+//
+// The created fields are taken from the stored system data, or from the
+// request when creating the resource. The last modified fields are always
+// taken from the request. System data supplied by clients in request
+// bodies must not be passed to this function.
+func MergeSystemData(stored, request *SystemData) *SystemData {
+	if stored == nil && request == nil {
+		return nil
+	}
+
+	merged := &SystemData{}
+	if stored != nil {
+		stored.Copy(merged)
+	}
+
+	if request != nil {
+		if stored == nil {
+			merged.CreatedBy = request.CreatedBy
+			merged.CreatedByType = request.CreatedByType
+			merged.CreatedAt = copyTime(request.CreatedAt)
+
+			// ARM should supply the created fields on creation,
+			// but they are the same as the last modified fields.
+			if merged.CreatedAt == nil {
+				merged.CreatedBy = request.LastModifiedBy
+				merged.CreatedByType = request.LastModifiedByType
+				merged.CreatedAt = copyTime(request.LastModifiedAt)
+			}
+		}
+		merged.LastModifiedBy = request.LastModifiedBy
+		merged.LastModifiedByType = request.LastModifiedByType
+		merged.LastModifiedAt = copyTime(request.LastModifiedAt)
+	}
+
+	return merged
 }
 
 // ProvisioningState represents the asynchronous provisioning state of an ARM resource
@@ -97,3 +165,10 @@ const (
 	ProvisioningStateProvisioning ProvisioningState = "Provisioning"
 	ProvisioningStateUpdating     ProvisioningState = "Updating"
 )
+
+// PagedResponse is the response body of a list operation. NextLink,
+// if present, is the URL from which to fetch the next page of results.
+type PagedResponse struct {
+	Value    []any  `json:"value"`
+	NextLink string `json:"nextLink,omitempty"`
+}
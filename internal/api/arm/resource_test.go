@@ -0,0 +1,129 @@
+package arm
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSystemDataCopy(t *testing.T) {
+	createdAt := time.Date(2024, 1, 1, 12, 0, 0, 500, time.UTC)
+	lastModifiedAt := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+
+	src := &SystemData{
+		CreatedBy:          "creator@example.com",
+		CreatedByType:      CreatedByTypeUser,
+		CreatedAt:          &createdAt,
+		LastModifiedBy:     "modifier@example.com",
+		LastModifiedByType: CreatedByTypeApplication,
+		LastModifiedAt:     &lastModifiedAt,
+	}
+
+	// Copying into a zero value must still copy the timestamps.
+	dst := &SystemData{}
+	src.Copy(dst)
+	if diff := cmp.Diff(src, dst); diff != "" {
+		t.Errorf("Unexpected copy (-want +got):\n%s", diff)
+	}
+	if dst.CreatedAt == src.CreatedAt || dst.LastModifiedAt == src.LastModifiedAt {
+		t.Errorf("Copy shares timestamps with the source")
+	}
+
+	// Copying nil timestamps must clear them.
+	(&SystemData{}).Copy(dst)
+	if dst.CreatedAt != nil || dst.LastModifiedAt != nil {
+		t.Errorf("Expected timestamps to be cleared, got %v and %v", dst.CreatedAt, dst.LastModifiedAt)
+	}
+}
+
+func TestMergeSystemData(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	modified := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	stored := &SystemData{
+		CreatedBy:          "creator@example.com",
+		CreatedByType:      CreatedByTypeUser,
+		CreatedAt:          &created,
+		LastModifiedBy:     "creator@example.com",
+		LastModifiedByType: CreatedByTypeUser,
+		LastModifiedAt:     &created,
+	}
+
+	tests := []struct {
+		name     string
+		stored   *SystemData
+		request  *SystemData
+		expected *SystemData
+	}{
+		{
+			name:     "Create without header",
+			stored:   nil,
+			request:  nil,
+			expected: nil,
+		},
+		{
+			name:   "Create",
+			stored: nil,
+			request: &SystemData{
+				CreatedBy:          "creator@example.com",
+				CreatedByType:      CreatedByTypeUser,
+				CreatedAt:          &created,
+				LastModifiedBy:     "creator@example.com",
+				LastModifiedByType: CreatedByTypeUser,
+				LastModifiedAt:     &created,
+			},
+			expected: stored,
+		},
+		{
+			name:   "Create with only last modified fields",
+			stored: nil,
+			request: &SystemData{
+				LastModifiedBy:     "creator@example.com",
+				LastModifiedByType: CreatedByTypeUser,
+				LastModifiedAt:     &created,
+			},
+			expected: stored,
+		},
+		{
+			name:   "Update",
+			stored: stored,
+			request: &SystemData{
+				CreatedBy:          "impostor@example.com",
+				CreatedByType:      CreatedByTypeKey,
+				CreatedAt:          &modified,
+				LastModifiedBy:     "00000000-0000-0000-0000-000000000000",
+				LastModifiedByType: CreatedByTypeManagedIdentity,
+				LastModifiedAt:     &modified,
+			},
+			expected: &SystemData{
+				CreatedBy:          "creator@example.com",
+				CreatedByType:      CreatedByTypeUser,
+				CreatedAt:          &created,
+				LastModifiedBy:     "00000000-0000-0000-0000-000000000000",
+				LastModifiedByType: CreatedByTypeManagedIdentity,
+				LastModifiedAt:     &modified,
+			},
+		},
+		{
+			name:     "Update without header",
+			stored:   stored,
+			expected: stored,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := MergeSystemData(tt.stored, tt.request)
+			if diff := cmp.Diff(tt.expected, actual); diff != "" {
+				t.Errorf("Unexpected system data (-want +got):\n%s", diff)
+			}
+			if actual != nil && actual == tt.stored {
+				t.Errorf("Stored system data was returned instead of a copy")
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package arm
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	resourceIDSegmentSubscriptions  = "subscriptions"
+	resourceIDSegmentResourceGroups = "resourceGroups"
+	resourceIDSegmentProviders      = "providers"
+)
+
+// See https://learn.microsoft.com/en-us/azure/azure-resource-manager/management/resource-name-rules#microsoftresources
+var resourceGroupNameRegexp = regexp.MustCompile(`^[-\p{L}\p{N}_\.\(\)]{1,90}$`)
+
+// ResourceID represents a parsed Azure Resource Manager resource ID.
+// Subscription, resource group and nested child resource IDs such as
+//
+//	/subscriptions/{id}/resourceGroups/{rg}/providers/{namespace}/{type}/{name}/{childType}/{childName}
+//
+// are all supported. A ResourceID retains the original casing of the
+// string it was parsed from, but compares case-insensitively like ARM.
+type ResourceID struct {
+	// segments holds the path segments of the original
+	// resource ID, excluding the leading empty segment.
+	segments []string
+
+	subscriptionID    string
+	resourceGroupName string
+	providerNamespace string
+	resourceTypes     []string
+	names             []string
+}
+
+// ParseResourceID parses and validates a resource ID string.
+func ParseResourceID(id string) (*ResourceID, error) {
+	invalid := func(format string, a ...any) error {
+		return fmt.Errorf("invalid resource ID '%s': %s", id, fmt.Sprintf(format, a...))
+	}
+
+	if !strings.HasPrefix(id, "/") {
+		return nil, invalid("must begin with '/'")
+	}
+
+	segments := strings.Split(id[1:], "/")
+	for _, segment := range segments {
+		if segment == "" {
+			return nil, invalid("contains an empty segment")
+		}
+	}
+
+	if len(segments) < 2 || !strings.EqualFold(segments[0], resourceIDSegmentSubscriptions) {
+		return nil, invalid("must begin with '/%s/{subscriptionId}'", resourceIDSegmentSubscriptions)
+	}
+
+	r := &ResourceID{
+		segments:       segments,
+		subscriptionID: segments[1],
+	}
+
+	if _, err := uuid.Parse(r.subscriptionID); err != nil {
+		return nil, invalid("subscription ID '%s' is not a valid UUID", r.subscriptionID)
+	}
+
+	remaining := segments[2:]
+
+	if len(remaining) > 0 && strings.EqualFold(remaining[0], resourceIDSegmentResourceGroups) {
+		if len(remaining) < 2 {
+			return nil, invalid("missing resource group name")
+		}
+		r.resourceGroupName = remaining[1]
+		if !resourceGroupNameRegexp.MatchString(r.resourceGroupName) || strings.HasSuffix(r.resourceGroupName, ".") {
+			return nil, invalid("resource group name '%s' is not valid", r.resourceGroupName)
+		}
+		remaining = remaining[2:]
+	}
+
+	if len(remaining) > 0 {
+		if !strings.EqualFold(remaining[0], resourceIDSegmentProviders) || len(remaining) < 2 {
+			return nil, invalid("expected '/%s/{namespace}' after resource group", resourceIDSegmentProviders)
+		}
+		r.providerNamespace = remaining[1]
+		remaining = remaining[2:]
+
+		if len(remaining) == 0 {
+			return nil, invalid("missing resource type")
+		}
+		if len(remaining)%2 != 0 {
+			return nil, invalid("resource type '%s' has no name", remaining[len(remaining)-1])
+		}
+		for i := 0; i < len(remaining); i += 2 {
+			r.resourceTypes = append(r.resourceTypes, remaining[i])
+			r.names = append(r.names, remaining[i+1])
+		}
+	}
+
+	return r, nil
+}
+
+// NewResourceID formats and validates a resource ID. The resource type
+// includes the provider namespace, such as "Microsoft.Network/virtualNetworks/subnets",
+// and names must hold one name per type after the namespace. Pass an empty
+// resource type and no names for a subscription or resource group ID, and
+// an empty resource group name for a subscription-scoped resource.
+func NewResourceID(subscriptionID, resourceGroupName, resourceType string, names ...string) (*ResourceID, error) {
+	var b strings.Builder
+
+	b.WriteString("/" + resourceIDSegmentSubscriptions + "/" + subscriptionID)
+	if resourceGroupName != "" {
+		b.WriteString("/" + resourceIDSegmentResourceGroups + "/" + resourceGroupName)
+	}
+
+	if resourceType != "" {
+		typeSegments := strings.Split(resourceType, "/")
+		if len(typeSegments)-1 != len(names) {
+			return nil, fmt.Errorf("resource type '%s' requires %d names, got %d", resourceType, len(typeSegments)-1, len(names))
+		}
+		b.WriteString("/" + resourceIDSegmentProviders + "/" + typeSegments[0])
+		for i, name := range names {
+			b.WriteString("/" + typeSegments[i+1] + "/" + name)
+		}
+	} else if len(names) > 0 {
+		return nil, fmt.Errorf("names given without a resource type")
+	}
+
+	return ParseResourceID(b.String())
+}
+
+// SubscriptionID returns the subscription ID.
+func (r *ResourceID) SubscriptionID() string {
+	return r.subscriptionID
+}
+
+// ResourceGroupName returns the resource group name, or an
+// empty string for subscription-scoped resource IDs.
+func (r *ResourceID) ResourceGroupName() string {
+	return r.resourceGroupName
+}
+
+// ProviderNamespace returns the resource provider namespace, or an
+// empty string for subscription and resource group IDs.
+func (r *ResourceID) ProviderNamespace() string {
+	return r.providerNamespace
+}
+
+// ResourceType returns the fully-qualified resource type, such as
+// "Microsoft.RedHatOpenShift/hcpOpenShiftClusters/nodePools". The
+// types of subscription and resource group IDs are returned in the
+// same form as ARM: "Microsoft.Resources/subscriptions" and
+// "Microsoft.Resources/resourceGroups".
+func (r *ResourceID) ResourceType() string {
+	switch {
+	case r.providerNamespace != "":
+		return r.providerNamespace + "/" + strings.Join(r.resourceTypes, "/")
+	case r.resourceGroupName != "":
+		return "Microsoft.Resources/" + resourceIDSegmentResourceGroups
+	default:
+		return "Microsoft.Resources/" + resourceIDSegmentSubscriptions
+	}
+}
+
+// HasResourceType returns true if the fully-qualified resource
+// type matches the given type, ignoring case.
+func (r *ResourceID) HasResourceType(resourceType string) bool {
+	return strings.EqualFold(r.ResourceType(), resourceType)
+}
+
+// Name returns the name of the resource identified. For subscription
+// and resource group IDs this is the subscription ID or resource group
+// name respectively.
+func (r *ResourceID) Name() string {
+	switch {
+	case len(r.names) > 0:
+		return r.names[len(r.names)-1]
+	case r.resourceGroupName != "":
+		return r.resourceGroupName
+	default:
+		return r.subscriptionID
+	}
+}
+
+// Parent returns the ID of the resource containing this resource. The
+// parent of a nested child resource is the resource it is nested under,
+// the parent of a top-level resource is its resource group (or its
+// subscription if subscription-scoped), the parent of a resource group
+// is its subscription, and a subscription has no parent (nil).
+func (r *ResourceID) Parent() *ResourceID {
+	var trim int
+
+	switch {
+	case len(r.names) > 1:
+		trim = 2 // {childType}/{childName}
+	case len(r.names) == 1:
+		trim = 4 // providers/{namespace}/{type}/{name}
+	case r.resourceGroupName != "":
+		trim = 2 // resourceGroups/{name}
+	default:
+		return nil
+	}
+
+	parent, err := ParseResourceID("/" + strings.Join(r.segments[:len(r.segments)-trim], "/"))
+	if err != nil {
+		// Should never happen since the parent was already validated.
+		panic(err)
+	}
+	return parent
+}
+
+// String returns the resource ID with its original casing.
+func (r *ResourceID) String() string {
+	return "/" + strings.Join(r.segments, "/")
+}
+
+// Equal returns true if both resource IDs identify the same resource.
+// Resource IDs are compared case-insensitively.
+func (r *ResourceID) Equal(other *ResourceID) bool {
+	if r == nil || other == nil {
+		return r == other
+	}
+	return strings.EqualFold(r.String(), other.String())
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (r *ResourceID) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (r *ResourceID) UnmarshalText(text []byte) error {
+	parsed, err := ParseResourceID(string(text))
+	if err != nil {
+		return err
+	}
+	*r = *parsed
+	return nil
+}
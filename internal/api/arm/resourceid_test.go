@@ -0,0 +1,257 @@
+package arm
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const testSubscriptionID = "00000000-0000-0000-0000-000000000000"
+
+func TestParseResourceID(t *testing.T) {
+	tests := []struct {
+		name                    string
+		id                      string
+		expectError             bool
+		expectResourceGroupName string
+		expectProviderNamespace string
+		expectResourceType      string
+		expectName              string
+		expectParent            string
+	}{
+		{
+			name:               "Subscription",
+			id:                 "/subscriptions/" + testSubscriptionID,
+			expectResourceType: "Microsoft.Resources/subscriptions",
+			expectName:         testSubscriptionID,
+		},
+		{
+			name:                    "Resource group",
+			id:                      "/subscriptions/" + testSubscriptionID + "/resourceGroups/MyGroup",
+			expectResourceGroupName: "MyGroup",
+			expectResourceType:      "Microsoft.Resources/resourceGroups",
+			expectName:              "MyGroup",
+			expectParent:            "/subscriptions/" + testSubscriptionID,
+		},
+		{
+			name:                    "Top-level resource",
+			id:                      "/subscriptions/" + testSubscriptionID + "/resourcegroups/rg/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/MyCluster",
+			expectResourceGroupName: "rg",
+			expectProviderNamespace: "Microsoft.RedHatOpenShift",
+			expectResourceType:      "Microsoft.RedHatOpenShift/hcpOpenShiftClusters",
+			expectName:              "MyCluster",
+			expectParent:            "/subscriptions/" + testSubscriptionID + "/resourcegroups/rg",
+		},
+		{
+			name:                    "Nested child resource",
+			id:                      "/subscriptions/" + testSubscriptionID + "/resourceGroups/rg/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/MyCluster/nodePools/MyNodePool",
+			expectResourceGroupName: "rg",
+			expectProviderNamespace: "Microsoft.RedHatOpenShift",
+			expectResourceType:      "Microsoft.RedHatOpenShift/hcpOpenShiftClusters/nodePools",
+			expectName:              "MyNodePool",
+			expectParent:            "/subscriptions/" + testSubscriptionID + "/resourceGroups/rg/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/MyCluster",
+		},
+		{
+			name:                    "Subscription-scoped resource",
+			id:                      "/subscriptions/" + testSubscriptionID + "/providers/Microsoft.Features/features/MyFeature",
+			expectProviderNamespace: "Microsoft.Features",
+			expectResourceType:      "Microsoft.Features/features",
+			expectName:              "MyFeature",
+			expectParent:            "/subscriptions/" + testSubscriptionID,
+		},
+		{
+			name:        "Empty string",
+			id:          "",
+			expectError: true,
+		},
+		{
+			name:        "Missing leading slash",
+			id:          "subscriptions/" + testSubscriptionID,
+			expectError: true,
+		},
+		{
+			name:        "Trailing slash",
+			id:          "/subscriptions/" + testSubscriptionID + "/",
+			expectError: true,
+		},
+		{
+			name:        "Invalid subscription ID",
+			id:          "/subscriptions/xyz/resourceGroups/rg",
+			expectError: true,
+		},
+		{
+			name:        "Missing resource group name",
+			id:          "/subscriptions/" + testSubscriptionID + "/resourceGroups",
+			expectError: true,
+		},
+		{
+			name:        "Invalid resource group name",
+			id:          "/subscriptions/" + testSubscriptionID + "/resourceGroups/rg!",
+			expectError: true,
+		},
+		{
+			name:        "Unknown segment",
+			id:          "/subscriptions/" + testSubscriptionID + "/resourceGroups/rg/things/thing",
+			expectError: true,
+		},
+		{
+			name:        "Missing resource type",
+			id:          "/subscriptions/" + testSubscriptionID + "/resourceGroups/rg/providers/Microsoft.Network",
+			expectError: true,
+		},
+		{
+			name:        "Missing resource name",
+			id:          "/subscriptions/" + testSubscriptionID + "/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resourceID, err := ParseResourceID(tt.id)
+			if tt.expectError {
+				if err == nil {
+					t.Fatalf("expected an error parsing '%s'", tt.id)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resourceID.String() != tt.id {
+				t.Errorf("expected string '%s', got '%s'", tt.id, resourceID.String())
+			}
+			if resourceID.SubscriptionID() != testSubscriptionID {
+				t.Errorf("expected subscription ID '%s', got '%s'", testSubscriptionID, resourceID.SubscriptionID())
+			}
+			if resourceID.ResourceGroupName() != tt.expectResourceGroupName {
+				t.Errorf("expected resource group name '%s', got '%s'", tt.expectResourceGroupName, resourceID.ResourceGroupName())
+			}
+			if resourceID.ProviderNamespace() != tt.expectProviderNamespace {
+				t.Errorf("expected provider namespace '%s', got '%s'", tt.expectProviderNamespace, resourceID.ProviderNamespace())
+			}
+			if resourceID.ResourceType() != tt.expectResourceType {
+				t.Errorf("expected resource type '%s', got '%s'", tt.expectResourceType, resourceID.ResourceType())
+			}
+			if resourceID.Name() != tt.expectName {
+				t.Errorf("expected name '%s', got '%s'", tt.expectName, resourceID.Name())
+			}
+
+			parent := resourceID.Parent()
+			switch {
+			case tt.expectParent == "" && parent != nil:
+				t.Errorf("expected no parent, got '%s'", parent)
+			case tt.expectParent != "" && parent == nil:
+				t.Errorf("expected parent '%s', got none", tt.expectParent)
+			case parent != nil && parent.String() != tt.expectParent:
+				t.Errorf("expected parent '%s', got '%s'", tt.expectParent, parent)
+			}
+		})
+	}
+}
+
+func TestNewResourceID(t *testing.T) {
+	tests := []struct {
+		name              string
+		resourceGroupName string
+		resourceType      string
+		names             []string
+		expected          string
+		expectError       bool
+	}{
+		{
+			name:     "Subscription",
+			expected: "/subscriptions/" + testSubscriptionID,
+		},
+		{
+			name:              "Resource group",
+			resourceGroupName: "rg",
+			expected:          "/subscriptions/" + testSubscriptionID + "/resourceGroups/rg",
+		},
+		{
+			name:              "Nested child resource",
+			resourceGroupName: "rg",
+			resourceType:      "Microsoft.Network/virtualNetworks/subnets",
+			names:             []string{"vnet", "subnet"},
+			expected:          "/subscriptions/" + testSubscriptionID + "/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/subnet",
+		},
+		{
+			name:              "Too few names",
+			resourceGroupName: "rg",
+			resourceType:      "Microsoft.Network/virtualNetworks/subnets",
+			names:             []string{"subnet"},
+			expectError:       true,
+		},
+		{
+			name:              "Names without a resource type",
+			resourceGroupName: "rg",
+			names:             []string{"vnet"},
+			expectError:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resourceID, err := NewResourceID(testSubscriptionID, tt.resourceGroupName, tt.resourceType, tt.names...)
+			if tt.expectError {
+				if err == nil {
+					t.Fatalf("expected an error, got '%s'", resourceID)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resourceID.String() != tt.expected {
+				t.Errorf("expected '%s', got '%s'", tt.expected, resourceID)
+			}
+		})
+	}
+}
+
+func TestResourceIDEqual(t *testing.T) {
+	a, err := ParseResourceID("/subscriptions/" + testSubscriptionID + "/resourceGroups/RG/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/Cluster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ParseResourceID("/SUBSCRIPTIONS/" + testSubscriptionID + "/resourcegroups/rg/providers/microsoft.redhatopenshift/hcpopenshiftclusters/cluster")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !a.Equal(b) {
+		t.Errorf("expected '%s' to equal '%s'", a, b)
+	}
+	if a.Equal(a.Parent()) {
+		t.Errorf("expected '%s' not to equal its parent", a)
+	}
+	if !b.HasResourceType("Microsoft.RedHatOpenShift/hcpOpenShiftClusters") {
+		t.Errorf("expected '%s' to have the cluster resource type", b)
+	}
+}
+
+func TestResourceIDJSON(t *testing.T) {
+	id := "/subscriptions/" + testSubscriptionID + "/resourceGroups/rg"
+
+	var value struct {
+		ID *ResourceID `json:"id"`
+	}
+	if err := json.Unmarshal([]byte(`{"id":"`+id+`"}`), &value); err != nil {
+		t.Fatal(err)
+	}
+	if value.ID.ResourceGroupName() != "rg" {
+		t.Errorf("expected resource group name 'rg', got '%s'", value.ID.ResourceGroupName())
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"id":"`+id+`"}` {
+		t.Errorf("unexpected JSON: %s", data)
+	}
+
+	if err := json.Unmarshal([]byte(`{"id":"/subscriptions/xyz"}`), &value); err == nil {
+		t.Errorf("expected an error unmarshaling an invalid resource ID")
+	}
+}
@@ -0,0 +1,44 @@
+package arm
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// WriteJSONResponse writes body as JSON with the given status code. If body
+// cannot be marshaled, an internal server error is written instead. Errors
+// are logged to logger.
+func WriteJSONResponse(w http.ResponseWriter, logger *slog.Logger, statusCode int, body any) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		WriteInternalServerError(w, logger, err)
+		return
+	}
+
+	w.Header()["Content-Type"] = []string{"application/json"}
+	w.WriteHeader(statusCode)
+	if _, err = w.Write(data); err != nil {
+		logger.Error(err.Error())
+	}
+}
+
+// WriteResourceNotFoundError writes the error ARM expects when a request
+// addresses a resource that does not exist.
+func WriteResourceNotFoundError(w http.ResponseWriter, resourceID *ResourceID) {
+	ErrorResourceNotFound.Write(w, "",
+		resourceID.ResourceType(),
+		resourceID.Name(),
+		resourceID.ResourceGroupName())
+}
+
+// WriteMethodNotAllowedError writes the error for a request whose path is
+// valid but whose method is not. The Allow header lists the valid methods.
+func WriteMethodNotAllowedError(w http.ResponseWriter, method string, allowed []string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	ErrorMethodNotAllowed.Write(w, "", method, strings.Join(allowed, ", "))
+}
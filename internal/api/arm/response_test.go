@@ -0,0 +1,68 @@
+package arm
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteJSONResponse(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         any
+		expectStatus int
+		expectBody   string
+	}{
+		{
+			name:         "Marshalable body",
+			body:         map[string]string{"name": "value"},
+			expectStatus: http.StatusCreated,
+			expectBody:   `{"name":"value"}`,
+		},
+		{
+			name:         "Unmarshalable body",
+			body:         make(chan int),
+			expectStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := httptest.NewRecorder()
+
+			WriteJSONResponse(writer, slog.New(slog.NewTextHandler(io.Discard, nil)), http.StatusCreated, tt.body)
+
+			if writer.Code != tt.expectStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectStatus, writer.Code)
+			}
+			if contentType := writer.Header().Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("Expected JSON content type, got %q", contentType)
+			}
+			if tt.expectBody != "" && writer.Body.String() != tt.expectBody {
+				t.Errorf("Expected body %s, got %s", tt.expectBody, writer.Body.String())
+			}
+		})
+	}
+}
+
+func TestWriteResourceNotFoundError(t *testing.T) {
+	resourceID, err := ParseResourceID("/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.RedHatOpenShift/hcpOpenShiftClusters/cluster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := httptest.NewRecorder()
+
+	WriteResourceNotFoundError(writer, resourceID)
+
+	if writer.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, writer.Code)
+	}
+	if code := writer.Header().Get(HeaderNameErrorCode); code != CloudErrorCodeResourceNotFound {
+		t.Errorf("Expected error code %q, got %q", CloudErrorCodeResourceNotFound, code)
+	}
+}
@@ -28,7 +28,7 @@ type Feature struct {
 
 type AvailabilityZone struct {
 	Location     *string        `json:"location,omitempty"`
-	ZoneMappings *[]ZoneMapping `json:"zoneMppings,omitempty"`
+	ZoneMappings *[]ZoneMapping `json:"zoneMappings,omitempty"`
 }
 
 type ZoneMapping struct {
@@ -0,0 +1,80 @@
+package arm
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Tag limits enforced by Azure Resource Manager.
+// See https://learn.microsoft.com/en-us/azure/azure-resource-manager/management/tag-resources#limitations
+const (
+	MaxTags           = 50
+	MaxTagKeyLength   = 512
+	MaxTagValueLength = 256
+
+	// tagKeyForbiddenCharacters may not appear in tag keys.
+	tagKeyForbiddenCharacters = `<>%&\?/`
+)
+
+// reservedTagKeyPrefixes are tag key prefixes reserved
+// for use by Azure. They are matched case-insensitively.
+var reservedTagKeyPrefixes = []string{"microsoft", "azure", "windows"}
+
+// ValidateTags checks resource tags against the limits Azure Resource
+// Manager places on them, returning an error detail for each violation.
+// Error details for individual tags target "tags.{key}".
+func ValidateTags(tags map[string]string) []CloudErrorBody {
+	var errorDetails []CloudErrorBody
+
+	invalid := func(target, format string, a ...any) {
+		errorDetails = append(errorDetails, CloudErrorBody{
+			Code:    CloudErrorCodeInvalidRequestContent,
+			Message: fmt.Sprintf(format, a...),
+			Target:  target,
+		})
+	}
+
+	if len(tags) > MaxTags {
+		invalid("tags", "Too many tags: %d (must be at most %d)", len(tags), MaxTags)
+	}
+
+	// Sort keys so error details are reported in a stable order.
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		target := "tags." + key
+
+		switch {
+		case strings.TrimSpace(key) == "":
+			invalid(target, "Tag key must not be empty")
+		case utf8.RuneCountInString(key) > MaxTagKeyLength:
+			invalid(target, "Tag key is too long (must be at most %d characters)", MaxTagKeyLength)
+		case strings.ContainsAny(key, tagKeyForbiddenCharacters):
+			invalid(target, "Tag key '%s' contains a forbidden character (must not contain any of: %s)", key, tagKeyForbiddenCharacters)
+		case strings.HasSuffix(key, " ") || strings.HasSuffix(key, "."):
+			invalid(target, "Tag key '%s' must not end with a space or period", key)
+		default:
+			for _, prefix := range reservedTagKeyPrefixes {
+				if strings.HasPrefix(strings.ToLower(key), prefix) {
+					invalid(target, "Tag key '%s' uses the reserved prefix '%s'", key, prefix)
+					break
+				}
+			}
+		}
+
+		if utf8.RuneCountInString(tags[key]) > MaxTagValueLength {
+			invalid(target, "Value of tag '%s' is too long (must be at most %d characters)", key, MaxTagValueLength)
+		}
+	}
+
+	return errorDetails
+}
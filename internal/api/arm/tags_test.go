@@ -0,0 +1,105 @@
+package arm
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestValidateTags(t *testing.T) {
+	tooMany := make(map[string]string)
+	for i := 0; i <= MaxTags; i++ {
+		tooMany[fmt.Sprintf("key%02d", i)] = "value"
+	}
+
+	tests := []struct {
+		name          string
+		tags          map[string]string
+		expectTargets []string
+	}{
+		{
+			name: "No tags",
+		},
+		{
+			name: "Valid tags",
+			tags: map[string]string{
+				"environment":                        "production",
+				"cost-center":                        "",
+				strings.Repeat("k", MaxTagKeyLength): strings.Repeat("v", MaxTagValueLength),
+				"Ünïcödé":                            "välue",
+				"MyMicrosoftTag":                     "prefix only counts at the start",
+				"environment.owner":                  "periods are fine mid-key",
+			},
+		},
+		{
+			name:          "Too many tags",
+			tags:          tooMany,
+			expectTargets: []string{"tags"},
+		},
+		{
+			name:          "Key too long",
+			tags:          map[string]string{strings.Repeat("k", MaxTagKeyLength+1): ""},
+			expectTargets: []string{"tags." + strings.Repeat("k", MaxTagKeyLength+1)},
+		},
+		{
+			name:          "Value too long",
+			tags:          map[string]string{"key": strings.Repeat("v", MaxTagValueLength+1)},
+			expectTargets: []string{"tags.key"},
+		},
+		{
+			name: "Forbidden characters",
+			tags: map[string]string{
+				"a<b": "",
+				"a/b": "",
+				"a%b": "",
+				"a?b": "",
+			},
+			expectTargets: []string{"tags.a%b", "tags.a/b", "tags.a<b", "tags.a?b"},
+		},
+		{
+			name: "Reserved prefixes",
+			tags: map[string]string{
+				"microsoft.owner": "",
+				"Azure-Region":    "",
+				"WINDOWS":         "",
+			},
+			expectTargets: []string{"tags.Azure-Region", "tags.WINDOWS", "tags.microsoft.owner"},
+		},
+		{
+			name: "Empty key and trailing period",
+			tags: map[string]string{
+				"":       "",
+				"owner.": "",
+			},
+			expectTargets: []string{"tags.", "tags.owner."},
+		},
+		{
+			name: "Invalid key and value",
+			tags: map[string]string{
+				"azure": strings.Repeat("v", MaxTagValueLength+1),
+			},
+			expectTargets: []string{"tags.azure", "tags.azure"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errorDetails := ValidateTags(tt.tags)
+
+			if len(errorDetails) != len(tt.expectTargets) {
+				t.Fatalf("Expected %d errors, got %d: %v", len(tt.expectTargets), len(errorDetails), errorDetails)
+			}
+			for i, detail := range errorDetails {
+				if detail.Code != CloudErrorCodeInvalidRequestContent {
+					t.Errorf("Expected code %q, got %q", CloudErrorCodeInvalidRequestContent, detail.Code)
+				}
+				if detail.Target != tt.expectTargets[i] {
+					t.Errorf("Expected target %q, got %q", tt.expectTargets[i], detail.Target)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package arm
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+// UsageUnitCount is the unit of a Usage that counts discrete things.
+const UsageUnitCount = "Count"
+
+// Usage reports consumption of one quota in a location, in the format
+// of the ARM "usages" operation.
+type Usage struct {
+	Name         UsageName `json:"name"`
+	Unit         string    `json:"unit"`
+	CurrentValue int64     `json:"currentValue"`
+	Limit        int64     `json:"limit"`
+}
+
+// UsageName identifies the quota a Usage reports on.
+type UsageName struct {
+	Value          string `json:"value"`
+	LocalizedValue string `json:"localizedValue"`
+}
+
+// UsageList is the response body of the ARM "usages" operation.
+type UsageList struct {
+	Value    []Usage `json:"value"`
+	NextLink string  `json:"nextLink,omitempty"`
+}
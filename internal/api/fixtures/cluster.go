@@ -0,0 +1,174 @@
+package fixtures
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+)
+
+// clusterVariant is a change to the valid cluster that makes it invalid.
+// A variant either modifies the internal cluster, or the request body
+// where the change cannot be expressed in the internal model.
+type clusterVariant struct {
+	name   string
+	target string
+	schema bool
+	modify func(*api.HCPOpenShiftCluster)
+	mutate func(body map[string]any)
+}
+
+var clusterVariants = []clusterVariant{
+	{
+		name: ValidName,
+	},
+	{
+		name:   "overlapping-cidrs",
+		target: "properties.spec.network.machineCidr",
+		modify: func(c *api.HCPOpenShiftCluster) {
+			c.Properties.Spec.Network.MachineCIDR = c.Properties.Spec.Network.PodCIDR
+		},
+	},
+	{
+		name:   "reserved-service-cidr",
+		target: "properties.spec.network.serviceCidr",
+		modify: func(c *api.HCPOpenShiftCluster) {
+			c.Properties.Spec.Network.ServiceCIDR = "169.254.0.0/16"
+		},
+	},
+	{
+		name:   "malformed-pod-cidr",
+		target: "properties.spec.network.podCidr",
+		modify: func(c *api.HCPOpenShiftCluster) {
+			address, _, _ := strings.Cut(c.Properties.Spec.Network.PodCIDR, "/")
+			c.Properties.Spec.Network.PodCIDR = address
+		},
+	},
+	{
+		name:   "host-prefix-out-of-range",
+		target: "properties.spec.network.hostPrefix",
+		modify: func(c *api.HCPOpenShiftCluster) {
+			c.Properties.Spec.Network.HostPrefix = api.MaxHostPrefix + 1
+		},
+	},
+	{
+		name:   "invalid-visibility",
+		target: "properties.spec.api.visibility",
+		modify: func(c *api.HCPOpenShiftCluster) {
+			c.Properties.Spec.API.Visibility = "secret"
+		},
+	},
+	{
+		name:   "subnet-id-wrong-resource-type",
+		target: "properties.spec.platform.subnetId",
+		modify: func(c *api.HCPOpenShiftCluster) {
+			c.Properties.Spec.Platform.SubnetID = c.Properties.Spec.Platform.NetworkSecurityGroupID
+		},
+	},
+	{
+		name:   "missing-subnet-id",
+		target: "properties.spec.platform.subnetId",
+		schema: true,
+		modify: func(c *api.HCPOpenShiftCluster) {
+			c.Properties.Spec.Platform.SubnetID = ""
+		},
+	},
+	{
+		name:   "invalid-tag-key",
+		target: "tags.env<prod>",
+		modify: func(c *api.HCPOpenShiftCluster) {
+			c.Tags["env<prod>"] = "true"
+		},
+	},
+	{
+		name:   "host-prefix-wrong-type",
+		target: "properties.spec.network.hostPrefix",
+		schema: true,
+		mutate: func(body map[string]any) {
+			network := field(body, "properties.spec.network")
+			network["hostPrefix"] = fmt.Sprint(network["hostPrefix"])
+		},
+	},
+	{
+		name:   "unknown-field",
+		target: "properties.spec.workerCount",
+		schema: true,
+		mutate: func(body map[string]any) {
+			field(body, "properties.spec")["workerCount"] = 3
+		},
+	},
+}
+
+// Cluster returns a valid cluster derived from seed. Its network ranges
+// do not overlap each other or any reserved range.
+func Cluster(seed int64) *api.HCPOpenShiftCluster {
+	g, refs, _, _ := newBase(seed)
+
+	cluster := api.NewDefaultHCPOpenShiftCluster()
+	cluster.Location = Location
+	cluster.Tags = map[string]string{"fixture-seed": fmt.Sprint(seed)}
+
+	spec := &cluster.Properties.Spec
+	spec.Version.ID = "4.16.0"
+	spec.Version.ChannelGroup = "stable"
+	spec.DNS.BaseDomainPrefix = g.name("dns")
+	spec.Network.PodCIDR = fmt.Sprintf("10.%d.0.0/14", 128+4*g.rand.Intn(32))
+	spec.Network.ServiceCIDR = fmt.Sprintf("172.%d.0.0/16", 16+g.rand.Intn(16))
+	spec.Network.MachineCIDR = fmt.Sprintf("10.%d.0.0/16", g.rand.Intn(128))
+	spec.Network.HostPrefix = int32(api.MinHostPrefix + g.rand.Intn(api.MaxHostPrefix-api.MinHostPrefix+1))
+	spec.API.Visibility = api.Visibility(g.pick(string(api.VisibilityPublic), string(api.VisibilityPrivate)))
+	spec.Platform.ManagedResourceGroup = g.name("managed")
+	spec.Platform.SubnetID = refs.subnetID
+	spec.Platform.NetworkSecurityGroupID = refs.networkSecurityGroupID
+	spec.Platform.EtcdEncryptionSetID = refs.diskEncryptionSetID
+	spec.Platform.OutboundType = api.OutboundTypeLoadBalancer
+
+	return cluster
+}
+
+// newBase returns the generator for seed after drawing the values that
+// cluster and node pool fixtures for the same seed share.
+func newBase(seed int64) (g *generator, refs references, clusterName, nodePoolName string) {
+	g = newGenerator(seed)
+	refs = g.references()
+	clusterName = g.name("cluster")
+	nodePoolName = g.name("np")
+	return
+}
+
+func newClusterFixture(version api.Version, seed int64, index int) (Fixture, error) {
+	_, _, clusterName, _ := newBase(seed)
+	variant := clusterVariants[index]
+
+	cluster := Cluster(seed)
+	if variant.modify != nil {
+		variant.modify(cluster)
+	}
+
+	body, err := clientBody(version.NewHCPOpenShiftCluster(cluster), variant.mutate)
+	if err != nil {
+		return Fixture{}, err
+	}
+
+	var references []string
+	platform := cluster.Properties.Spec.Platform
+	for _, id := range []string{platform.SubnetID, platform.NetworkSecurityGroupID, platform.EtcdEncryptionSetID} {
+		if id != "" {
+			references = append(references, id)
+		}
+	}
+
+	return Fixture{
+		Name:            variant.name,
+		Resource:        ResourceTypeCluster,
+		APIVersion:      version.String(),
+		ResourceID:      clusterResourceID(resourceName(clusterName, index)),
+		Body:            body,
+		References:      references,
+		Target:          variant.target,
+		SchemaViolation: variant.schema,
+	}, nil
+}
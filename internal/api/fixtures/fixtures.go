@@ -0,0 +1,220 @@
+// Package fixtures generates request bodies for clusters and node pools in
+// every registered API version, both valid and deliberately invalid. The
+// bodies are derived from a seed, so the same seed always produces the same
+// bodies, and are in the versioned wire format a client would send.
+package fixtures
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+
+	// Register every API version so fixtures cover them all.
+	_ "github.com/Azure/ARO-HCP/internal/api/v20240301preview"
+	_ "github.com/Azure/ARO-HCP/internal/api/v20240610preview"
+)
+
+const (
+	// SubscriptionID is the subscription of fixture resources and
+	// of the Azure resources fixture bodies reference.
+	SubscriptionID = "00000000-0000-0000-0000-000000000000"
+	// ResourceGroup is the resource group of fixture resources.
+	ResourceGroup = "fixtures"
+	// Location is the location of fixture resources.
+	Location = "eastus"
+
+	// ValidName is the name of the valid fixture of each resource type.
+	ValidName = "valid"
+)
+
+// ResourceType distinguishes cluster fixtures from node pool fixtures.
+type ResourceType string
+
+const (
+	ResourceTypeCluster  ResourceType = "cluster"
+	ResourceTypeNodePool ResourceType = "nodepool"
+)
+
+// Fixture is a request body for creating a resource.
+type Fixture struct {
+	// Name identifies the fixture among those for the same
+	// resource type and API version.
+	Name       string
+	Resource   ResourceType
+	APIVersion string
+	// ResourceID is the path to send the body to.
+	ResourceID string
+	Body       json.RawMessage
+	// References are the IDs of Azure resources the body refers to,
+	// which must exist for the request to pass dynamic validation.
+	References []string
+	// Target is the field an invalid fixture is expected to be
+	// rejected for. It is empty for valid fixtures.
+	Target string
+	// SchemaViolation is true if the body violates the OpenAPI
+	// schema, as opposed to validation the schema cannot express.
+	SchemaViolation bool
+}
+
+// Valid returns true if the fixture is expected to be accepted.
+func (f Fixture) Valid() bool {
+	return f.Target == ""
+}
+
+// All returns the fixtures for every registered API version.
+func All(seed int64) ([]Fixture, error) {
+	var fixtures []Fixture
+	for _, version := range api.Versions() {
+		versionFixtures, err := ForVersion(version, seed)
+		if err != nil {
+			return nil, err
+		}
+		fixtures = append(fixtures, versionFixtures...)
+	}
+	return fixtures, nil
+}
+
+// ForVersion returns the cluster and node pool fixtures for an API version.
+// The valid fixture of each resource type comes first.
+func ForVersion(version api.Version, seed int64) ([]Fixture, error) {
+	var fixtures []Fixture
+
+	for index, variant := range clusterVariants {
+		fixture, err := newClusterFixture(version, seed, index)
+		if err != nil {
+			return nil, fmt.Errorf("%s cluster fixture %s: %w", version, variant.name, err)
+		}
+		fixtures = append(fixtures, fixture)
+	}
+
+	for index, variant := range nodePoolVariants {
+		fixture, err := newNodePoolFixture(version, seed, index)
+		if err != nil {
+			return nil, fmt.Errorf("%s node pool fixture %s: %w", version, variant.name, err)
+		}
+		fixtures = append(fixtures, fixture)
+	}
+
+	return fixtures, nil
+}
+
+// generator draws fixture values from a seeded source.
+type generator struct {
+	rand *rand.Rand
+}
+
+func newGenerator(seed int64) *generator {
+	return &generator{rand: rand.New(rand.NewSource(seed))}
+}
+
+// name returns a prefixed random name of lowercase letters and digits.
+func (g *generator) name(prefix string) string {
+	const characters = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+	var builder strings.Builder
+	builder.WriteString(prefix)
+	builder.WriteString("-")
+	for i := 0; i < 6; i++ {
+		builder.WriteByte(characters[g.rand.Intn(len(characters))])
+	}
+	return builder.String()
+}
+
+func (g *generator) pick(values ...string) string {
+	return values[g.rand.Intn(len(values))]
+}
+
+// references are the Azure resources a fixture's body refers to.
+type references struct {
+	subnetID               string
+	networkSecurityGroupID string
+	diskEncryptionSetID    string
+}
+
+func (g *generator) references() references {
+	resourceGroupID := "/subscriptions/" + SubscriptionID + "/resourceGroups/" + ResourceGroup
+	return references{
+		subnetID:               resourceGroupID + "/providers/Microsoft.Network/virtualNetworks/" + g.name("vnet") + "/subnets/" + g.name("subnet"),
+		networkSecurityGroupID: resourceGroupID + "/providers/Microsoft.Network/networkSecurityGroups/" + g.name("nsg"),
+		diskEncryptionSetID:    resourceGroupID + "/providers/Microsoft.Compute/diskEncryptionSets/" + g.name("des"),
+	}
+}
+
+// resourceName gives each fixture of a resource type a distinct name,
+// so fixtures can be created side by side.
+func resourceName(base string, index int) string {
+	return fmt.Sprintf("%s-%d", base, index)
+}
+
+func clusterResourceID(name string) string {
+	return "/subscriptions/" + SubscriptionID + "/resourceGroups/" + ResourceGroup + "/providers/" + api.ResourceType + "/" + name
+}
+
+// clientBody converts a versioned resource to the body a client would
+// send: read-only resource properties and empty values are removed.
+func clientBody(versioned any, mutate func(map[string]any)) (json.RawMessage, error) {
+	data, err := json.Marshal(versioned)
+	if err != nil {
+		return nil, err
+	}
+
+	var body map[string]any
+	if err = json.Unmarshal(data, &body); err != nil {
+		return nil, err
+	}
+
+	for _, key := range []string{"id", "name", "type", "systemData"} {
+		delete(body, key)
+	}
+	if properties, ok := body["properties"].(map[string]any); ok {
+		delete(properties, "provisioningState")
+	}
+	pruneEmpty(body)
+
+	if mutate != nil {
+		mutate(body)
+	}
+
+	return json.MarshalIndent(body, "", "  ")
+}
+
+// pruneEmpty removes empty strings, nulls, and objects and arrays that are
+// or become empty.
+func pruneEmpty(object map[string]any) {
+	for key, value := range object {
+		if isEmpty(value) {
+			delete(object, key)
+		}
+	}
+}
+
+func isEmpty(value any) bool {
+	switch value := value.(type) {
+	case nil:
+		return true
+	case string:
+		return value == ""
+	case map[string]any:
+		pruneEmpty(value)
+		return len(value) == 0
+	case []any:
+		return len(value) == 0
+	default:
+		return false
+	}
+}
+
+// field returns the object at a dotted path in body.
+func field(body map[string]any, path string) map[string]any {
+	object := body
+	for _, key := range strings.Split(path, ".") {
+		object = object[key].(map[string]any)
+	}
+	return object
+}
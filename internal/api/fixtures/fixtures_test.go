@@ -0,0 +1,138 @@
+package fixtures
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"path"
+	"regexp"
+	"testing"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+)
+
+var rxResourceName = regexp.MustCompile(`^[a-zA-Z0-9-]{3,24}$`)
+
+func TestFixturesReproducible(t *testing.T) {
+	first, err := All(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := All(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := All(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(first) != len(second) || len(first) != len(other) {
+		t.Fatalf("Expected the same number of fixtures for every seed, got %d, %d and %d", len(first), len(second), len(other))
+	}
+
+	for i := range first {
+		if first[i].ResourceID != second[i].ResourceID || !bytes.Equal(first[i].Body, second[i].Body) {
+			t.Errorf("%s %s %s: expected the same fixture for the same seed", first[i].APIVersion, first[i].Resource, first[i].Name)
+		}
+		if first[i].ResourceID == other[i].ResourceID || bytes.Equal(first[i].Body, other[i].Body) {
+			t.Errorf("%s %s %s: expected a different fixture for a different seed", first[i].APIVersion, first[i].Resource, first[i].Name)
+		}
+	}
+}
+
+func TestFixturesCoverEveryVersion(t *testing.T) {
+	fixtures, err := All(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := make(map[string]map[ResourceType]bool)
+	names := make(map[string]bool)
+	for _, fixture := range fixtures {
+		if fixture.Valid() {
+			if valid[fixture.APIVersion] == nil {
+				valid[fixture.APIVersion] = make(map[ResourceType]bool)
+			}
+			valid[fixture.APIVersion][fixture.Resource] = true
+		}
+
+		name := path.Base(fixture.ResourceID)
+		if !rxResourceName.MatchString(name) {
+			t.Errorf("%s %s %s: invalid resource name '%s'", fixture.APIVersion, fixture.Resource, fixture.Name, name)
+		}
+
+		key := fixture.APIVersion + " " + fixture.ResourceID
+		if names[key] {
+			t.Errorf("%s %s %s: duplicate resource ID '%s'", fixture.APIVersion, fixture.Resource, fixture.Name, fixture.ResourceID)
+		}
+		names[key] = true
+
+		if !json.Valid(fixture.Body) {
+			t.Errorf("%s %s %s: body is not valid JSON", fixture.APIVersion, fixture.Resource, fixture.Name)
+		}
+	}
+
+	for _, version := range api.Versions() {
+		for _, resource := range []ResourceType{ResourceTypeCluster, ResourceTypeNodePool} {
+			if !valid[version.String()][resource] {
+				t.Errorf("%s: no valid %s fixture", version, resource)
+			}
+		}
+	}
+}
+
+func TestClusterFixturesValidateStatic(t *testing.T) {
+	// Several seeds, so the randomly chosen values
+	// of valid clusters are checked more than once.
+	for seed := int64(0); seed < 20; seed++ {
+		for _, version := range api.Versions() {
+			fixtures, err := ForVersion(version, seed)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, fixture := range fixtures {
+				if fixture.Resource != ResourceTypeCluster {
+					continue
+				}
+
+				cluster := version.NewHCPOpenShiftCluster(nil)
+				if err = json.Unmarshal(fixture.Body, cluster); err != nil {
+					if fixture.Valid() || !fixture.SchemaViolation {
+						t.Errorf("seed %d %s %s: unexpected unmarshal error: %v", seed, version, fixture.Name, err)
+					}
+					continue
+				}
+
+				cloudError := cluster.ValidateStatic(version.NewHCPOpenShiftCluster(nil), false, http.MethodPut)
+
+				switch {
+				case fixture.Valid():
+					if cloudError != nil {
+						t.Errorf("seed %d %s %s: unexpected error: %v", seed, version, fixture.Name, cloudError)
+					}
+				case fixture.SchemaViolation:
+					// Checked against the OpenAPI schema by the frontend.
+				case cloudError == nil:
+					t.Errorf("seed %d %s %s: expected an error for '%s'", seed, version, fixture.Name, fixture.Target)
+				default:
+					targets := []string{cloudError.Target}
+					for _, detail := range cloudError.Details {
+						targets = append(targets, detail.Target)
+					}
+					found := false
+					for _, target := range targets {
+						found = found || target == fixture.Target
+					}
+					if !found {
+						t.Errorf("seed %d %s %s: expected an error for '%s', got %v", seed, version, fixture.Name, fixture.Target, cloudError)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package fixtures
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"encoding/json"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+)
+
+// nodePoolVariant is a change to the valid node pool request body that
+// makes it invalid.
+type nodePoolVariant struct {
+	name   string
+	target string
+	mutate func(body map[string]any)
+}
+
+var nodePoolVariants = []nodePoolVariant{
+	{
+		name: ValidName,
+	},
+	{
+		name:   "missing-vm-size",
+		target: "properties.spec.platform.vmSize",
+		mutate: func(body map[string]any) {
+			delete(field(body, "properties.spec.platform"), "vmSize")
+		},
+	},
+	{
+		name:   "replicas-wrong-type",
+		target: "properties.spec.replicas",
+		mutate: func(body map[string]any) {
+			field(body, "properties.spec")["replicas"] = "three"
+		},
+	},
+	{
+		name:   "missing-autoscaling-max",
+		target: "properties.spec.autoScaling.max",
+		mutate: func(body map[string]any) {
+			spec := field(body, "properties.spec")
+			delete(spec, "replicas")
+			spec["autoScaling"] = map[string]any{"min": 1}
+		},
+	},
+	{
+		name:   "unknown-field",
+		target: "properties.spec.nodeCount",
+		mutate: func(body map[string]any) {
+			field(body, "properties.spec")["nodeCount"] = 3
+		},
+	},
+}
+
+// nodePoolBody returns a valid node pool request body derived from seed,
+// in the cluster fixture's subnet.
+//
+// Node pools have no versioned conversion yet (see api.Version), so the
+// body is built directly in the wire format, which is the same in every
+// API version so far. Its shape is checked against each version's OpenAPI
+// schema by the frontend tests.
+func nodePoolBody(seed int64) map[string]any {
+	g, refs, _, _ := newBase(seed)
+	cluster := Cluster(seed)
+
+	return map[string]any{
+		"location": Location,
+		"tags":     map[string]any{"fixture-seed": cluster.Tags["fixture-seed"]},
+		"properties": map[string]any{
+			"spec": map[string]any{
+				"version": map[string]any{
+					"id":           cluster.Properties.Spec.Version.ID,
+					"channelGroup": cluster.Properties.Spec.Version.ChannelGroup,
+				},
+				"platform": map[string]any{
+					"subnetId":   refs.subnetID,
+					"vmSize":     g.pick("Standard_D4s_v3", "Standard_D8s_v3", "Standard_E4s_v3"),
+					"diskSizeGB": 64 << g.rand.Intn(3),
+				},
+				"replicas":   1 + g.rand.Intn(5),
+				"autoRepair": true,
+				"labels":     map[string]any{"fixture": "true"},
+			},
+		},
+	}
+}
+
+func newNodePoolFixture(version api.Version, seed int64, index int) (Fixture, error) {
+	_, refs, clusterName, nodePoolName := newBase(seed)
+	variant := nodePoolVariants[index]
+
+	body := nodePoolBody(seed)
+	if variant.mutate != nil {
+		variant.mutate(body)
+	}
+
+	data, err := json.MarshalIndent(body, "", "  ")
+	if err != nil {
+		return Fixture{}, err
+	}
+
+	return Fixture{
+		Name:            variant.name,
+		Resource:        ResourceTypeNodePool,
+		APIVersion:      version.String(),
+		ResourceID:      clusterResourceID(resourceName(clusterName, 0)) + "/nodePools/" + resourceName(nodePoolName, index),
+		Body:            data,
+		References:      []string{refs.subnetID},
+		Target:          variant.target,
+		SchemaViolation: variant.target != "",
+	}, nil
+}
@@ -4,6 +4,10 @@ package api
 // Licensed under the Apache License 2.0.
 
 import (
+	"encoding/json"
+	"log/slog"
+	"reflect"
+
 	configv1 "github.com/openshift/api/config/v1"
 
 	"github.com/Azure/ARO-HCP/internal/api/arm"
@@ -12,6 +16,7 @@ import (
 // HCPOpenShiftCluster represents an ARO HCP OpenShift cluster resource.
 type HCPOpenShiftCluster struct {
 	arm.TrackedResource
+	Identity   *arm.ManagedServiceIdentity   `json:"identity,omitempty"   validate:"omitempty"`
 	Properties HCPOpenShiftClusterProperties `json:"properties,omitempty" validate:"required_for_put"`
 }
 
@@ -54,7 +59,7 @@ type DNSProfile struct {
 // NetworkProfile represents a cluster network configuration.
 // Visibility for the entire struct is "read create".
 type NetworkProfile struct {
-	NetworkType NetworkType `json:"networkType,omitempty"`
+	NetworkType NetworkType `json:"networkType,omitempty" validate:"omitempty,enum_networktype"`
 	PodCIDR     string      `json:"podCidr,omitempty"     validate:"required_for_put,cidrv4"`
 	ServiceCIDR string      `json:"serviceCidr,omitempty" validate:"required_for_put,cidrv4"`
 	MachineCIDR string      `json:"machineCidr,omitempty" validate:"required_for_put,cidrv4"`
@@ -75,29 +80,30 @@ type APIProfile struct {
 }
 
 // ProxyProfile represents the cluster proxy configuration.
-// Visibility for the entire struct is "read create update".
+// Visibility for the entire struct is "read create update",
+// except for TrustedCA which is write-only.
 type ProxyProfile struct {
 	HTTPProxy  string `json:"httpProxy,omitempty"`
 	HTTPSProxy string `json:"httpsProxy,omitempty"`
 	NoProxy    string `json:"noProxy,omitempty"`
-	TrustedCA  string `json:"trustedCa,omitempty"`
+	TrustedCA  string `json:"trustedCa,omitempty"  visibility:"create update"`
 }
 
 // PlatformProfile represents the Azure platform configuration.
 // Visibility for the entire struct is "read create".
 type PlatformProfile struct {
 	ManagedResourceGroup string       `json:"managedResourceGroup,omitempty" validate:"required_for_put"`
-	SubnetID             string       `json:"subnetId,omitempty"             validate:"required_for_put"`
+	SubnetID             string       `json:"subnetId,omitempty"             validate:"required_for_put,omitempty,resource_id=Microsoft.Network/virtualNetworks/subnets"`
 	OutboundType         OutboundType `json:"outboundType,omitempty"         validate:"omitempty,enum_outboundtype"`
 	//TODO: Is nsg required for PUT, or will we create if not specified?
-	NetworkSecurityGroupID string `json:"networkSecurityGroupId,omitempty" validate:"required_for_put"`
-	EtcdEncryptionSetID    string `json:"etcdEncryptionSetId,omitempty"`
+	NetworkSecurityGroupID string `json:"networkSecurityGroupId,omitempty" validate:"required_for_put,omitempty,resource_id=Microsoft.Network/networkSecurityGroups"`
+	EtcdEncryptionSetID    string `json:"etcdEncryptionSetId,omitempty"    validate:"omitempty,resource_id=Microsoft.Compute/diskEncryptionSets"`
 }
 
 // ExternalAuthConfigProfile represents the external authentication configuration.
 type ExternalAuthConfigProfile struct {
 	Enabled       bool                     `json:"enabled,omitempty"       visibility:"read create"`
-	ExternalAuths []*configv1.OIDCProvider `json:"externalAuths,omitempty" visibility:"read"`
+	ExternalAuths []*configv1.OIDCProvider `json:"externalAuths,omitempty" visibility:"read" key:"Name"`
 }
 
 // IngressProfile represents a cluster ingress configuration.
@@ -107,6 +113,87 @@ type IngressProfile struct {
 	Visibility Visibility `json:"visibility,omitempty" visibility:"read create" validate:"required_for_put,enum_visibility"`
 }
 
+// clusterStructTagMap is the StructTagMap for the internal cluster model.
+var clusterStructTagMap = newClusterStructTagMap()
+
+func newClusterStructTagMap() StructTagMap {
+	structTagMap := NewStructTagMap[HCPOpenShiftCluster]()
+
+	// Fields of external types cannot carry visibility struct tags.
+	// External auth client secrets are write-only.
+	structTagMap["Properties.Spec.ExternalAuth.ExternalAuths.OIDCClients.ClientSecret"] = reflect.StructTag("visibility:\"create update\"")
+	// External auth clients are identified by their component,
+	// as in the OpenShift API.
+	structTagMap["Properties.Spec.ExternalAuth.ExternalAuths.OIDCClients"] += reflect.StructTag(" key:\"ComponentNamespace,ComponentName\"")
+
+	return structTagMap
+}
+
+// versionedClusterFieldPaths maps field paths of the versioned cluster
+// models that differ from the internal cluster model to the internal
+// field path whose struct tag applies.
+var versionedClusterFieldPaths = map[string]string{
+	"Properties.Spec.Fips":                                      "Properties.Spec.FIPS",
+	"Properties.Spec.Network.MachineCidr":                       "Properties.Spec.Network.MachineCIDR",
+	"Properties.Spec.Network.PodCidr":                           "Properties.Spec.Network.PodCIDR",
+	"Properties.Spec.Network.ServiceCidr":                       "Properties.Spec.Network.ServiceCIDR",
+	"Properties.Spec.Proxy.TrustedCa":                           "Properties.Spec.Proxy.TrustedCA",
+	"Properties.Spec.ExternalAuth.ExternalAuths.Clients.Secret": "Properties.Spec.ExternalAuth.ExternalAuths.OIDCClients.ClientSecret",
+}
+
+// NewVersionedClusterStructTagMap returns a StructTagMap for validating
+// versioned cluster models. It holds the internal cluster model's struct
+// tags plus entries for the versioned field paths that are named
+// differently. API versions may override entries in the returned map.
+func NewVersionedClusterStructTagMap() StructTagMap {
+	structTagMap := newClusterStructTagMap()
+	for versionedPath, internalPath := range versionedClusterFieldPaths {
+		structTagMap[versionedPath] = structTagMap[internalPath]
+	}
+	return structTagMap
+}
+
+// PreserveWriteOnly copies write-only field values from the current cluster
+// wherever this cluster leaves them unset. Call this when replacing a stored
+// cluster with one normalized from a request body.
+func (cluster *HCPOpenShiftCluster) PreserveWriteOnly(current *HCPOpenShiftCluster) {
+	PreserveWriteOnly(cluster, current, clusterStructTagMap)
+}
+
+// PreserveUnavailable copies field values from the current cluster for
+// every field that is unavailable in the given API version. Call this when
+// replacing a stored cluster with one normalized from a request body so a
+// client using an older API version does not wipe fields added later.
+func (cluster *HCPOpenShiftCluster) PreserveUnavailable(current *HCPOpenShiftCluster, version Version) {
+	PreserveUnavailable(cluster, current, version.ClusterStructTagMap())
+}
+
+// ValidateSemantics performs cross-field validation of a normalized
+// cluster that struct tags cannot express.
+func (cluster *HCPOpenShiftCluster) ValidateSemantics() []arm.CloudErrorBody {
+	errorDetails := arm.ValidateTags(cluster.Tags)
+	return append(errorDetails, ValidateNetworkProfile(&cluster.Properties.Spec.Network, "properties.spec.network")...)
+}
+
+// LogValue implements slog.LogValuer so that write-only fields
+// such as secrets are redacted whenever a cluster is logged.
+func (cluster *HCPOpenShiftCluster) LogValue() slog.Value {
+	var redacted HCPOpenShiftCluster
+
+	// Round-trip through JSON to obtain a deep copy.
+	data, err := json.Marshal(cluster)
+	if err == nil {
+		err = json.Unmarshal(data, &redacted)
+	}
+	if err != nil {
+		return slog.StringValue(RedactedValue)
+	}
+
+	RedactWriteOnly(&redacted, clusterStructTagMap)
+
+	return slog.AnyValue(redacted)
+}
+
 // Creates an HCPOpenShiftCluster with any non-zero default values.
 func NewDefaultHCPOpenShiftCluster() *HCPOpenShiftCluster {
 	return &HCPOpenShiftCluster{
@@ -11,7 +11,7 @@ import (
 // OpenShift clusters.
 type HCPOpenShiftClusterNodePool struct {
 	arm.TrackedResource
-	Properties HCPOpenShiftClusterNodePoolProperties `json:":properties,omitempty"`
+	Properties HCPOpenShiftClusterNodePoolProperties `json:"properties,omitempty"`
 }
 
 // HCPOpenShiftClusterNodePoolProperties represents the property bag of a
@@ -31,10 +31,10 @@ type NodePoolProfile struct {
 	DiskSize               int32               `json:"diskSize,omitempty"`
 	EphemeralOSDisk        bool                `json:"ephemeralOsDisk,omitempty"`
 	Replicas               int32               `json:"replicas,omitempty"`
-	SubnetID               string              `json:"subnetId,omitempty"`
+	SubnetID               string              `json:"subnetId,omitempty"               validate:"omitempty,resource_id=Microsoft.Network/virtualNetworks/subnets"`
 	EncryptionAtHost       bool                `json:"encryptionAtHost,omitempty"`
 	AutoRepair             bool                `json:"autoRepair,omitempty"`
-	DiscEncryptionSetID    string              `json:"discEncryptionSetId,omitempty"`
+	DiscEncryptionSetID    string              `json:"discEncryptionSetId,omitempty"    validate:"omitempty,resource_id=Microsoft.Compute/diskEncryptionSets"`
 	TuningConfigs          []string            `json:"tuningConfigs,omitempty"`
 	AvailabilityZone       string              `json:"availabilityZone,omitempty"`
 	DiscStorageAccountType string              `json:"discStorageAccountType,omitempty"`
@@ -0,0 +1,116 @@
+package api
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"fmt"
+	"net/netip"
+
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+)
+
+const (
+	// HostPrefix bounds supported for hosted control plane clusters.
+	MinHostPrefix = 23
+	MaxHostPrefix = 26
+)
+
+// reservedRange is an address range that cluster networks must not overlap.
+type reservedRange struct {
+	prefix      netip.Prefix
+	description string
+	// ovnOnly is true if the range is only reserved by OVN-Kubernetes.
+	ovnOnly bool
+}
+
+var reservedRanges = []reservedRange{
+	{netip.MustParsePrefix("0.0.0.0/8"), "the \"this network\" range", false},
+	{netip.MustParsePrefix("127.0.0.0/8"), "the loopback range", false},
+	{netip.MustParsePrefix("169.254.0.0/16"), "the link-local range", false},
+	{netip.MustParsePrefix("168.63.129.16/32"), "the Azure platform virtual IP", false},
+	{netip.MustParsePrefix("224.0.0.0/4"), "the multicast range", false},
+	{netip.MustParsePrefix("240.0.0.0/4"), "the reserved class E range", false},
+	{netip.MustParsePrefix("100.64.0.0/16"), "the OVN-Kubernetes join subnet", true},
+	{netip.MustParsePrefix("100.88.0.0/16"), "the OVN-Kubernetes transit switch subnet", true},
+}
+
+// networkCIDR pairs a parsed CIDR with its display name and error target.
+type networkCIDR struct {
+	prefix netip.Prefix
+	name   string
+	target string
+}
+
+// ValidateNetworkProfile performs cross-field validation of a normalized
+// network profile. Individual fields are expected to have already passed
+// struct tag validation, so unparseable CIDRs are silently skipped here
+// to avoid reporting the same problem twice. target is the path of the
+// network profile within the request body, used for error targets.
+func ValidateNetworkProfile(p *NetworkProfile, target string) []arm.CloudErrorBody {
+	var errorDetails []arm.CloudErrorBody
+
+	addError := func(target, format string, a ...any) {
+		errorDetails = append(errorDetails, arm.CloudErrorBody{
+			Code:    arm.CloudErrorCodeInvalidRequestContent,
+			Message: fmt.Sprintf(format, a...),
+			Target:  target,
+		})
+	}
+
+	var cidrs []networkCIDR
+	for _, item := range []struct {
+		value string
+		name  string
+		field string
+	}{
+		{p.PodCIDR, "pod CIDR", "podCidr"},
+		{p.ServiceCIDR, "service CIDR", "serviceCidr"},
+		{p.MachineCIDR, "machine CIDR", "machineCidr"},
+	} {
+		prefix, err := netip.ParsePrefix(item.value)
+		if err != nil {
+			continue
+		}
+		cidrs = append(cidrs, networkCIDR{prefix.Masked(), item.name, target + "." + item.field})
+	}
+
+	for i, cidr := range cidrs {
+		for _, other := range cidrs[:i] {
+			if cidr.prefix.Overlaps(other.prefix) {
+				addError(cidr.target, "The %s '%s' overlaps with the %s '%s'", cidr.name, cidr.prefix, other.name, other.prefix)
+			}
+		}
+		for _, reserved := range reservedRanges {
+			if reserved.ovnOnly && p.NetworkType != NetworkTypeOVNKubernetes {
+				continue
+			}
+			if cidr.prefix.Overlaps(reserved.prefix) {
+				addError(cidr.target, "The %s '%s' overlaps with %s '%s'", cidr.name, cidr.prefix, reserved.description, reserved.prefix)
+			}
+		}
+	}
+
+	hostPrefixTarget := target + ".hostPrefix"
+	podCIDR, err := netip.ParsePrefix(p.PodCIDR)
+
+	switch p.NetworkType {
+	case NetworkTypeOVNKubernetes:
+		if p.HostPrefix < MinHostPrefix || p.HostPrefix > MaxHostPrefix {
+			addError(hostPrefixTarget, "The host prefix '%d' must be between %d and %d", p.HostPrefix, MinHostPrefix, MaxHostPrefix)
+		} else if err == nil && int(p.HostPrefix) <= podCIDR.Bits() {
+			addError(hostPrefixTarget, "The host prefix '%d' must be longer than the pod CIDR prefix '/%d'", p.HostPrefix, podCIDR.Bits())
+		}
+
+	case NetworkTypeOther:
+		// Third-party network plugins may allocate pod addresses without
+		// per-node subnets, so only require that a host prefix, if given,
+		// fits within the pod CIDR. OVN-Kubernetes reserved ranges are
+		// not checked above for the same reason.
+		if p.HostPrefix != 0 && err == nil && (int(p.HostPrefix) <= podCIDR.Bits() || int(p.HostPrefix) > podCIDR.Addr().BitLen()) {
+			addError(hostPrefixTarget, "The host prefix '%d' must be between %d and %d", p.HostPrefix, podCIDR.Bits()+1, podCIDR.Addr().BitLen())
+		}
+	}
+
+	return errorDetails
+}
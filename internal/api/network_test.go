@@ -0,0 +1,161 @@
+package api
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"testing"
+)
+
+func TestValidateNetworkProfile(t *testing.T) {
+	const target = "properties.spec.network"
+
+	validProfile := func() *NetworkProfile {
+		return &NetworkProfile{
+			NetworkType: NetworkTypeOVNKubernetes,
+			PodCIDR:     "10.128.0.0/14",
+			ServiceCIDR: "172.30.0.0/16",
+			MachineCIDR: "10.0.0.0/16",
+			HostPrefix:  23,
+		}
+	}
+
+	tests := []struct {
+		name           string
+		modify         func(*NetworkProfile)
+		expectTargets  []string
+		expectMessages []string
+	}{
+		{
+			name:   "Valid profile",
+			modify: func(p *NetworkProfile) {},
+		},
+		{
+			name: "Pod CIDR overlaps machine CIDR",
+			modify: func(p *NetworkProfile) {
+				p.MachineCIDR = "10.130.0.0/16"
+			},
+			expectTargets: []string{target + ".machineCidr"},
+			expectMessages: []string{
+				"The machine CIDR '10.130.0.0/16' overlaps with the pod CIDR '10.128.0.0/14'",
+			},
+		},
+		{
+			name: "Service CIDR overlaps pod CIDR and machine CIDR",
+			modify: func(p *NetworkProfile) {
+				p.ServiceCIDR = "10.0.0.0/8"
+			},
+			expectTargets: []string{
+				target + ".serviceCidr",
+				target + ".machineCidr",
+			},
+		},
+		{
+			name: "Unmasked CIDRs are compared by network",
+			modify: func(p *NetworkProfile) {
+				p.ServiceCIDR = "10.129.1.1/16"
+			},
+			expectTargets: []string{target + ".serviceCidr"},
+			expectMessages: []string{
+				"The service CIDR '10.129.0.0/16' overlaps with the pod CIDR '10.128.0.0/14'",
+			},
+		},
+		{
+			name: "Machine CIDR overlaps link-local range",
+			modify: func(p *NetworkProfile) {
+				p.MachineCIDR = "169.254.0.0/24"
+			},
+			expectTargets: []string{target + ".machineCidr"},
+		},
+		{
+			name: "Service CIDR overlaps OVN-Kubernetes join subnet",
+			modify: func(p *NetworkProfile) {
+				p.ServiceCIDR = "100.64.0.0/16"
+			},
+			expectTargets: []string{target + ".serviceCidr"},
+			expectMessages: []string{
+				"The service CIDR '100.64.0.0/16' overlaps with the OVN-Kubernetes join subnet '100.64.0.0/16'",
+			},
+		},
+		{
+			name: "OVN-Kubernetes ranges are allowed with other network types",
+			modify: func(p *NetworkProfile) {
+				p.NetworkType = NetworkTypeOther
+				p.ServiceCIDR = "100.64.0.0/16"
+			},
+		},
+		{
+			name: "Host prefix below minimum",
+			modify: func(p *NetworkProfile) {
+				p.HostPrefix = 22
+			},
+			expectTargets: []string{target + ".hostPrefix"},
+			expectMessages: []string{
+				"The host prefix '22' must be between 23 and 26",
+			},
+		},
+		{
+			name: "Host prefix above maximum",
+			modify: func(p *NetworkProfile) {
+				p.HostPrefix = 27
+			},
+			expectTargets: []string{target + ".hostPrefix"},
+		},
+		{
+			name: "Host prefix not longer than pod CIDR prefix",
+			modify: func(p *NetworkProfile) {
+				p.PodCIDR = "10.128.0.0/24"
+			},
+			expectTargets: []string{target + ".hostPrefix"},
+			expectMessages: []string{
+				"The host prefix '23' must be longer than the pod CIDR prefix '/24'",
+			},
+		},
+		{
+			name: "Host prefix is optional with other network types",
+			modify: func(p *NetworkProfile) {
+				p.NetworkType = NetworkTypeOther
+				p.HostPrefix = 0
+			},
+		},
+		{
+			name: "Host prefix outside the pod CIDR with other network types",
+			modify: func(p *NetworkProfile) {
+				p.NetworkType = NetworkTypeOther
+				p.HostPrefix = 12
+			},
+			expectTargets: []string{target + ".hostPrefix"},
+			expectMessages: []string{
+				"The host prefix '12' must be between 15 and 32",
+			},
+		},
+		{
+			name: "Malformed CIDRs are skipped",
+			modify: func(p *NetworkProfile) {
+				p.PodCIDR = "bogus"
+				p.ServiceCIDR = ""
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := validProfile()
+			tt.modify(profile)
+
+			errorDetails := ValidateNetworkProfile(profile, target)
+
+			if len(errorDetails) != len(tt.expectTargets) {
+				t.Fatalf("Expected %d errors, got %d: %v", len(tt.expectTargets), len(errorDetails), errorDetails)
+			}
+			for i, detail := range errorDetails {
+				if detail.Target != tt.expectTargets[i] {
+					t.Errorf("Expected target %q, got %q", tt.expectTargets[i], detail.Target)
+				}
+				if i < len(tt.expectMessages) && detail.Message != tt.expectMessages[i] {
+					t.Errorf("Expected message %q, got %q", tt.expectMessages[i], detail.Message)
+				}
+			}
+		})
+	}
+}
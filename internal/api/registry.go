@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
+	"sort"
 	"strings"
 
 	validator "github.com/go-playground/validator/v10"
@@ -19,6 +20,7 @@ const (
 	ProviderNamespaceDisplay = "Azure Red Hat OpenShift"
 	ResourceType             = ProviderNamespace + "/" + "hcpOpenShiftClusters"
 	ResourceTypeDisplay      = "Hosted Control Plane (HCP) OpenShift Clusters"
+	NodePoolResourceType     = ResourceType + "/" + "nodePools"
 )
 
 type VersionedHCPOpenShiftCluster interface {
@@ -37,8 +39,11 @@ type Version interface {
 	// Resource Types
 	// Passing a nil pointer creates a resource with default values.
 	NewHCPOpenShiftCluster(*HCPOpenShiftCluster) VersionedHCPOpenShiftCluster
-	// FIXME Disable until we have generated structs for node pools.
-	//NewHCPOpenShiftClusterNodePool(*HCPOpenShiftClusterNodePool) VersionedHCPOpenShiftClusterNodePool
+	// Returns the StructTagMap for HCPOpenShiftCluster as seen through
+	// this version, including any version-specific overrides.
+	ClusterStructTagMap() StructTagMap
+	// Passing a nil pointer creates a resource with default values.
+	NewHCPOpenShiftClusterNodePool(*HCPOpenShiftClusterNodePool) VersionedHCPOpenShiftClusterNodePool
 }
 
 // apiRegistry is the map of registered API versions
@@ -53,6 +58,22 @@ func Lookup(key string) (version Version, ok bool) {
 	return
 }
 
+// Versions returns all registered API versions sorted by their
+// api-version parameter values.
+func Versions() []Version {
+	keys := make([]string, 0, len(apiRegistry))
+	for key := range apiRegistry {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	versions := make([]Version, 0, len(keys))
+	for _, key := range keys {
+		versions = append(versions, apiRegistry[key])
+	}
+	return versions
+}
+
 // GetJSONTagName extracts the JSON field name from the "json" key in
 // a struct tag. Returns an empty string if no "json" key is present,
 // or if the value is "-".
@@ -65,6 +86,17 @@ func GetJSONTagName(tag reflect.StructTag) string {
 	return fieldName
 }
 
+// EnumValidateTag returns a "oneof" validation tag value for the given
+// enum values. Commas in enum values are escaped since validator treats
+// them as tag separators.
+func EnumValidateTag[S ~string](values ...S) string {
+	s := make([]string, len(values))
+	for i, e := range values {
+		s[i] = strings.ReplaceAll(string(e), ",", "0x2C")
+	}
+	return fmt.Sprintf("oneof=%s", strings.Join(s, " "))
+}
+
 func NewValidator() *validator.Validate {
 	var err error
 
@@ -89,6 +121,25 @@ func NewValidator() *validator.Validate {
 		panic(err)
 	}
 
+	// Use this for string fields specifying an Azure resource ID.
+	// The optional parameter restricts the resource type, such as
+	// "resource_id=Microsoft.Network/virtualNetworks".
+	err = validate.RegisterValidation("resource_id", func(fl validator.FieldLevel) bool {
+		field := fl.Field()
+		if field.Kind() != reflect.String {
+			panic("String type required for resource_id")
+		}
+		resourceID, err := arm.ParseResourceID(field.String())
+		if err != nil {
+			return false
+		}
+		resourceType := fl.Param()
+		return resourceType == "" || resourceID.HasResourceType(resourceType)
+	})
+	if err != nil {
+		panic(err)
+	}
+
 	// Use this for fields required in PUT requests. Do not apply to read-only fields.
 	err = validate.RegisterValidation("required_for_put", func(fl validator.FieldLevel) bool {
 		val := fl.Top().FieldByName("Method")
@@ -119,6 +170,19 @@ func NewValidator() *validator.Validate {
 		panic(err)
 	}
 
+	// User assigned identities must be given if and only if
+	// the managed service identity type includes them.
+	validate.RegisterStructValidation(func(sl validator.StructLevel) {
+		identity := sl.Current().Interface().(arm.ManagedServiceIdentity)
+		if identity.Type.HasUserAssigned() {
+			if len(identity.UserAssignedIdentities) == 0 {
+				sl.ReportError(identity.UserAssignedIdentities, "userAssignedIdentities", "UserAssignedIdentities", "required", "")
+			}
+		} else if len(identity.UserAssignedIdentities) > 0 {
+			sl.ReportError(identity.UserAssignedIdentities, "userAssignedIdentities", "UserAssignedIdentities", "excluded", string(identity.Type))
+		}
+	}, arm.ManagedServiceIdentity{})
+
 	return validate
 }
 
@@ -152,6 +216,14 @@ func ValidateRequest(validate *validator.Validate, method string, resource any)
 					message = fmt.Sprintf("Unrecognized API version '%s'", fieldErr.Value())
 				case "required", "required_for_put": // custom tag
 					message = fmt.Sprintf("Missing required field '%s'", fieldErr.Field())
+				case "excluded": // custom tag
+					message = fmt.Sprintf("Field '%s' is not allowed when '%s' is specified", fieldErr.Field(), fieldErr.Param())
+				case "resource_id": // custom tag
+					if fieldErr.Param() != "" {
+						message += fmt.Sprintf(" (must be a %s resource ID)", fieldErr.Param())
+					} else {
+						message += " (must be a resource ID)"
+					}
 				case "cidrv4":
 					message += " (must be a v4 CIDR address)"
 				case "ipv4":
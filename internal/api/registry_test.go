@@ -9,6 +9,8 @@ import (
 	"testing"
 
 	validator "github.com/go-playground/validator/v10"
+
+	"github.com/Azure/ARO-HCP/internal/api/arm"
 )
 
 func TestGetJSONTagName(t *testing.T) {
@@ -62,6 +64,11 @@ type TestRequiredForPut struct {
 	StructField any `json:"field" validate:"required_for_put"`
 }
 
+type TestResourceID struct {
+	ResourceID string `validate:"resource_id"`
+	SubnetID   string `validate:"omitempty,resource_id=Microsoft.Network/virtualNetworks/subnets"`
+}
+
 func TestNewValidator(t *testing.T) {
 	var nilSlice []int
 	var nilMap map[int]int
@@ -189,9 +196,112 @@ func TestNewValidator(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "Validation passes on resource IDs",
+			context: validateContext{
+				Method: http.MethodPost, // not relevant
+				Resource: TestResourceID{
+					ResourceID: "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg",
+					SubnetID:   "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/subnet",
+				},
+			},
+		},
+		{
+			name: "Validation fails on malformed resource ID",
+			context: validateContext{
+				Method: http.MethodPost, // not relevant
+				Resource: TestResourceID{
+					ResourceID: "bogus",
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "Validation fails on resource ID of wrong type",
+			context: validateContext{
+				Method: http.MethodPost, // not relevant
+				Resource: TestResourceID{
+					ResourceID: "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg",
+					SubnetID:   "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet",
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "Validation passes on system assigned identity",
+			context: validateContext{
+				Method: http.MethodPut,
+				Resource: arm.ManagedServiceIdentity{
+					Type: arm.ManagedServiceIdentityTypeSystemAssigned,
+				},
+			},
+		},
+		{
+			name: "Validation passes on user assigned identities",
+			context: validateContext{
+				Method: http.MethodPut,
+				Resource: arm.ManagedServiceIdentity{
+					Type: arm.ManagedServiceIdentityTypeSystemAssignedUserAssigned,
+					UserAssignedIdentities: map[string]*arm.UserAssignedIdentity{
+						"/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/identity": {},
+					},
+				},
+			},
+		},
+		{
+			name: "Validation fails on unknown identity type",
+			context: validateContext{
+				Method: http.MethodPut,
+				Resource: arm.ManagedServiceIdentity{
+					Type: "Bogus",
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "Validation fails on missing user assigned identities",
+			context: validateContext{
+				Method: http.MethodPut,
+				Resource: arm.ManagedServiceIdentity{
+					Type: arm.ManagedServiceIdentityTypeUserAssigned,
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "Validation fails on unexpected user assigned identities",
+			context: validateContext{
+				Method: http.MethodPut,
+				Resource: arm.ManagedServiceIdentity{
+					Type: arm.ManagedServiceIdentityTypeSystemAssigned,
+					UserAssignedIdentities: map[string]*arm.UserAssignedIdentity{
+						"/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/identity": {},
+					},
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "Validation fails on malformed user assigned identity",
+			context: validateContext{
+				Method: http.MethodPut,
+				Resource: arm.ManagedServiceIdentity{
+					Type: arm.ManagedServiceIdentityTypeUserAssigned,
+					UserAssignedIdentities: map[string]*arm.UserAssignedIdentity{
+						"/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet": {},
+					},
+				},
+			},
+			expectError: true,
+		},
 	}
 
 	validate := NewValidator()
+	validate.RegisterAlias("enum_managedserviceidentitytype", EnumValidateTag(
+		arm.ManagedServiceIdentityTypeNone,
+		arm.ManagedServiceIdentityTypeSystemAssigned,
+		arm.ManagedServiceIdentityTypeSystemAssignedUserAssigned,
+		arm.ManagedServiceIdentityTypeUserAssigned))
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -205,7 +315,7 @@ func TestNewValidator(t *testing.T) {
 			} else {
 				for _, fieldError := range err.(validator.ValidationErrors) {
 					switch fieldError.Tag() {
-					case "api_version":
+					case "api_version", "resource_id", "enum_managedserviceidentitytype", "required", "excluded":
 						// Valid tag, nothing more to check.
 					case "required_for_put":
 						// Verify the validate instance is using GetJSONTagName.
@@ -12,6 +12,15 @@ func Ptr[T any](p T) *T {
 	return &p
 }
 
+// Deref returns the value p points to, or the zero value if p is nil.
+func Deref[T any](p *T) T {
+	if p == nil {
+		var zero T
+		return zero
+	}
+	return *p
+}
+
 // DeleteNilsFromPtrSlice returns a slice with nil pointers removed.
 func DeleteNilsFromPtrSlice[S ~[]*E, E any](s S) S {
 	return slices.DeleteFunc(s, func(e *E) bool { return e == nil })
@@ -0,0 +1,37 @@
+package v20240301preview
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+	"github.com/Azure/ARO-HCP/internal/api/v20240610preview"
+)
+
+// HcpOpenShiftClusterResource is the cluster model of this version. It is
+// the 2024-06-10-preview model without disableUserWorkloadMonitoring, so it
+// shares that version's model and conversions.
+type HcpOpenShiftClusterResource struct {
+	v20240610preview.HcpOpenShiftClusterResource
+}
+
+func (v version) NewHCPOpenShiftCluster(from *api.HCPOpenShiftCluster) api.VersionedHCPOpenShiftCluster {
+	out := &HcpOpenShiftClusterResource{*v20240610preview.NewHcpOpenShiftClusterResource(from)}
+	out.Properties.Spec.DisableUserWorkloadMonitoring = nil
+	return out
+}
+
+func (c *HcpOpenShiftClusterResource) Normalize(out *api.HCPOpenShiftCluster) {
+	disableUserWorkloadMonitoring := out.Properties.Spec.DisableUserWorkloadMonitoring
+	c.HcpOpenShiftClusterResource.Normalize(out)
+	out.Properties.Spec.DisableUserWorkloadMonitoring = disableUserWorkloadMonitoring
+}
+
+func (c *HcpOpenShiftClusterResource) ValidateStatic(current api.VersionedHCPOpenShiftCluster, updating bool, method string) *arm.CloudError {
+	return v20240610preview.ValidateClusterStatic(c, current, clusterStructTagMap, updating, method)
+}
+
+func (v version) NewHCPOpenShiftClusterNodePool(from *api.HCPOpenShiftClusterNodePool) api.VersionedHCPOpenShiftClusterNodePool {
+	return v20240610preview.NewHcpOpenShiftClusterNodePoolResource(from)
+}
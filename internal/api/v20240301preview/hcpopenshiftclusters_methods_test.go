@@ -0,0 +1,44 @@
+package v20240301preview
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+)
+
+func TestClusterOmitsDisableUserWorkloadMonitoring(t *testing.T) {
+	cluster := api.NewDefaultHCPOpenShiftCluster()
+	cluster.Properties.Spec.DisableUserWorkloadMonitoring = true
+
+	data, err := json.Marshal(version{}.NewHCPOpenShiftCluster(cluster))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var body struct {
+		Properties struct {
+			Spec map[string]json.RawMessage `json:"spec"`
+		} `json:"properties"`
+	}
+	if err = json.Unmarshal(data, &body); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := body.Properties.Spec["disableUserWorkloadMonitoring"]; found {
+		t.Error("Expected disableUserWorkloadMonitoring to be omitted")
+	}
+
+	// A request cannot set the field either.
+	request := version{}.NewHCPOpenShiftCluster(nil)
+	if err = json.Unmarshal([]byte(`{"properties":{"spec":{"disableUserWorkloadMonitoring":true}}}`), request); err != nil {
+		t.Fatal(err)
+	}
+	normalized := api.NewDefaultHCPOpenShiftCluster()
+	request.Normalize(normalized)
+	if normalized.Properties.Spec.DisableUserWorkloadMonitoring {
+		t.Error("Expected disableUserWorkloadMonitoring to be ignored")
+	}
+}
@@ -0,0 +1,32 @@
+package v20240301preview
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"reflect"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+)
+
+type version struct{}
+
+// String returns the api-version parameter value for this API.
+func (v version) String() string {
+	return "2024-03-01-preview"
+}
+
+// ClusterStructTagMap returns the StructTagMap for HCPOpenShiftCluster
+// as seen through this version.
+func (v version) ClusterStructTagMap() api.StructTagMap {
+	return clusterStructTagMap
+}
+
+var clusterStructTagMap = api.NewVersionedClusterStructTagMap()
+
+func init() {
+	// This field was added in version 2024-06-10-preview.
+	clusterStructTagMap["Properties.Spec.DisableUserWorkloadMonitoring"] = reflect.StructTag("json:\"disableUserWorkloadMonitoring,omitempty\" visibility:\"\"")
+
+	api.Register(version{})
+}
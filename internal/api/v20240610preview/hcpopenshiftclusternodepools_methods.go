@@ -0,0 +1,249 @@
+package v20240610preview
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/arm"
+	"github.com/Azure/ARO-HCP/internal/api/v20240610preview/generated"
+)
+
+type HcpOpenShiftClusterNodePoolResource struct {
+	generated.HcpOpenShiftClusterNodePoolResource
+}
+
+func (v version) NewHCPOpenShiftClusterNodePool(from *api.HCPOpenShiftClusterNodePool) api.VersionedHCPOpenShiftClusterNodePool {
+	return NewHcpOpenShiftClusterNodePoolResource(from)
+}
+
+// NewHcpOpenShiftClusterNodePoolResource converts an internal node pool to
+// this version's node pool model. Passing a nil pointer creates a resource
+// with default values. Older API versions whose node pool model is a subset
+// of this one share it through this function.
+func NewHcpOpenShiftClusterNodePoolResource(from *api.HCPOpenShiftClusterNodePool) *HcpOpenShiftClusterNodePoolResource {
+	if from == nil {
+		from = &api.HCPOpenShiftClusterNodePool{}
+	}
+
+	profile := &from.Properties.Profile
+
+	out := &HcpOpenShiftClusterNodePoolResource{
+		generated.HcpOpenShiftClusterNodePoolResource{
+			ID:       api.Ptr(from.Resource.ID),
+			Name:     api.Ptr(from.Resource.Name),
+			Type:     api.Ptr(from.Resource.Type),
+			Location: api.Ptr(from.TrackedResource.Location),
+			Tags:     map[string]*string{},
+			Properties: &generated.NodePoolProperties{
+				ProvisioningState: api.Ptr(generated.ResourceProvisioningState(from.Properties.ProvisioningState)),
+				Spec: &generated.NodePoolSpec{
+					Version: &generated.VersionProfile{
+						ID: api.Ptr(profile.Version),
+					},
+					Platform: &generated.NodePoolPlatformProfile{
+						SubnetID:               api.Ptr(profile.SubnetID),
+						VMSize:                 api.Ptr(profile.VMSize),
+						DiskSizeGB:             api.Ptr(profile.DiskSize),
+						DiskStorageAccountType: api.Ptr(profile.DiscStorageAccountType),
+						AvailabilityZone:       api.Ptr(profile.AvailabilityZone),
+						EncryptionAtHost:       api.Ptr(profile.EncryptionAtHost),
+						DiscEncryptionSetID:    api.Ptr(profile.DiscEncryptionSetID),
+						EphemeralOsDisk:        api.Ptr(profile.EphemeralOSDisk),
+					},
+					Replicas:      api.Ptr(profile.Replicas),
+					AutoRepair:    api.Ptr(profile.AutoRepair),
+					Labels:        map[string]*string{},
+					Taints:        make([]*generated.Taint, 0, len(profile.Taints)),
+					TuningConfigs: api.StringSliceToStringPtrSlice(profile.TuningConfigs),
+				},
+			},
+		},
+	}
+
+	if from.Resource.SystemData != nil {
+		out.SystemData = &generated.SystemData{
+			CreatedBy:          api.Ptr(from.Resource.SystemData.CreatedBy),
+			CreatedByType:      api.Ptr(generated.CreatedByType(from.Resource.SystemData.CreatedByType)),
+			CreatedAt:          from.Resource.SystemData.CreatedAt,
+			LastModifiedBy:     api.Ptr(from.Resource.SystemData.LastModifiedBy),
+			LastModifiedByType: api.Ptr(generated.CreatedByType(from.Resource.SystemData.LastModifiedByType)),
+			LastModifiedAt:     from.Resource.SystemData.LastModifiedAt,
+		}
+	}
+
+	for key, val := range from.TrackedResource.Tags {
+		out.Tags[key] = api.Ptr(val)
+	}
+
+	// Autoscaling cannot be used together with a fixed replica count.
+	if profile.Autoscaling != (api.NodePoolAutoscaling{}) {
+		out.Properties.Spec.AutoScaling = &generated.NodePoolAutoScaling{
+			Min: api.Ptr(profile.Autoscaling.MinReplicas),
+			Max: api.Ptr(profile.Autoscaling.MaxReplicas),
+		}
+	}
+
+	// The internal model holds labels as "key=value"
+	// and taints as "key=value:effect" strings.
+	for _, label := range profile.Labels {
+		key, value, _ := strings.Cut(label, "=")
+		out.Properties.Spec.Labels[key] = api.Ptr(value)
+	}
+
+	for _, taint := range profile.Taints {
+		taint, effect, _ := strings.Cut(taint, ":")
+		key, value, _ := strings.Cut(taint, "=")
+		out.Properties.Spec.Taints = append(out.Properties.Spec.Taints, &generated.Taint{
+			Key:    api.Ptr(key),
+			Value:  api.Ptr(value),
+			Effect: api.Ptr(generated.Effect(effect)),
+		})
+	}
+
+	return out
+}
+
+func (n *HcpOpenShiftClusterNodePoolResource) Normalize(out *api.HCPOpenShiftClusterNodePool) {
+	if n.ID != nil {
+		out.Resource.ID = *n.ID
+	}
+	if n.Name != nil {
+		out.Resource.Name = *n.Name
+	}
+	if n.Type != nil {
+		out.Resource.Type = *n.Type
+	}
+	if n.SystemData != nil {
+		out.Resource.SystemData = &arm.SystemData{
+			CreatedAt:      n.SystemData.CreatedAt,
+			LastModifiedAt: n.SystemData.LastModifiedAt,
+		}
+		if n.SystemData.CreatedBy != nil {
+			out.Resource.SystemData.CreatedBy = *n.SystemData.CreatedBy
+		}
+		if n.SystemData.CreatedByType != nil {
+			out.Resource.SystemData.CreatedByType = arm.CreatedByType(*n.SystemData.CreatedByType)
+		}
+		if n.SystemData.LastModifiedBy != nil {
+			out.Resource.SystemData.LastModifiedBy = *n.SystemData.LastModifiedBy
+		}
+		if n.SystemData.LastModifiedByType != nil {
+			out.Resource.SystemData.LastModifiedByType = arm.CreatedByType(*n.SystemData.LastModifiedByType)
+		}
+	}
+	if n.Location != nil {
+		out.TrackedResource.Location = *n.Location
+	}
+	out.Tags = make(map[string]string)
+	for k, v := range n.Tags {
+		if v != nil {
+			out.Tags[k] = *v
+		}
+	}
+	if n.Properties != nil {
+		if n.Properties.ProvisioningState != nil {
+			out.Properties.ProvisioningState = arm.ProvisioningState(*n.Properties.ProvisioningState)
+		}
+		if n.Properties.Spec != nil {
+			normalizeNodePoolSpec(n.Properties.Spec, &out.Properties.Profile)
+		}
+	}
+}
+
+func normalizeNodePoolSpec(p *generated.NodePoolSpec, out *api.NodePoolProfile) {
+	if p.Version != nil && p.Version.ID != nil {
+		out.Version = *p.Version.ID
+	}
+	if p.Platform != nil {
+		normalizeNodePoolPlatform(p.Platform, out)
+	}
+	if p.Replicas != nil {
+		out.Replicas = *p.Replicas
+	}
+	if p.AutoRepair != nil {
+		out.AutoRepair = *p.AutoRepair
+	}
+	if p.AutoScaling != nil {
+		if p.AutoScaling.Min != nil {
+			out.Autoscaling.MinReplicas = *p.AutoScaling.Min
+		}
+		if p.AutoScaling.Max != nil {
+			out.Autoscaling.MaxReplicas = *p.AutoScaling.Max
+		}
+	}
+	if p.Labels != nil {
+		out.Labels = make([]string, 0, len(p.Labels))
+		for key, value := range p.Labels {
+			out.Labels = append(out.Labels, fmt.Sprintf("%s=%s", key, api.Deref(value)))
+		}
+		// Map iteration order is random.
+		sort.Strings(out.Labels)
+	}
+	if p.Taints != nil {
+		out.Taints = make([]string, 0, len(p.Taints))
+		for _, taint := range api.DeleteNilsFromPtrSlice(p.Taints) {
+			out.Taints = append(out.Taints, fmt.Sprintf("%s=%s:%s", api.Deref(taint.Key), api.Deref(taint.Value), api.Deref(taint.Effect)))
+		}
+	}
+	if p.TuningConfigs != nil {
+		out.TuningConfigs = api.StringPtrSliceToStringSlice(p.TuningConfigs)
+	}
+}
+
+func normalizeNodePoolPlatform(p *generated.NodePoolPlatformProfile, out *api.NodePoolProfile) {
+	if p.SubnetID != nil {
+		out.SubnetID = *p.SubnetID
+	}
+	if p.VMSize != nil {
+		out.VMSize = *p.VMSize
+	}
+	if p.DiskSizeGB != nil {
+		out.DiskSize = *p.DiskSizeGB
+	}
+	if p.DiskStorageAccountType != nil {
+		out.DiscStorageAccountType = *p.DiskStorageAccountType
+	}
+	if p.AvailabilityZone != nil {
+		out.AvailabilityZone = *p.AvailabilityZone
+	}
+	if p.EncryptionAtHost != nil {
+		out.EncryptionAtHost = *p.EncryptionAtHost
+	}
+	if p.DiscEncryptionSetID != nil {
+		out.DiscEncryptionSetID = *p.DiscEncryptionSetID
+	}
+	if p.EphemeralOsDisk != nil {
+		out.EphemeralOSDisk = *p.EphemeralOsDisk
+	}
+}
+
+func (n *HcpOpenShiftClusterNodePoolResource) ValidateStatic() *arm.CloudError {
+	var errorDetails []arm.CloudErrorBody
+
+	cloudError := arm.ErrorMultipleErrorsOccurred.New("")
+	cloudError.Details = make([]arm.CloudErrorBody, 0)
+
+	normalized := &api.HCPOpenShiftClusterNodePool{}
+	n.Normalize(normalized)
+
+	errorDetails = api.ValidateRequest(validate, http.MethodPut, normalized)
+	if errorDetails != nil {
+		cloudError.Details = append(cloudError.Details, errorDetails...)
+	}
+
+	switch len(cloudError.Details) {
+	case 0:
+		cloudError = nil
+	case 1:
+		// Promote a single validation error out of details.
+		cloudError.CloudErrorBody = &cloudError.Details[0]
+	}
+
+	return cloudError
+}
@@ -0,0 +1,38 @@
+package v20240610preview
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+)
+
+func TestNodePoolRoundTrip(t *testing.T) {
+	nodePool := &api.HCPOpenShiftClusterNodePool{}
+	nodePool.Location = "eastus"
+	nodePool.Tags = map[string]string{"env": "test"}
+	nodePool.Properties.Profile = api.NodePoolProfile{
+		Version:             "4.15",
+		SubnetID:            "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/subnet",
+		VMSize:              "Standard_D8s_v3",
+		DiskSize:            128,
+		DiscEncryptionSetID: "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/diskEncryptionSets/des",
+		Replicas:            3,
+		Labels:              []string{"a=1", "b=2"},
+		Taints:              []string{"key=value:NoSchedule"},
+		TuningConfigs:       []string{},
+	}
+
+	out := &api.HCPOpenShiftClusterNodePool{}
+	version{}.NewHCPOpenShiftClusterNodePool(nodePool).Normalize(out)
+
+	if !reflect.DeepEqual(out.Properties.Profile, nodePool.Properties.Profile) {
+		t.Errorf("Expected profile %+v, got %+v", nodePool.Properties.Profile, out.Properties.Profile)
+	}
+	if !reflect.DeepEqual(out.Tags, nodePool.Tags) {
+		t.Errorf("Expected tags %v, got %v", nodePool.Tags, out.Tags)
+	}
+}
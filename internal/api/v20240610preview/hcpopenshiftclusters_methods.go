@@ -4,8 +4,6 @@ package v20240610preview
 // Licensed under the Apache License 2.0.
 
 import (
-	"net/http"
-
 	configv1 "github.com/openshift/api/config/v1"
 
 	"github.com/Azure/ARO-HCP/internal/api"
@@ -17,6 +15,10 @@ type HcpOpenShiftClusterResource struct {
 	generated.HcpOpenShiftClusterResource
 }
 
+type ManagedServiceIdentity struct {
+	generated.ManagedServiceIdentity
+}
+
 type VersionProfile struct {
 	generated.VersionProfile
 }
@@ -53,6 +55,25 @@ type IngressProfile struct {
 	generated.IngressProfile
 }
 
+func newManagedServiceIdentity(from *arm.ManagedServiceIdentity) *generated.ManagedServiceIdentity {
+	out := &generated.ManagedServiceIdentity{
+		PrincipalID:            api.Ptr(from.PrincipalID),
+		TenantID:               api.Ptr(from.TenantID),
+		Type:                   api.Ptr(generated.ManagedServiceIdentityType(from.Type)),
+		UserAssignedIdentities: make(map[string]*generated.UserAssignedIdentity, len(from.UserAssignedIdentities)),
+	}
+
+	for key, val := range from.UserAssignedIdentities {
+		out.UserAssignedIdentities[key] = &generated.UserAssignedIdentity{}
+		if val != nil {
+			out.UserAssignedIdentities[key].ClientID = api.Ptr(val.ClientID)
+			out.UserAssignedIdentities[key].PrincipalID = api.Ptr(val.PrincipalID)
+		}
+	}
+
+	return out
+}
+
 func newVersionProfile(from *api.VersionProfile) *generated.VersionProfile {
 	return &generated.VersionProfile{
 		ID:                api.Ptr(from.ID),
@@ -93,11 +114,11 @@ func newAPIProfile(from *api.APIProfile) *generated.APIProfile {
 }
 
 func newProxyProfile(from *api.ProxyProfile) *generated.ProxyProfile {
+	// TrustedCA is write-only so omit it.
 	return &generated.ProxyProfile{
 		HTTPProxy:  api.Ptr(from.HTTPProxy),
 		HTTPSProxy: api.Ptr(from.HTTPSProxy),
 		NoProxy:    api.Ptr(from.NoProxy),
-		TrustedCa:  api.Ptr(from.TrustedCA),
 	}
 }
 
@@ -174,18 +195,26 @@ func newTokenClaimValidationRuleProfile(from configv1.TokenClaimValidationRule)
 }
 
 func newExternalAuthClientProfile(from configv1.OIDCClientConfig) *generated.ExternalAuthClientProfile {
+	// ClientSecret is write-only so omit it.
 	return &generated.ExternalAuthClientProfile{
 		Component: &generated.ExternalAuthClientComponentProfile{
 			Name:                api.Ptr(from.ComponentName),
 			AuthClientNamespace: api.Ptr(from.ComponentNamespace),
 		},
 		ID:          api.Ptr(from.ClientID),
-		Secret:      api.Ptr(from.ClientSecret.Name),
 		ExtraScopes: api.StringSliceToStringPtrSlice(from.ExtraScopes),
 	}
 }
 
 func (v version) NewHCPOpenShiftCluster(from *api.HCPOpenShiftCluster) api.VersionedHCPOpenShiftCluster {
+	return NewHcpOpenShiftClusterResource(from)
+}
+
+// NewHcpOpenShiftClusterResource converts an internal cluster to this
+// version's cluster model. Passing a nil pointer creates a resource with
+// default values. Older API versions whose cluster model is a subset of
+// this one share it through this function.
+func NewHcpOpenShiftClusterResource(from *api.HCPOpenShiftCluster) *HcpOpenShiftClusterResource {
 	if from == nil {
 		from = api.NewDefaultHCPOpenShiftCluster()
 	}
@@ -197,7 +226,6 @@ func (v version) NewHCPOpenShiftCluster(from *api.HCPOpenShiftCluster) api.Versi
 			Type:     api.Ptr(from.Resource.Type),
 			Location: api.Ptr(from.TrackedResource.Location),
 			Tags:     map[string]*string{},
-			// FIXME Skipping ManagedServiceIdentity
 			Properties: &generated.HcpOpenShiftClusterProperties{
 				ProvisioningState: api.Ptr(generated.ProvisioningState(from.Properties.ProvisioningState)),
 				Spec: &generated.ClusterSpec{
@@ -222,6 +250,10 @@ func (v version) NewHCPOpenShiftCluster(from *api.HCPOpenShiftCluster) api.Versi
 		},
 	}
 
+	if from.Identity != nil {
+		out.Identity = newManagedServiceIdentity(from.Identity)
+	}
+
 	if from.Resource.SystemData != nil {
 		out.SystemData = &generated.SystemData{
 			CreatedBy:          api.Ptr(from.Resource.SystemData.CreatedBy),
@@ -276,7 +308,10 @@ func (c *HcpOpenShiftClusterResource) Normalize(out *api.HCPOpenShiftCluster) {
 			out.Resource.SystemData.LastModifiedByType = arm.CreatedByType(*c.SystemData.LastModifiedByType)
 		}
 	}
-	// FIXME Skipping ManagedServiceIdentity
+	if c.Identity != nil {
+		out.Identity = &arm.ManagedServiceIdentity{}
+		normalizeManagedServiceIdentity(c.Identity, out.Identity)
+	}
 	if c.Location != nil {
 		out.TrackedResource.Location = *c.Location
 	}
@@ -338,23 +373,35 @@ func (c *HcpOpenShiftClusterResource) Normalize(out *api.HCPOpenShiftCluster) {
 }
 
 func (c *HcpOpenShiftClusterResource) ValidateStatic(current api.VersionedHCPOpenShiftCluster, updating bool, method string) *arm.CloudError {
-	var normalized api.HCPOpenShiftCluster
+	return ValidateClusterStatic(c, current, clusterStructTagMap, updating, method)
+}
+
+// ValidateClusterStatic validates a cluster request of any API version
+// sharing this version's cluster model, checking field visibility against
+// the given StructTagMap. The request and current values must have the
+// same type.
+func ValidateClusterStatic(c, current api.VersionedHCPOpenShiftCluster, structTagMap api.StructTagMap, updating bool, method string) *arm.CloudError {
 	var errorDetails []arm.CloudErrorBody
 
-	cloudError := arm.NewCloudError(
-		http.StatusBadRequest,
-		arm.CloudErrorCodeMultipleErrorsOccurred, "",
-		"Content validation failed on multiple fields")
+	cloudError := arm.ErrorMultipleErrorsOccurred.New("")
 	cloudError.Details = make([]arm.CloudErrorBody, 0)
 
-	errorDetails = api.ValidateVisibility(c, current, clusterStructTagMap, updating)
+	errorDetails = api.ValidateVisibility(c, current, structTagMap, updating)
 	if errorDetails != nil {
 		cloudError.Details = append(cloudError.Details, errorDetails...)
 	}
 
-	c.Normalize(&normalized)
+	// Normalize onto the defaults the frontend stores
+	// so omitted fields are validated as stored.
+	normalized := api.NewDefaultHCPOpenShiftCluster()
+	c.Normalize(normalized)
 
-	errorDetails = api.ValidateRequest(validate, method, &normalized)
+	errorDetails = api.ValidateRequest(validate, method, normalized)
+	if errorDetails != nil {
+		cloudError.Details = append(cloudError.Details, errorDetails...)
+	}
+
+	errorDetails = normalized.ValidateSemantics()
 	if errorDetails != nil {
 		cloudError.Details = append(cloudError.Details, errorDetails...)
 	}
@@ -370,6 +417,34 @@ func (c *HcpOpenShiftClusterResource) ValidateStatic(current api.VersionedHCPOpe
 	return cloudError
 }
 
+func (i *ManagedServiceIdentity) Normalize(out *arm.ManagedServiceIdentity) {
+	normalizeManagedServiceIdentity(&i.ManagedServiceIdentity, out)
+}
+
+func normalizeManagedServiceIdentity(i *generated.ManagedServiceIdentity, out *arm.ManagedServiceIdentity) {
+	if i.PrincipalID != nil {
+		out.PrincipalID = *i.PrincipalID
+	}
+	if i.TenantID != nil {
+		out.TenantID = *i.TenantID
+	}
+	if i.Type != nil {
+		out.Type = arm.ManagedServiceIdentityType(*i.Type)
+	}
+	out.UserAssignedIdentities = make(map[string]*arm.UserAssignedIdentity, len(i.UserAssignedIdentities))
+	for key, val := range i.UserAssignedIdentities {
+		out.UserAssignedIdentities[key] = &arm.UserAssignedIdentity{}
+		if val != nil {
+			if val.ClientID != nil {
+				out.UserAssignedIdentities[key].ClientID = *val.ClientID
+			}
+			if val.PrincipalID != nil {
+				out.UserAssignedIdentities[key].PrincipalID = *val.PrincipalID
+			}
+		}
+	}
+}
+
 func (p *VersionProfile) Normalize(out *api.VersionProfile) {
 	normalizeVersion(&p.VersionProfile, out)
 }
@@ -549,7 +624,9 @@ func normalizeExternalAuthConfig(p *generated.ExternalAuthConfigProfile, out *ap
 						provider.ClaimMappings.Username.PrefixPolicy = configv1.UsernamePrefixPolicy(*item.Claim.Mappings.Username.PrefixPolicy)
 					}
 					if item.Claim.Mappings.Username.Prefix != nil {
-						provider.ClaimMappings.Username.Prefix.PrefixString = *item.Claim.Mappings.Username.Prefix
+						provider.ClaimMappings.Username.Prefix = &configv1.UsernamePrefix{
+							PrefixString: *item.Claim.Mappings.Username.Prefix,
+						}
 					}
 				}
 				if item.Claim.Mappings.Groups != nil {
@@ -561,20 +638,20 @@ func normalizeExternalAuthConfig(p *generated.ExternalAuthConfigProfile, out *ap
 					}
 				}
 			}
-		}
 
-		validationRuleSequence := api.DeleteNilsFromPtrSlice(item.Claim.ValidationRules)
-		provider.ClaimValidationRules = make([]configv1.TokenClaimValidationRule, len(validationRuleSequence))
-		for index, rule := range validationRuleSequence {
-			provider.ClaimValidationRules[index] = configv1.TokenClaimValidationRule{
-				Type:          configv1.TokenValidationRuleTypeRequiredClaim,
-				RequiredClaim: &configv1.TokenRequiredClaim{},
-			}
-			if rule.Claim != nil {
-				provider.ClaimValidationRules[index].RequiredClaim.Claim = *rule.Claim
-			}
-			if rule.RequiredValue != nil {
-				provider.ClaimValidationRules[index].RequiredClaim.RequiredValue = *rule.RequiredValue
+			validationRuleSequence := api.DeleteNilsFromPtrSlice(item.Claim.ValidationRules)
+			provider.ClaimValidationRules = make([]configv1.TokenClaimValidationRule, len(validationRuleSequence))
+			for index, rule := range validationRuleSequence {
+				provider.ClaimValidationRules[index] = configv1.TokenClaimValidationRule{
+					Type:          configv1.TokenValidationRuleTypeRequiredClaim,
+					RequiredClaim: &configv1.TokenRequiredClaim{},
+				}
+				if rule.Claim != nil {
+					provider.ClaimValidationRules[index].RequiredClaim.Claim = *rule.Claim
+				}
+				if rule.RequiredValue != nil {
+					provider.ClaimValidationRules[index].RequiredClaim.RequiredValue = *rule.RequiredValue
+				}
 			}
 		}
 
@@ -0,0 +1,75 @@
+package v20240610preview
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the Apache License 2.0.
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Azure/ARO-HCP/internal/api"
+	"github.com/Azure/ARO-HCP/internal/api/v20240610preview/generated"
+)
+
+func TestValidateStaticNetworkDefaults(t *testing.T) {
+	tests := []struct {
+		name          string
+		network       generated.NetworkProfile
+		expectTarget  string
+		expectMessage string
+	}{
+		{
+			name: "Omitted network type applies OVN-Kubernetes reserved ranges",
+			network: generated.NetworkProfile{
+				PodCidr: api.Ptr("100.64.0.0/14"),
+			},
+			expectTarget:  "properties.spec.network.podCidr",
+			expectMessage: "The pod CIDR '100.64.0.0/14' overlaps with the OVN-Kubernetes join subnet '100.64.0.0/16'",
+		},
+		{
+			name: "Omitted network type applies OVN-Kubernetes host prefix",
+			network: generated.NetworkProfile{
+				PodCidr: api.Ptr("10.128.0.0/24"),
+			},
+			expectTarget:  "properties.spec.network.hostPrefix",
+			expectMessage: "The host prefix '23' must be longer than the pod CIDR prefix '/24'",
+		},
+		{
+			name: "Omitted host prefix defaults within bounds",
+			network: generated.NetworkProfile{
+				NetworkType: api.Ptr(generated.NetworkTypeOVNKubernetes),
+				PodCidr:     api.Ptr("10.128.0.0/14"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.network.ServiceCidr = api.Ptr("172.30.0.0/16")
+			tt.network.MachineCidr = api.Ptr("10.0.0.0/16")
+
+			// Deliberately not created with defaults,
+			// so omitted fields are left unset.
+			cluster := &HcpOpenShiftClusterResource{}
+			cluster.Properties = &generated.HcpOpenShiftClusterProperties{
+				Spec: &generated.ClusterSpec{Network: &tt.network},
+			}
+
+			var found bool
+			if cloudError := cluster.ValidateStatic(version{}.NewHCPOpenShiftCluster(nil), false, http.MethodPut); cloudError != nil {
+				for _, detail := range append(cloudError.Details, *cloudError.CloudErrorBody) {
+					if strings.HasPrefix(detail.Target, "properties.spec.network") {
+						if detail.Target != tt.expectTarget || detail.Message != tt.expectMessage {
+							t.Errorf("Unexpected error for %s: %s", detail.Target, detail.Message)
+						}
+						found = true
+					}
+				}
+			}
+			if tt.expectTarget != "" && !found {
+				t.Errorf("Expected an error for %s", tt.expectTarget)
+			}
+		})
+	}
+}
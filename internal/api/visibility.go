@@ -19,6 +19,13 @@ import (
 
 const VisibilityStructTagKey = "visibility"
 
+// ElementKeyStructTagKey names the struct tag that lists the fields
+// identifying an element of a slice field, separated by commas. Slice
+// elements are matched up by these fields when preserving field values,
+// so that adding, removing or reordering elements does not pair an
+// element with the stored value of another.
+const ElementKeyStructTagKey = "key"
+
 // VisibilityFlags holds a visibility struct tag value as bit flags.
 type VisibilityFlags uint8
 
@@ -37,6 +44,19 @@ func (f VisibilityFlags) ReadOnly() bool {
 	return f&(VisibilityRead|VisibilityCreate|VisibilityUpdate) == VisibilityRead
 }
 
+// WriteOnly returns true if the field can be written on create or update
+// but is never returned in responses, such as a secret.
+func (f VisibilityFlags) WriteOnly() bool {
+	return f&VisibilityRead == 0 && f&(VisibilityCreate|VisibilityUpdate) != 0
+}
+
+// Unavailable returns true if the field does not exist in an API version.
+// An empty visibility struct tag value marks such a field. Older versions
+// of the API set this in their StructTagMap for fields added later.
+func (f VisibilityFlags) Unavailable() bool {
+	return f&VisibilityDefault == 0
+}
+
 func (f VisibilityFlags) CanUpdate() bool {
 	return f&VisibilityUpdate != 0
 }
@@ -99,12 +119,22 @@ type StructTagMap map[string]reflect.StructTag
 
 func buildStructTagMap(structTagMap StructTagMap, t reflect.Type, path string) {
 	switch t.Kind() {
-	case reflect.Pointer, reflect.Slice:
+	case reflect.Map, reflect.Pointer, reflect.Slice:
 		buildStructTagMap(structTagMap, t.Elem(), path)
 
 	case reflect.Struct:
 		for i := 0; i < t.NumField(); i++ {
 			field := t.Field(i)
+
+			// Fields of embedded structs are promoted, so omit the
+			// embedded struct name from the path. This way internal
+			// and versioned structs which embed different wrapper
+			// types still produce matching paths.
+			if field.Anonymous {
+				buildStructTagMap(structTagMap, field.Type, path)
+				continue
+			}
+
 			subpath := join(path, field.Name)
 
 			if len(field.Tag) > 0 {
@@ -121,8 +151,9 @@ func buildStructTagMap(structTagMap StructTagMap, t reflect.Type, path string) {
 // its own visibiilty map for tracked resource types.
 //
 // Note: This assumes field names for internal and versioned structs are
-// identical where visibility is explicitly specified. If some divergence
-// emerges, one workaround could be to pass a field name override map.
+// identical where visibility is explicitly specified. Where they diverge,
+// the versioned field paths must be added to the map explicitly, as
+// NewVersionedClusterStructTagMap does.
 func NewStructTagMap[T any]() StructTagMap {
 	structTagMap := StructTagMap{}
 	buildStructTagMap(structTagMap, reflect.TypeFor[T](), "")
@@ -158,7 +189,8 @@ func ValidateVisibility(newVal, curVal interface{}, structTagMap StructTagMap, u
 // also includes subscripts for arrays, maps and slices when evaluating their
 // immediate elements.
 func (vv *validateVisibility) recurse(newVal, curVal reflect.Value, mapKey, namespace, fieldname string, implicitVisibility VisibilityFlags) {
-	flags, ok := GetVisibilityFlags(vv.structTagMap[mapKey])
+	tag := vv.structTagMap[mapKey]
+	flags, ok := GetVisibilityFlags(tag)
 	if !ok {
 		flags = implicitVisibility
 	}
@@ -177,6 +209,16 @@ func (vv *validateVisibility) recurse(newVal, curVal reflect.Value, mapKey, name
 		}
 	}
 
+	// Write-only fields are never included in responses, so the current
+	// value cannot be meaningfully compared. An absent value means "keep
+	// the existing value" and any value present is treated as a change.
+	if flags.WriteOnly() {
+		if !newVal.IsZero() {
+			vv.checkFlags(flags, namespace, fieldname)
+		}
+		return
+	}
+
 	switch newVal.Kind() {
 	case reflect.Bool:
 		if newVal.Bool() != curVal.Bool() {
@@ -233,7 +275,7 @@ func (vv *validateVisibility) recurse(newVal, curVal reflect.Value, mapKey, name
 			}
 		}
 
-	case reflect.Interface, reflect.Pointer:
+	case reflect.Interface:
 		// We already know that newVal is not nil.
 		if curVal.IsNil() {
 			vv.checkFlags(flags, namespace, fieldname)
@@ -241,30 +283,51 @@ func (vv *validateVisibility) recurse(newVal, curVal reflect.Value, mapKey, name
 			vv.recurse(newVal.Elem(), curVal.Elem(), mapKey, namespace, fieldname, flags)
 		}
 
+	case reflect.Pointer:
+		// We already know that newVal is not nil. A nil current value
+		// compares like a zero value so that read-only fields nested in
+		// the new value are still checked.
+		if curVal.IsNil() {
+			curVal = reflect.New(newVal.Type().Elem())
+		}
+		vv.recurse(newVal.Elem(), curVal.Elem(), mapKey, namespace, fieldname, flags)
+
 	case reflect.Map:
-		// We already know that newVal is not nil.
-		if curVal.IsNil() || newVal.Len() != curVal.Len() {
-			vv.checkFlags(flags, namespace, fieldname)
-		} else {
-			iter := newVal.MapRange()
-			for iter.Next() {
-				k := iter.Key()
-
-				subscript := fmt.Sprintf("[%q]", k.Interface())
-				if curVal.MapIndex(k).IsValid() {
-					vv.recurse(newVal.MapIndex(k), curVal.MapIndex(k), mapKey, namespace, fieldname+subscript, flags)
-				} else {
-					vv.checkFlags(flags, namespace, fieldname+subscript)
+		// We already know that newVal is not nil. A nil current value
+		// compares like an empty map.
+		if newVal.Len() != curVal.Len() {
+			if !vv.checkFlagsOK(flags, namespace, fieldname) {
+				return
+			}
+		}
+		iter := newVal.MapRange()
+		for iter.Next() {
+			k := iter.Key()
+
+			subscript := fmt.Sprintf("[%q]", k.Interface())
+			curElem := curVal.MapIndex(k)
+			if !curElem.IsValid() {
+				// Added elements compare like zero values.
+				if newVal.Len() == curVal.Len() && !vv.checkFlagsOK(flags, namespace, fieldname+subscript) {
+					continue
 				}
+				curElem = reflect.Zero(newVal.Type().Elem())
 			}
+			vv.recurse(newVal.MapIndex(k), curElem, mapKey, namespace, fieldname+subscript, flags)
 		}
 
 	case reflect.Struct:
 		for i := 0; i < newVal.NumField(); i++ {
 			structField := newVal.Type().Field(i)
+			if structField.Anonymous {
+				// Promoted fields share the parent's path.
+				vv.recurse(newVal.Field(i), curVal.Field(i), mapKey, namespace, fieldname, flags)
+				continue
+			}
 			mapKeyNext := join(mapKey, structField.Name)
 			namespaceNext := join(namespace, fieldname)
-			fieldnameNext := GetJSONTagName(vv.structTagMap[mapKeyNext])
+			tagNext := vv.structTagMap[mapKeyNext]
+			fieldnameNext := GetJSONTagName(tagNext)
 			if fieldnameNext == "" {
 				fieldnameNext = structField.Name
 			}
@@ -273,6 +336,13 @@ func (vv *validateVisibility) recurse(newVal, curVal reflect.Value, mapKey, name
 	}
 }
 
+// checkFlagsOK is checkFlags that returns false if the change was rejected.
+func (vv *validateVisibility) checkFlagsOK(flags VisibilityFlags, namespace, fieldname string) bool {
+	errs := len(vv.errs)
+	vv.checkFlags(flags, namespace, fieldname)
+	return len(vv.errs) == errs
+}
+
 func (vv *validateVisibility) checkFlags(flags VisibilityFlags, namespace, fieldname string) {
 	if flags.ReadOnly() {
 		vv.errs = append(vv.errs,
@@ -290,3 +360,159 @@ func (vv *validateVisibility) checkFlags(flags VisibilityFlags, namespace, field
 			})
 	}
 }
+
+// PreserveWriteOnly copies write-only field values from the current value
+// (curVal) to the new value (newVal) wherever the new value leaves them
+// unset. Write-only fields are omitted from responses, so a client doing
+// a read-modify-write cycle cannot echo them back. An absent write-only
+// field in a request therefore means "keep the existing value". newVal
+// must be a pointer so it can be modified.
+func PreserveWriteOnly(newVal, curVal interface{}, structTagMap StructTagMap) {
+	preserveFields(reflect.ValueOf(newVal), reflect.ValueOf(curVal), structTagMap, "", VisibilityDefault, func(flags VisibilityFlags, newVal reflect.Value) bool {
+		return flags.WriteOnly() && newVal.IsZero()
+	})
+}
+
+// PreserveUnavailable copies field values from the current value (curVal)
+// to the new value (newVal) for every field that is unavailable in the API
+// version described by structTagMap. A client using an older API version
+// cannot set or even see fields added in newer versions, so a request with
+// that version must not wipe them. newVal must be a pointer so it can be
+// modified.
+func PreserveUnavailable(newVal, curVal interface{}, structTagMap StructTagMap) {
+	preserveFields(reflect.ValueOf(newVal), reflect.ValueOf(curVal), structTagMap, "", VisibilityDefault, func(flags VisibilityFlags, newVal reflect.Value) bool {
+		return flags.Unavailable()
+	})
+}
+
+// preserveFields walks newVal and curVal in parallel and copies a field
+// from curVal to newVal when the preserve function returns true for it.
+// Fields with write-only or unavailable visibility are never descended
+// into, since their visibility applies to the field as a whole.
+func preserveFields(newVal, curVal reflect.Value, structTagMap StructTagMap, mapKey string, implicitVisibility VisibilityFlags, preserve func(VisibilityFlags, reflect.Value) bool) {
+	tag := structTagMap[mapKey]
+	flags, ok := GetVisibilityFlags(tag)
+	if !ok {
+		flags = implicitVisibility
+	}
+
+	if newVal.Type() != curVal.Type() {
+		panic(fmt.Sprintf("%s: value types differ (%s vs %s)", mapKey, newVal.Type().Name(), curVal.Type().Name()))
+	}
+
+	if flags.WriteOnly() || flags.Unavailable() {
+		if preserve(flags, newVal) && newVal.CanSet() {
+			newVal.Set(curVal)
+		}
+		return
+	}
+
+	switch newVal.Kind() {
+	case reflect.Pointer:
+		if !newVal.IsNil() && !curVal.IsNil() {
+			preserveFields(newVal.Elem(), curVal.Elem(), structTagMap, mapKey, flags, preserve)
+		}
+
+	case reflect.Slice, reflect.Array:
+		if keyFields, ok := tag.Lookup(ElementKeyStructTagKey); ok {
+			fields := strings.Split(keyFields, ",")
+			curElems := make(map[string]reflect.Value, curVal.Len())
+			for i := 0; i < curVal.Len(); i++ {
+				if key, ok := elementKey(curVal.Index(i), fields); ok {
+					curElems[key] = curVal.Index(i)
+				}
+			}
+			for i := 0; i < newVal.Len(); i++ {
+				if key, ok := elementKey(newVal.Index(i), fields); ok {
+					if curElem, found := curElems[key]; found {
+						preserveFields(newVal.Index(i), curElem, structTagMap, mapKey, flags, preserve)
+					}
+				}
+			}
+		} else if newVal.Len() == curVal.Len() {
+			// Without key fields, elements can only be matched up by position.
+			for i := 0; i < newVal.Len(); i++ {
+				preserveFields(newVal.Index(i), curVal.Index(i), structTagMap, mapKey, flags, preserve)
+			}
+		}
+
+	case reflect.Struct:
+		for i := 0; i < newVal.NumField(); i++ {
+			structField := newVal.Type().Field(i)
+			if !structField.IsExported() {
+				continue
+			}
+			mapKeyNext := mapKey
+			if !structField.Anonymous {
+				mapKeyNext = join(mapKey, structField.Name)
+			}
+			preserveFields(newVal.Field(i), curVal.Field(i), structTagMap, mapKeyNext, flags, preserve)
+		}
+	}
+}
+
+// elementKey returns the values of the named fields of a slice element,
+// which must be a struct or a pointer to one, joined into a single key.
+// It returns false for a nil element.
+func elementKey(v reflect.Value, fields []string) (string, bool) {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", false
+		}
+		v = v.Elem()
+	}
+
+	values := make([]string, len(fields))
+	for i, field := range fields {
+		values[i] = fmt.Sprint(v.FieldByName(strings.TrimSpace(field)).Interface())
+	}
+	return strings.Join(values, "/"), true
+}
+
+// RedactedValue replaces the value of write-only string fields in output
+// that must not reveal them, such as logs.
+const RedactedValue = "REDACTED"
+
+// RedactWriteOnly replaces the value of any non-empty write-only string
+// field in v with RedactedValue. v must be a pointer so it can be modified.
+func RedactWriteOnly(v interface{}, structTagMap StructTagMap) {
+	redactWriteOnly(reflect.ValueOf(v), structTagMap, "", VisibilityDefault)
+}
+
+func redactWriteOnly(v reflect.Value, structTagMap StructTagMap, mapKey string, implicitVisibility VisibilityFlags) {
+	tag := structTagMap[mapKey]
+	flags, ok := GetVisibilityFlags(tag)
+	if !ok {
+		flags = implicitVisibility
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		if flags.WriteOnly() && v.Len() > 0 && v.CanSet() {
+			v.SetString(RedactedValue)
+		}
+
+	case reflect.Pointer:
+		if !v.IsNil() {
+			redactWriteOnly(v.Elem(), structTagMap, mapKey, flags)
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			redactWriteOnly(v.Index(i), structTagMap, mapKey, flags)
+		}
+
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			structField := v.Type().Field(i)
+			if !structField.IsExported() {
+				continue
+			}
+			mapKeyNext := mapKey
+			if !structField.Anonymous {
+				mapKeyNext = join(mapKey, structField.Name)
+			}
+			redactWriteOnly(v.Field(i), structTagMap, mapKeyNext, flags)
+		}
+	}
+}
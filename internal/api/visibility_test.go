@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	configv1 "github.com/openshift/api/config/v1"
 )
 
 func TestVisibilityFlags(t *testing.T) {
@@ -17,6 +18,8 @@ func TestVisibilityFlags(t *testing.T) {
 		expectString          string
 		expectReadOnly        bool
 		expectCanUpdate       bool
+		expectWriteOnly       bool
+		expectUnavailable     bool
 		expectCaseInsensitive bool
 	}{
 		{
@@ -25,6 +28,8 @@ func TestVisibilityFlags(t *testing.T) {
 			expectString:          "",
 			expectReadOnly:        false,
 			expectCanUpdate:       false,
+			expectWriteOnly:       false,
+			expectUnavailable:     true,
 			expectCaseInsensitive: false,
 		},
 		{
@@ -33,6 +38,8 @@ func TestVisibilityFlags(t *testing.T) {
 			expectString:          "read",
 			expectReadOnly:        true,
 			expectCanUpdate:       false,
+			expectWriteOnly:       false,
+			expectUnavailable:     false,
 			expectCaseInsensitive: false,
 		},
 		{
@@ -41,6 +48,8 @@ func TestVisibilityFlags(t *testing.T) {
 			expectString:          "create",
 			expectReadOnly:        false,
 			expectCanUpdate:       false,
+			expectWriteOnly:       true,
+			expectUnavailable:     false,
 			expectCaseInsensitive: false,
 		},
 		{
@@ -49,6 +58,8 @@ func TestVisibilityFlags(t *testing.T) {
 			expectString:          "update",
 			expectReadOnly:        false,
 			expectCanUpdate:       true,
+			expectWriteOnly:       true,
+			expectUnavailable:     false,
 			expectCaseInsensitive: false,
 		},
 		{
@@ -57,14 +68,28 @@ func TestVisibilityFlags(t *testing.T) {
 			expectString:          "nocase",
 			expectReadOnly:        false,
 			expectCanUpdate:       false,
+			expectWriteOnly:       false,
+			expectUnavailable:     true,
 			expectCaseInsensitive: true,
 		},
+		{
+			name:                  "Visibility: create update",
+			tag:                   reflect.StructTag("visibility:\"create update\""),
+			expectString:          "create update",
+			expectReadOnly:        false,
+			expectCanUpdate:       true,
+			expectWriteOnly:       true,
+			expectUnavailable:     false,
+			expectCaseInsensitive: false,
+		},
 		{
 			name:                  "Visibility: (all)",
 			tag:                   reflect.StructTag("visibility:\"read create update nocase\""),
 			expectString:          "read create update nocase",
 			expectReadOnly:        false,
 			expectCanUpdate:       true,
+			expectWriteOnly:       false,
+			expectUnavailable:     false,
 			expectCaseInsensitive: true,
 		},
 	}
@@ -81,6 +106,12 @@ func TestVisibilityFlags(t *testing.T) {
 			if flags.CanUpdate() != tt.expectCanUpdate {
 				t.Errorf("Expected flags.CanUpdate() to be %v, got %v", tt.expectCanUpdate, flags.CanUpdate())
 			}
+			if flags.WriteOnly() != tt.expectWriteOnly {
+				t.Errorf("Expected flags.WriteOnly() to be %v, got %v", tt.expectWriteOnly, flags.WriteOnly())
+			}
+			if flags.Unavailable() != tt.expectUnavailable {
+				t.Errorf("Expected flags.Unavailable() to be %v, got %v", tt.expectUnavailable, flags.Unavailable())
+			}
 			if flags.CaseInsensitive() != tt.expectCaseInsensitive {
 				t.Errorf("Expected flags.CaseInsensitive() to be %v, got %v", tt.expectCaseInsensitive, flags.CaseInsensitive())
 			}
@@ -981,3 +1012,415 @@ func TestValidateVisibility(t *testing.T) {
 		})
 	}
 }
+
+type TestModelWriteOnlyType struct {
+	TestModelEmbedded
+
+	// Write-only fields are never returned in responses.
+	CreateUpdate *string `visibility:"create update"`
+	Create       *string `visibility:"create"`
+
+	// Slice of struct type with a write-only field,
+	// whose elements are identified by name.
+	Items []*TestModelWriteOnlyItem `key:"Name"`
+}
+
+type TestModelEmbedded struct {
+	Read *string `visibility:"read"`
+}
+
+type TestModelWriteOnlyItem struct {
+	Name   string
+	Secret string `visibility:"create update"`
+}
+
+var TestModelWriteOnlyTypeStructTagMap = NewStructTagMap[TestModelWriteOnlyType]()
+
+func TestStructTagMapEmbedded(t *testing.T) {
+	// Fields of embedded structs are promoted.
+	expectedStructTagMap := StructTagMap{
+		"Read":         reflect.StructTag("visibility:\"read\""),
+		"CreateUpdate": reflect.StructTag("visibility:\"create update\""),
+		"Create":       reflect.StructTag("visibility:\"create\""),
+		"Items":        reflect.StructTag("key:\"Name\""),
+		"Items.Secret": reflect.StructTag("visibility:\"create update\""),
+	}
+
+	if !cmp.Equal(TestModelWriteOnlyTypeStructTagMap, expectedStructTagMap, nil) {
+		t.Errorf(
+			"StructTagMap had unexpected differences:\n%s",
+			cmp.Diff(expectedStructTagMap, TestModelWriteOnlyTypeStructTagMap, nil))
+	}
+}
+
+type TestModelMapType struct {
+	Items map[string]*TestModelEmbedded
+}
+
+func TestStructTagMapMapValues(t *testing.T) {
+	// Fields of map value types share the map field's path.
+	expectedStructTagMap := StructTagMap{
+		"Items.Read": reflect.StructTag("visibility:\"read\""),
+	}
+
+	actualStructTagMap := NewStructTagMap[TestModelMapType]()
+
+	if !cmp.Equal(actualStructTagMap, expectedStructTagMap, nil) {
+		t.Errorf(
+			"StructTagMap had unexpected differences:\n%s",
+			cmp.Diff(expectedStructTagMap, actualStructTagMap, nil))
+	}
+
+	// A read-only field in a map value cannot be changed.
+	current := TestModelMapType{
+		Items: map[string]*TestModelEmbedded{
+			"one": {Read: Ptr("apple")},
+		},
+	}
+	v := TestModelMapType{
+		Items: map[string]*TestModelEmbedded{
+			"one": {Read: Ptr("banana")},
+		},
+	}
+	errorDetails := ValidateVisibility(v, current, actualStructTagMap, true)
+	if len(errorDetails) != 1 {
+		t.Errorf("Expected 1 error, got %d: %v", len(errorDetails), errorDetails)
+	}
+}
+
+func TestValidateVisibilityNilCurrent(t *testing.T) {
+	// Absent current values compare like zero values,
+	// so nested read-only fields are still checked.
+	tests := []struct {
+		name           string
+		v              any
+		w              any
+		m              StructTagMap
+		errorsExpected int
+	}{
+		{
+			name:           "Set read-only field under nil pointer is rejected",
+			v:              TestModelType{A: &TestModelSubtype{Read: Ptr("strawberry")}},
+			w:              TestModelType{},
+			m:              TestModelTypeStructTagMap,
+			errorsExpected: 1,
+		},
+		{
+			name:           "Set creatable field under nil pointer is accepted",
+			v:              TestModelType{A: &TestModelSubtype{ReadCreate: Ptr("apple")}},
+			w:              TestModelType{},
+			m:              TestModelTypeStructTagMap,
+			errorsExpected: 0,
+		},
+		{
+			name:           "Set fields under nil pointer with read-only visibility are rejected",
+			v:              TestModelType{B: &TestModelSubtype{Implicit: Ptr("cherry"), ReadCreate: Ptr("apple")}},
+			w:              TestModelType{},
+			m:              TestModelTypeStructTagMap,
+			errorsExpected: 1,
+		},
+		{
+			name:           "Set zero values under nil pointer is accepted",
+			v:              TestModelType{A: &TestModelSubtype{Read: Ptr("")}},
+			w:              TestModelType{},
+			m:              TestModelTypeStructTagMap,
+			errorsExpected: 0,
+		},
+		{
+			name: "Set read-only field in element of nil map is rejected",
+			v: TestModelMapType{
+				Items: map[string]*TestModelEmbedded{
+					"one": {Read: Ptr("apple")},
+				},
+			},
+			w:              TestModelMapType{},
+			m:              NewStructTagMap[TestModelMapType](),
+			errorsExpected: 1,
+		},
+		{
+			name: "Set read-only field in added map element is rejected",
+			v: TestModelMapType{
+				Items: map[string]*TestModelEmbedded{
+					"one": {Read: Ptr("apple")},
+					"two": {Read: Ptr("banana")},
+				},
+			},
+			w: TestModelMapType{
+				Items: map[string]*TestModelEmbedded{
+					"one": {Read: Ptr("apple")},
+				},
+			},
+			m:              NewStructTagMap[TestModelMapType](),
+			errorsExpected: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cloudErrors := ValidateVisibility(tt.v, tt.w, tt.m, false)
+			if len(cloudErrors) != tt.errorsExpected {
+				t.Errorf("Expected %d errors, got %d: %v", tt.errorsExpected, len(cloudErrors), cloudErrors)
+			}
+		})
+	}
+}
+
+func TestValidateVisibilityWriteOnly(t *testing.T) {
+	// Write-only fields are never present in the current value
+	// since it is always derived from a response representation.
+	current := TestModelWriteOnlyType{
+		TestModelEmbedded: TestModelEmbedded{
+			Read: Ptr("strawberry"),
+		},
+	}
+
+	tests := []struct {
+		name           string
+		v              any
+		updating       bool
+		errorsExpected int
+	}{
+		{
+			name:           "Create: Absent write-only fields are accepted",
+			v:              TestModelWriteOnlyType{},
+			updating:       false,
+			errorsExpected: 0,
+		},
+		{
+			name: "Create: Set write-only fields is accepted",
+			v: TestModelWriteOnlyType{
+				CreateUpdate: Ptr("apple"),
+				Create:       Ptr("pear"),
+			},
+			updating:       false,
+			errorsExpected: 0,
+		},
+		{
+			name:           "Update: Absent write-only fields are accepted",
+			v:              TestModelWriteOnlyType{},
+			updating:       true,
+			errorsExpected: 0,
+		},
+		{
+			name: "Update: Set updatable write-only field is accepted",
+			v: TestModelWriteOnlyType{
+				CreateUpdate: Ptr("apple"),
+			},
+			updating:       true,
+			errorsExpected: 0,
+		},
+		{
+			name: "Update: Set create-only write-only field is rejected",
+			v: TestModelWriteOnlyType{
+				Create: Ptr("pear"),
+			},
+			updating:       true,
+			errorsExpected: 1,
+		},
+		{
+			name: "Update: Set promoted read-only field to different value is rejected",
+			v: TestModelWriteOnlyType{
+				TestModelEmbedded: TestModelEmbedded{
+					Read: Ptr("pretzel"),
+				},
+			},
+			updating:       true,
+			errorsExpected: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cloudErrors := ValidateVisibility(tt.v, current, TestModelWriteOnlyTypeStructTagMap, tt.updating)
+			if len(cloudErrors) != tt.errorsExpected {
+				t.Errorf("Expected %d errors, got %d: %v", tt.errorsExpected, len(cloudErrors), cloudErrors)
+			}
+		})
+	}
+}
+
+func TestPreserveWriteOnly(t *testing.T) {
+	current := TestModelWriteOnlyType{
+		CreateUpdate: Ptr("apple"),
+		Create:       Ptr("pear"),
+		Items: []*TestModelWriteOnlyItem{
+			{Name: "one", Secret: "cherry"},
+			{Name: "two", Secret: "melon"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		v        TestModelWriteOnlyType
+		expected TestModelWriteOnlyType
+	}{
+		{
+			name: "Absent write-only fields keep current values",
+			v: TestModelWriteOnlyType{
+				Items: []*TestModelWriteOnlyItem{
+					{Name: "one"},
+					{Name: "two"},
+				},
+			},
+			expected: current,
+		},
+		{
+			name: "Present write-only fields replace current values",
+			v: TestModelWriteOnlyType{
+				CreateUpdate: Ptr("banana"),
+				Items: []*TestModelWriteOnlyItem{
+					{Name: "one", Secret: "peach"},
+					{Name: "two"},
+				},
+			},
+			expected: TestModelWriteOnlyType{
+				CreateUpdate: Ptr("banana"),
+				Create:       Ptr("pear"),
+				Items: []*TestModelWriteOnlyItem{
+					{Name: "one", Secret: "peach"},
+					{Name: "two", Secret: "melon"},
+				},
+			},
+		},
+		{
+			name: "Slice elements are matched by key when an element is removed",
+			v: TestModelWriteOnlyType{
+				Items: []*TestModelWriteOnlyItem{
+					{Name: "two"},
+				},
+			},
+			expected: TestModelWriteOnlyType{
+				CreateUpdate: Ptr("apple"),
+				Create:       Ptr("pear"),
+				Items: []*TestModelWriteOnlyItem{
+					{Name: "two", Secret: "melon"},
+				},
+			},
+		},
+		{
+			name: "Slice elements are matched by key when an element is added",
+			v: TestModelWriteOnlyType{
+				Items: []*TestModelWriteOnlyItem{
+					{Name: "three", Secret: "lemon"},
+					{Name: "two"},
+					{Name: "one"},
+				},
+			},
+			expected: TestModelWriteOnlyType{
+				CreateUpdate: Ptr("apple"),
+				Create:       Ptr("pear"),
+				Items: []*TestModelWriteOnlyItem{
+					{Name: "three", Secret: "lemon"},
+					{Name: "two", Secret: "melon"},
+					{Name: "one", Secret: "cherry"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			PreserveWriteOnly(&tt.v, &current, TestModelWriteOnlyTypeStructTagMap)
+			if !cmp.Equal(tt.v, tt.expected) {
+				t.Error(cmp.Diff(tt.expected, tt.v))
+			}
+		})
+	}
+}
+
+func TestClusterPreserveWriteOnlyResizedClients(t *testing.T) {
+	newClient := func(name, secret string) configv1.OIDCClientConfig {
+		return configv1.OIDCClientConfig{
+			ComponentNamespace: "openshift-console",
+			ComponentName:      name,
+			ClientID:           name,
+			ClientSecret:       configv1.SecretNameReference{Name: secret},
+		}
+	}
+	newCluster := func(clients ...configv1.OIDCClientConfig) *HCPOpenShiftCluster {
+		cluster := NewDefaultHCPOpenShiftCluster()
+		cluster.Properties.Spec.ExternalAuth.ExternalAuths = []*configv1.OIDCProvider{
+			{Name: "entra", OIDCClients: clients},
+		}
+		return cluster
+	}
+
+	current := newCluster(newClient("console", "console-secret"), newClient("cli", "cli-secret"))
+
+	// Adding a client in front of the stored
+	// ones must not shift their secrets.
+	cluster := newCluster(newClient("extra", "extra-secret"), newClient("console", ""), newClient("cli", ""))
+	cluster.PreserveWriteOnly(current)
+
+	expected := newCluster(newClient("extra", "extra-secret"), newClient("console", "console-secret"), newClient("cli", "cli-secret"))
+	if diff := cmp.Diff(expected, cluster); diff != "" {
+		t.Errorf("Unexpected cluster after adding a client (-want +got):\n%s", diff)
+	}
+
+	// Removing a client keeps the secrets of the others.
+	cluster = newCluster(newClient("cli", ""))
+	cluster.PreserveWriteOnly(current)
+
+	expected = newCluster(newClient("cli", "cli-secret"))
+	if diff := cmp.Diff(expected, cluster); diff != "" {
+		t.Errorf("Unexpected cluster after removing a client (-want +got):\n%s", diff)
+	}
+}
+
+func TestPreserveUnavailable(t *testing.T) {
+	// Simulate an older API version lacking the Items field.
+	structTagMap := NewStructTagMap[TestModelWriteOnlyType]()
+	structTagMap["Items"] = reflect.StructTag("visibility:\"\"")
+
+	current := TestModelWriteOnlyType{
+		CreateUpdate: Ptr("apple"),
+		Items: []*TestModelWriteOnlyItem{
+			{Name: "one", Secret: "cherry"},
+		},
+	}
+
+	v := TestModelWriteOnlyType{
+		CreateUpdate: Ptr("banana"),
+	}
+
+	expected := TestModelWriteOnlyType{
+		CreateUpdate: Ptr("banana"),
+		Items: []*TestModelWriteOnlyItem{
+			{Name: "one", Secret: "cherry"},
+		},
+	}
+
+	PreserveUnavailable(&v, &current, structTagMap)
+	if !cmp.Equal(v, expected) {
+		t.Error(cmp.Diff(expected, v))
+	}
+}
+
+func TestRedactWriteOnly(t *testing.T) {
+	v := TestModelWriteOnlyType{
+		TestModelEmbedded: TestModelEmbedded{
+			Read: Ptr("strawberry"),
+		},
+		CreateUpdate: Ptr("apple"),
+		Items: []*TestModelWriteOnlyItem{
+			{Name: "one", Secret: "cherry"},
+			{Name: "two"},
+		},
+	}
+
+	expected := TestModelWriteOnlyType{
+		TestModelEmbedded: TestModelEmbedded{
+			Read: Ptr("strawberry"),
+		},
+		CreateUpdate: Ptr(RedactedValue),
+		Items: []*TestModelWriteOnlyItem{
+			{Name: "one", Secret: RedactedValue},
+			{Name: "two"},
+		},
+	}
+
+	RedactWriteOnly(&v, TestModelWriteOnlyTypeStructTagMap)
+	if !cmp.Equal(v, expected) {
+		t.Error(cmp.Diff(expected, v))
+	}
+}